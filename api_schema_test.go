@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -140,6 +144,43 @@ func TestRepositoryStructure(t *testing.T) {
 	}
 }
 
+// TestRepositoryLegacyTokenMigration проверяет чтение устаревшего ключа "token" и перезапись конфигурации
+func TestRepositoryLegacyTokenMigration(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	configPath := filepath.Join(home, ".criage", "config.json")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `{"repositories": [
+		{"name": "old", "url": "https://old.example.com", "priority": 1, "enabled": true, "token": "legacy-token"},
+		{"name": "new", "url": "https://new.example.com", "priority": 2, "enabled": true, "token": "stale", "auth_token": "current"}
+	]}`
+	if err := os.WriteFile(configPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if config.Repositories[0].AuthToken != "legacy-token" {
+		t.Errorf("expected legacy token to be migrated, got %q", config.Repositories[0].AuthToken)
+	}
+	if config.Repositories[1].AuthToken != "current" {
+		t.Errorf("auth_token must take precedence over token, got %q", config.Repositories[1].AuthToken)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"token"`) || !strings.Contains(string(data), `"auth_token": "legacy-token"`) {
+		t.Errorf("expected config to be rewritten with auth_token:\n%s", data)
+	}
+}
+
 // TestPackageListResponseStructure проверяет новую структуру для списка пакетов
 func TestPackageListResponseStructure(t *testing.T) {
 	packageList := PackageListResponse{
@@ -288,32 +329,52 @@ func TestRateLimiterFunctionality(t *testing.T) {
 	}
 }
 
-// BenchmarkRateLimiter бенчмарк для rate limiter
+// BenchmarkRateLimiter бенчмарк для rate limiter: установившаяся частота и
+// накладные расходы на выдачу разрешений из запаса
 func BenchmarkRateLimiter(b *testing.B) {
-	rl := NewRateLimiter(1000) // 1000 запросов в секунду
-	defer rl.Close()
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		rl.Wait()
-	}
+	b.Run("steady", func(b *testing.B) {
+		rl := NewRateLimiter(1000) // 1000 запросов в секунду
+		defer rl.Close()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			rl.Wait()
+		}
+	})
+
+	b.Run("burst", func(b *testing.B) {
+		// Запас покрывает все итерации, поэтому Wait не ждет
+		rl := NewBurstRateLimiter(1000, b.N)
+		defer rl.Close()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			rl.Wait()
+		}
+	})
 }
 
 // TestNewApiEndpoints проверяет новые эндпоинты API
 func TestNewApiEndpoints(t *testing.T) {
-	pm, err := NewPackageManager()
+	dir := t.TempDir()
+	config := defaultConfig(dir)
+	// Локальная область по умолчанию — ./criage_modules в текущем каталоге
+	config.LocalPath = filepath.Join(dir, "criage_modules")
+	config.Repositories = nil
+	pm, err := NewPackageManagerWithConfig(config, nil)
 	if err != nil {
-		t.Skipf("Failed to create PackageManager: %v", err)
+		t.Fatalf("Failed to create PackageManager: %v", err)
 	}
+	defer pm.Close()
 
 	// Проверяем, что методы существуют (компиляция пройдет только если методы определены)
 	// Вызываем методы с пустыми параметрами для проверки их наличия
-	_, err = pm.ListRepositoryPackages("", 1, 10)
+	_, err = pm.ListRepositoryPackages(context.Background(), "", 1, 10)
 	if err == nil {
 		t.Log("ListRepositoryPackages method is available")
 	}
 
-	_, err = pm.GetPackageVersionInfo("", "", "")
+	_, err = pm.GetPackageVersionInfo(context.Background(), "", "", "")
 	if err == nil {
 		t.Log("GetPackageVersionInfo method is available")
 	}
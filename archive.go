@@ -0,0 +1,584 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bzip2enc "github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Поддерживаемые форматы архивов
+const (
+	FormatCriage = "criage"
+	FormatTarZst = "tar.zst"
+	FormatTarGz  = "tar.gz"
+	FormatTarXz  = "tar.xz"
+	FormatTarBz2 = "tar.bz2"
+	FormatZip    = "zip"
+)
+
+// supportedFormats форматы, для которых реализованы упаковка и извлечение
+var supportedFormats = []string{FormatTarGz, FormatTarZst, FormatTarXz, FormatTarBz2, FormatZip, FormatCriage}
+
+// compressionCodecs алгоритмы сжатия форматов из supportedFormats и диапазоны их уровней
+var compressionCodecs = []CompressionCodec{
+	{Name: "gzip", Formats: []string{FormatTarGz}, MinLevel: gzip.BestSpeed, MaxLevel: gzip.BestCompression, DefaultLevel: 6},
+	{Name: "zstd", Formats: []string{FormatTarZst, FormatCriage}, MinLevel: 1, MaxLevel: 22, DefaultLevel: 3},
+	{Name: "xz", Formats: []string{FormatTarXz}, MinLevel: 0, MaxLevel: 9, DefaultLevel: 6},
+	{Name: "bzip2", Formats: []string{FormatTarBz2}, MinLevel: 1, MaxLevel: 9, DefaultLevel: 9},
+	{Name: "deflate", Formats: []string{FormatZip}, MinLevel: flate.BestSpeed, MaxLevel: flate.BestCompression, DefaultLevel: 6},
+}
+
+// xzDictionarySizes размер словаря LZMA2 для уровней xz 0-9, как у пресетов утилиты xz
+var xzDictionarySizes = []int{256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20, 8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20}
+
+// compressionCodecFor возвращает алгоритм сжатия формата
+func compressionCodecFor(format string) (CompressionCodec, bool) {
+	for _, codec := range compressionCodecs {
+		if containsString(codec.Formats, format) {
+			return codec, true
+		}
+	}
+	return CompressionCodec{}, false
+}
+
+// recommendedCompressionLevel возвращает рекомендуемый уровень сжатия формата (0 для неизвестного формата)
+func recommendedCompressionLevel(format string) int {
+	codec, _ := compressionCodecFor(format)
+	return codec.DefaultLevel
+}
+
+// validateCompressionLevel проверяет формат и уровень сжатия по диапазону алгоритма формата
+func validateCompressionLevel(format string, level int) error {
+	codec, ok := compressionCodecFor(format)
+	if !ok {
+		return unsupportedFormatError(format)
+	}
+	if level < codec.MinLevel || level > codec.MaxLevel {
+		return fmt.Errorf("уровень сжатия %d недопустим для формата %s (%s): допустимо от %d до %d", level, format, codec.Name, codec.MinLevel, codec.MaxLevel)
+	}
+	return nil
+}
+
+// unsupportedFormatError сообщает о неподдерживаемом формате и перечисляет доступные
+func unsupportedFormatError(format string) error {
+	return fmt.Errorf("формат '%s' не поддерживается; поддерживаются: %s", format, strings.Join(supportedFormats, ", "))
+}
+
+// archiveMetadataName имя служебной записи с метаданными внутри архива
+const archiveMetadataName = ".criage-metadata.json"
+
+// manifestFileName имя файла манифеста пакета
+const manifestFileName = "criage.yaml"
+
+// archiveExtensions расширения файлов для каждого формата (в порядке проверки)
+var archiveExtensions = []struct {
+	ext    string
+	format string
+}{
+	{".criage", FormatCriage},
+	{".tar.zst", FormatTarZst},
+	{".tzst", FormatTarZst},
+	{".tar.gz", FormatTarGz},
+	{".tgz", FormatTarGz},
+	{".tar.xz", FormatTarXz},
+	{".txz", FormatTarXz},
+	{".tar.bz2", FormatTarBz2},
+	{".tbz2", FormatTarBz2},
+	{".tbz", FormatTarBz2},
+	{".zip", FormatZip},
+}
+
+// archiveMagics сигнатуры сжатых потоков для определения формата по содержимому.
+// Архивы criage отличаются от tar.zst только метаданными и читаются так же;
+// zip распознается и по сигнатуре пустого архива (конец каталога).
+var archiveMagics = []struct {
+	magic  []byte
+	format string
+}{
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, FormatTarZst},
+	{[]byte{0x1f, 0x8b}, FormatTarGz},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, FormatTarXz},
+	{[]byte("BZh"), FormatTarBz2},
+	{[]byte("PK\x03\x04"), FormatZip},
+	{[]byte("PK\x05\x06"), FormatZip},
+}
+
+// detectArchiveFormat определяет формат архива по расширению файла
+func detectArchiveFormat(path string) (string, error) {
+	lower := strings.ToLower(path)
+	for _, e := range archiveExtensions {
+		if strings.HasSuffix(lower, e.ext) {
+			return e.format, nil
+		}
+	}
+	return "", fmt.Errorf("не удалось определить формат архива: %s; поддерживаются: %s", filepath.Base(path), strings.Join(supportedFormats, ", "))
+}
+
+// sniffArchiveFormat определяет формат архива по сигнатуре в начале файла
+func sniffArchiveFormat(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header := make([]byte, 6)
+	n, _ := io.ReadFull(file, header)
+	for _, m := range archiveMagics {
+		if bytes.HasPrefix(header[:n], m.magic) {
+			return m.format, nil
+		}
+	}
+	return "", fmt.Errorf("неизвестная сигнатура архива: %s", filepath.Base(path))
+}
+
+// archiveFormat определяет формат архива по сигнатуре, а если она не распознана — по расширению.
+// Расширение сохраняется, когда оно согласуется с содержимым (criage и tar.zst сжаты одинаково).
+func archiveFormat(path string) (string, error) {
+	format, err := detectArchiveFormat(path)
+	sniffed, sniffErr := sniffArchiveFormat(path)
+	if sniffErr != nil {
+		return format, err
+	}
+	if err != nil {
+		return sniffed, nil
+	}
+
+	extCodec, _ := compressionCodecFor(format)
+	sniffedCodec, _ := compressionCodecFor(sniffed)
+	if extCodec.Name == sniffedCodec.Name {
+		return format, nil
+	}
+	logger.Warnf("Расширение %s не соответствует содержимому: архив в формате %s", filepath.Base(path), sniffed)
+	return sniffed, nil
+}
+
+// archiveExtension возвращает каноническое расширение для формата
+func archiveExtension(format string) string {
+	return "." + format
+}
+
+// createArchive упаковывает содержимое srcDir в архив заданного формата
+func (pm *PackageManager) createArchive(srcDir, outputPath, format string, compressionLevel int) error {
+	if err := validateCompressionLevel(format, compressionLevel); err != nil {
+		return err
+	}
+
+	absOutput, err := filepath.Abs(outputPath)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	compressor, err := newCompressor(out, format, compressionLevel)
+	if err != nil {
+		os.Remove(outputPath)
+		return err
+	}
+
+	tw := tar.NewWriter(compressor)
+
+	// Форматы criage и zip хранят метаданные отдельной записью в начале архива
+	if format == FormatCriage || format == FormatZip {
+		if err := writeArchiveMetadata(tw, srcDir, absOutput, format); err != nil {
+			compressor.Close()
+			os.Remove(outputPath)
+			return err
+		}
+	}
+
+	err = filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Не упаковываем сам выходной архив
+		if absPath, err := filepath.Abs(path); err == nil && absPath == absOutput {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if closeErr := compressor.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return err
+	}
+
+	return nil
+}
+
+// writeArchiveMetadata записывает метаданные архива служебной записью, включая
+// контрольные суммы всех файлов srcDir, кроме самого архива absOutput
+func writeArchiveMetadata(tw *tar.Writer, srcDir, absOutput, format string) error {
+	checksums, err := contentChecksums(srcDir, absOutput)
+	if err != nil {
+		return fmt.Errorf("ошибка вычисления контрольных сумм: %w", err)
+	}
+
+	metadata := ArchiveMetadata{
+		CompressionType: format,
+		CreatedAt:       time.Now().Format(time.RFC3339),
+		CreatedBy:       fmt.Sprintf("%s/%s", ServerName, ServerVersion),
+		Checksums:       checksums,
+	}
+
+	manifestPath := filepath.Join(srcDir, manifestFileName)
+	if data, err := os.ReadFile(manifestPath); err == nil {
+		var manifest PackageManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return fmt.Errorf("ошибка разбора манифеста: %w", err)
+		}
+		metadata.PackageManifest = &manifest
+	}
+
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    archiveMetadataName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = tw.Write(data)
+	return err
+}
+
+// contentChecksums вычисляет контрольные суммы обычных файлов каталога по путям внутри архива
+func contentChecksums(srcDir, absOutput string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		if absPath, err := filepath.Abs(path); err == nil && absPath == absOutput {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		checksum, err := calculateChecksum(path, defaultChecksumAlgorithm)
+		if err != nil {
+			return err
+		}
+		checksums[filepath.ToSlash(relPath)] = checksum
+		return nil
+	})
+	return checksums, err
+}
+
+// verifyArchiveContents читает метаданные архива и сверяет содержимое файлов с записанными
+// в них контрольными суммами. Возвращает метаданные или nil, если архив их не содержит.
+func verifyArchiveContents(archivePath string) (*ArchiveMetadata, error) {
+	tr, closer, err := openArchive(archivePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closer()
+
+	var metadata *ArchiveMetadata
+	seen := make(map[string]bool)
+	for first := true; ; first = false {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения архива: %w", err)
+		}
+
+		// Метаданные записываются первой записью архива
+		if first && header.Name == archiveMetadataName {
+			metadata = &ArchiveMetadata{}
+			if err := json.NewDecoder(tr).Decode(metadata); err != nil {
+				return nil, fmt.Errorf("ошибка разбора метаданных архива: %w", err)
+			}
+			continue
+		}
+		if metadata == nil || len(metadata.Checksums) == 0 {
+			return metadata, nil
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		expected, ok := metadata.Checksums[header.Name]
+		if !ok {
+			return nil, errorWithCause(ErrChecksumMismatch, "файл %s отсутствует в контрольных суммах архива", header.Name)
+		}
+		algorithm, _ := parseChecksum(expected)
+		actual, err := hashChecksum(tr, algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка проверки %s: %w", header.Name, err)
+		}
+		if actual != normalizeChecksum(expected) {
+			return nil, errorWithCause(ErrChecksumMismatch, "контрольная сумма %s не совпадает: ожидалось %s, получено %s", header.Name, expected, actual)
+		}
+		seen[header.Name] = true
+	}
+
+	if metadata != nil {
+		for _, name := range sortedKeys(metadata.Checksums) {
+			if !seen[name] {
+				return nil, errorWithCause(ErrChecksumMismatch, "файл %s указан в контрольных суммах, но отсутствует в архиве", name)
+			}
+		}
+	}
+	return metadata, nil
+}
+
+// newCompressor создает потоковый компрессор для формата
+func newCompressor(w io.Writer, format string, level int) (io.WriteCloser, error) {
+	switch format {
+	case FormatCriage, FormatTarZst:
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	case FormatTarGz:
+		return gzip.NewWriterLevel(w, level)
+	case FormatTarXz:
+		return xz.WriterConfig{DictCap: xzDictionarySizes[level]}.NewWriter(w)
+	case FormatTarBz2:
+		// Стандартная библиотека умеет только распаковывать bzip2
+		return bzip2enc.NewWriter(w, &bzip2enc.WriterConfig{Level: level})
+	case FormatZip:
+		return newZipWriter(w, level), nil
+	default:
+		return nil, unsupportedFormatError(format)
+	}
+}
+
+// newDecompressor создает потоковый декомпрессор для формата
+func newDecompressor(r io.Reader, format string) (io.ReadCloser, error) {
+	switch format {
+	case FormatCriage, FormatTarZst:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case FormatTarGz:
+		return gzip.NewReader(r)
+	case FormatTarXz:
+		decoder, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(decoder), nil
+	case FormatTarBz2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	default:
+		return nil, unsupportedFormatError(format)
+	}
+}
+
+// openArchive открывает архив и возвращает tar reader поверх декомпрессора.
+// Если задан report, по мере чтения архива сообщается о прогрессе.
+func openArchive(archivePath string, report ProgressFunc) (*tar.Reader, func(), error) {
+	format, err := archiveFormat(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var size int64
+	if stat, err := file.Stat(); err == nil {
+		size = stat.Size()
+	}
+
+	// Каталог zip находится в конце файла, поэтому zip читается с произвольным доступом, без отчета о прогрессе
+	var decompressor io.ReadCloser
+	if format == FormatZip {
+		decompressor, err = zipAsTar(file, size)
+	} else {
+		decompressor, err = newDecompressor(newProgressReader(file, size, "Извлечение", report), format)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("ошибка чтения архива: %w", err)
+	}
+
+	closer := func() {
+		decompressor.Close()
+		file.Close()
+	}
+
+	return tar.NewReader(decompressor), closer, nil
+}
+
+// extractArchive извлекает архив в destPath. Символические ссылки обрабатываются
+// после файлов согласно symlink_policy; возвращаются имена пропущенных ссылок.
+func (pm *PackageManager) extractArchive(ctx context.Context, archivePath, destPath string) ([]string, error) {
+	policy, err := pm.symlinkPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	tr, closer, err := openArchive(archivePath, progressFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer closer()
+
+	if err := os.MkdirAll(destPath, 0755); err != nil {
+		return nil, err
+	}
+
+	var links []pendingSymlink
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения архива: %w", err)
+		}
+
+		if header.Name == archiveMetadataName {
+			continue
+		}
+
+		target, err := safeJoin(destPath, header.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, target, os.FileMode(header.Mode).Perm()); err != nil {
+				return nil, err
+			}
+		case tar.TypeSymlink:
+			if filepath.IsAbs(header.Linkname) {
+				return nil, fmt.Errorf("недопустимая символическая ссылка: %s -> %s", header.Name, header.Linkname)
+			}
+			if _, err := safeJoin(destPath, filepath.Join(filepath.Dir(header.Name), header.Linkname)); err != nil {
+				return nil, err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, err
+			}
+			links = append(links, pendingSymlink{name: header.Name, target: target, linkname: header.Linkname})
+		}
+	}
+
+	return applySymlinks(destPath, links, policy)
+}
+
+// extractFile записывает содержимое текущей записи архива в файл
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	if mode == 0 {
+		mode = 0644
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Права из заголовка tar устанавливаются явно: OpenFile учитывает umask
+	// и не меняет права уже существующего файла
+	if err := file.Chmod(mode); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, r)
+	return err
+}
+
+// safeJoin объединяет путь внутри архива с базовой директорией, не допуская выхода за ее пределы
+func safeJoin(baseDir, name string) (string, error) {
+	target := filepath.Join(baseDir, filepath.FromSlash(name))
+	rel, err := filepath.Rel(baseDir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("недопустимый путь в архиве: %s", name)
+	}
+	return target, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic записывает файл через временный файл в том же каталоге и
+// переименование, чтобы при сбое на диске оставалась прежняя или новая версия целиком
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicFunc(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicFunc атомарно заменяет файл содержимым, которое записывает write.
+// Права существующего файла сохраняются, perm задает права нового файла.
+// При любой ошибке временный файл удаляется, а исходный файл не меняется.
+func writeFileAtomicFunc(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := temp.Name()
+	defer os.Remove(tempPath)
+
+	if err := write(temp); err != nil {
+		temp.Close()
+		return err
+	}
+	// Данные должны оказаться на диске до переименования, иначе после сбоя
+	// на месте файла может остаться пустой файл
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tempPath, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, path)
+}
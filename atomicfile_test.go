@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestWriteFileAtomic проверяет, что ошибка во время записи не затрагивает исходный файл
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	original := []byte(`{"timeout": 30}`)
+	if err := os.WriteFile(path, original, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	writeErr := errors.New("disk full")
+	err := writeFileAtomicFunc(path, 0644, func(w io.Writer) error {
+		// Часть данных уже записана, когда происходит сбой
+		if _, err := w.Write([]byte(`{"time`)); err != nil {
+			return err
+		}
+		return writeErr
+	})
+	if !errors.Is(err, writeErr) {
+		t.Fatalf("expected write error, got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != string(original) {
+		t.Errorf("original file was modified: %q (err %v)", data, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected temporary file to be removed, got %d entries", len(entries))
+	}
+
+	// Успешная запись заменяет содержимое целиком и сохраняет права существующего файла
+	if err := writeFileAtomic(path, []byte(`{"timeout": 60}`), 0600); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != `{"timeout": 60}` {
+		t.Errorf("unexpected content after write: %q", data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0644 {
+		t.Errorf("expected existing mode 0644 to be kept, got %v", info.Mode().Perm())
+	}
+
+	// Новый файл создается с переданными правами
+	created := filepath.Join(dir, "new.json")
+	if err := writeFileAtomic(created, []byte(`{}`), 0600); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	if info, err := os.Stat(created); err != nil {
+		t.Fatalf("Stat: %v", err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 for new file, got %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Важность замечаний проверки окружения
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// severityRank задает порядок вывода: сначала ошибки
+var severityRank = map[string]int{
+	SeverityError:   0,
+	SeverityWarning: 1,
+	SeverityInfo:    2,
+}
+
+// packagesFileName файл со списком установленных пакетов в каталоге установки
+const packagesFileName = "packages.json"
+
+// configuredPath каталог из конфигурации с именем его параметра
+type configuredPath struct {
+	name, path string
+}
+
+// configuredPaths возвращает рабочие каталоги из конфигурации
+func (pm *PackageManager) configuredPaths() []configuredPath {
+	return []configuredPath{
+		{"global_path", pm.getConfig().GlobalPath},
+		{"local_path", pm.getConfig().LocalPath},
+		{"cache_path", pm.getConfig().CachePath},
+		{"temp_path", pm.getConfig().TempPath},
+	}
+}
+
+// checkWritableDir проверяет, что каталог существует и доступен для записи
+func checkWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("каталог %s недоступен: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s не является каталогом", dir)
+	}
+
+	file, err := os.CreateTemp(dir, ".criage-write-check-*")
+	if err != nil {
+		return fmt.Errorf("каталог %s недоступен для записи: %w", dir, err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	return nil
+}
+
+// pingRepository проверяет, что репозиторий отвечает на запросы, и возвращает код ответа
+// и задержку от установки соединения до первого байта ответа (без ожидания rate limiter)
+func (pm *PackageManager) pingRepository(ctx context.Context, repo Repository) (status int, latency time.Duration, err error) {
+	var started, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		// При повторе после 429 отсчет начинается заново
+		GetConn:              func(string) { started = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), "GET", fmt.Sprintf("%s/api/v1/", repo.URL), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	resp, err := pm.doRequest(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if !started.IsZero() && firstByte.After(started) {
+		latency = firstByte.Sub(started)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return resp.StatusCode, latency, fmt.Errorf("ошибка сервера: %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, latency, nil
+}
+
+// VerifyAll проверяет целостность всех установленных пакетов
+func (pm *PackageManager) VerifyAll() []*VerifyResult {
+	pm.packagesMutex.RLock()
+	names := make([]string, 0, len(pm.installedPackages))
+	for name := range pm.installedPackages {
+		names = append(names, name)
+	}
+	pm.packagesMutex.RUnlock()
+	sort.Strings(names)
+
+	var results []*VerifyResult
+	for _, name := range names {
+		if result, err := pm.VerifyPackage(name); err == nil {
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// FindOrphans возвращает каталоги в путях установки, не принадлежащие ни одному установленному пакету
+func (pm *PackageManager) FindOrphans() ([]string, error) {
+	owned := make(map[string]bool)
+	pm.packagesMutex.RLock()
+	for _, info := range pm.installedPackages {
+		owned[filepath.Clean(info.InstallPath)] = true
+	}
+	pm.packagesMutex.RUnlock()
+
+	var orphans []string
+	for _, root := range []string{pm.getConfig().GlobalPath, pm.getConfig().LocalPath} {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("ошибка чтения %s: %w", root, err)
+		}
+
+		for _, entry := range entries {
+			path := filepath.Clean(filepath.Join(root, entry.Name()))
+			if entry.IsDir() && entry.Name() != locksDirName && entry.Name() != configBackupDirName && !owned[path] {
+				orphans = append(orphans, path)
+			}
+		}
+	}
+
+	sort.Strings(orphans)
+	return orphans, nil
+}
+
+// LicenseReport группирует установленные пакеты по лицензиям; пакеты без лицензии
+// попадают в группу с пустым ключом
+func (pm *PackageManager) LicenseReport() map[string][]string {
+	pm.packagesMutex.RLock()
+	defer pm.packagesMutex.RUnlock()
+
+	report := make(map[string][]string)
+	for _, info := range pm.installedPackages {
+		report[info.License] = append(report[info.License], info.Name)
+	}
+	for license := range report {
+		sort.Strings(report[license])
+	}
+
+	return report
+}
+
+// AuditEnvironment выполняет все проверки окружения параллельно и собирает замечания
+// в один отчет, упорядоченный по важности
+func (pm *PackageManager) AuditEnvironment(ctx context.Context) (*AuditReport, error) {
+	checks := []func() ([]AuditFinding, error){
+		func() ([]AuditFinding, error) {
+			var findings []AuditFinding
+			for _, diagnostic := range pm.Doctor(ctx).Diagnostics {
+				if severity, ok := diagnosticSeverity[diagnostic.Status]; ok {
+					findings = append(findings, AuditFinding{Check: "doctor", Severity: severity, Subject: diagnostic.Subject, Message: diagnostic.Message})
+				}
+			}
+			return findings, nil
+		},
+		func() ([]AuditFinding, error) {
+			var findings []AuditFinding
+			for _, result := range pm.VerifyAll() {
+				if result.OK {
+					continue
+				}
+				message := "пакет поврежден"
+				switch {
+				case len(result.MissingFiles) > 0:
+					message = fmt.Sprintf("отсутствуют файлы: %d", len(result.MissingFiles))
+				case !result.ManifestPresent:
+					message = "отсутствует манифест"
+				case result.ActualSize != result.ExpectedSize:
+					message = fmt.Sprintf("размер %s вместо %s", formatSize(result.ActualSize), formatSize(result.ExpectedSize))
+				}
+				findings = append(findings, AuditFinding{Check: "verify_all", Severity: SeverityError, Subject: result.Name, Message: message})
+			}
+			return findings, nil
+		},
+		func() ([]AuditFinding, error) {
+			var findings []AuditFinding
+			for _, global := range []bool{false, true} {
+				outdated, err := pm.ListPackages(ctx, global, true)
+				if err != nil {
+					return nil, err
+				}
+				for _, pkg := range outdated {
+					findings = append(findings, AuditFinding{
+						Check:    "outdated_packages",
+						Severity: SeverityInfo,
+						Subject:  pkg.Name,
+						Message:  fmt.Sprintf("доступно обновление %s → %s", pkg.Version, pkg.AvailableVersion),
+					})
+				}
+			}
+			return findings, nil
+		},
+		func() ([]AuditFinding, error) {
+			orphans, err := pm.FindOrphans()
+			if err != nil {
+				return nil, err
+			}
+			var findings []AuditFinding
+			for _, path := range orphans {
+				findings = append(findings, AuditFinding{Check: "find_orphans", Severity: SeverityWarning, Subject: path, Message: "каталог не принадлежит установленному пакету"})
+			}
+			return findings, nil
+		},
+		func() ([]AuditFinding, error) {
+			var findings []AuditFinding
+			for license, packages := range pm.LicenseReport() {
+				for _, name := range packages {
+					if license == "" {
+						findings = append(findings, AuditFinding{Check: "license_report", Severity: SeverityWarning, Subject: name, Message: "лицензия не указана"})
+					} else {
+						findings = append(findings, AuditFinding{Check: "license_report", Severity: SeverityInfo, Subject: name, Message: "лицензия " + license})
+					}
+				}
+			}
+			return findings, nil
+		},
+	}
+
+	results := make([][]AuditFinding, len(checks))
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check func() ([]AuditFinding, error)) {
+			defer wg.Done()
+			results[i], errs[i] = check()
+		}(i, check)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	report := &AuditReport{}
+	for i := range checks {
+		// Сбой отдельной проверки попадает в отчет, а не прерывает аудит
+		if errs[i] != nil {
+			report.Findings = append(report.Findings, AuditFinding{Check: "audit", Severity: SeverityError, Message: errs[i].Error()})
+			continue
+		}
+		report.Findings = append(report.Findings, results[i]...)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] < severityRank[b.Severity]
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		return a.Subject < b.Subject
+	})
+
+	for _, finding := range report.Findings {
+		switch finding.Severity {
+		case SeverityError:
+			report.Errors++
+		case SeverityWarning:
+			report.Warnings++
+		default:
+			report.Info++
+		}
+	}
+
+	return report, nil
+}
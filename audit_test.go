@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAuditEnvironment проверяет, что сводный отчет содержит замечания каждой проверки
+func TestAuditEnvironment(t *testing.T) {
+	pm := newTestPackageManager(t)
+
+	// Поврежденный пакет без лицензии
+	installTestArchive(t, pm, PackageManifest{Name: "damaged", Version: "1.0.0", Files: []string{"src/main.txt"}}, false)
+	if err := os.Remove(filepath.Join(pm.getConfig().LocalPath, "damaged", "src", "main.txt")); err != nil {
+		t.Fatal(err)
+	}
+	// Устаревший пакет с лицензией
+	installTestArchive(t, pm, PackageManifest{Name: "stale", Version: "1.0.0", License: "MIT"}, false)
+	// Каталог, не принадлежащий ни одному пакету
+	if err := os.MkdirAll(filepath.Join(pm.getConfig().LocalPath, "leftover"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newMockRepository(t, &RepositoryPackage{Name: "stale", Versions: []RepositoryVersion{{Version: "1.1.0"}}})
+	unreachable := newMockRepository(t)
+	unreachable.Close()
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1), unreachable.repository("offline", 2)}
+
+	report, err := pm.AuditEnvironment(context.Background())
+	if err != nil {
+		t.Fatalf("AuditEnvironment: %v", err)
+	}
+
+	expected := []AuditFinding{
+		{Check: "doctor", Severity: SeverityWarning, Subject: "offline"},
+		{Check: "verify_all", Severity: SeverityError, Subject: "damaged"},
+		{Check: "outdated_packages", Severity: SeverityInfo, Subject: "stale"},
+		{Check: "find_orphans", Severity: SeverityWarning, Subject: filepath.Join(pm.getConfig().LocalPath, "leftover")},
+		{Check: "license_report", Severity: SeverityWarning, Subject: "damaged"},
+		{Check: "license_report", Severity: SeverityInfo, Subject: "stale"},
+	}
+	for _, want := range expected {
+		found := false
+		for _, got := range report.Findings {
+			if got.Check == want.Check && got.Severity == want.Severity && got.Subject == want.Subject {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing finding %+v in report %+v", want, report.Findings)
+		}
+	}
+
+	if report.Errors == 0 || report.Findings[0].Severity != SeverityError {
+		t.Errorf("expected errors to be listed first, got %+v", report.Findings)
+	}
+	for i := 1; i < len(report.Findings); i++ {
+		if severityRank[report.Findings[i-1].Severity] > severityRank[report.Findings[i].Severity] {
+			t.Errorf("findings are not ordered by severity: %+v", report.Findings)
+			break
+		}
+	}
+
+	s := newTestServer(t, pm)
+	result, err := s.callTool(context.Background(), "audit_environment", nil)
+	if err != nil {
+		t.Fatalf("audit_environment failed: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "[verify_all] damaged") {
+		t.Errorf("unexpected tool output:\n%s", result.Content[0].Text)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import "fmt"
+
+// Autoremove удаляет пакеты, установленные автоматически как зависимости, которые
+// больше не нужны ни одному пакету, установленному пользователем. Набор ненужных
+// пакетов пересчитывается после каждого удаления. С dryRun пакеты только перечисляются.
+func (pm *PackageManager) Autoremove(dryRun bool) ([]*PackageInfo, error) {
+	if dryRun {
+		return pm.orphanedPackages(), nil
+	}
+
+	removed := []*PackageInfo{}
+	for {
+		orphans := pm.orphanedPackages()
+		if len(orphans) == 0 {
+			return removed, nil
+		}
+
+		orphan := orphans[0]
+		if err := pm.UninstallPackage(orphan.Name, orphan.Global, false); err != nil {
+			return removed, fmt.Errorf("ошибка удаления %s: %w", orphan.Name, err)
+		}
+		logger.Infof("Пакет %s (%s) удален как ненужная зависимость", orphan.Name, orphan.Version)
+		removed = append(removed, orphan)
+	}
+}
+
+// orphanedPackages возвращает автоматически установленные пакеты, недостижимые по
+// зависимостям ни от одного пакета, установленного пользователем
+func (pm *PackageManager) orphanedPackages() []*PackageInfo {
+	packages := pm.InstalledPackages()
+	byName := make(map[string]*PackageInfo, len(packages))
+	var queue []string
+	for _, pkg := range packages {
+		byName[pkg.Name] = pkg
+		if !pkg.Automatic {
+			queue = append(queue, pkg.Name)
+		}
+	}
+
+	required := make(map[string]bool)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if required[name] {
+			continue
+		}
+		required[name] = true
+
+		if pkg, ok := byName[name]; ok {
+			queue = append(queue, sortedKeys(pkg.Dependencies)...)
+		}
+	}
+
+	orphans := []*PackageInfo{}
+	for _, pkg := range packages {
+		if pkg.Automatic && !required[pkg.Name] {
+			orphans = append(orphans, pkg)
+		}
+	}
+	return orphans
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAutoremove проверяет, что после удаления пакета верхнего уровня его цепочка
+// автоматических зависимостей удаляется, а нужная другому пакету зависимость остается
+func TestAutoremove(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	for name, deps := range map[string]map[string]string{
+		"app":    {"http": "^1.0.0", "json": "*"},
+		"http":   {"log": "^1.0.0"},
+		"log":    nil,
+		"json":   nil,
+		"report": {"json": "^1.0.0"},
+	} {
+		repo.publish(t, name, "1.0.0", buildTestArchive(t, pm, PackageManifest{Name: name, Version: "1.0.0", Dependencies: deps}, nil, FormatTarGz))
+		repo.setDependencies(name, "1.0.0", deps)
+	}
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	for _, name := range []string{"app", "report"} {
+		if _, err := pm.InstallPackage(ctx, name, "", false, false, false, false, "", ""); err != nil {
+			t.Fatalf("InstallPackage(%s): %v", name, err)
+		}
+	}
+	if removed, err := pm.Autoremove(false); err != nil || len(removed) != 0 {
+		t.Fatalf("nothing must be removed while app is installed, got %v, %v", removed, err)
+	}
+
+	if err := pm.UninstallPackage("app", false, false); err != nil {
+		t.Fatalf("UninstallPackage: %v", err)
+	}
+
+	preview, err := pm.Autoremove(true)
+	if err != nil {
+		t.Fatalf("Autoremove dry run: %v", err)
+	}
+	if names := packageNames(preview); len(names) != 2 || names[0] != "http" || names[1] != "log" {
+		t.Errorf("expected http and log in preview, got %v", names)
+	}
+	if _, exists := pm.getInstalledPackage("http"); !exists {
+		t.Fatal("dry run must not remove packages")
+	}
+
+	removed, err := pm.Autoremove(false)
+	if err != nil {
+		t.Fatalf("Autoremove: %v", err)
+	}
+	if names := packageNames(removed); len(names) != 2 || names[0] != "http" || names[1] != "log" {
+		t.Errorf("expected http and log to be removed, got %v", names)
+	}
+	for _, name := range []string{"http", "log"} {
+		if _, exists := pm.getInstalledPackage(name); exists {
+			t.Errorf("%s must be removed", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(pm.getConfig().LocalPath, "log")); !os.IsNotExist(err) {
+		t.Errorf("files of log must be removed, stat error: %v", err)
+	}
+	for _, name := range []string{"json", "report"} {
+		if _, exists := pm.getInstalledPackage(name); !exists {
+			t.Errorf("%s must stay installed", name)
+		}
+	}
+}
+
+// packageNames возвращает имена пакетов в исходном порядке
+func packageNames(packages []*PackageInfo) []string {
+	names := make([]string, len(packages))
+	for i, pkg := range packages {
+		names[i] = pkg.Name
+	}
+	return names
+}
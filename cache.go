@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheArchivesDir подкаталог CachePath для скачанных архивов
+const cacheArchivesDir = "archives"
+
+// cacheDir возвращает каталог кеша архивов
+func (pm *PackageManager) cacheDir() string {
+	return filepath.Join(pm.getConfig().CachePath, cacheArchivesDir)
+}
+
+// cacheFileName возвращает имя файла кеша для контрольной суммы
+func cacheFileName(checksum string) string {
+	return strings.ReplaceAll(normalizeChecksum(checksum), ":", "-")
+}
+
+// lookupCache ищет в кеше архив с указанной контрольной суммой.
+// Файл, не прошедший проверку, удаляется из кеша.
+func (pm *PackageManager) lookupCache(checksum string) (string, bool) {
+	matches, err := filepath.Glob(filepath.Join(pm.cacheDir(), cacheFileName(checksum)+".*"))
+	if err != nil || len(matches) == 0 {
+		return "", false
+	}
+
+	path := matches[0]
+	if err := verifyChecksum(path, checksum); err != nil {
+		os.Remove(path)
+		return "", false
+	}
+
+	// Обновляем время последнего использования для LRU
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return path, true
+}
+
+// inCache сообщает, есть ли в кеше архив с контрольной суммой, не изменяя кеш
+func (pm *PackageManager) inCache(checksum string) bool {
+	if checksum == "" {
+		return false
+	}
+	matches, err := filepath.Glob(filepath.Join(pm.cacheDir(), cacheFileName(checksum)+".*"))
+	return err == nil && len(matches) > 0
+}
+
+// storeInCache перемещает проверенный архив в кеш и при необходимости освобождает место
+func (pm *PackageManager) storeInCache(archivePath, checksum string) (string, error) {
+	if err := os.MkdirAll(pm.cacheDir(), 0755); err != nil {
+		return "", fmt.Errorf("ошибка создания каталога кеша: %w", err)
+	}
+
+	ext := filepath.Ext(archivePath)
+	if format, err := archiveFormat(archivePath); err == nil {
+		ext = archiveExtension(format)
+	}
+	cachedPath := filepath.Join(pm.cacheDir(), cacheFileName(checksum)+ext)
+
+	if err := os.Rename(archivePath, cachedPath); err != nil {
+		return "", fmt.Errorf("ошибка сохранения в кеш: %w", err)
+	}
+
+	if err := pm.evictCache(cachedPath); err != nil {
+		logger.Warnf("Error evicting cache: %v", err)
+	}
+
+	return cachedPath, nil
+}
+
+// evictCache удаляет давно не использовавшиеся архивы, пока размер кеша превышает MaxCacheSize.
+// Файл keep не удаляется, даже если сам превышает лимит.
+func (pm *PackageManager) evictCache(keep string) error {
+	if pm.getConfig().MaxCacheSize <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(pm.cacheDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("ошибка чтения кеша: %w", err)
+	}
+
+	type cacheEntry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []cacheEntry
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheEntry{
+			path:    filepath.Join(pm.cacheDir(), entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	for _, file := range files {
+		if total <= pm.getConfig().MaxCacheSize {
+			break
+		}
+		if file.path == keep {
+			continue
+		}
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("ошибка удаления %s из кеша: %w", file.path, err)
+		}
+		total -= file.size
+	}
+
+	return nil
+}
+
+// CleanCache удаляет архивы из кеша. При olderThan > 0 удаляются только архивы,
+// не использовавшиеся дольше указанного времени. В режиме dryRun файлы не удаляются.
+// Затрагивается только каталог кеша архивов: установленные пакеты и TempPath не трогаются.
+func (pm *PackageManager) CleanCache(olderThan time.Duration, dryRun bool) (*CacheCleanResult, error) {
+	result := &CacheCleanResult{DryRun: dryRun}
+
+	entries, err := os.ReadDir(pm.cacheDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения кеша: %w", err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if olderThan > 0 && info.ModTime().After(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(pm.cacheDir(), entry.Name())
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return result, fmt.Errorf("ошибка удаления %s: %w", path, err)
+			}
+		}
+
+		result.Files++
+		result.Bytes += info.Size()
+		result.Paths = append(result.Paths, path)
+	}
+
+	return result, nil
+}
+
+// fetchArchive возвращает путь к архиву пакета. Если контрольная сумма известна,
+// архив берется из кеша, а скачанный файл сохраняется в кеш после проверки.
+// temporary сообщает, что файл нужно удалить после использования; size — ожидаемый размер (-1, если неизвестен).
+func (pm *PackageManager) fetchArchive(ctx context.Context, url, checksum, packageName, version string, size int64) (path string, temporary bool, err error) {
+	if checksum != "" {
+		if cached, ok := pm.lookupCache(checksum); ok {
+			logger.Debugf("Архив %s %s взят из кеша", packageName, version)
+			return cached, false, nil
+		}
+	}
+
+	logger.Debugf("Скачивание %s", url)
+	archivePath, err := pm.downloadPackage(ctx, url, packageName, version, size)
+	if err != nil {
+		return "", false, err
+	}
+
+	if checksum == "" {
+		return archivePath, true, nil
+	}
+
+	if err := verifyChecksum(archivePath, checksum); err != nil {
+		os.Remove(archivePath)
+		return "", false, err
+	}
+
+	cachedPath, err := pm.storeInCache(archivePath, checksum)
+	if err != nil {
+		// Кеш не обязателен для установки: используем скачанный файл, если он остался на месте
+		if _, statErr := os.Stat(archivePath); statErr == nil {
+			return archivePath, true, nil
+		}
+		return "", false, err
+	}
+
+	return cachedPath, false, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zeebo/blake3"
+)
+
+// Алгоритмы контрольных сумм; алгоритм указывается префиксом (sha512:<hex>),
+// контрольная сумма без префикса считается SHA-256
+const (
+	checksumSHA256 = "sha256"
+	checksumSHA512 = "sha512"
+	checksumBLAKE3 = "blake3"
+
+	defaultChecksumAlgorithm = checksumSHA256
+)
+
+// checksumAlgorithms конструкторы хешей поддерживаемых алгоритмов
+var checksumAlgorithms = map[string]func() hash.Hash{
+	checksumSHA256: sha256.New,
+	checksumSHA512: sha512.New,
+	checksumBLAKE3: func() hash.Hash { return blake3.New() },
+}
+
+// newChecksumHash создает хеш для алгоритма контрольной суммы
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	newHash, ok := checksumAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("неподдерживаемый алгоритм контрольной суммы %q (поддерживаются: %s)", algorithm, strings.Join(sortedKeys(checksumAlgorithms), ", "))
+	}
+	return newHash(), nil
+}
+
+// parseChecksum разделяет контрольную сумму на алгоритм и значение в нижнем регистре
+func parseChecksum(checksum string) (algorithm, digest string) {
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if algorithm, digest, found := strings.Cut(checksum, ":"); found {
+		return algorithm, digest
+	}
+	return defaultChecksumAlgorithm, checksum
+}
+
+// normalizeChecksum приводит контрольную сумму к виду <алгоритм>:<hex> в нижнем регистре
+func normalizeChecksum(checksum string) string {
+	algorithm, digest := parseChecksum(checksum)
+	return algorithm + ":" + digest
+}
+
+// hashChecksum вычисляет контрольную сумму содержимого r в формате <алгоритм>:<hex>
+func hashChecksum(r io.Reader, algorithm string) (string, error) {
+	hash, err := newChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return algorithm + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// calculateChecksum вычисляет контрольную сумму файла в формате <алгоритм>:<hex>
+func calculateChecksum(path, algorithm string) (string, error) {
+	if _, err := newChecksumHash(algorithm); err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return hashChecksum(file, algorithm)
+}
+
+// verifyChecksum сверяет контрольную сумму файла с ожидаемой; алгоритм берется из
+// ее префикса (без префикса — sha256)
+func verifyChecksum(path, expected string) error {
+	algorithm, _ := parseChecksum(expected)
+	actual, err := calculateChecksum(path, algorithm)
+	if err != nil {
+		return fmt.Errorf("ошибка вычисления контрольной суммы: %w", err)
+	}
+
+	expected = normalizeChecksum(expected)
+	if actual != expected {
+		return errorWithCause(ErrChecksumMismatch, "контрольная сумма не совпадает: ожидалось %s, получено %s", expected, actual)
+	}
+
+	return nil
+}
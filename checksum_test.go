@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestChecksumAlgorithms проверяет вычисление и сверку контрольных сумм всех
+// поддерживаемых алгоритмов по известным значениям для строки "abc"
+func TestChecksumAlgorithms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vectors := map[string]string{
+		checksumSHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		checksumSHA512: "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+		checksumBLAKE3: "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85",
+	}
+	for algorithm, digest := range vectors {
+		checksum, err := calculateChecksum(path, algorithm)
+		if err != nil {
+			t.Fatalf("%s: calculateChecksum: %v", algorithm, err)
+		}
+		if want := algorithm + ":" + digest; checksum != want {
+			t.Errorf("%s: expected %s, got %s", algorithm, want, checksum)
+		}
+
+		if err := verifyChecksum(path, strings.ToUpper(algorithm+":"+digest)); err != nil {
+			t.Errorf("%s: verifyChecksum: %v", algorithm, err)
+		}
+		wrong := algorithm + ":" + strings.Repeat("0", len(digest))
+		if err := verifyChecksum(path, wrong); err == nil || !strings.Contains(err.Error(), "не совпадает") {
+			t.Errorf("%s: expected mismatch error, got %v", algorithm, err)
+		}
+	}
+
+	// Без префикса контрольная сумма считается SHA-256
+	if err := verifyChecksum(path, vectors[checksumSHA256]); err != nil {
+		t.Errorf("verifyChecksum without prefix: %v", err)
+	}
+	if err := verifyChecksum(path, vectors[checksumBLAKE3]); err == nil {
+		t.Error("expected blake3 digest without prefix to be checked as sha256")
+	}
+}
+
+// TestUnsupportedChecksumAlgorithm проверяет понятную ошибку для неизвестного префикса
+func TestUnsupportedChecksumAlgorithm(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := calculateChecksum(path, "md5"); err == nil || !strings.Contains(err.Error(), "неподдерживаемый алгоритм") {
+		t.Errorf("expected unsupported algorithm error, got %v", err)
+	}
+	err := verifyChecksum(path, "md5:900150983cd24fb0d6963f7d28e17f72")
+	if err == nil || !strings.Contains(err.Error(), `"md5"`) || !strings.Contains(err.Error(), "blake3, sha256, sha512") {
+		t.Errorf("expected error naming the unknown and supported algorithms, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Области установки в отчете об уплотнении индекса
+const (
+	scopeGlobal = "global"
+	scopeLocal  = "local"
+)
+
+// CompactIndex переписывает packages.json глобальной и локальной областей: удаляет записи
+// с отсутствующим каталогом установки и дубликаты, нормализует имена и переносит записи,
+// оказавшиеся не в своей области
+func (pm *PackageManager) CompactIndex() (*CompactIndexResult, error) {
+	pm.packagesMutex.Lock()
+	defer pm.packagesMutex.Unlock()
+	pm.packagesFileMutex.Lock()
+	defer pm.packagesFileMutex.Unlock()
+
+	roots := map[string]string{
+		scopeGlobal: pm.getConfig().GlobalPath,
+		scopeLocal:  pm.getConfig().LocalPath,
+	}
+	scopes := []string{scopeGlobal, scopeLocal}
+
+	loaded := make(map[string]map[string]*PackageInfo, len(scopes))
+	for _, scope := range scopes {
+		path := filepath.Join(roots[scope], packagesFileName)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("ошибка чтения %s: %w", path, err)
+		}
+
+		var packages map[string]*PackageInfo
+		if err := json.Unmarshal(data, &packages); err != nil {
+			return nil, fmt.Errorf("поврежденный JSON в %s: %w", path, err)
+		}
+		loaded[scope] = packages
+	}
+
+	result := &CompactIndexResult{}
+	compacted := map[string]map[string]*PackageInfo{
+		scopeGlobal: make(map[string]*PackageInfo),
+		scopeLocal:  make(map[string]*PackageInfo),
+	}
+
+	for _, scope := range scopes {
+		keys := make([]string, 0, len(loaded[scope]))
+		for key := range loaded[scope] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			info := loaded[scope][key]
+			if info == nil {
+				result.Removed = append(result.Removed, IndexChange{Scope: scope, Name: key, Reason: "пустая запись"})
+				continue
+			}
+
+			name := strings.TrimSpace(info.Name)
+			if name == "" {
+				name = strings.TrimSpace(key)
+			}
+			if original := info.Name; name != key || name != original {
+				if name != key {
+					original = key
+				}
+				result.Fixed = append(result.Fixed, IndexChange{Scope: scope, Name: name, Reason: fmt.Sprintf("имя нормализовано (было %q)", original)})
+				info.Name = name
+			}
+
+			if info.InstallPath == "" {
+				result.Removed = append(result.Removed, IndexChange{Scope: scope, Name: name, Reason: "не указан каталог установки"})
+				continue
+			}
+			if _, err := os.Stat(info.InstallPath); err != nil {
+				result.Removed = append(result.Removed, IndexChange{Scope: scope, Name: name, Reason: fmt.Sprintf("каталог %s не существует", info.InstallPath)})
+				continue
+			}
+
+			// Область определяется расположением каталога установки, а не файлом, в котором запись найдена
+			target := scope
+			for _, candidate := range scopes {
+				if pathWithin(roots[candidate], info.InstallPath) {
+					target = candidate
+					break
+				}
+			}
+			if target != scope {
+				result.Fixed = append(result.Fixed, IndexChange{Scope: target, Name: name, Reason: fmt.Sprintf("запись перенесена из области %s", scope)})
+			}
+			if global := target == scopeGlobal; info.Global != global {
+				if target == scope {
+					result.Fixed = append(result.Fixed, IndexChange{Scope: target, Name: name, Reason: "исправлен признак global"})
+				}
+				info.Global = global
+			}
+
+			if existing, ok := compacted[target][name]; ok {
+				result.Removed = append(result.Removed, IndexChange{Scope: target, Name: name, Reason: "дубликат"})
+				// Из дубликатов оставляем более позднюю установку
+				if existing.InstallDate.After(info.InstallDate) {
+					continue
+				}
+			}
+			compacted[target][name] = info
+		}
+	}
+
+	for _, scope := range scopes {
+		if loaded[scope] == nil && len(compacted[scope]) == 0 {
+			continue
+		}
+
+		data, err := json.MarshalIndent(compacted[scope], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(roots[scope], packagesFileName)
+		if err := writeFileAtomic(path, data, 0644); err != nil {
+			return nil, fmt.Errorf("ошибка записи %s: %w", path, err)
+		}
+		result.Kept += len(compacted[scope])
+	}
+
+	// Память приводится в соответствие с файлами: локальные записи перекрывают глобальные, как при загрузке
+	pm.installedPackages = make(map[string]*PackageInfo)
+	for _, scope := range scopes {
+		for name, info := range compacted[scope] {
+			pm.installedPackages[name] = info
+		}
+	}
+
+	return result, nil
+}
+
+// pathWithin сообщает, находится ли path внутри каталога root
+func pathWithin(root, path string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCompactIndex проверяет очистку packages.json с устаревшими, дублирующимися
+// и попавшими не в свою область записями
+func TestCompactIndex(t *testing.T) {
+	pm := newTestPackageManager(t)
+
+	mkdir := func(path string) string {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	writeIndex := func(root string, packages map[string]*PackageInfo) {
+		data, err := json.Marshal(packages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, packagesFileName), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readIndex := func(root string) map[string]*PackageInfo {
+		data, err := os.ReadFile(filepath.Join(root, packagesFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var packages map[string]*PackageInfo
+		if err := json.Unmarshal(data, &packages); err != nil {
+			t.Fatal(err)
+		}
+		return packages
+	}
+
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	globalTool := mkdir(filepath.Join(pm.getConfig().GlobalPath, "tool"))
+	localLib := mkdir(filepath.Join(pm.getConfig().LocalPath, "lib"))
+	localMoved := mkdir(filepath.Join(pm.getConfig().LocalPath, "moved"))
+
+	writeIndex(pm.getConfig().GlobalPath, map[string]*PackageInfo{
+		"tool":  {Name: "tool", InstallPath: globalTool, Global: true},
+		"gone":  {Name: "gone", InstallPath: filepath.Join(pm.getConfig().GlobalPath, "gone"), Global: true},
+		"moved": {Name: "moved", InstallPath: localMoved, Global: true},
+		"empty": nil,
+	})
+	writeIndex(pm.getConfig().LocalPath, map[string]*PackageInfo{
+		"lib":   {Name: "lib", InstallPath: localLib, InstallDate: newer},
+		" lib ": {Name: " lib ", InstallPath: localLib, InstallDate: older},
+		"flag":  {Name: "flag", InstallPath: mkdir(filepath.Join(pm.getConfig().LocalPath, "flag")), Global: true},
+	})
+	if err := pm.loadInstalledPackages(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := pm.CompactIndex()
+	if err != nil {
+		t.Fatalf("CompactIndex: %v", err)
+	}
+
+	global := readIndex(pm.getConfig().GlobalPath)
+	if len(global) != 1 || global["tool"] == nil {
+		t.Errorf("expected only tool in global index, got %v", global)
+	}
+
+	local := readIndex(pm.getConfig().LocalPath)
+	if len(local) != 3 || local["lib"] == nil || local["moved"] == nil || local["flag"] == nil {
+		t.Fatalf("expected lib, moved and flag in local index, got %v", local)
+	}
+	if !local["lib"].InstallDate.Equal(newer) {
+		t.Errorf("expected the newest duplicate to be kept, got %v", local["lib"].InstallDate)
+	}
+	if local["moved"].Global || local["flag"].Global {
+		t.Error("expected global flag to be cleared for local entries")
+	}
+
+	if result.Kept != 4 {
+		t.Errorf("Kept = %d, want 4", result.Kept)
+	}
+	removed := make(map[string]bool)
+	for _, change := range result.Removed {
+		removed[change.Name] = true
+	}
+	for _, name := range []string{"gone", "empty", "lib"} {
+		if !removed[name] {
+			t.Errorf("expected %s to be reported as removed, got %+v", name, result.Removed)
+		}
+	}
+	if len(result.Fixed) < 3 {
+		t.Errorf("expected name, scope and flag fixes, got %+v", result.Fixed)
+	}
+
+	if _, exists := pm.getInstalledPackage("gone"); exists {
+		t.Error("removed entry must not stay in memory")
+	}
+	if info, exists := pm.getInstalledPackage("moved"); !exists || info.Global {
+		t.Errorf("expected moved to be a local package in memory, got %+v", info)
+	}
+
+	// Повторное уплотнение ничего не меняет
+	again, err := pm.CompactIndex()
+	if err != nil {
+		t.Fatalf("second CompactIndex: %v", err)
+	}
+	if len(again.Removed) != 0 || len(again.Fixed) != 0 || again.Kept != 4 {
+		t.Errorf("expected idempotent compaction, got %+v", again)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// configSetting изменяемый через config_set параметр конфигурации
+type configSetting struct {
+	apply   func(config *Config, value interface{}) error
+	network bool // после изменения пересоздаются HTTP-клиент и rate limiter
+}
+
+// configSettings параметры, которые можно менять через config_set, по ключам config.json
+var configSettings = map[string]configSetting{
+	"timeout":             intSetting(func(c *Config) *int { return &c.Timeout }, 1, 3600, true),
+	"download_timeout":    intSetting(func(c *Config) *int { return &c.DownloadTimeout }, 0, 86400, false),
+	"max_concurrency":     intSetting(func(c *Config) *int { return &c.MaxConcurrency }, 1, 64, false),
+	"compression_level":   intSetting(func(c *Config) *int { return &c.CompressionLevel }, 1, 22, false),
+	"requests_per_second": intSetting(func(c *Config) *int { return &c.RequestsPerSecond }, 0, 1000, true),
+	"rate_burst":          intSetting(func(c *Config) *int { return &c.RateBurst }, 0, 1000, true),
+	"max_retries":         intSetting(func(c *Config) *int { return &c.MaxRetries }, 0, 10, false),
+	"stats_cache_ttl":     intSetting(func(c *Config) *int { return &c.StatsCacheTTL }, -1, 86400, false),
+	"index_max_age":       intSetting(func(c *Config) *int { return &c.IndexMaxAge }, -1, 30*86400, false),
+	"max_cache_size":      sizeSetting(func(c *Config) *int64 { return &c.MaxCacheSize }),
+	"max_download_size":   sizeSetting(func(c *Config) *int64 { return &c.MaxDownloadSize }),
+	"force_https": {apply: func(c *Config, value interface{}) error {
+		b, err := configBool(value)
+		if err != nil {
+			return err
+		}
+		c.ForceHTTPS = b
+		return nil
+	}},
+	"offline": {apply: func(c *Config, value interface{}) error {
+		b, err := configBool(value)
+		if err != nil {
+			return err
+		}
+		c.Offline = b
+		return nil
+	}},
+	"symlink_policy":     stringSetting(func(c *Config) *string { return &c.SymlinkPolicy }, SymlinkPreserve, SymlinkDereference, SymlinkSkip),
+	"default_os":         stringSetting(func(c *Config) *string { return &c.DefaultOS }),
+	"default_arch":       stringSetting(func(c *Config) *string { return &c.DefaultArch }),
+	"language":           stringSetting(func(c *Config) *string { return &c.Language }, localeRussian, localeEnglish),
+	"arch_compatibility": archCompatibilitySetting(),
+}
+
+// intSetting целочисленный параметр в диапазоне [minValue, maxValue]
+func intSetting(field func(*Config) *int, minValue, maxValue int, network bool) configSetting {
+	return configSetting{network: network, apply: func(c *Config, value interface{}) error {
+		n, err := configInt(value)
+		if err != nil {
+			return err
+		}
+		if n < int64(minValue) || n > int64(maxValue) {
+			return fmt.Errorf("значение %d вне допустимого диапазона %d–%d", n, minValue, maxValue)
+		}
+		*field(c) = int(n)
+		return nil
+	}}
+}
+
+// sizeSetting размер в байтах; 0 — без ограничения
+func sizeSetting(field func(*Config) *int64) configSetting {
+	return configSetting{apply: func(c *Config, value interface{}) error {
+		n, err := configInt(value)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("размер не может быть отрицательным")
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+// stringSetting строковый параметр; allowed ограничивает допустимые значения (пустая строка допустима всегда)
+func stringSetting(field func(*Config) *string, allowed ...string) configSetting {
+	return configSetting{apply: func(c *Config, value interface{}) error {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("ожидается строка")
+		}
+		s = strings.TrimSpace(s)
+		if s != "" && len(allowed) > 0 && !containsString(allowed, s) {
+			return fmt.Errorf("недопустимое значение %q (допустимо: %s)", s, strings.Join(allowed, ", "))
+		}
+		*field(c) = s
+		return nil
+	}}
+}
+
+// configInt разбирает целое число из JSON-числа или строки
+func configInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > math.MaxInt64 {
+			return 0, fmt.Errorf("ожидается целое число, получено %v", v)
+		}
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("ожидается целое число, получено %q", v)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("ожидается целое число")
+	}
+}
+
+// configBool разбирает логическое значение из JSON или строки
+func configBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("ожидается true или false, получено %q", v)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("ожидается true или false")
+	}
+}
+
+// getConfig возвращает действующую конфигурацию. Опубликованный Config не изменяется:
+// изменения публикуют новую копию (см. updateConfig), поэтому читать ее можно без блокировок
+func (pm *PackageManager) getConfig() *Config {
+	return pm.config.Load()
+}
+
+// updateConfig изменяет копию конфигурации функцией update, сохраняет ее на диск и
+// публикует; вызывается под configMutex. При ошибке изменения или сохранения действующая
+// конфигурация остается прежней. Возвращает предыдущую конфигурацию.
+func (pm *PackageManager) updateConfig(update func(config *Config) error) (*Config, error) {
+	previous := pm.getConfig()
+	updated := cloneConfig(previous)
+	if err := update(updated); err != nil {
+		return nil, err
+	}
+	if err := pm.saveConfig(updated); err != nil {
+		return nil, err
+	}
+	pm.config.Store(updated)
+	return previous, nil
+}
+
+// cloneConfig возвращает копию конфигурации, не разделяющую с ней списки и карты
+func cloneConfig(config *Config) *Config {
+	clone := *config
+	clone.Repositories = append([]Repository(nil), config.Repositories...)
+	clone.AllowedHosts = append([]string(nil), config.AllowedHosts...)
+	if config.NetworkProfiles != nil {
+		clone.NetworkProfiles = make(map[string]NetworkProfile, len(config.NetworkProfiles))
+		for name, profile := range config.NetworkProfiles {
+			clone.NetworkProfiles[name] = profile
+		}
+	}
+	if config.ArchCompatibility != nil {
+		clone.ArchCompatibility = make(map[string][]string, len(config.ArchCompatibility))
+		for arch, compatible := range config.ArchCompatibility {
+			clone.ArchCompatibility[arch] = append([]string(nil), compatible...)
+		}
+	}
+	return &clone
+}
+
+// ConfigSnapshot возвращает копию текущей конфигурации со скрытыми токенами репозиториев
+// и учетными данными прокси
+func (pm *PackageManager) ConfigSnapshot() *Config {
+	snapshot := cloneConfig(pm.getConfig())
+	for i, repo := range snapshot.Repositories {
+		snapshot.Repositories[i] = redactedRepository(repo)
+	}
+	snapshot.HTTPProxy = redactSecrets(snapshot.HTTPProxy)
+	snapshot.HTTPSProxy = redactSecrets(snapshot.HTTPSProxy)
+	return snapshot
+}
+
+// ConfigValue возвращает значение параметра конфигурации по ключу config.json
+func (pm *PackageManager) ConfigValue(key string) (interface{}, error) {
+	values, err := configValues(pm.ConfigSnapshot())
+	if err != nil {
+		return nil, err
+	}
+	value, ok := values[key]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный параметр конфигурации: %s", key)
+	}
+	return value, nil
+}
+
+// SetConfigValue проверяет и изменяет параметр конфигурации, сохраняя конфигурацию на диск.
+// При ошибке проверки или сохранения конфигурация не меняется.
+func (pm *PackageManager) SetConfigValue(key string, value interface{}) (*ConfigChange, error) {
+	key = strings.TrimSpace(key)
+	setting, ok := configSettings[key]
+	if !ok {
+		return nil, fmt.Errorf("параметр %s нельзя изменить (доступны: %s)", key, strings.Join(sortedKeys(configSettings), ", "))
+	}
+
+	pm.configMutex.Lock()
+	defer pm.configMutex.Unlock()
+
+	previous, err := pm.updateConfig(func(config *Config) error {
+		if err := setting.apply(config, value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if setting.network {
+		if err := pm.reconfigureNetwork(); err != nil {
+			return nil, err
+		}
+	}
+
+	before, _ := configValues(previous)
+	after, _ := configValues(pm.getConfig())
+	logger.Infof("Параметр конфигурации %s изменен: %v -> %v", key, before[key], after[key])
+	return &ConfigChange{Key: key, Previous: before[key], Value: after[key]}, nil
+}
+
+// configValues представляет конфигурацию в виде карты по ключам config.json
+func configValues(config *Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// configBackupDirName каталог пути установки, в котором хранятся файлы конфигурации
+// удаленных без purge пакетов (по подкаталогу на пакет)
+const configBackupDirName = ".config-backup"
+
+// configBackupPath возвращает каталог сохраненной конфигурации пакета
+func (pm *PackageManager) configBackupPath(packageName string, global bool) string {
+	root := pm.getConfig().LocalPath
+	if global {
+		root = pm.getConfig().GlobalPath
+	}
+	return filepath.Join(root, configBackupDirName, packageName)
+}
+
+// preserveConfig переносит файлы конфигурации пакета из каталога установки в резервный
+// каталог, заменяя сохраненные ранее. Возвращает перенесенные пути относительно пакета.
+func (pm *PackageManager) preserveConfig(info *PackageInfo) ([]string, error) {
+	backup := pm.configBackupPath(info.Name, info.Global)
+	if err := os.RemoveAll(backup); err != nil {
+		return nil, err
+	}
+
+	var preserved []string
+	for _, rel := range info.Config {
+		src, err := safeJoin(info.InstallPath, rel)
+		if err != nil {
+			logger.Warnf("Пакет %s: пропущен файл конфигурации: %v", info.Name, err)
+			continue
+		}
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			continue
+		}
+
+		dst, err := safeJoin(backup, rel)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return nil, err
+		}
+		preserved = append(preserved, filepath.ToSlash(filepath.Clean(rel)))
+	}
+
+	if len(preserved) == 0 {
+		os.RemoveAll(backup)
+	}
+	return preserved, nil
+}
+
+// PreservedConfig возвращает файлы сохраненной конфигурации пакета относительно его каталога
+func (pm *PackageManager) PreservedConfig(packageName string, global bool) []string {
+	backup := pm.configBackupPath(packageName, global)
+
+	var files []string
+	filepath.Walk(backup, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(backup, path); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// RestoreConfig возвращает сохраненную при удалении конфигурацию в каталог установленного
+// пакета, заменяя файлы новой версии, и удаляет резервную копию
+func (pm *PackageManager) RestoreConfig(packageName string) ([]string, error) {
+	info, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	lock, err := pm.lockPackage(packageName, info.Global)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	backup := pm.configBackupPath(packageName, info.Global)
+	files := pm.PreservedConfig(packageName, info.Global)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("для пакета %s нет сохраненной конфигурации", packageName)
+	}
+
+	for _, rel := range files {
+		dst, err := safeJoin(info.InstallPath, rel)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(filepath.Join(backup, filepath.FromSlash(rel)), dst); err != nil {
+			return nil, fmt.Errorf("ошибка восстановления %s: %w", rel, err)
+		}
+	}
+	if err := os.RemoveAll(backup); err != nil {
+		return nil, err
+	}
+
+	updated := *info
+	updated.PreservedConfig = nil
+	if err := pm.savePackageInfo(&updated); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения информации о пакете: %w", err)
+	}
+	pm.packagesMutex.Lock()
+	pm.installedPackages[packageName] = &updated
+	pm.packagesMutex.Unlock()
+
+	return files, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestUninstallConfigPreservation проверяет сохранение файлов конфигурации при удалении
+// без purge, их восстановление после повторной установки и полное удаление с purge
+func TestUninstallConfigPreservation(t *testing.T) {
+	pm := newTestPackageManager(t)
+	ctx := context.Background()
+	archivePath := buildTestArchive(t, pm, PackageManifest{
+		Name:    "app",
+		Version: "1.0.0",
+		Config:  []string{"etc/app.conf", "data/state", "../outside.conf", "missing.conf"},
+	}, map[string]string{
+		"bin/app":       "binary",
+		"etc/app.conf":  "default",
+		"data/state/db": "records",
+	}, FormatTarGz)
+
+	install := func() *PackageInfo {
+		t.Helper()
+		info, err := pm.installFromArchive(ctx, archivePath, false, false, nil)
+		if err != nil {
+			t.Fatalf("install: %v", err)
+		}
+		return info
+	}
+
+	info := install()
+	confPath := filepath.Join(info.InstallPath, "etc", "app.conf")
+	if err := os.WriteFile(confPath, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Без purge конфигурация переносится в резервный каталог
+	if err := pm.UninstallPackage("app", false, false); err != nil {
+		t.Fatalf("uninstall: %v", err)
+	}
+	if _, err := os.Stat(info.InstallPath); !os.IsNotExist(err) {
+		t.Error("install directory must be removed")
+	}
+	want := []string{"data/state/db", "etc/app.conf"}
+	if got := pm.PreservedConfig("app", false); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected preserved config %v, got %v", want, got)
+	}
+
+	// Повторная установка предлагает восстановить конфигурацию, но не подменяет файлы сама
+	info = install()
+	if !reflect.DeepEqual(info.PreservedConfig, want) {
+		t.Errorf("expected reinstall to report preserved config, got %v", info.PreservedConfig)
+	}
+	if data, _ := os.ReadFile(confPath); string(data) != "default" {
+		t.Errorf("reinstall must install packaged config, got %q", data)
+	}
+	s := newTestServer(t, pm)
+	if text := formatPreservedConfig(defaultLocale, info); !strings.Contains(text, "restore_config") {
+		t.Errorf("expected restore offer, got %q", text)
+	}
+
+	text, err := callToolText(t, s, "restore_config", map[string]interface{}{"name": "app"})
+	if err != nil {
+		t.Fatalf("restore_config: %v", err)
+	}
+	if !strings.Contains(text, "etc/app.conf") {
+		t.Errorf("unexpected restore output:\n%s", text)
+	}
+	if data, _ := os.ReadFile(confPath); string(data) != "edited" {
+		t.Errorf("expected edited config to be restored, got %q", data)
+	}
+	if restored, _ := pm.getInstalledPackage("app"); len(restored.PreservedConfig) != 0 || len(pm.PreservedConfig("app", false)) != 0 {
+		t.Errorf("backup must be consumed by restore, got %v", restored.PreservedConfig)
+	}
+	if _, err := pm.RestoreConfig("app"); err == nil {
+		t.Error("expected error when there is nothing to restore")
+	}
+
+	// С purge удаляется и конфигурация, и сохраненная ранее копия
+	if err := pm.UninstallPackage("app", false, false); err != nil {
+		t.Fatalf("uninstall: %v", err)
+	}
+	install()
+	if err := pm.UninstallPackage("app", false, true); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if got := pm.PreservedConfig("app", false); len(got) != 0 {
+		t.Errorf("purge must remove preserved config, got %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(pm.getConfig().LocalPath, "..", "outside.conf")); !os.IsNotExist(err) {
+		t.Error("config paths outside the package must be ignored")
+	}
+	if orphans, err := pm.FindOrphans(); err != nil || len(orphans) != 0 {
+		t.Errorf("expected no orphans, got %+v, %v", orphans, err)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DependencyTree строит дерево зависимостей установленного пакета. Установленные
+// зависимости раскрываются по их сведениям об установке, отсутствующие — по старшей
+// подходящей версии из репозиториев. Циклы отмечаются и дальше не раскрываются.
+func (pm *PackageManager) DependencyTree(ctx context.Context, packageName string) (*DependencyNode, error) {
+	info, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	root := &DependencyNode{Name: info.Name, Version: info.Version, Installed: true}
+	resolver := newDependencyResolver(pm)
+	if err := pm.expandDependencies(ctx, resolver, root, info.Dependencies, map[string]bool{info.Name: true}); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// expandDependencies добавляет узлу дочерние узлы для deps. ancestors содержит
+// пакеты текущей ветки от корня и служит для обнаружения циклов.
+func (pm *PackageManager) expandDependencies(ctx context.Context, resolver *dependencyResolver, node *DependencyNode, deps map[string]string, ancestors map[string]bool) error {
+	for _, name := range sortedKeys(deps) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		child := &DependencyNode{Name: name, Constraint: normalizedConstraint(deps[name])}
+		node.Dependencies = append(node.Dependencies, child)
+
+		constraint, err := parseConstraint(deps[name])
+		if err != nil {
+			child.Error = err.Error()
+			continue
+		}
+
+		var childDeps map[string]string
+		if info, exists := pm.getInstalledPackage(name); exists {
+			child.Version = info.Version
+			child.Installed = true
+			child.Conflict = !constraint.satisfies(info.Version)
+			childDeps = info.Dependencies
+		} else {
+			version, err := resolver.choose(ctx, name, []RequirerConstraint{{Requirer: node.Name, Constraint: deps[name]}})
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				child.Missing = true
+				child.Error = err.Error()
+				continue
+			}
+			child.Version = version
+			childDeps = resolver.versionDependencies(name, version)
+		}
+
+		if ancestors[name] {
+			child.Cycle = true
+			continue
+		}
+
+		ancestors[name] = true
+		err = pm.expandDependencies(ctx, resolver, child, childDeps, ancestors)
+		delete(ancestors, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WhyInstalled сообщает, установлен ли пакет пользователем или как зависимость,
+// и перечисляет установленные пакеты, которые от него зависят
+func (pm *PackageManager) WhyInstalled(packageName string) (*WhyInstalledResult, error) {
+	info, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	result := &WhyInstalledResult{
+		Name:       info.Name,
+		Version:    info.Version,
+		Automatic:  info.Automatic,
+		Dependents: []ReverseDependency{},
+	}
+	for _, dependent := range pm.InstalledPackages() {
+		constraint, ok := dependent.Dependencies[packageName]
+		if !ok || dependent.Name == packageName {
+			continue
+		}
+
+		parsed, err := parseConstraint(constraint)
+		result.Dependents = append(result.Dependents, ReverseDependency{
+			Name:       dependent.Name,
+			Version:    dependent.Version,
+			Constraint: normalizedConstraint(constraint),
+			Satisfied:  err == nil && parsed.satisfies(info.Version),
+		})
+	}
+
+	return result, nil
+}
+
+// renderDependencyTree выводит дерево зависимостей псевдографикой
+func renderDependencyTree(root *DependencyNode) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s@%s\n", root.Name, root.Version))
+	renderDependencyNodes(&output, root.Dependencies, "")
+	return output.String()
+}
+
+func renderDependencyNodes(output *strings.Builder, nodes []*DependencyNode, prefix string) {
+	for i, node := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		output.WriteString(prefix + branch + node.Name + " " + node.Constraint)
+		switch {
+		case node.Missing:
+			output.WriteString(" ❌ не найден")
+		case node.Version != "":
+			output.WriteString(" → " + node.Version)
+		}
+		if node.Installed {
+			output.WriteString(" [установлен]")
+		}
+		if node.Conflict {
+			output.WriteString(fmt.Sprintf(" ⚠️ конфликт: требуется %s", node.Constraint))
+		}
+		if node.Cycle {
+			output.WriteString(" 🔁 цикл")
+		}
+		if node.Error != "" && !node.Missing {
+			output.WriteString(fmt.Sprintf(" ❌ %s", node.Error))
+		}
+		output.WriteString("\n")
+
+		renderDependencyNodes(output, node.Dependencies, prefix+indent)
+	}
+}
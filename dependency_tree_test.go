@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestDependencyTree проверяет дерево из нескольких уровней с конфликтом, циклом
+// и зависимостью, подобранной в репозитории
+func TestDependencyTree(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t,
+		&RepositoryPackage{Name: "fmt", Versions: []RepositoryVersion{
+			{Version: "1.0.0"},
+			{Version: "1.1.0", Dependencies: map[string]string{"unicode": "^3.0.0"}},
+		}},
+	)
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	for _, info := range []*PackageInfo{
+		{Name: "app", Version: "1.0.0", Dependencies: map[string]string{"http": "^2.0.0", "log": "~1.2", "fmt": "1.x"}},
+		{Name: "http", Version: "2.3.0", Dependencies: map[string]string{"log": "^1.0.0", "net": "*"}},
+		{Name: "net", Version: "0.9.0", Dependencies: map[string]string{"http": ">=2.0.0"}},
+		{Name: "log", Version: "1.5.0"},
+	} {
+		pm.installedPackages[info.Name] = info
+	}
+
+	tree, err := pm.DependencyTree(context.Background(), "app")
+	if err != nil {
+		t.Fatalf("DependencyTree: %v", err)
+	}
+
+	expected := "app@1.0.0\n" +
+		"├── fmt 1.x → 1.1.0\n" +
+		"│   └── unicode ^3.0.0 ❌ не найден\n" +
+		"├── http ^2.0.0 → 2.3.0 [установлен]\n" +
+		"│   ├── log ^1.0.0 → 1.5.0 [установлен]\n" +
+		"│   └── net * → 0.9.0 [установлен]\n" +
+		"│       └── http >=2.0.0 → 2.3.0 [установлен] 🔁 цикл\n" +
+		"└── log ~1.2 → 1.5.0 [установлен] ⚠️ конфликт: требуется ~1.2\n"
+	if got := renderDependencyTree(tree); got != expected {
+		t.Errorf("unexpected tree:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	if _, err := pm.DependencyTree(context.Background(), "absent"); err == nil {
+		t.Error("expected error for package that is not installed")
+	}
+}
+
+// TestWhyInstalled проверяет отметку автоматической установки зависимостей
+// и список зависящих пакетов для установленного пользователем и транзитивного пакета
+func TestWhyInstalled(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	for name, deps := range map[string]map[string]string{"app": {"http": "^1.0.0"}, "http": {"log": "~1.2"}, "log": nil} {
+		manifest := PackageManifest{Name: name, Version: "1.2.0", Dependencies: deps}
+		repo.publish(t, name, "1.2.0", buildTestArchive(t, pm, manifest, nil, FormatTarGz))
+		repo.setDependencies(name, "1.2.0", deps)
+	}
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	if _, err := pm.InstallPackage(context.Background(), "app", "", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("InstallPackage: %v", err)
+	}
+
+	app, err := pm.WhyInstalled("app")
+	if err != nil {
+		t.Fatalf("WhyInstalled(app): %v", err)
+	}
+	if app.Automatic || len(app.Dependents) != 0 {
+		t.Errorf("expected explicitly installed app without dependents, got %+v", app)
+	}
+
+	transitive, err := pm.WhyInstalled("log")
+	if err != nil {
+		t.Fatalf("WhyInstalled(log): %v", err)
+	}
+	if !transitive.Automatic {
+		t.Error("expected log to be installed automatically")
+	}
+	if len(transitive.Dependents) != 1 || transitive.Dependents[0] != (ReverseDependency{Name: "http", Version: "1.2.0", Constraint: "~1.2", Satisfied: true}) {
+		t.Errorf("unexpected dependents of log: %+v", transitive.Dependents)
+	}
+
+	s := newTestServer(t, pm)
+	text, err := callToolText(t, s, "why_installed", map[string]interface{}{"name": "http"})
+	if err != nil {
+		t.Fatalf("why_installed: %v", err)
+	}
+	if !strings.Contains(text, "автоматически") || !strings.Contains(text, "app@1.2.0 требует ^1.0.0") {
+		t.Errorf("unexpected output: %q", text)
+	}
+
+	if _, err := pm.WhyInstalled("absent"); err == nil {
+		t.Error("expected error for package that is not installed")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"sort"
+)
+
+// defaultDiskUsageTop число крупнейших пакетов в отчете по умолчанию
+const defaultDiskUsageTop = 10
+
+// DiskUsage подсчитывает место, занимаемое установленными пакетами (отдельно глобальными
+// и локальными), кешем и временным каталогом. Размер пакета пересчитывается по каталогу
+// установки; записанный при установке размер используется, только если каталога нет.
+// top ограничивает список крупнейших пакетов.
+func (pm *PackageManager) DiskUsage(top int) *DiskUsageResult {
+	if top <= 0 {
+		top = defaultDiskUsageTop
+	}
+
+	pm.packagesMutex.RLock()
+	packages := make([]*PackageInfo, 0, len(pm.installedPackages))
+	for _, info := range pm.installedPackages {
+		packages = append(packages, info)
+	}
+	pm.packagesMutex.RUnlock()
+
+	result := &DiskUsageResult{
+		Global:  ScopeUsage{Path: pm.getConfig().GlobalPath},
+		Local:   ScopeUsage{Path: pm.getConfig().LocalPath},
+		Cache:   DirectoryUsage{Path: pm.getConfig().CachePath, Size: pm.calculateDirSize(pm.getConfig().CachePath)},
+		Temp:    DirectoryUsage{Path: pm.getConfig().TempPath, Size: pm.calculateDirSize(pm.getConfig().TempPath)},
+		Largest: []PackageUsage{},
+	}
+
+	var usage []PackageUsage
+	for _, info := range packages {
+		pkg := PackageUsage{Name: info.Name, Version: info.Version, Global: info.Global, Size: info.Size, RecordedSize: info.Size}
+		if _, err := os.Stat(info.InstallPath); err == nil {
+			pkg.Size = pm.calculateDirSize(info.InstallPath)
+		}
+		usage = append(usage, pkg)
+
+		scope := &result.Local
+		if info.Global {
+			scope = &result.Global
+		}
+		scope.Packages++
+		scope.Size += pkg.Size
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Size != usage[j].Size {
+			return usage[i].Size > usage[j].Size
+		}
+		return usage[i].Name < usage[j].Name
+	})
+	if len(usage) > top {
+		usage = usage[:top]
+	}
+	result.Largest = append(result.Largest, usage...)
+
+	result.Total = result.Global.Size + result.Local.Size + result.Cache.Size + result.Temp.Size
+	return result
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestDiskUsage проверяет итоги по областям, крупнейшие пакеты и размеры кеша и временного каталога
+func TestDiskUsage(t *testing.T) {
+	pm := newTestPackageManager(t)
+
+	writeFile := func(path string, size int) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addPackage := func(name string, global bool, recorded int64, files map[string]int) {
+		root := pm.getConfig().LocalPath
+		if global {
+			root = pm.getConfig().GlobalPath
+		}
+		info := &PackageInfo{Name: name, Version: "1.0.0", Global: global, Size: recorded, InstallPath: filepath.Join(root, name)}
+		for file, size := range files {
+			writeFile(filepath.Join(info.InstallPath, file), size)
+		}
+		pm.installedPackages[name] = info
+	}
+
+	// Записанный размер устарел: учитывается пересчитанный по каталогу
+	addPackage("big", true, 10, map[string]int{"bin/big": 3000, "lib/big.so": 1000})
+	addPackage("small", false, 100, map[string]int{"small.txt": 100})
+	addPackage("medium", false, 500, map[string]int{"a": 200, "b/c": 300})
+	// Каталог удален вручную: используется записанный размер
+	addPackage("gone", false, 50, nil)
+	writeFile(filepath.Join(pm.cacheDir(), "archive"), 700)
+	writeFile(filepath.Join(pm.getConfig().TempPath, "partial"), 20)
+
+	result := pm.DiskUsage(2)
+	if result.Global.Packages != 1 || result.Global.Size != 4000 {
+		t.Errorf("Unexpected global usage: %+v", result.Global)
+	}
+	if result.Local.Packages != 3 || result.Local.Size != 650 {
+		t.Errorf("Unexpected local usage: %+v", result.Local)
+	}
+	if result.Cache.Size != 700 || result.Temp.Size != 20 {
+		t.Errorf("Unexpected cache/temp usage: cache %+v, temp %+v", result.Cache, result.Temp)
+	}
+	if result.Total != 4000+650+700+20 {
+		t.Errorf("Total = %d, want %d", result.Total, 4000+650+700+20)
+	}
+	want := []PackageUsage{
+		{Name: "big", Version: "1.0.0", Global: true, Size: 4000, RecordedSize: 10},
+		{Name: "medium", Version: "1.0.0", Size: 500, RecordedSize: 500},
+	}
+	if !reflect.DeepEqual(result.Largest, want) {
+		t.Errorf("Largest = %+v, want %+v", result.Largest, want)
+	}
+
+	text, err := callToolText(t, newTestServer(t, pm), "disk_usage", nil)
+	if err != nil {
+		t.Fatalf("disk_usage failed: %v", err)
+	}
+	if !strings.Contains(text, "Всего занято: 5.2 KB") || !strings.Contains(text, "gone (1.0.0, локальный): 50 B") {
+		t.Errorf("Unexpected tool output:\n%s", text)
+	}
+}
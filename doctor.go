@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// diagnosticSeverity важность замечания аудита для непройденной проверки doctor
+var diagnosticSeverity = map[DiagnosticStatus]string{
+	DiagnosticWarn: SeverityWarning,
+	DiagnosticFail: SeverityError,
+}
+
+// Doctor проверяет настройку окружения: файл конфигурации, рабочие каталоги,
+// packages.json, каталоги установленных пакетов и доступность репозиториев.
+// Для каждой непройденной проверки предлагается способ исправления.
+func (pm *PackageManager) Doctor(ctx context.Context) *DoctorReport {
+	var diagnostics []Diagnostic
+	add := func(check, subject string, status DiagnosticStatus, message, fix string) {
+		diagnostics = append(diagnostics, Diagnostic{Check: check, Subject: subject, Status: status, Message: message, Fix: fix})
+	}
+
+	if pm.configPath == "" {
+		add("config", "", DiagnosticPass, "конфигурация передана программно, файл не используется", "")
+	} else if data, err := os.ReadFile(pm.configPath); errors.Is(err, os.ErrNotExist) {
+		add("config", pm.configPath, DiagnosticPass, "файл конфигурации не создан, используются значения по умолчанию", "")
+	} else if err != nil {
+		add("config", pm.configPath, DiagnosticFail, fmt.Sprintf("не удалось прочитать: %v", err), "проверьте права доступа к файлу конфигурации")
+	} else if err := json.Unmarshal(data, &Config{}); err != nil {
+		add("config", pm.configPath, DiagnosticFail, fmt.Sprintf("поврежденный JSON: %v", err), "исправьте файл или удалите его, чтобы вернуть настройки по умолчанию")
+	} else {
+		add("config", pm.configPath, DiagnosticPass, "файл конфигурации корректен", "")
+	}
+
+	for _, p := range pm.configuredPaths() {
+		if err := checkWritableDir(p.path); err != nil {
+			fix := fmt.Sprintf("выдайте права на запись или укажите другой каталог в параметре %s", p.name)
+			if errors.Is(err, os.ErrNotExist) {
+				fix = fmt.Sprintf("создайте каталог %s или укажите другой в параметре %s", p.path, p.name)
+			}
+			add("path", p.name, DiagnosticFail, err.Error(), fix)
+		} else {
+			add("path", p.name, DiagnosticPass, fmt.Sprintf("каталог %s доступен для записи", p.path), "")
+		}
+	}
+
+	for _, root := range []string{pm.getConfig().GlobalPath, pm.getConfig().LocalPath} {
+		path := filepath.Join(root, packagesFileName)
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			add("packages_file", path, DiagnosticPass, "пакеты еще не устанавливались", "")
+		case err != nil:
+			add("packages_file", path, DiagnosticFail, fmt.Sprintf("не удалось прочитать: %v", err), "проверьте права доступа к файлу")
+		case json.Unmarshal(data, &map[string]*PackageInfo{}) != nil:
+			add("packages_file", path, DiagnosticFail, "поврежденный JSON", "восстановите файл по каталогам установки инструментом repair_state")
+		default:
+			add("packages_file", path, DiagnosticPass, "файл корректен", "")
+		}
+	}
+
+	pm.packagesMutex.RLock()
+	packages := make([]*PackageInfo, 0, len(pm.installedPackages))
+	for _, info := range pm.installedPackages {
+		packages = append(packages, info)
+	}
+	pm.packagesMutex.RUnlock()
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+
+	missing := 0
+	for _, info := range packages {
+		if _, err := os.Stat(info.InstallPath); err != nil {
+			missing++
+			add("install_path", info.Name, DiagnosticFail, fmt.Sprintf("каталог установки %s отсутствует", info.InstallPath),
+				"переустановите пакет с force или удалите запись инструментом compact_index")
+		}
+	}
+	if missing == 0 {
+		add("install_path", "", DiagnosticPass, fmt.Sprintf("каталоги установки на месте у всех пакетов (%d)", len(packages)), "")
+	}
+
+	var repositories []Repository
+	for _, repo := range pm.getConfig().Repositories {
+		if repo.Enabled {
+			repositories = append(repositories, repo)
+		}
+	}
+	pings := make([]Diagnostic, len(repositories))
+	pm.runConcurrently(len(repositories), func(i int) {
+		repo := repositories[i]
+		pings[i] = Diagnostic{Check: "repository", Subject: repo.Name}
+		if _, latency, err := pm.pingRepository(ctx, repo); err != nil {
+			pings[i].Status = DiagnosticWarn
+			pings[i].Message = fmt.Sprintf("репозиторий недоступен: %v", err)
+			pings[i].Fix = fmt.Sprintf("проверьте адрес %s и сетевые настройки или отключите репозиторий", repo.URL)
+		} else {
+			pings[i].Status = DiagnosticPass
+			pings[i].Message = fmt.Sprintf("репозиторий отвечает (%d мс)", latency.Milliseconds())
+		}
+	})
+	diagnostics = append(diagnostics, pings...)
+
+	report := &DoctorReport{Diagnostics: diagnostics}
+	for _, diagnostic := range diagnostics {
+		switch diagnostic.Status {
+		case DiagnosticPass:
+			report.Passed++
+		case DiagnosticWarn:
+			report.Warnings++
+		default:
+			report.Failures++
+		}
+	}
+	return report
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDoctorHealthy проверяет, что в исправном окружении все проверки пройдены
+func TestDoctorHealthy(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "alpha", Version: "1.0.0"}, false)
+	repo := newMockRepository(t)
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	if err := os.WriteFile(pm.configPath, []byte(`{"timeout": 5}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := pm.Doctor(context.Background())
+	if report.Warnings != 0 || report.Failures != 0 {
+		t.Errorf("expected all checks to pass, got %+v", report.Diagnostics)
+	}
+	checks := make(map[string]bool)
+	for _, diagnostic := range report.Diagnostics {
+		checks[diagnostic.Check] = true
+		if diagnostic.Fix != "" {
+			t.Errorf("passed check %s has a fix: %q", diagnostic.Check, diagnostic.Fix)
+		}
+	}
+	for _, check := range []string{"config", "path", "packages_file", "install_path", "repository"} {
+		if !checks[check] {
+			t.Errorf("missing check %s in %+v", check, report.Diagnostics)
+		}
+	}
+
+	result, err := newTestServer(t, pm).callTool(context.Background(), "doctor", nil)
+	if err != nil || result.IsError {
+		t.Fatalf("doctor failed: %+v, %v", result, err)
+	}
+}
+
+// TestDoctorCorrupted проверяет ошибки и советы для поврежденного окружения
+func TestDoctorCorrupted(t *testing.T) {
+	pm := newTestPackageManager(t)
+	info := installTestArchive(t, pm, PackageManifest{Name: "alpha", Version: "1.0.0"}, false)
+	if err := os.RemoveAll(info.InstallPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pm.getConfig().LocalPath, packagesFileName), []byte("{broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pm.configPath, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(pm.getConfig().TempPath); err != nil {
+		t.Fatal(err)
+	}
+	unreachable := newMockRepository(t)
+	unreachable.Close()
+	pm.getConfig().Repositories = []Repository{unreachable.repository("offline", 1)}
+
+	report := pm.Doctor(context.Background())
+	expected := []Diagnostic{
+		{Check: "config", Subject: pm.configPath, Status: DiagnosticFail},
+		{Check: "path", Subject: "temp_path", Status: DiagnosticFail},
+		{Check: "packages_file", Subject: filepath.Join(pm.getConfig().LocalPath, packagesFileName), Status: DiagnosticFail},
+		{Check: "install_path", Subject: "alpha", Status: DiagnosticFail},
+		{Check: "repository", Subject: "offline", Status: DiagnosticWarn},
+	}
+	for _, want := range expected {
+		found := false
+		for _, got := range report.Diagnostics {
+			if got.Check == want.Check && got.Subject == want.Subject && got.Status == want.Status {
+				found = got.Fix != ""
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing diagnostic with fix %+v in %+v", want, report.Diagnostics)
+		}
+	}
+	if report.Failures != 4 || report.Warnings != 1 {
+		t.Errorf("unexpected counts: %d failures, %d warnings", report.Failures, report.Warnings)
+	}
+
+	result, err := newTestServer(t, pm).callTool(context.Background(), "doctor", nil)
+	if err != nil {
+		t.Fatalf("doctor failed: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "compact_index") {
+		t.Errorf("unexpected tool output:\n%s", result.Content[0].Text)
+	}
+}
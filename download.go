@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// downloadChunkSize размер буфера, которым скачиваемый файл копируется на диск
+const downloadChunkSize = 32 * 1024
+
+// downloadPhase название этапа скачивания в уведомлениях о прогрессе
+const downloadPhase = "Скачивание"
+
+// copyDownload копирует тело ответа в dst фиксированными блоками, сообщая о скорости
+// и оставшемся времени. limit ограничивает размер файла (0 — без ограничения);
+// total — ожидаемый размер из Content-Length (0 или меньше, если неизвестен).
+func copyDownload(ctx context.Context, dst io.Writer, src io.Reader, total, limit int64, report ProgressFunc) (int64, error) {
+	if limit > 0 && total > limit {
+		return 0, downloadTooLargeError(total, limit)
+	}
+	if total < 0 {
+		total = 0
+	}
+
+	buf := make([]byte, downloadChunkSize)
+	started := time.Now()
+	var written, reported int64
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if limit > 0 && written+int64(n) > limit {
+				return written, downloadTooLargeError(written+int64(n), limit)
+			}
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+
+		done := readErr == io.EOF
+		if report != nil && (written-reported >= progressStep || (done && written != reported)) {
+			reported = written
+			rate, remaining := downloadETA(written, total, time.Since(started))
+			report(written, total, formatDownloadProgress(rate, remaining))
+		}
+
+		if done {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
+
+// downloadETA вычисляет скорость скачивания в байтах в секунду и оставшееся время.
+// Оставшееся время отрицательно, если его нельзя оценить.
+func downloadETA(done, total int64, elapsed time.Duration) (float64, time.Duration) {
+	if elapsed <= 0 || done <= 0 {
+		return 0, -1
+	}
+
+	rate := float64(done) / elapsed.Seconds()
+	if total <= 0 {
+		return rate, -1
+	}
+	if done >= total {
+		return rate, 0
+	}
+
+	remaining := time.Duration(float64(total-done) / rate * float64(time.Second))
+	return rate, remaining
+}
+
+// formatDownloadProgress формирует сообщение о прогрессе: этап, скорость и оставшееся время
+func formatDownloadProgress(rate float64, remaining time.Duration) string {
+	if rate <= 0 {
+		return downloadPhase
+	}
+
+	message := fmt.Sprintf("%s: %s/с", downloadPhase, formatSize(int64(rate)))
+	if remaining >= 0 {
+		message += fmt.Sprintf(", осталось %s", remaining.Round(time.Second))
+	}
+	return message
+}
+
+// downloadTooLargeError сообщает о превышении max_download_size
+func downloadTooLargeError(size, limit int64) error {
+	return fmt.Errorf("размер скачиваемого файла (%s) превышает max_download_size (%s)", formatSize(size), formatSize(limit))
+}
+
+// ErrFileUnavailable архив пакета для выбранной платформы отсутствует в репозитории
+var ErrFileUnavailable = errors.New("файл пакета недоступен")
+
+// probeDownload проверяет запросом HEAD, что архив пакета есть в репозитории, до начала
+// скачивания, и возвращает его размер из Content-Length (-1, если неизвестен).
+// Ответы 404 и 410 означают отсутствие файла для платформы. Прочие ответы (например,
+// 405 от сервера без поддержки HEAD) проверку не прерывают: окончательный ответ дает GET.
+func (pm *PackageManager) probeDownload(ctx context.Context, resolved *resolvedPackage) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, resolved.DownloadURL, nil)
+	if err != nil {
+		return -1, err
+	}
+
+	resp, err := pm.doRequest(req)
+	if err != nil {
+		return -1, fmt.Errorf("ошибка проверки файла: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return -1, fmt.Errorf("%w: %s %s для %s/%s отсутствует в репозитории %s",
+			ErrFileUnavailable, resolved.Info.Name, resolved.Version.Version, resolved.File.OS, resolved.File.Arch, resolved.Repository.Name)
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		if limit := pm.getConfig().MaxDownloadSize; limit > 0 && resp.ContentLength > limit {
+			return -1, downloadTooLargeError(resp.ContentLength, limit)
+		}
+		return resp.ContentLength, nil
+	default:
+		logger.Debugf("Проверка %s пропущена: ответ HEAD %d", resolved.DownloadURL, resp.StatusCode)
+		return -1, nil
+	}
+}
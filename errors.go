@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// Причины ошибок, по которым MCP-слой определяет код ошибки инструмента.
+// Ошибки менеджера пакетов сохраняют текст для человека и оборачивают причину,
+// поэтому ее можно проверить через errors.Is.
+var (
+	// ErrPackageNotFound пакет не найден в репозиториях или среди установленных
+	ErrPackageNotFound = errors.New("пакет не найден")
+	// ErrVersionNotFound репозиторий не знает запрошенной версии пакета
+	ErrVersionNotFound = errors.New("версия пакета не найдена")
+	// ErrAlreadyInstalled пакет уже установлен в запрошенной версии
+	ErrAlreadyInstalled = errors.New("пакет уже установлен")
+	// ErrChecksumMismatch содержимое не совпадает с ожидаемой контрольной суммой
+	ErrChecksumMismatch = errors.New("контрольная сумма не совпадает")
+	// ErrUnauthorized репозиторий отклонил токен авторизации или доступ к ресурсу
+	ErrUnauthorized = errors.New("нет доступа к репозиторию")
+)
+
+// ErrorCode код категории ошибки инструмента для программной обработки клиентом
+type ErrorCode string
+
+const (
+	ErrorCodePackageNotFound  ErrorCode = "package-not-found"
+	ErrorCodeVersionNotFound  ErrorCode = "version-not-found"
+	ErrorCodeChecksumMismatch ErrorCode = "checksum-mismatch"
+	ErrorCodeNetwork          ErrorCode = "network-error"
+	ErrorCodeUnauthorized     ErrorCode = "unauthorized"
+	ErrorCodeAlreadyInstalled ErrorCode = "already-installed"
+	ErrorCodeOther            ErrorCode = "tool-error" // ошибка без отдельной категории
+)
+
+// ErrorData машиночитаемое описание ошибки инструмента; передается в поле "error"
+// structuredContent результата с isError
+type ErrorData struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// causeError ошибка с текстом для человека и причинами, доступными errors.Is и errors.As
+type causeError struct {
+	message string
+	causes  []error
+}
+
+func (e *causeError) Error() string { return e.message }
+
+func (e *causeError) Unwrap() []error { return e.causes }
+
+// errorWithCause возвращает ошибку с текстом fmt.Errorf(format, args...), причиной которой
+// считается cause; ошибки, обернутые в format через %w, тоже остаются доступны
+func errorWithCause(cause error, format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	return &causeError{message: err.Error(), causes: []error{cause, err}}
+}
+
+// errorCode определяет категорию ошибки. Ошибка может содержать несколько причин
+// (например, «не найден» с ошибками отдельных репозиториев), поэтому более точные
+// категории проверяются раньше общих.
+func errorCode(err error) ErrorCode {
+	var urlErr *url.Error
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrChecksumMismatch):
+		return ErrorCodeChecksumMismatch
+	case errors.Is(err, ErrUnauthorized):
+		return ErrorCodeUnauthorized
+	case errors.Is(err, ErrVersionNotFound):
+		return ErrorCodeVersionNotFound
+	case errors.As(err, &urlErr), errors.As(err, &netErr), errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeNetwork
+	case errors.Is(err, ErrAlreadyInstalled):
+		return ErrorCodeAlreadyInstalled
+	case errors.Is(err, ErrPackageNotFound):
+		return ErrorCodePackageNotFound
+	default:
+		return ErrorCodeOther
+	}
+}
+
+// statusError возвращает ошибку неуспешного ответа репозитория; для 401 и 403
+// ее причиной считается ErrUnauthorized
+func statusError(status int, format string, args ...interface{}) error {
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return errorWithCause(ErrUnauthorized, format, args...)
+	}
+	return fmt.Errorf(format, args...)
+}
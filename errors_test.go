@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// callToolError вызывает инструмент через tools/call и возвращает код ошибки из structuredContent
+func callToolError(t *testing.T, s *MCPServer, name string, args map[string]interface{}) ErrorData {
+	t.Helper()
+
+	response := s.handleMessage(context.Background(), MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": name, "arguments": args},
+	})
+	result, ok := response.Result.(CallToolResult)
+	if !ok || !result.IsError {
+		t.Fatalf("%s: expected tool error, got %+v", name, response)
+	}
+	content, ok := result.StructuredContent.(map[string]interface{})
+	if !ok {
+		t.Fatalf("%s: expected structured error, got %+v", name, result.StructuredContent)
+	}
+	data, ok := content["error"].(ErrorData)
+	if !ok {
+		t.Fatalf("%s: expected ErrorData, got %+v", name, content)
+	}
+	if !strings.Contains(result.Content[0].Text, data.Message) {
+		t.Errorf("%s: structured message %q differs from text %q", name, data.Message, result.Content[0].Text)
+	}
+	return data
+}
+
+// TestToolErrorCodes проверяет, что каждая категория сбоя доходит до клиента своим кодом
+func TestToolErrorCodes(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	defer repo.Close()
+	archivePath := buildTestArchive(t, pm, PackageManifest{Name: "lib", Version: "1.0.0"}, map[string]string{"lib.txt": "lib"}, FormatTarGz)
+	repo.publish(t, "lib", "1.0.0", archivePath)
+	pm.getConfig().Repositories = []Repository{repo.repository("main", 1)}
+	s := newTestServer(t, pm)
+
+	if data := callToolError(t, s, "install_package", map[string]interface{}{"name": "missing"}); data.Code != ErrorCodePackageNotFound {
+		t.Errorf("missing package: expected %s, got %+v", ErrorCodePackageNotFound, data)
+	}
+	if data := callToolError(t, s, "install_package", map[string]interface{}{"name": "lib", "version": "9.9.9"}); data.Code != ErrorCodeVersionNotFound {
+		t.Errorf("missing version: expected %s, got %+v", ErrorCodeVersionNotFound, data)
+	}
+
+	if _, err := callToolText(t, s, "install_package", map[string]interface{}{"name": "lib"}); err != nil {
+		t.Fatalf("install_package: %v", err)
+	}
+	if data := callToolError(t, s, "install_package", map[string]interface{}{"name": "lib"}); data.Code != ErrorCodeAlreadyInstalled {
+		t.Errorf("repeated install: expected %s, got %+v", ErrorCodeAlreadyInstalled, data)
+	}
+
+	_, archiveURL := serveFile(t, archivePath)
+	data := callToolError(t, s, "install_from_url", map[string]interface{}{
+		"url": archiveURL, "expected_checksum": "sha256:" + strings.Repeat("0", 64), "force": true,
+	})
+	if data.Code != ErrorCodeChecksumMismatch {
+		t.Errorf("bad checksum: expected %s, got %+v", ErrorCodeChecksumMismatch, data)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	pm.getConfig().Repositories = []Repository{{Name: "down", URL: closed.URL, Enabled: true}}
+	if data := callToolError(t, s, "install_package", map[string]interface{}{"name": "other"}); data.Code != ErrorCodeNetwork {
+		t.Errorf("unreachable repository: expected %s, got %+v", ErrorCodeNetwork, data)
+	}
+
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer private.Close()
+	pm.getConfig().Repositories = []Repository{{Name: "private", URL: private.URL, Enabled: true, AuthToken: "wrong"}}
+	if data := callToolError(t, s, "install_package", map[string]interface{}{"name": "other"}); data.Code != ErrorCodeUnauthorized {
+		t.Errorf("rejected token: expected %s, got %+v", ErrorCodeUnauthorized, data)
+	}
+
+	if data := callToolError(t, s, "uninstall_package", map[string]interface{}{}); data.Code != ErrorCodeOther {
+		t.Errorf("validation error: expected %s, got %+v", ErrorCodeOther, data)
+	}
+}
+
+// TestTypedErrors проверяет, что ошибки менеджера пакетов сохраняют текст и позволяют
+// определить причину через errors.Is
+func TestTypedErrors(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	defer repo.Close()
+	archivePath := buildTestArchive(t, pm, PackageManifest{Name: "lib", Version: "1.0.0"}, map[string]string{"lib.txt": "lib"}, FormatTarGz)
+	repo.publish(t, "lib", "1.0.0", archivePath)
+	pm.getConfig().Repositories = []Repository{repo.repository("main", 1)}
+	ctx := context.Background()
+
+	install := func(name, version string) error {
+		_, err := pm.InstallPackage(ctx, name, version, false, false, false, false, "", "")
+		return err
+	}
+
+	err := install("missing", "")
+	if !errors.Is(err, ErrPackageNotFound) || !strings.Contains(err.Error(), "пакет missing не найден") {
+		t.Errorf("expected ErrPackageNotFound with the original message, got %v", err)
+	}
+	if err := install("lib", "9.9.9"); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("expected ErrVersionNotFound, got %v", err)
+	}
+	if err := install("lib", ""); err != nil {
+		t.Fatalf("InstallPackage: %v", err)
+	}
+	if err := install("lib", ""); !errors.Is(err, ErrAlreadyInstalled) || errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("expected only ErrAlreadyInstalled, got %v", err)
+	}
+
+	_, archiveURL := serveFile(t, archivePath)
+	if _, err := pm.InstallFromURL(ctx, archiveURL, "sha256:"+strings.Repeat("0", 64), false, true); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if err := pm.UninstallPackage("missing", false, false); !errors.Is(err, ErrPackageNotFound) || !strings.Contains(err.Error(), "не установлен") {
+		t.Errorf("expected ErrPackageNotFound for an uninstalled package, got %v", err)
+	}
+
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer private.Close()
+	if _, err := pm.GetPackageVersionInfo(ctx, private.URL, "lib", "1.0.0"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+	pm.getConfig().Repositories = []Repository{{Name: "private", URL: private.URL, Enabled: true}}
+	if err := install("other", ""); !errors.Is(err, ErrUnauthorized) || !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("expected not found error to keep the repository cause, got %v", err)
+	}
+}
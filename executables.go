@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// executableRunTimeout ограничивает время запуска исполняемого файла с --version
+const executableRunTimeout = 10 * time.Second
+
+// CheckExecutables проверяет исполняемые файлы, перечисленные в манифесте установленного
+// пакета: файл должен существовать, а в Unix — иметь бит исполнения. С run каждый файл
+// дополнительно запускается с --version.
+func (pm *PackageManager) CheckExecutables(ctx context.Context, packageName string, run bool) (*ExecutableCheckResult, error) {
+	info, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	manifest, err := pm.loadManifestFromDir(info.InstallPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения манифеста пакета: %w", err)
+	}
+
+	result := &ExecutableCheckResult{Name: info.Name, Version: info.Version, Executables: []ExecutableCheck{}}
+	for _, name := range manifest.Executables {
+		check := pm.checkExecutable(ctx, info.InstallPath, name, run)
+		if !check.OK {
+			result.Failed++
+		}
+		result.Executables = append(result.Executables, check)
+	}
+
+	return result, nil
+}
+
+// checkExecutable проверяет один исполняемый файл пакета
+func (pm *PackageManager) checkExecutable(ctx context.Context, installPath, name string, run bool) ExecutableCheck {
+	check := ExecutableCheck{Path: name}
+
+	path, err := safeJoin(installPath, name)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		check.Error = "файл не найден"
+		return check
+	}
+	check.Exists = true
+	if stat.IsDir() {
+		check.Error = "это каталог"
+		return check
+	}
+
+	// В Windows бита исполнения нет: достаточно наличия файла
+	check.Executable = runtime.GOOS == "windows" || stat.Mode().Perm()&0111 != 0
+	if !check.Executable {
+		check.Error = fmt.Sprintf("нет права на исполнение (%s)", stat.Mode().Perm())
+		return check
+	}
+
+	if run {
+		runCtx, cancel := context.WithTimeout(ctx, executableRunTimeout)
+		defer cancel()
+
+		output, err := exec.CommandContext(runCtx, path, "--version").CombinedOutput()
+		if err != nil {
+			check.Error = fmt.Sprintf("ошибка запуска: %v", err)
+			return check
+		}
+		check.Ran = true
+		check.Output = firstLine(string(output))
+	}
+
+	check.OK = true
+	return check
+}
+
+// firstLine возвращает первую непустую строку текста
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
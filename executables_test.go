@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestCheckExecutables проверяет сохранение бита исполнения при установке и отчет о проблемных файлах
+func TestCheckExecutables(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts and permission bits are Unix-specific")
+	}
+
+	pm := newTestPackageManager(t)
+	srcDir := t.TempDir()
+	manifest := PackageManifest{
+		Name:        "runner",
+		Version:     "1.0.0",
+		Executables: []string{"bin/runner", "bin/plain", "bin/missing", "../outside"},
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]struct {
+		content string
+		mode    os.FileMode
+	}{
+		manifestFileName: {string(data), 0644},
+		"bin/runner":     {"#!/bin/sh\necho runner 1.0.0\n", 0755},
+		"bin/plain":      {"#!/bin/sh\necho plain\n", 0644},
+	}
+	for name, file := range files {
+		path := filepath.Join(srcDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file.content), file.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, file.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "runner-1.0.0"+archiveExtension(FormatTarGz))
+	if err := pm.createArchive(srcDir, archivePath, FormatTarGz, 3); err != nil {
+		t.Fatalf("createArchive: %v", err)
+	}
+	if _, err := pm.installFromArchive(context.Background(), archivePath, false, false, nil); err != nil {
+		t.Fatalf("installFromArchive: %v", err)
+	}
+
+	result, err := pm.CheckExecutables(context.Background(), "runner", true)
+	if err != nil {
+		t.Fatalf("CheckExecutables: %v", err)
+	}
+	if result.Failed != 3 || len(result.Executables) != 4 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	checks := make(map[string]ExecutableCheck)
+	for _, check := range result.Executables {
+		checks[check.Path] = check
+	}
+	if runner := checks["bin/runner"]; !runner.OK || !runner.Ran || runner.Output != "runner 1.0.0" {
+		t.Errorf("expected runner to launch, got %+v", runner)
+	}
+	if plain := checks["bin/plain"]; plain.OK || !plain.Exists || plain.Executable {
+		t.Errorf("expected plain to lack the executable bit, got %+v", plain)
+	}
+	if missing := checks["bin/missing"]; missing.OK || missing.Exists {
+		t.Errorf("expected missing to be reported, got %+v", missing)
+	}
+	if outside := checks["../outside"]; outside.OK || outside.Error == "" {
+		t.Errorf("expected path outside the package to be rejected, got %+v", outside)
+	}
+}
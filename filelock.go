@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// locksDirName подкаталог пути установки с файлами блокировок пакетов
+const locksDirName = ".locks"
+
+// fileLock эксклюзивная рекомендательная блокировка файла. Блокировка действует
+// между процессами, использующими общий каталог ~/.criage, и между разными
+// открытиями файла в одном процессе.
+type fileLock struct {
+	file *os.File
+}
+
+// lockFile захватывает блокировку файла path, ожидая ее освобождения другими владельцами
+func lockFile(path string) (*fileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога блокировки: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла блокировки: %w", err)
+	}
+	if err := lockHandle(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("ошибка блокировки %s: %w", path, err)
+	}
+
+	return &fileLock{file: file}, nil
+}
+
+// Unlock освобождает блокировку
+func (l *fileLock) Unlock() {
+	unlockHandle(l.file)
+	l.file.Close()
+}
+
+// lockPackage блокирует установку и удаление пакета в области global/local
+func (pm *PackageManager) lockPackage(packageName string, global bool) (*fileLock, error) {
+	root := pm.getConfig().LocalPath
+	if global {
+		root = pm.getConfig().GlobalPath
+	}
+	return lockFile(filepath.Join(root, locksDirName, packageName+".lock"))
+}
+
+// lockPackagesFile блокирует packages.json на время чтения, изменения и записи
+func lockPackagesFile(packagesPath string) (*fileLock, error) {
+	return lockFile(filepath.Join(filepath.Dir(packagesPath), locksDirName, filepath.Base(packagesPath)+".lock"))
+}
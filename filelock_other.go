@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package main
+
+import "os"
+
+// На платформах без блокировок файлов установки не синхронизируются между процессами
+
+func lockHandle(file *os.File) error {
+	return nil
+}
+
+func unlockHandle(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentInstalls проверяет, что параллельные установки из двух менеджеров
+// с общими каталогами (как два процесса с одним ~/.criage) не теряют записи packages.json
+func TestConcurrentInstalls(t *testing.T) {
+	pm := newTestPackageManager(t)
+	other := newTestPackageManager(t)
+	other.config.Store(pm.getConfig())
+
+	const count = 8
+	archives := make([]string, count)
+	for i := range archives {
+		name := fmt.Sprintf("pkg%d", i)
+		archives[i] = buildTestArchive(t, pm, PackageManifest{Name: name, Version: "1.0.0"},
+			map[string]string{"data.txt": name}, FormatTarGz)
+	}
+	shared := buildTestArchive(t, pm, PackageManifest{Name: "shared", Version: "1.0.0"},
+		map[string]string{"a.txt": "a", "b.txt": "b"}, FormatTarGz)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*count)
+	for i, archive := range archives {
+		manager := pm
+		if i%2 == 1 {
+			manager = other
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := manager.installFromArchive(context.Background(), archive, false, false, nil)
+			errs <- err
+		}()
+		// Один и тот же пакет переустанавливается параллельно
+		go func() {
+			defer wg.Done()
+			_, err := manager.installFromArchive(context.Background(), shared, false, true, nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("install failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(pm.getConfig().LocalPath, "packages.json"))
+	if err != nil {
+		t.Fatalf("Failed to read packages.json: %v", err)
+	}
+	var packages map[string]*PackageInfo
+	if err := json.Unmarshal(data, &packages); err != nil {
+		t.Fatalf("packages.json is not valid JSON: %v", err)
+	}
+	if len(packages) != count+1 {
+		t.Errorf("expected %d packages in packages.json, got %d", count+1, len(packages))
+	}
+	for i := 0; i < count; i++ {
+		if _, ok := packages[fmt.Sprintf("pkg%d", i)]; !ok {
+			t.Errorf("pkg%d is missing from packages.json", i)
+		}
+	}
+
+	for _, file := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Stat(filepath.Join(pm.getConfig().LocalPath, "shared", file)); err != nil {
+			t.Errorf("shared package is incomplete: %v", err)
+		}
+	}
+
+	// Каталог блокировок не считается осиротевшим
+	if err := pm.loadInstalledPackages(); err != nil {
+		t.Fatalf("loadInstalledPackages: %v", err)
+	}
+	orphans, err := pm.FindOrphans()
+	if err != nil {
+		t.Fatalf("FindOrphans: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("expected no orphans, got %v", orphans)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// maxSearchSuggestions число подсказок «возможно, вы имели в виду»
+const maxSearchSuggestions = 5
+
+// levenshtein возвращает расстояние редактирования между строками в символах
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// suggestionThreshold наибольшее расстояние до имени пакета, при котором оно предлагается:
+// половина длины запроса, но не менее 1 и не более 3
+func suggestionThreshold(query string) int {
+	return min(3, max(1, len([]rune(query))/2))
+}
+
+// suggestPackages ищет в списках пакетов репозиториев имена, близкие к запросу по
+// расстоянию редактирования. Используется, когда поиск не нашел ничего: например, при опечатке.
+func (pm *PackageManager) suggestPackages(ctx context.Context, query string) []SearchSuggestion {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	threshold := suggestionThreshold(query)
+
+	seen := make(map[string]bool)
+	var suggestions []SearchSuggestion
+	for _, repo := range pm.repositoriesByPriority() {
+		for page := 1; ; page++ {
+			list, err := pm.ListRepositoryPackages(ctx, repo.URL, page, searchIndexPageSize)
+			if err != nil {
+				logger.Debugf("Список пакетов %s для подсказок недоступен: %v", repo.Name, err)
+				break
+			}
+
+			for _, pkg := range list.Packages {
+				if seen[pkg.Name] {
+					continue
+				}
+				if distance := levenshtein(query, strings.ToLower(pkg.Name)); distance <= threshold {
+					seen[pkg.Name] = true
+					suggestions = append(suggestions, SearchSuggestion{Name: pkg.Name, Distance: distance, Repository: repo.Name})
+				}
+			}
+
+			if len(list.Packages) == 0 || page >= list.TotalPages {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	// Устойчивая сортировка оставляет выше пакеты более приоритетных репозиториев
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Distance != suggestions[j].Distance {
+			return suggestions[i].Distance < suggestions[j].Distance
+		}
+		return suggestions[i].Name < suggestions[j].Name
+	})
+	if len(suggestions) > maxSearchSuggestions {
+		suggestions = suggestions[:maxSearchSuggestions]
+	}
+	return suggestions
+}
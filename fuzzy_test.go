@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLevenshtein проверяет расстояние редактирования, в том числе для не-ASCII строк
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"json", "", 4},
+		{"json", "json", 0},
+		{"jsno", "json", 2},
+		{"kitten", "sitting", 3},
+		{"пакет", "пакеты", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein(tt.b, tt.a); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+// TestSearchSuggestions проверяет подсказки по опечатке в запросе, не давший результатов
+func TestSearchSuggestions(t *testing.T) {
+	var packages []*RepositoryPackage
+	for _, name := range []string{"json-tool", "json-tools", "yaml-lint", "toml", "jsonnet", "xml-tool"} {
+		packages = append(packages, &RepositoryPackage{Name: name, Versions: []RepositoryVersion{{Version: "1.0.0"}}})
+	}
+	repo := newMockRepository(t, packages...)
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	s := newTestServer(t, pm)
+
+	page, err := pm.SearchPackages(context.Background(), "jsn-tool", SearchFilter{}, 1, 20)
+	if err != nil {
+		t.Fatalf("SearchPackages: %v", err)
+	}
+	if len(page.Results) != 0 {
+		t.Fatalf("expected no exact results, got %+v", page.Results)
+	}
+	want := []SearchSuggestion{
+		{Name: "json-tool", Distance: 1, Repository: "mock"},
+		{Name: "json-tools", Distance: 2, Repository: "mock"},
+		{Name: "xml-tool", Distance: 3, Repository: "mock"},
+	}
+	if !reflect.DeepEqual(page.Suggestions, want) {
+		t.Errorf("expected suggestions %+v, got %+v", want, page.Suggestions)
+	}
+
+	// Найденные результаты подсказок не требуют
+	page, err = pm.SearchPackages(context.Background(), "json", SearchFilter{}, 1, 20)
+	if err != nil {
+		t.Fatalf("SearchPackages: %v", err)
+	}
+	if len(page.Results) == 0 || page.Suggestions != nil {
+		t.Errorf("expected results without suggestions, got %+v", page)
+	}
+
+	// Слишком далекие имена не предлагаются
+	page, err = pm.SearchPackages(context.Background(), "kubernetes", SearchFilter{}, 1, 20)
+	if err != nil {
+		t.Fatalf("SearchPackages: %v", err)
+	}
+	if len(page.Suggestions) != 0 {
+		t.Errorf("expected no suggestions for unrelated query, got %+v", page.Suggestions)
+	}
+
+	text, err := callToolText(t, s, "search_packages", map[string]interface{}{"query": "yml-lint"})
+	if err != nil {
+		t.Fatalf("search_packages: %v", err)
+	}
+	if !strings.Contains(text, "вы имели в виду") || !strings.Contains(text, "yaml-lint") {
+		t.Errorf("expected suggestion in tool output, got:\n%s", text)
+	}
+}
@@ -2,6 +2,15 @@ module criage-mcp-server
 
 go 1.24.4
 
-toolchain go1.22.0
+require (
+	github.com/dsnet/compress v0.0.2-0.20230904184137-39efe44ab707
+	github.com/klauspost/compress v1.18.0
+	github.com/ulikunitz/xz v0.5.15
+	github.com/zeebo/blake3 v0.2.4
+	golang.org/x/crypto v0.36.0
+)
 
-require github.com/criage-oss/criage-common v1.0.7
+require (
+	github.com/klauspost/cpuid/v2 v2.0.12 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+)
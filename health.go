@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Параметры отслеживания доступности репозиториев
+const (
+	healthFailureThreshold = 3           // подряд идущих сбоев до понижения репозитория
+	healthCooldown         = time.Minute // время, на которое репозиторий понижается
+)
+
+// hostHealth состояние доступности одного хоста репозитория
+type hostHealth struct {
+	consecutiveFailures int
+	totalFailures       int
+	lastError           string
+	lastFailure         time.Time
+	lastSuccess         time.Time
+	demotedUntil        time.Time
+}
+
+// healthTracker запоминает недавние сбои репозиториев. Хост, несколько раз подряд
+// не ответивший, временно проверяется последним; после паузы он снова получает
+// обычный приоритет, а первый успешный ответ сбрасывает счетчик сбоев.
+type healthTracker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	hosts     map[string]*hostHealth
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		threshold: healthFailureThreshold,
+		cooldown:  healthCooldown,
+		now:       time.Now,
+		hosts:     make(map[string]*hostHealth),
+	}
+}
+
+// repositoryHost возвращает ключ отслеживания для URL репозитория
+func repositoryHost(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(parsed.Host)
+}
+
+// host возвращает запись хоста, создавая ее при необходимости; вызывается под mu
+func (h *healthTracker) host(host string) *hostHealth {
+	state, ok := h.hosts[host]
+	if !ok {
+		state = &hostHealth{}
+		h.hosts[host] = state
+	}
+	return state
+}
+
+// recordSuccess отмечает успешный ответ хоста
+func (h *healthTracker) recordSuccess(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state := h.host(host)
+	state.consecutiveFailures = 0
+	state.demotedUntil = time.Time{}
+	state.lastSuccess = h.now()
+}
+
+// recordFailure отмечает сбой хоста и понижает его после порога подряд идущих сбоев
+func (h *healthTracker) recordFailure(host, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state := h.host(host)
+	state.consecutiveFailures++
+	state.totalFailures++
+	state.lastError = reason
+	state.lastFailure = h.now()
+	if state.consecutiveFailures >= h.threshold {
+		state.demotedUntil = state.lastFailure.Add(h.cooldown)
+	}
+}
+
+// demoted сообщает, понижен ли хост в данный момент
+func (h *healthTracker) demoted(host string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, ok := h.hosts[host]
+	return ok && h.now().Before(state.demotedUntil)
+}
+
+// status возвращает состояние репозитория для отчета
+func (h *healthTracker) status(repo Repository) RepositoryHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := RepositoryHealth{Name: repo.Name, URL: repo.URL, Enabled: repo.Enabled, Healthy: true}
+	state, ok := h.hosts[repositoryHost(repo.URL)]
+	if !ok {
+		return result
+	}
+
+	result.ConsecutiveFailures = state.consecutiveFailures
+	result.TotalFailures = state.totalFailures
+	result.LastError = state.lastError
+	result.Healthy = state.consecutiveFailures == 0
+	if !state.lastFailure.IsZero() {
+		lastFailure := state.lastFailure
+		result.LastFailure = &lastFailure
+	}
+	if !state.lastSuccess.IsZero() {
+		lastSuccess := state.lastSuccess
+		result.LastSuccess = &lastSuccess
+	}
+	if h.now().Before(state.demotedUntil) {
+		demotedUntil := state.demotedUntil
+		result.Demoted = true
+		result.DemotedUntil = &demotedUntil
+	}
+	return result
+}
+
+// RepositoryHealth возвращает состояние доступности настроенных репозиториев
+func (pm *PackageManager) RepositoryHealth() []RepositoryHealth {
+	repositories := pm.ListRepositories()
+	result := make([]RepositoryHealth, 0, len(repositories))
+	for _, repo := range repositories {
+		result = append(result, pm.health.status(repo))
+	}
+	return result
+}
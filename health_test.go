@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRepositoryHealthFailover проверяет понижение нестабильного репозитория и его
+// восстановление после паузы
+func TestRepositoryHealthFailover(t *testing.T) {
+	pm := newTestPackageManager(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	pm.health.now = func() time.Time { return now }
+
+	backup := newMockRepository(t)
+	backup.publish(t, "tool", "1.0.0", buildTestArchive(t, pm, PackageManifest{Name: "tool", Version: "1.0.0"},
+		map[string]string{"src/main.txt": "tool"}, FormatTarGz))
+
+	// Основное зеркало то отвечает ошибкой, то проксирует запросы в резервный репозиторий
+	var mu sync.Mutex
+	down := true
+	hits := 0
+	flapping := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		isDown := down
+		mu.Unlock()
+		if isDown {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		backup.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer flapping.Close()
+
+	pm.getConfig().Repositories = []Repository{
+		{Name: "primary", URL: flapping.URL, Priority: 10, Enabled: true},
+		backup.repository("backup", 1),
+	}
+	ctx := context.Background()
+
+	for i := 0; i < healthFailureThreshold; i++ {
+		resolved, err := pm.findPackage(ctx, "tool", "", "", "")
+		if err != nil || resolved.Repository.Name != "backup" {
+			t.Fatalf("attempt %d: expected fallback to backup, got %+v, %v", i+1, resolved, err)
+		}
+	}
+
+	status := pm.RepositoryHealth()[0]
+	if !status.Demoted || status.ConsecutiveFailures != healthFailureThreshold || status.LastError == "" {
+		t.Fatalf("expected primary to be demoted, got %+v", status)
+	}
+
+	// Пониженный репозиторий не опрашивается, пока резервный отвечает
+	requests := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits
+	}
+	hitsBefore := requests()
+	if resolved, err := pm.findPackage(ctx, "tool", "", "", ""); err != nil || resolved.Repository.Name != "backup" {
+		t.Fatalf("expected backup while primary is demoted, got %+v, %v", resolved, err)
+	}
+	if extra := requests() - hitsBefore; extra != 0 {
+		t.Errorf("demoted repository must not be queried first, got %d extra requests", extra)
+	}
+
+	// После паузы репозиторий снова пробуется первым и восстанавливается при успехе
+	now = now.Add(healthCooldown + time.Second)
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	resolved, err := pm.findPackage(ctx, "tool", "", "", "")
+	if err != nil || resolved.Repository.Name != "primary" {
+		t.Fatalf("expected primary after cooldown, got %+v, %v", resolved, err)
+	}
+	if status := pm.RepositoryHealth()[0]; !status.Healthy || status.Demoted || status.TotalFailures != healthFailureThreshold {
+		t.Errorf("expected primary to recover, got %+v", status)
+	}
+
+	// Одиночный сбой не понижает репозиторий
+	mu.Lock()
+	down = true
+	mu.Unlock()
+	pm.findPackage(ctx, "tool", "", "", "")
+	if status := pm.RepositoryHealth()[0]; status.Demoted || status.ConsecutiveFailures != 1 {
+		t.Errorf("expected a single failure without demotion, got %+v", status)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Языки сообщений инструментов. Язык задается параметром конфигурации language,
+// а если он не задан — возможностью locale, объявленной клиентом при initialize.
+const (
+	localeRussian = "ru"
+	localeEnglish = "en"
+	defaultLocale = localeRussian
+)
+
+// messageKey идентификатор сообщения в каталоге
+type messageKey string
+
+const (
+	msgInstalled            messageKey = "installed"
+	msgInstalledFrom        messageKey = "installed_from"
+	msgInstallTiming        messageKey = "install_timing"
+	msgChecksumMissing      messageKey = "checksum_missing"
+	msgChecksumMatched      messageKey = "checksum_matched"
+	msgArchiveVerified      messageKey = "archive_verified"
+	msgArchiveUnverified    messageKey = "archive_unverified"
+	msgSkippedSymlinks      messageKey = "skipped_symlinks"
+	msgPreservedConfigFound messageKey = "preserved_config_found"
+	msgUninstalled          messageKey = "uninstalled"
+	msgConfigPreserved      messageKey = "config_preserved"
+	msgUpdated              messageKey = "updated"
+	msgUpdatePinned         messageKey = "update_pinned"
+	msgAllUpToDate          messageKey = "all_up_to_date"
+	msgUpdateSummary        messageKey = "update_summary"
+	msgUpdateItemPinned     messageKey = "update_item_pinned"
+	msgSearchFound          messageKey = "search_found"
+	msgSearchEmptyPage      messageKey = "search_empty_page"
+	msgSearchSuggestions    messageKey = "search_suggestions"
+	msgSearchSuggestion     messageKey = "search_suggestion"
+	msgSearchResult         messageKey = "search_result"
+	msgSearchDescription    messageKey = "search_description"
+	msgSearchAuthor         messageKey = "search_author"
+	msgSearchLicense        messageKey = "search_license"
+	msgSearchRepository     messageKey = "search_repository"
+	msgSearchDownloads      messageKey = "search_downloads"
+)
+
+// messageCatalog форматные строки сообщений по языкам; сообщение, которого нет
+// в каталоге языка, берется из каталога языка по умолчанию
+var messageCatalog = map[string]map[messageKey]string{
+	localeRussian: {
+		msgInstalled:            "Пакет %s успешно установлен",
+		msgInstalledFrom:        "Пакет %s (%s) успешно установлен из %s",
+		msgInstallTiming:        "\n⏱️ %.0f мс: скачивание %.0f мс, извлечение %.0f мс, копирование %.0f мс",
+		msgChecksumMissing:      "\n⚠️ Контрольная сумма не указана, архив не проверялся",
+		msgChecksumMatched:      "\n✅ Контрольная сумма совпадает: %s",
+		msgArchiveVerified:      "\n✅ Содержимое совпадает с контрольными суммами архива",
+		msgArchiveUnverified:    "\n⚠️ Архив не содержит контрольных сумм, содержимое не проверялось",
+		msgSkippedSymlinks:      "\n\n⚠️ Пропущено символических ссылок: %d\n",
+		msgPreservedConfigFound: "\n\n💾 Найдена конфигурация, сохраненная при удалении: %s. Восстановить ее: restore_config",
+		msgUninstalled:          "Пакет %s успешно удален",
+		msgConfigPreserved:      "\n💾 Сохранены файлы конфигурации: %s (восстановить после повторной установки: restore_config)",
+		msgUpdated:              "Пакет %s успешно обновлен: %s → %s",
+		msgUpdatePinned:         "📌 Пакет %s закреплен на версии %s, обновление пропущено",
+		msgAllUpToDate:          "Все пакеты имеют последние версии\n",
+		msgUpdateSummary:        "🔄 Обновлено: %d, пропущено: %d, ошибок: %d\n\n",
+		msgUpdateItemPinned:     "📌 %s: закреплен на %s (доступна %s)\n",
+		msgSearchFound:          "Найдено пакетов: %d (страница %d из %d, по %d на странице)\n\n",
+		msgSearchEmptyPage:      "На этой странице результатов нет\n",
+		msgSearchSuggestions:    "Возможно, вы имели в виду:\n",
+		msgSearchSuggestion:     "   💡 %s (отличий: %d)\n",
+		msgSearchResult:         "📦 %s (%s) — релевантность %s\n",
+		msgSearchDescription:    "   Описание: %s\n",
+		msgSearchAuthor:         "   Автор: %s\n",
+		msgSearchLicense:        "   Лицензия: %s\n",
+		msgSearchRepository:     "   Репозиторий: %s\n",
+		msgSearchDownloads:      "   Загрузок: %d\n\n",
+	},
+	localeEnglish: {
+		msgInstalled:            "Package %s installed successfully",
+		msgInstalledFrom:        "Package %s (%s) installed successfully from %s",
+		msgInstallTiming:        "\n⏱️ %.0f ms: download %.0f ms, extract %.0f ms, copy %.0f ms",
+		msgChecksumMissing:      "\n⚠️ No checksum given, the archive was not verified",
+		msgChecksumMatched:      "\n✅ Checksum matches: %s",
+		msgArchiveVerified:      "\n✅ Contents match the archive checksums",
+		msgArchiveUnverified:    "\n⚠️ The archive has no checksums, contents were not verified",
+		msgSkippedSymlinks:      "\n\n⚠️ Symbolic links skipped: %d\n",
+		msgPreservedConfigFound: "\n\n💾 Found configuration preserved on uninstall: %s. Restore it with restore_config",
+		msgUninstalled:          "Package %s uninstalled successfully",
+		msgConfigPreserved:      "\n💾 Configuration files preserved: %s (restore after reinstalling with restore_config)",
+		msgUpdated:              "Package %s updated successfully: %s → %s",
+		msgUpdatePinned:         "📌 Package %s is pinned to version %s, update skipped",
+		msgAllUpToDate:          "All packages are up to date\n",
+		msgUpdateSummary:        "🔄 Updated: %d, skipped: %d, failed: %d\n\n",
+		msgUpdateItemPinned:     "📌 %s: pinned to %s (%s available)\n",
+		msgSearchFound:          "Packages found: %d (page %d of %d, %d per page)\n\n",
+		msgSearchEmptyPage:      "No results on this page\n",
+		msgSearchSuggestions:    "Did you mean:\n",
+		msgSearchSuggestion:     "   💡 %s (distance: %d)\n",
+		msgSearchResult:         "📦 %s (%s) — relevance %s\n",
+		msgSearchDescription:    "   Description: %s\n",
+		msgSearchAuthor:         "   Author: %s\n",
+		msgSearchLicense:        "   License: %s\n",
+		msgSearchRepository:     "   Repository: %s\n",
+		msgSearchDownloads:      "   Downloads: %d\n\n",
+	},
+}
+
+// normalizeLocale приводит обозначение языка ("en-US", "EN_gb") к ключу каталога;
+// для языка без каталога возвращает пустую строку
+func normalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	if _, ok := messageCatalog[locale]; !ok {
+		return ""
+	}
+	return locale
+}
+
+// translate форматирует сообщение key на языке locale
+func translate(locale string, key messageKey, args ...interface{}) string {
+	format, ok := messageCatalog[locale][key]
+	if !ok {
+		format = messageCatalog[defaultLocale][key]
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// locale возвращает язык сообщений: параметр конфигурации language, затем
+// возможность locale клиента (в capabilities или experimental), затем русский
+func (s *MCPServer) locale() string {
+	if locale := normalizeLocale(s.packageManager.getConfig().Language); locale != "" {
+		return locale
+	}
+
+	if client := s.clientParams(); client != nil {
+		value, ok := client.Capabilities["locale"].(string)
+		if experimental, isMap := client.Capabilities["experimental"].(map[string]interface{}); !ok && isMap {
+			value, _ = experimental["locale"].(string)
+		}
+		if locale := normalizeLocale(value); locale != "" {
+			return locale
+		}
+	}
+	return defaultLocale
+}
+
+// message форматирует сообщение key на языке клиента
+func (s *MCPServer) message(key messageKey, args ...interface{}) string {
+	return translate(s.locale(), key, args...)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestMessageCatalog проверяет, что у каждого языка есть все сообщения каталога по умолчанию
+// с теми же параметрами форматирования
+func TestMessageCatalog(t *testing.T) {
+	for locale, messages := range messageCatalog {
+		for key, format := range messageCatalog[defaultLocale] {
+			translated, ok := messages[key]
+			if !ok {
+				t.Errorf("locale %s: missing message %s", locale, key)
+				continue
+			}
+			if strings.Count(translated, "%") != strings.Count(format, "%") {
+				t.Errorf("locale %s: message %s has different format verbs: %q vs %q", locale, key, translated, format)
+			}
+		}
+	}
+
+	for input, want := range map[string]string{"en": "en", "en-US": "en", "RU_ru": "ru", "de": "", "": ""} {
+		if got := normalizeLocale(input); got != want {
+			t.Errorf("normalizeLocale(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// TestLocalizedToolOutput проверяет выбор языка сообщений: по умолчанию русский,
+// возможность locale клиента и параметр конфигурации language, который ее переопределяет
+func TestLocalizedToolOutput(t *testing.T) {
+	repo := newMockRepository(t, &RepositoryPackage{Name: "json-tool", Versions: []RepositoryVersion{{Version: "1.0.0"}}})
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	installTestArchive(t, pm, PackageManifest{Name: "alpha", Version: "1.0.0"}, false)
+	s := newTestServer(t, pm)
+
+	search := func() string {
+		t.Helper()
+		text, err := callToolText(t, s, "search_packages", map[string]interface{}{"query": "json"})
+		if err != nil {
+			t.Fatalf("search_packages: %v", err)
+		}
+		return text
+	}
+
+	if text := search(); !strings.Contains(text, "Найдено пакетов: 1") || !strings.Contains(text, "Репозиторий: mock") {
+		t.Errorf("expected Russian output by default, got:\n%s", text)
+	}
+
+	s.handleMessage(context.Background(), MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"protocolVersion": MCPVersion,
+			"clientInfo":      map[string]interface{}{"name": "test-client", "version": "1.0.0"},
+			"capabilities": map[string]interface{}{
+				"experimental": map[string]interface{}{"locale": "en-US"},
+			},
+		},
+	})
+	if text := search(); !strings.Contains(text, "Packages found: 1") || !strings.Contains(text, "Repository: mock") {
+		t.Errorf("expected English output for client locale, got:\n%s", text)
+	}
+	text, err := callToolText(t, s, "uninstall_package", map[string]interface{}{"name": "alpha"})
+	if err != nil {
+		t.Fatalf("uninstall_package: %v", err)
+	}
+	if text != "Package alpha uninstalled successfully" {
+		t.Errorf("unexpected uninstall output: %q", text)
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	if _, err := pm.SetConfigValue("language", localeRussian); err != nil {
+		t.Fatalf("SetConfigValue: %v", err)
+	}
+	if text := search(); !strings.Contains(text, "Найдено пакетов: 1") {
+		t.Errorf("expected configured language to override client locale, got:\n%s", text)
+	}
+	if _, err := pm.SetConfigValue("language", "de"); err == nil {
+		t.Error("expected error for unsupported language")
+	}
+}
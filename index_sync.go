@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultIndexMaxAge возраст локального индекса репозитория, после которого он считается устаревшим
+const defaultIndexMaxAge = 24 * time.Hour
+
+// indexMaxAge возвращает допустимый возраст индекса: index_max_age в секундах,
+// 0 — значение по умолчанию, отрицательное значение отключает проверку
+func (pm *PackageManager) indexMaxAge() time.Duration {
+	switch age := pm.getConfig().IndexMaxAge; {
+	case age == 0:
+		return defaultIndexMaxAge
+	case age < 0:
+		return 0
+	default:
+		return time.Duration(age) * time.Second
+	}
+}
+
+// indexBuiltAt возвращает время построения сохраненного индекса репозитория
+func (pm *PackageManager) indexBuiltAt(repositoryURL string) (time.Time, bool) {
+	data, err := os.ReadFile(pm.indexPath(repositoryURL))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var header struct {
+		BuiltAt time.Time `json:"built_at"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return time.Time{}, false
+	}
+	return header.BuiltAt, true
+}
+
+// SyncRepositoryIndex выгружает полный список пакетов репозитория и сохраняет его
+// локальную копию в CachePath. Копия используется для поиска по индексу и в автономном режиме.
+func (pm *PackageManager) SyncRepositoryIndex(ctx context.Context, repo Repository) (*IndexSyncResult, error) {
+	index, err := pm.BuildSearchIndex(ctx, repo.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Индекс репозитория %s синхронизирован: пакетов %d", repo.Name, len(index.Packages))
+	return &IndexSyncResult{
+		Repository: repo.Name,
+		URL:        repo.URL,
+		Packages:   len(index.Packages),
+		SyncedAt:   index.BuiltAt,
+	}, nil
+}
+
+// refreshIndexes проверяет возраст индексов включенных репозиториев. Отсутствующие и
+// устаревшие индексы синхронизируются заново; в автономном режиме или при ошибке
+// синхронизации возвращаются предупреждения, а поиск использует то, что есть.
+func (pm *PackageManager) refreshIndexes(ctx context.Context) []string {
+	maxAge := pm.indexMaxAge()
+	var warnings []string
+	for _, repo := range pm.repositoriesByPriority() {
+		builtAt, ok := pm.indexBuiltAt(repo.URL)
+		if ok && (maxAge == 0 || time.Since(builtAt) <= maxAge) {
+			continue
+		}
+
+		problem := fmt.Sprintf("нет локального индекса репозитория %s", repo.Name)
+		if ok {
+			problem = fmt.Sprintf("индекс репозитория %s устарел (синхронизирован %s)", repo.Name, builtAt.Format("2006-01-02 15:04"))
+		}
+		if pm.offline(ctx) {
+			warnings = append(warnings, problem)
+			continue
+		}
+		if _, err := pm.SyncRepositoryIndex(ctx, repo); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s, обновить не удалось: %v", problem, err))
+		}
+	}
+	return warnings
+}
+
+// SearchIndexed ищет пакеты по локальным копиям индексов репозиториев, предварительно
+// синхронизируя отсутствующие и устаревшие индексы
+func (pm *PackageManager) SearchIndexed(ctx context.Context, query string, filter SearchFilter, page, limit int) (*SearchPage, error) {
+	page, limit = searchPageBounds(page, limit)
+	return pm.searchIndex(ctx, query, filter, page, limit)
+}
+
+// searchIndex выполняет поиск для SearchPackages по локальным индексам репозиториев
+func (pm *PackageManager) searchIndex(ctx context.Context, query string, filter SearchFilter, page, limit int) (*SearchPage, error) {
+	warnings := pm.refreshIndexes(ctx)
+
+	found, err := pm.SearchOffline(query, "")
+	if err != nil {
+		if len(warnings) > 0 {
+			return nil, fmt.Errorf("%w (%s)", err, strings.Join(warnings, "; "))
+		}
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, result := range found {
+		if filter.matches(result) {
+			results = append(results, result)
+		}
+	}
+
+	searchPage := &SearchPage{
+		Results:    []SearchResult{},
+		Total:      len(results),
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (len(results) + limit - 1) / limit,
+		Warnings:   warnings,
+	}
+	if start := (page - 1) * limit; start < len(results) {
+		searchPage.Results = results[start:min(start+limit, len(results))]
+	}
+	return searchPage, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ageIndex сдвигает время синхронизации сохраненного индекса репозитория в прошлое
+func ageIndex(t *testing.T, pm *PackageManager, repositoryURL string, age time.Duration) {
+	t.Helper()
+
+	path := pm.indexPath(repositoryURL)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index SearchIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	index.BuiltAt = time.Now().Add(-age)
+	if data, err = json.Marshal(index); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSyncRepositoryIndex проверяет синхронизацию индекса из репозитория, поиск по нему,
+// обновление устаревшего индекса и поиск без сети с предупреждением о возрасте
+func TestSyncRepositoryIndex(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t,
+		&RepositoryPackage{Name: "json-tool", Description: "JSON formatter", LatestVersion: "1.0.0", Versions: []RepositoryVersion{{Version: "1.0.0"}}},
+		&RepositoryPackage{Name: "yaml-lint", Description: "YAML linter", LatestVersion: "0.3.0", Versions: []RepositoryVersion{{Version: "0.3.0"}}},
+	)
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	result, err := pm.SyncRepositoryIndex(ctx, pm.getConfig().Repositories[0])
+	if err != nil {
+		t.Fatalf("SyncRepositoryIndex: %v", err)
+	}
+	if result.Repository != "mock" || result.Packages != 2 || result.SyncedAt.IsZero() {
+		t.Errorf("unexpected sync result: %+v", result)
+	}
+
+	page, err := pm.SearchIndexed(ctx, "json", SearchFilter{}, 1, 20)
+	if err != nil {
+		t.Fatalf("SearchIndexed: %v", err)
+	}
+	if page.Total != 1 || page.Results[0].Name != "json-tool" || len(page.Warnings) != 0 {
+		t.Errorf("expected json-tool from the synced index, got %+v", page)
+	}
+
+	// Свежий индекс не запрашивается заново: новый пакет появится после обновления
+	repo.mu.Lock()
+	repo.packages["toml-kit"] = &RepositoryPackage{Name: "toml-kit", Description: "TOML parser", Versions: []RepositoryVersion{{Version: "2.0.0"}}}
+	repo.mu.Unlock()
+	if page, err := pm.SearchIndexed(ctx, "toml", SearchFilter{}, 1, 20); err != nil || page.Total != 0 {
+		t.Fatalf("fresh index must be used as is, got %+v, %v", page, err)
+	}
+
+	pm.getConfig().IndexMaxAge = 60
+	ageIndex(t, pm, repo.URL, time.Hour)
+	page, err = pm.SearchIndexed(ctx, "toml", SearchFilter{}, 1, 20)
+	if err != nil {
+		t.Fatalf("SearchIndexed: %v", err)
+	}
+	if page.Total != 1 || page.Results[0].Name != "toml-kit" || len(page.Warnings) != 0 {
+		t.Errorf("expected stale index to be refreshed, got %+v", page)
+	}
+
+	// Без сети устаревший индекс используется с предупреждением
+	ageIndex(t, pm, repo.URL, time.Hour)
+	repo.Close()
+	pm.getConfig().Offline = true
+	page, err = pm.SearchPackages(ctx, "yaml", SearchFilter{}, 1, 20)
+	if err != nil {
+		t.Fatalf("offline SearchPackages: %v", err)
+	}
+	if page.Total != 1 || page.Results[0].Name != "yaml-lint" {
+		t.Errorf("expected yaml-lint from the stale index, got %+v", page)
+	}
+	if len(page.Warnings) != 1 || !strings.Contains(page.Warnings[0], "устарел") {
+		t.Errorf("expected stale index warning, got %v", page.Warnings)
+	}
+}
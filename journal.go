@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// journalEntry изменение файловой системы и действие, отменяющее его
+type journalEntry struct {
+	description string
+	undo        func() error
+}
+
+// installJournal записывает в памяти изменения файловой системы во время установки.
+// При ошибке rollback отменяет их в обратном порядке, возвращая прежнее состояние;
+// commit удаляет резервные копии, которые больше не понадобятся.
+type installJournal struct {
+	entries []journalEntry
+	backups []string
+}
+
+// record добавляет изменение в журнал
+func (j *installJournal) record(description string, undo func() error) {
+	j.entries = append(j.entries, journalEntry{description: description, undo: undo})
+}
+
+// replaceDir освобождает path: существующий каталог переносится в резервную копию
+// рядом с ним и при откате возвращается на место
+func (j *installJournal) replaceDir(path string) error {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	backup := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.backup-%d", filepath.Base(path), time.Now().UnixNano()))
+	if err := os.Rename(path, backup); err != nil {
+		return err
+	}
+	j.backups = append(j.backups, backup)
+	j.record("перенос "+path+" в резервную копию", func() error {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		return os.Rename(backup, path)
+	})
+	return nil
+}
+
+// mkdirAll создает каталог с недостающими родителями; откат удаляет все созданное,
+// начиная с первого не существовавшего родителя, вместе с содержимым
+func (j *installJournal) mkdirAll(path string, perm os.FileMode) error {
+	created := ""
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		created = dir
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(path, perm); err != nil {
+		return err
+	}
+	if created != "" {
+		j.record("создание "+created, func() error {
+			return os.RemoveAll(created)
+		})
+	}
+	return nil
+}
+
+// rollback отменяет записанные изменения в обратном порядке и возвращает cause,
+// дополненную ошибками отката, если вернуть прежнее состояние не удалось
+func (j *installJournal) rollback(cause error) error {
+	var failures []error
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		entry := j.entries[i]
+		if err := entry.undo(); err != nil {
+			logger.Errorf("Ошибка отката (%s): %v", entry.description, err)
+			failures = append(failures, fmt.Errorf("откат (%s): %w", entry.description, err))
+		}
+	}
+	j.entries, j.backups = nil, nil
+
+	if len(failures) > 0 {
+		return errors.Join(append([]error{cause}, failures...)...)
+	}
+	return cause
+}
+
+// commit фиксирует изменения: резервные копии удаляются, откат становится невозможен
+func (j *installJournal) commit() {
+	for _, backup := range j.backups {
+		if err := os.RemoveAll(backup); err != nil {
+			logger.Warnf("Не удалось удалить резервную копию %s: %v", backup, err)
+		}
+	}
+	j.entries, j.backups = nil, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// rootEntries возвращает имена записей каталога установки, кроме блокировок
+func rootEntries(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != locksDirName {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// TestInstallRollback проверяет, что сбой после копирования файлов не оставляет
+// на диске частично установленного пакета, а при переустановке возвращает прежнюю версию
+func TestInstallRollback(t *testing.T) {
+	pm := newTestPackageManager(t)
+	ctx := context.Background()
+	v1 := buildTestArchive(t, pm, PackageManifest{Name: "app", Version: "1.0.0"},
+		map[string]string{"bin/app": "v1", "share/readme.txt": "first"}, FormatTarGz)
+	v2 := buildTestArchive(t, pm, PackageManifest{Name: "app", Version: "2.0.0"},
+		map[string]string{"bin/app": "v2", "lib/extra.so": "new"}, FormatTarGz)
+
+	// packages.json на месте каталога: сохранение сведений о пакете завершается ошибкой
+	packagesPath := filepath.Join(pm.getConfig().LocalPath, "packages.json")
+	if err := os.Mkdir(packagesPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	before := rootEntries(t, pm.getConfig().LocalPath)
+	if _, err := pm.installFromArchive(ctx, v1, false, false, nil); err == nil {
+		t.Fatal("expected install to fail when package info cannot be saved")
+	}
+	if after := rootEntries(t, pm.getConfig().LocalPath); !reflect.DeepEqual(before, after) {
+		t.Errorf("failed install left files behind: before %v, after %v", before, after)
+	}
+	if _, exists := pm.getInstalledPackage("app"); exists {
+		t.Error("failed install must not register the package")
+	}
+
+	// Успешная установка первой версии
+	if err := os.Remove(packagesPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.installFromArchive(ctx, v1, false, false, nil); err != nil {
+		t.Fatalf("install v1: %v", err)
+	}
+	installPath, err := pm.getInstallPath("app", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := snapshotTree(t, installPath)
+	registry, err := os.ReadFile(packagesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Сбой принудительной переустановки возвращает первую версию на место
+	if err := os.Remove(packagesPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(packagesPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	before = rootEntries(t, pm.getConfig().LocalPath)
+	if _, err := pm.installFromArchive(ctx, v2, false, true, nil); err == nil {
+		t.Fatal("expected forced install to fail when package info cannot be saved")
+	}
+	if got := snapshotTree(t, installPath); !reflect.DeepEqual(want, got) {
+		t.Errorf("previous version was not restored:\nwant %v\ngot  %v", want, got)
+	}
+	if after := rootEntries(t, pm.getConfig().LocalPath); !reflect.DeepEqual(before, after) {
+		t.Errorf("failed reinstall left files behind: before %v, after %v", before, after)
+	}
+	if info, _ := pm.getInstalledPackage("app"); info == nil || info.Version != "1.0.0" {
+		t.Errorf("expected app 1.0.0 to stay registered, got %+v", info)
+	}
+
+	// После успешной переустановки резервная копия не остается
+	if err := os.Remove(packagesPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(packagesPath, registry, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.installFromArchive(ctx, v2, false, true, nil); err != nil {
+		t.Fatalf("install v2: %v", err)
+	}
+	if entries := rootEntries(t, pm.getConfig().LocalPath); !reflect.DeepEqual(entries, []string{"app", "packages.json"}) {
+		t.Errorf("unexpected entries after reinstall: %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(installPath, "share", "readme.txt")); !os.IsNotExist(err) {
+		t.Error("files of the previous version must be removed after reinstall")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// LogLevel уровень подробности журнала
+type LogLevel int
+
+// Уровни журнала в порядке возрастания важности
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var logLevelNames = map[LogLevel]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// parseLogLevel разбирает название уровня журнала
+func parseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("неизвестный уровень журнала: %s (допустимо: debug, info, warn, error)", name)
+	}
+}
+
+// Logger журнал с уровнями. Никогда не пишет в stdout: там идет поток JSON-RPC.
+type Logger struct {
+	mu     sync.Mutex
+	level  LogLevel
+	out    *log.Logger
+	closer io.Closer
+
+	// Подписчики получают все сообщения независимо от level и сами отбирают нужные
+	sinks    map[int]func(LogLevel, string)
+	nextSink int
+}
+
+// logger общий журнал сервера и пакетного менеджера
+var logger = NewLogger(os.Stderr, LevelInfo)
+
+// NewLogger создает журнал, пишущий в w сообщения уровня level и выше
+func NewLogger(w io.Writer, level LogLevel) *Logger {
+	if w == os.Stdout {
+		w = os.Stderr
+	}
+
+	return &Logger{
+		level: level,
+		out:   log.New(w, "", log.LstdFlags),
+	}
+}
+
+// Configure применяет уровень и файл журнала из конфигурации.
+// Пустой logFile означает stderr; stdout в качестве файла журнала не допускается.
+func (l *Logger) Configure(level, logFile string) error {
+	parsed, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+
+	var writer io.Writer = os.Stderr
+	var closer io.Closer
+	if logFile != "" {
+		if isStdoutPath(logFile) {
+			return fmt.Errorf("журнал нельзя писать в stdout: %s", logFile)
+		}
+		if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+			return fmt.Errorf("ошибка создания директории журнала: %w", err)
+		}
+		file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("ошибка открытия файла журнала: %w", err)
+		}
+		writer, closer = file, file
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closer != nil {
+		l.closer.Close()
+	}
+	l.level = parsed
+	l.out = log.New(writer, "", log.LstdFlags)
+	l.closer = closer
+
+	return nil
+}
+
+// isStdoutPath сообщает, указывает ли путь на стандартный вывод
+func isStdoutPath(path string) bool {
+	switch filepath.Clean(path) {
+	case "-", "/dev/stdout", "/dev/fd/1", "/proc/self/fd/1":
+		return true
+	}
+	return false
+}
+
+// SetLevel меняет порог журнала
+func (l *Logger) SetLevel(level LogLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+// Level возвращает текущий порог журнала
+func (l *Logger) Level() LogLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level
+}
+
+// Subscribe добавляет получателя сообщений журнала и возвращает функцию отписки.
+// Получатель вызывается вне блокировки журнала и может сам писать в журнал.
+func (l *Logger) Subscribe(sink func(level LogLevel, message string)) (unsubscribe func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.sinks == nil {
+		l.sinks = make(map[int]func(LogLevel, string))
+	}
+	id := l.nextSink
+	l.nextSink++
+	l.sinks[id] = sink
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.sinks, id)
+	}
+}
+
+func (l *Logger) logf(level LogLevel, format string, args ...interface{}) {
+	l.mu.Lock()
+	sinks := make([]func(LogLevel, string), 0, len(l.sinks))
+	for _, sink := range l.sinks {
+		sinks = append(sinks, sink)
+	}
+	if level < l.level && len(sinks) == 0 {
+		l.mu.Unlock()
+		return
+	}
+
+	// Сообщения могут включать URL и ответы репозиториев: секреты в журнал не попадают
+	message := redactSecrets(fmt.Sprintf(format, args...))
+	if level >= l.level {
+		l.out.Printf("[%s] %s", strings.ToUpper(level.String()), message)
+	}
+	l.mu.Unlock()
+
+	for _, sink := range sinks {
+		sink(level, message)
+	}
+}
+
+// Debugf записывает отладочное сообщение
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(LevelDebug, format, args...) }
+
+// Infof записывает информационное сообщение
+func (l *Logger) Infof(format string, args ...interface{}) { l.logf(LevelInfo, format, args...) }
+
+// Warnf записывает предупреждение
+func (l *Logger) Warnf(format string, args ...interface{}) { l.logf(LevelWarn, format, args...) }
+
+// Errorf записывает сообщение об ошибке
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(LevelError, format, args...) }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoggerThreshold проверяет, что сообщения ниже порога не записываются
+func TestLoggerThreshold(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, LevelWarn)
+
+	l.Debugf("debug message")
+	l.Infof("info message")
+	l.Warnf("warn message")
+	l.Errorf("error message")
+
+	out := buf.String()
+	for _, suppressed := range []string{"debug message", "info message"} {
+		if strings.Contains(out, suppressed) {
+			t.Errorf("expected %q to be suppressed, got:\n%s", suppressed, out)
+		}
+	}
+	for _, want := range []string{"[WARN] warn message", "[ERROR] error message"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	l.SetLevel(LevelDebug)
+	l.Debugf("now visible")
+	if !strings.Contains(buf.String(), "[DEBUG] now visible") {
+		t.Errorf("expected debug message after lowering level, got:\n%s", buf.String())
+	}
+}
+
+// TestLoggerConfigure проверяет запись в файл и запрет вывода журнала в stdout
+func TestLoggerConfigure(t *testing.T) {
+	l := NewLogger(&bytes.Buffer{}, LevelInfo)
+	logFile := filepath.Join(t.TempDir(), "logs", "server.log")
+
+	if err := l.Configure("error", logFile); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() { l.Configure("info", "") })
+
+	l.Warnf("hidden")
+	l.Errorf("written")
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	if strings.Contains(string(data), "hidden") || !strings.Contains(string(data), "written") {
+		t.Errorf("unexpected log file content:\n%s", data)
+	}
+
+	for _, path := range []string{"-", "/dev/stdout"} {
+		if err := l.Configure("info", path); err == nil {
+			t.Errorf("expected stdout log file %q to be rejected", path)
+		}
+	}
+	if err := l.Configure("verbose", ""); err == nil {
+		t.Error("expected unknown level to be rejected")
+	}
+}
+
+// TestSetLogLevelTool проверяет изменение уровня журнала инструментом set_log_level
+func TestSetLogLevelTool(t *testing.T) {
+	previous := logger.Level()
+	t.Cleanup(func() { logger.SetLevel(previous) })
+
+	s := newTestServer(t, newTestPackageManager(t))
+	if _, err := callToolText(t, s, "set_log_level", map[string]interface{}{"level": "error"}); err != nil {
+		t.Fatalf("set_log_level failed: %v", err)
+	}
+	if logger.Level() != LevelError {
+		t.Errorf("expected level error, got %s", logger.Level())
+	}
+
+	if _, err := callToolText(t, s, "set_log_level", map[string]interface{}{"level": "loud"}); err == nil {
+		t.Error("expected error for unknown level")
+	}
+	if logger.Level() != LevelError {
+		t.Errorf("invalid level must not change the threshold, got %s", logger.Level())
+	}
+}
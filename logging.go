@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// mcpLogLevels уровни журнала MCP (RFC 5424) и соответствующие им уровни журнала сервера
+var mcpLogLevels = map[string]LogLevel{
+	"debug":     LevelDebug,
+	"info":      LevelInfo,
+	"notice":    LevelInfo,
+	"warning":   LevelWarn,
+	"error":     LevelError,
+	"critical":  LevelError,
+	"alert":     LevelError,
+	"emergency": LevelError,
+}
+
+// mcpLogLevelNames уровень MCP, с которым сообщение журнала сервера отправляется клиенту
+var mcpLogLevelNames = map[LogLevel]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warning",
+	LevelError: "error",
+}
+
+// SetLevelParams параметры запроса logging/setLevel
+type SetLevelParams struct {
+	Level string `json:"level"`
+}
+
+// LogMessageParams параметры уведомления notifications/message
+type LogMessageParams struct {
+	Level  string `json:"level"`
+	Logger string `json:"logger,omitempty"`
+	Data   string `json:"data"`
+}
+
+// handleSetLevel обрабатывает logging/setLevel: после него сообщения журнала уровня
+// level и выше отправляются клиенту уведомлениями notifications/message
+func (s *MCPServer) handleSetLevel(message MCPMessage) *MCPMessage {
+	var params SetLevelParams
+	paramBytes, _ := json.Marshal(message.Params)
+	json.Unmarshal(paramBytes, &params)
+
+	level, ok := mcpLogLevels[strings.ToLower(params.Level)]
+	if !ok {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      message.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: fmt.Sprintf("Неизвестный уровень журнала: %q", params.Level),
+			},
+		}
+	}
+
+	s.clientMutex.Lock()
+	s.logLevel = &level
+	if s.stopLogForwarding == nil {
+		s.stopLogForwarding = logger.Subscribe(s.forwardLog)
+	}
+	s.clientMutex.Unlock()
+
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      message.ID,
+		Result:  map[string]interface{}{},
+	}
+}
+
+// forwardLog отправляет клиенту сообщение журнала, если оно не ниже уровня из logging/setLevel
+func (s *MCPServer) forwardLog(level LogLevel, message string) {
+	s.clientMutex.RLock()
+	threshold := s.logLevel
+	s.clientMutex.RUnlock()
+	if threshold == nil || level < *threshold {
+		return
+	}
+
+	s.sendNotification("notifications/message", LogMessageParams{
+		Level:  mcpLogLevelNames[level],
+		Logger: ServerName,
+		Data:   message,
+	})
+}
+
+// stopLogging прекращает отправку сообщений журнала клиенту
+func (s *MCPServer) stopLogging() {
+	s.clientMutex.Lock()
+	stop := s.stopLogForwarding
+	s.stopLogForwarding = nil
+	s.logLevel = nil
+	s.clientMutex.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+}
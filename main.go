@@ -1,19 +1,50 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 const (
-	MCPVersion    = "2024-11-05"
+	MCPVersion    = "2025-06-18"
 	ServerName    = "criage-mcp-server"
 	ServerVersion = "1.0.0"
 )
 
+// supportedProtocolVersions поддерживаемые версии протокола MCP, от новой к старой
+var supportedProtocolVersions = []string{MCPVersion, "2025-03-26", "2024-11-05"}
+
+// negotiateProtocolVersion выбирает старшую поддерживаемую версию, не превышающую
+// запрошенную клиентом. Версии MCP имеют вид YYYY-MM-DD и сравниваются как строки.
+func negotiateProtocolVersion(requested string) (string, error) {
+	if requested == "" {
+		return MCPVersion, nil
+	}
+	if _, err := time.Parse("2006-01-02", requested); err != nil {
+		return "", fmt.Errorf("некорректная версия протокола: %s", requested)
+	}
+
+	for _, version := range supportedProtocolVersions {
+		if version <= requested {
+			return version, nil
+		}
+	}
+
+	return "", fmt.Errorf("версия протокола %s не поддерживается", requested)
+}
+
 // MCP Protocol structures
 type MCPMessage struct {
 	JSONRPC string      `json:"jsonrpc"`
@@ -61,11 +92,32 @@ type Tool struct {
 type CallToolParams struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"`
+}
+
+type RequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"`
+}
+
+type ProgressParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      int64       `json:"progress"`
+	Total         int64       `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
 }
 
+// CallToolResult результат вызова инструмента. StructuredContent содержит
+// исходные данные и является основным результатом для программных клиентов;
+// текстовый блок в Content предназначен для чтения человеком.
 type CallToolResult struct {
-	Content []ContentItem `json:"content"`
-	IsError bool          `json:"isError,omitempty"`
+	Content           []ContentItem `json:"content"`
+	StructuredContent interface{}   `json:"structuredContent,omitempty"`
+	IsError           bool          `json:"isError,omitempty"`
+}
+
+type CancelledParams struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
 }
 
 type ContentItem struct {
@@ -73,6 +125,26 @@ type ContentItem struct {
 	Text string `json:"text"`
 }
 
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// packageResourcePrefix префикс URI ресурсов установленных пакетов
+const packageResourcePrefix = "criage://packages/"
+
 func main() {
 	server := NewMCPServer()
 	server.Run()
@@ -80,47 +152,311 @@ func main() {
 
 type MCPServer struct {
 	packageManager *PackageManager
+
+	// Запись ответов и уведомлений в поток вывода
+	encoder    *json.Encoder
+	writeMutex sync.Mutex
+
+	// Выполняющиеся вызовы инструментов по идентификатору запроса
+	activeCalls map[string]context.CancelFunc
+	callsMutex  sync.Mutex
+	callsWG     sync.WaitGroup
+
+	// Завершение работы: контексты вызовов наследуются от callsCtx, который отменяется,
+	// если вызовы не уложились в shutdownTimeout; после начала завершения новые не принимаются
+	callsCtx        context.Context
+	cancelCalls     context.CancelFunc
+	closing         bool
+	shutdownTimeout time.Duration // 0 — defaultShutdownTimeout
+
+	// Сведения о клиенте и согласованная версия протокола, полученные при initialize
+	client          *InitializeParams
+	protocolVersion string
+	clientMutex     sync.RWMutex
+
+	// Порог сообщений журнала, заданный клиентом через logging/setLevel (nil — не отправлять)
+	logLevel          *LogLevel
+	stopLogForwarding func()
 }
 
 func NewMCPServer() *MCPServer {
 	pm, err := NewPackageManager()
 	if err != nil {
-		log.Fatalf("Не удалось создать пакетный менеджер: %v", err)
+		logger.Errorf("Не удалось создать пакетный менеджер: %v", err)
+		os.Exit(1)
 	}
 
 	return &MCPServer{
 		packageManager: pm,
+		activeCalls:    make(map[string]context.CancelFunc),
 	}
 }
 
 func (s *MCPServer) Run() {
-	decoder := json.NewDecoder(os.Stdin)
-	encoder := json.NewEncoder(os.Stdout)
+	// SIGINT и SIGTERM завершают работу после выполняющихся вызовов
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := s.ServeContext(ctx, os.Stdin, os.Stdout); err != nil {
+		logger.Errorf("Ошибка декодирования сообщения: %v", err)
+	}
+}
+
+// Serve обрабатывает поток JSON-RPC сообщений до конца ввода.
+func (s *MCPServer) Serve(r io.Reader, w io.Writer) error {
+	return s.ServeContext(context.Background(), r, w)
+}
+
+// ServeContext обрабатывает поток JSON-RPC сообщений до конца ввода или отмены ctx.
+// Вызовы инструментов выполняются в отдельных горутинах, чтобы цикл
+// оставался отзывчивым к уведомлениям об отмене. При отмене ctx новые сообщения
+// не обрабатываются, а выполняющиеся вызовы завершаются (см. shutdown).
+func (s *MCPServer) ServeContext(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.writeMutex.Lock()
+	s.encoder = json.NewEncoder(w)
+	s.writeMutex.Unlock()
+	defer s.stopLogging()
+
+	// Чтение ввода блокируется, поэтому выполняется отдельно от обработки сигнала
+	messages := make(chan json.RawMessage)
+	readErr := make(chan error, 1)
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		decoder := json.NewDecoder(r)
+		for {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case messages <- raw:
+			case <-stopped:
+				return
+			}
+		}
+	}()
 
 	for {
+		var raw json.RawMessage
+		select {
+		case <-ctx.Done():
+			s.shutdown()
+			return nil
+		case err := <-readErr:
+			// Дожидаемся завершения выполняющихся вызовов перед выходом
+			s.callsWG.Wait()
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case raw = <-messages:
+		}
+
+		// Массив на верхнем уровне — пакетный запрос JSON-RPC 2.0
+		if trimmed := bytes.TrimLeft(raw, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+			s.startBatch(raw)
+			continue
+		}
+
 		var message MCPMessage
-		if err := decoder.Decode(&message); err != nil {
-			log.Printf("Ошибка декодирования сообщения: %v", err)
+		if err := json.Unmarshal(raw, &message); err != nil {
+			s.send(invalidRequest(err))
+			continue
+		}
+
+		if message.Method == "tools/call" && message.ID != nil {
+			s.startToolCall(message)
 			continue
 		}
 
-		response := s.handleMessage(message)
-		if response != nil {
-			if err := encoder.Encode(response); err != nil {
-				log.Printf("Ошибка кодирования ответа: %v", err)
+		if response := s.handleMessage(context.Background(), message); response != nil {
+			s.send(response)
+		}
+	}
+}
+
+// invalidRequest формирует ответ на сообщение, не являющееся корректным запросом JSON-RPC
+func invalidRequest(err error) *MCPMessage {
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		Error: &MCPError{
+			Code:    -32600,
+			Message: "Неверный запрос",
+			Data:    redactSecrets(err.Error()),
+		},
+	}
+}
+
+// startBatch обрабатывает пакетный запрос в горутине и отправляет массив ответов.
+// Уведомления в пакете ответов не порождают; пустой пакет — неверный запрос.
+func (s *MCPServer) startBatch(raw json.RawMessage) {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(raw, &batch); err != nil {
+		s.send(invalidRequest(err))
+		return
+	}
+	if len(batch) == 0 {
+		s.send(invalidRequest(fmt.Errorf("пустой пакетный запрос")))
+		return
+	}
+
+	s.callsWG.Add(1)
+	go func() {
+		defer s.callsWG.Done()
+
+		var responses []*MCPMessage
+		for _, item := range batch {
+			var message MCPMessage
+			if err := json.Unmarshal(item, &message); err != nil {
+				responses = append(responses, invalidRequest(err))
+				continue
+			}
+
+			var response *MCPMessage
+			if message.Method == "tools/call" && message.ID != nil {
+				if ctx, done, err := s.trackCall(message.ID); err != nil {
+					response = shuttingDownError(message.ID, err)
+				} else {
+					response = s.handleToolsCall(ctx, message)
+					done()
+				}
+			} else {
+				response = s.handleMessage(context.Background(), message)
+			}
+
+			if response != nil && message.ID != nil {
+				responses = append(responses, response)
 			}
 		}
+
+		if len(responses) > 0 {
+			s.send(responses)
+		}
+	}()
+}
+
+// send потокобезопасно записывает сообщение (или массив ответов пакета) в поток вывода
+func (s *MCPServer) send(message interface{}) {
+	s.writeMutex.Lock()
+	var err error
+	if s.encoder != nil {
+		err = s.encoder.Encode(message)
+	}
+	s.writeMutex.Unlock()
+
+	// Журнал пишется после снятия блокировки: сообщения журнала могут отправляться клиенту
+	if err != nil {
+		logger.Errorf("Ошибка кодирования ответа: %v", err)
+	}
+}
+
+// sendNotification отправляет клиенту JSON-RPC уведомление
+func (s *MCPServer) sendNotification(method string, params interface{}) {
+	s.send(&MCPMessage{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// progressNotifier возвращает получателя прогресса, отправляющего notifications/progress.
+// Этапы операции (скачивание, извлечение) следуют друг за другом на общей шкале,
+// чтобы значение прогресса для токена только возрастало.
+func (s *MCPServer) progressNotifier(token interface{}) ProgressFunc {
+	var (
+		mu    sync.Mutex
+		phase string
+		base  int64
+		last  int64
+	)
+
+	return func(progress, total int64, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if current := progressPhase(message); current != phase {
+			phase = current
+			base = last
+		}
+		last = base + progress
+
+		params := ProgressParams{
+			ProgressToken: token,
+			Progress:      last,
+			Message:       message,
+		}
+		if total > 0 {
+			params.Total = base + total
+		}
+
+		s.sendNotification("notifications/progress", params)
+	}
+}
+
+// startToolCall запускает вызов инструмента в горутине с отменяемым контекстом
+func (s *MCPServer) startToolCall(message MCPMessage) {
+	ctx, done, err := s.trackCall(message.ID)
+	if err != nil {
+		s.send(shuttingDownError(message.ID, err))
+		return
+	}
+
+	s.callsWG.Add(1)
+	go func() {
+		defer s.callsWG.Done()
+		defer done()
+
+		s.send(s.handleToolsCall(ctx, message))
+	}()
+}
+
+// trackCall регистрирует вызов для отмены через notifications/cancelled или при
+// завершении работы. Возвращенную функцию нужно вызвать по завершении вызова.
+// После начала завершения работы новые вызовы не регистрируются.
+func (s *MCPServer) trackCall(id interface{}) (context.Context, func(), error) {
+	key := requestKey(id)
+
+	s.callsMutex.Lock()
+	if s.closing {
+		s.callsMutex.Unlock()
+		return nil, nil, errShuttingDown
 	}
+	ctx, cancel := context.WithCancel(s.callsContext())
+	s.activeCalls[key] = cancel
+	s.callsMutex.Unlock()
+
+	return ctx, func() {
+		s.callsMutex.Lock()
+		delete(s.activeCalls, key)
+		s.callsMutex.Unlock()
+		cancel()
+	}, nil
+}
+
+// requestKey приводит идентификатор JSON-RPC запроса к ключу карты активных вызовов
+func requestKey(id interface{}) string {
+	return fmt.Sprintf("%v", id)
 }
 
-func (s *MCPServer) handleMessage(message MCPMessage) *MCPMessage {
+func (s *MCPServer) handleMessage(ctx context.Context, message MCPMessage) *MCPMessage {
 	switch message.Method {
 	case "initialize":
 		return s.handleInitialize(message)
 	case "tools/list":
 		return s.handleToolsList(message)
 	case "tools/call":
-		return s.handleToolsCall(message)
+		return s.handleToolsCall(ctx, message)
+	case "resources/list":
+		return s.handleResourcesList(message)
+	case "resources/read":
+		return s.handleResourcesRead(message)
+	case "logging/setLevel":
+		return s.handleSetLevel(message)
+	case "notifications/cancelled":
+		s.handleCancelled(message)
+		return nil
 	default:
 		return &MCPMessage{
 			JSONRPC: "2.0",
@@ -133,11 +469,65 @@ func (s *MCPServer) handleMessage(message MCPMessage) *MCPMessage {
 	}
 }
 
+// handleCancelled отменяет выполняющийся вызов, на который ссылается уведомление
+func (s *MCPServer) handleCancelled(message MCPMessage) {
+	var params CancelledParams
+	paramBytes, _ := json.Marshal(message.Params)
+	if err := json.Unmarshal(paramBytes, &params); err != nil || params.RequestID == nil {
+		return
+	}
+
+	s.callsMutex.Lock()
+	cancel, ok := s.activeCalls[requestKey(params.RequestID)]
+	s.callsMutex.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
 func (s *MCPServer) handleInitialize(message MCPMessage) *MCPMessage {
+	var params InitializeParams
+	paramBytes, _ := json.Marshal(message.Params)
+	if err := json.Unmarshal(paramBytes, &params); err != nil {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      message.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: "Неверные параметры",
+				Data:    redactSecrets(err.Error()),
+			},
+		}
+	}
+
+	version, err := negotiateProtocolVersion(params.ProtocolVersion)
+	if err != nil {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      message.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: err.Error(),
+				Data: map[string]interface{}{
+					"requested": params.ProtocolVersion,
+					"supported": supportedProtocolVersions,
+				},
+			},
+		}
+	}
+
+	s.clientMutex.Lock()
+	s.client = &params
+	s.protocolVersion = version
+	s.clientMutex.Unlock()
+
 	result := InitializeResult{
-		ProtocolVersion: MCPVersion,
+		ProtocolVersion: version,
 		Capabilities: map[string]interface{}{
-			"tools": map[string]interface{}{},
+			"tools":     map[string]interface{}{},
+			"resources": map[string]interface{}{},
+			"logging":   map[string]interface{}{},
 		},
 		ServerInfo: ServerInfo{
 			Name:    ServerName,
@@ -152,6 +542,13 @@ func (s *MCPServer) handleInitialize(message MCPMessage) *MCPMessage {
 	}
 }
 
+// clientParams возвращает параметры initialize подключенного клиента (nil до инициализации)
+func (s *MCPServer) clientParams() *InitializeParams {
+	s.clientMutex.RLock()
+	defer s.clientMutex.RUnlock()
+	return s.client
+}
+
 func (s *MCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 	tools := []Tool{
 		{
@@ -180,11 +577,21 @@ func (s *MCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 					},
 					"arch": map[string]interface{}{
 						"type":        "string",
-						"description": "Целевая архитектура",
+						"description": "Целевая архитектура (по умолчанию default_arch или текущая)",
 					},
 					"os": map[string]interface{}{
 						"type":        "string",
-						"description": "Целевая операционная система",
+						"description": "Целевая операционная система (по умолчанию default_os или текущая)",
+					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Только показать план установки, ничего не скачивая и не изменяя",
+						"default":     false,
+					},
+					"offline": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Установить без обращения к сети: по локальному индексу (build_search_index) и архивам из кеша",
+						"default":     false,
 					},
 				},
 				"required": []string{"name"},
@@ -207,7 +614,7 @@ func (s *MCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 					},
 					"purge": map[string]interface{}{
 						"type":        "boolean",
-						"description": "Полное удаление с конфигурацией",
+						"description": "Полное удаление вместе с файлами конфигурации; без purge они сохраняются для восстановления после повторной установки",
 						"default":     false,
 					},
 				},
@@ -224,6 +631,37 @@ func (s *MCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 						"type":        "string",
 						"description": "Поисковый запрос",
 					},
+					"author": map[string]interface{}{
+						"type":        "string",
+						"description": "Автор пакета (подстрока, без учета регистра)",
+					},
+					"license": map[string]interface{}{
+						"type":        "string",
+						"description": "Лицензия пакета, например MIT",
+					},
+					"min_downloads": map[string]interface{}{
+						"type":        "integer",
+						"description": "Минимальное число загрузок",
+						"minimum":     0,
+					},
+					"page": map[string]interface{}{
+						"type":        "integer",
+						"description": "Номер страницы результатов",
+						"default":     1,
+						"minimum":     1,
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Результатов на странице (не более 100)",
+						"default":     20,
+						"minimum":     1,
+						"maximum":     100,
+					},
+					"from_index": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Искать по локальным копиям индексов (sync_repository) вместо запросов к репозиториям",
+						"default":     false,
+					},
 				},
 				"required": []string{"query"},
 			},
@@ -275,6 +713,20 @@ func (s *MCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 				"required": []string{"name"},
 			},
 		},
+		{
+			Name:        "update_all",
+			Description: "Обновляет все устаревшие пакеты",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"global": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Обновлять глобальные пакеты",
+						"default":     false,
+					},
+				},
+			},
+		},
 		{
 			Name:        "create_package",
 			Description: "Создает новый пакет",
@@ -314,13 +766,12 @@ func (s *MCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 					},
 					"format": map[string]interface{}{
 						"type":        "string",
-						"description": "Формат архива (criage, tar.zst, tar.gz и т.д.)",
+						"description": "Формат архива: criage, tar.zst, tar.gz, tar.xz, tar.bz2 или zip",
 						"default":     "criage",
 					},
 					"compression_level": map[string]interface{}{
 						"type":        "integer",
-						"description": "Уровень сжатия",
-						"default":     3,
+						"description": "Уровень сжатия: 1-22 для criage и tar.zst (по умолчанию 3), 1-9 для tar.gz (по умолчанию 6), 0-9 для tar.xz (по умолчанию 6), 1-9 для tar.bz2 (по умолчанию 9), 1-9 для zip (по умолчанию 6)",
 					},
 				},
 			},
@@ -384,153 +835,3164 @@ func (s *MCPServer) handleToolsList(message MCPMessage) *MCPMessage {
 						"type":        "string",
 						"description": "URL репозитория для получения статистики",
 					},
+					"force_refresh": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Запросить статистику у репозитория, не используя кеш",
+						"default":     false,
+					},
 				},
 				"required": []string{"repository_url"},
 			},
 		},
-	}
-
-	result := map[string]interface{}{
-		"tools": tools,
-	}
-
-	return &MCPMessage{
-		JSONRPC: "2.0",
-		ID:      message.ID,
-		Result:  result,
-	}
-}
-
-func (s *MCPServer) handleToolsCall(message MCPMessage) *MCPMessage {
-	var params CallToolParams
-	paramBytes, _ := json.Marshal(message.Params)
-	if err := json.Unmarshal(paramBytes, &params); err != nil {
-		return &MCPMessage{
-			JSONRPC: "2.0",
-			ID:      message.ID,
-			Error: &MCPError{
-				Code:    -32602,
-				Message: "Неверные параметры",
-				Data:    err.Error(),
+		{
+			Name:        "install_from_url",
+			Description: "Устанавливает пакет из архива по прямой ссылке, минуя API репозитория",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL архива пакета",
+					},
+					"expected_checksum": map[string]interface{}{
+						"type":        "string",
+						"description": "Ожидаемая контрольная сумма архива (sha256:<hex>, sha512:<hex> или blake3:<hex>; без префикса — sha256; необязательно)",
+					},
+					"checksum": map[string]interface{}{
+						"type":        "string",
+						"description": "Устаревший синоним expected_checksum",
+					},
+					"global": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Глобальная установка",
+						"default":     false,
+					},
+					"force": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Принудительная переустановка",
+						"default":     false,
+					},
+				},
+				"required": []string{"url"},
 			},
-		}
-	}
-
-	result, err := s.callTool(params.Name, params.Arguments)
-	if err != nil {
-		return &MCPMessage{
-			JSONRPC: "2.0",
-			ID:      message.ID,
-			Result: CallToolResult{
-				Content: []ContentItem{{
-					Type: "text",
-					Text: fmt.Sprintf("Ошибка: %v", err),
-				}},
-				IsError: true,
+		},
+		{
+			Name:        "list_by_category",
+			Description: "Группирует установленные пакеты по категориям (ключевым словам)",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"keyword": map[string]interface{}{
+						"type":        "string",
+						"description": "Показать только пакеты с указанным ключевым словом (необязательно)",
+					},
+				},
 			},
-		}
-	}
-
-	return &MCPMessage{
-		JSONRPC: "2.0",
-		ID:      message.ID,
-		Result:  result,
-	}
-}
-
-func (s *MCPServer) callTool(name string, args map[string]interface{}) (CallToolResult, error) {
-	switch name {
-	case "install_package":
-		return s.installPackage(args)
-	case "uninstall_package":
-		return s.uninstallPackage(args)
-	case "search_packages":
-		return s.searchPackages(args)
-	case "list_packages":
-		return s.listPackages(args)
-	case "package_info":
-		return s.packageInfo(args)
-	case "update_package":
-		return s.updatePackage(args)
-	case "create_package":
-		return s.createPackage(args)
-	case "build_package":
-		return s.buildPackage(args)
-	case "publish_package":
-		return s.publishPackage(args)
-	case "repository_info":
-		return s.repositoryInfo(args)
-	case "refresh_repository_index":
-		return s.refreshRepositoryIndex(args)
-	case "get_repository_stats":
-		return s.getRepositoryStats(args)
-	default:
-		return CallToolResult{}, fmt.Errorf("неизвестный инструмент: %s", name)
+		},
+		{
+			Name:        "release_notes",
+			Description: "Показывает описание изменений между установленной и целевой версиями пакета",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+					"from": map[string]interface{}{
+						"type":        "string",
+						"description": "Начальная версия (по умолчанию установленная)",
+					},
+					"to": map[string]interface{}{
+						"type":        "string",
+						"description": "Целевая версия (по умолчанию последняя)",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "verify_package",
+			Description: "Проверяет целостность установленного пакета",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета для проверки",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "package_files",
+			Description: "Перечисляет файлы установленного пакета с размерами и наличием на диске",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя установленного пакета",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "check_time_sync",
+			Description: "Сравнивает локальные часы с часами репозитория и сообщает о расхождении",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository_url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL репозитория (по умолчанию все включенные репозитории)",
+					},
+					"threshold_seconds": map[string]interface{}{
+						"type":        "integer",
+						"description": "Допустимое расхождение в секундах",
+						"default":     30,
+					},
+				},
+			},
+		},
+		{
+			Name:        "clean_cache",
+			Description: "Очищает кеш скачанных архивов",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"older_than_days": map[string]interface{}{
+						"type":        "integer",
+						"description": "Удалять только архивы, не использовавшиеся указанное число дней",
+						"default":     0,
+					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Только показать, что будет удалено",
+						"default":     false,
+					},
+				},
+			},
+		},
+		{
+			Name:        "disk_usage",
+			Description: "Показывает место на диске, занимаемое пакетами, кешем и временными файлами",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"top": map[string]interface{}{
+						"type":        "integer",
+						"description": "Сколько крупнейших пакетов показать",
+						"default":     defaultDiskUsageTop,
+						"minimum":     1,
+						"maximum":     100,
+					},
+				},
+			},
+		},
+		{
+			Name:        "raw_package_json",
+			Description: "Возвращает сырой JSON описания пакета из репозитория для отладки",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository_url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL репозитория",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+				},
+				"required": []string{"repository_url", "name"},
+			},
+		},
+		{
+			Name:        "client_info",
+			Description: "Показывает сведения о подключенном клиенте и объявленных им возможностях",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "set_log_level",
+			Description: "Меняет уровень подробности журнала сервера",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"level": map[string]interface{}{
+						"type":        "string",
+						"description": "Уровень журнала",
+						"enum":        []string{"debug", "info", "warn", "error"},
+					},
+				},
+				"required": []string{"level"},
+			},
+		},
+		{
+			Name:        "doctor",
+			Description: "Диагностирует типичные проблемы настройки: конфигурацию, каталоги, packages.json и доступность репозиториев",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "audit_environment",
+			Description: "Выполняет полную проверку окружения criage и возвращает сводный отчет",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "check_archive_naming",
+			Description: "Проверяет, что имя файла архива соответствует его манифесту (name-version-os-arch.format)",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"archive_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Путь к архиву пакета",
+					},
+				},
+				"required": []string{"archive_path"},
+			},
+		},
+		{
+			Name:        "build_search_index",
+			Description: "Загружает список пакетов репозитория и строит локальный поисковый индекс для поиска без сети",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository_url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL репозитория (по умолчанию все включенные репозитории)",
+					},
+				},
+			},
+		},
+		{
+			Name:        "search_offline",
+			Description: "Ищет пакеты по локальному индексу без обращения к репозиториям",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Поисковый запрос",
+					},
+					"repository_url": map[string]interface{}{
+						"type":        "string",
+						"description": "Искать только в индексе указанного репозитория",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+		{
+			Name:        "resolve_source",
+			Description: "Показывает, из какого репозитория и какого файла был бы установлен пакет, без скачивания",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+					"version": map[string]interface{}{
+						"type":        "string",
+						"description": "Версия пакета (по умолчанию последняя)",
+					},
+					"arch": map[string]interface{}{
+						"type":        "string",
+						"description": "Целевая архитектура (по умолчанию default_arch или текущая)",
+					},
+					"os": map[string]interface{}{
+						"type":        "string",
+						"description": "Целевая операционная система (по умолчанию default_os или текущая)",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "compact_index",
+			Description: "Уплотняет packages.json: удаляет записи без каталога установки и дубликаты, нормализует имена и разделяет глобальные и локальные записи",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "repair_state",
+			Description: "Восстанавливает поврежденный packages.json по манифестам в каталогах установки",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"force": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Перестроить packages.json, даже если он не поврежден",
+						"default":     false,
+					},
+				},
+			},
+		},
+		{
+			Name:        "downgrade_package",
+			Description: "Откатывает пакет на более старую версию",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+					"version": map[string]interface{}{
+						"type":        "string",
+						"description": "Целевая версия (старше установленной)",
+					},
+					"allow_same": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Разрешить переустановку той же версии",
+						"default":     false,
+					},
+				},
+				"required": []string{"name", "version"},
+			},
+		},
+		{
+			Name:        "replay_plan",
+			Description: "Выполняет план установки строго в указанных версиях без повторного разрешения",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"plan": map[string]interface{}{
+						"type":        "array",
+						"description": "Записи плана в порядке установки",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"name": map[string]interface{}{
+									"type": "string",
+								},
+								"version": map[string]interface{}{
+									"type": "string",
+								},
+								"scope": map[string]interface{}{
+									"type": "string",
+									"enum": []string{"local", "global"},
+								},
+							},
+							"required": []string{"name", "version"},
+						},
+					},
+				},
+				"required": []string{"plan"},
+			},
+		},
+		{
+			Name:        "pin_package",
+			Description: "Закрепляет установленный пакет: update_package и update_all будут его пропускать",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+					"version": map[string]interface{}{
+						"type":        "string",
+						"description": "Ожидаемая установленная версия (по умолчанию текущая)",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "unpin_package",
+			Description: "Снимает закрепление пакета",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "platform_info",
+			Description: "Показывает определенную платформу, значения по умолчанию из конфигурации и действующую целевую платформу",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"arch": map[string]interface{}{
+						"type":        "string",
+						"description": "Архитектура, переданная в вызове (для проверки приоритета)",
+					},
+					"os": map[string]interface{}{
+						"type":        "string",
+						"description": "Операционная система, переданная в вызове (для проверки приоритета)",
+					},
+				},
+			},
+		},
+		{
+			Name:        "list_repositories",
+			Description: "Список настроенных репозиториев",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "add_repository",
+			Description: "Добавляет репозиторий и сохраняет конфигурацию",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Уникальное имя репозитория",
+					},
+					"url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL репозитория",
+					},
+					"priority": map[string]interface{}{
+						"type":        "integer",
+						"description": "Приоритет: репозитории с большим значением проверяются раньше",
+					},
+					"enabled": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Включить репозиторий",
+						"default":     true,
+					},
+					"auth_token": map[string]interface{}{
+						"type":        "string",
+						"description": "Токен авторизации",
+					},
+					"timeout": map[string]interface{}{
+						"type":        "integer",
+						"description": "Таймаут запросов к репозиторию в секундах (по умолчанию общий timeout)",
+						"minimum":     0,
+					},
+					"rate_limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Частота запросов к репозиторию в секунду (по умолчанию общий requests_per_second, -1 — без ограничения)",
+						"minimum":     -1,
+					},
+					"public_key": map[string]interface{}{
+						"type":        "string",
+						"description": "Открытый ключ minisign; если задан, устанавливаются только подписанные им архивы",
+					},
+				},
+				"required": []string{"name", "url"},
+			},
+		},
+		{
+			Name:        "list_signing_keys",
+			Description: "Список ключей подписи minisign, закрепленных за репозиториями",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "add_signing_key",
+			Description: "Закрепляет за репозиторием открытый ключ minisign: после этого устанавливаются только подписанные им архивы",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя репозитория",
+					},
+					"public_key": map[string]interface{}{
+						"type":        "string",
+						"description": "Открытый ключ minisign: содержимое файла .pub или строка base64 из него",
+					},
+				},
+				"required": []string{"repository", "public_key"},
+			},
+		},
+		{
+			Name:        "remove_signing_key",
+			Description: "Удаляет ключ подписи репозитория; его архивы устанавливаются без проверки подписи",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя репозитория",
+					},
+				},
+				"required": []string{"repository"},
+			},
+		},
+		{
+			Name:        "remove_repository",
+			Description: "Удаляет репозиторий из конфигурации (последний репозиторий удалить нельзя)",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя репозитория",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "export_network_profile",
+			Description: "Сохраняет текущие сетевые настройки (частота запросов, таймаут, повторы, пул соединений) как именованный профиль",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя профиля",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "import_network_profile",
+			Description: "Добавляет или заменяет сетевой профиль, не применяя его",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"profile": map[string]interface{}{
+						"type":        "object",
+						"description": "Профиль в формате export_network_profile",
+						"properties": map[string]interface{}{
+							"name":                    map[string]interface{}{"type": "string"},
+							"requests_per_second":     map[string]interface{}{"type": "integer"},
+							"timeout":                 map[string]interface{}{"type": "integer"},
+							"max_retries":             map[string]interface{}{"type": "integer"},
+							"max_idle_conns":          map[string]interface{}{"type": "integer"},
+							"max_idle_conns_per_host": map[string]interface{}{"type": "integer"},
+							"idle_conn_timeout":       map[string]interface{}{"type": "integer"},
+						},
+						"required": []string{"name"},
+					},
+				},
+				"required": []string{"profile"},
+			},
+		},
+		{
+			Name:        "use_network_profile",
+			Description: "Применяет сохраненный сетевой профиль: перенастраивает ограничение частоты запросов и HTTP-клиент",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя профиля",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "check_executables",
+			Description: "Проверяет, что исполняемые файлы из манифеста установленного пакета существуют и имеют право на исполнение",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+					"run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Запустить каждый файл с --version",
+						"default":     false,
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "repository_health",
+			Description: "Показывает состояние доступности репозиториев: недавние сбои и временное понижение приоритета",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "package_version_info",
+			Description: "Показывает сведения о версии пакета в репозитории: зависимости, размер, контрольную сумму и файлы для платформ",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository_url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL репозитория",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+					"version": map[string]interface{}{
+						"type":        "string",
+						"description": "Версия пакета",
+					},
+				},
+				"required": []string{"repository_url", "name", "version"},
+			},
+		},
+		{
+			Name:        "compare_versions",
+			Description: "Сравнивает две версии пакета в репозитории: зависимости, размер и файлы для платформ",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"repository_url": map[string]interface{}{
+						"type":        "string",
+						"description": "URL репозитория",
+					},
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+					"from_version": map[string]interface{}{
+						"type":        "string",
+						"description": "Исходная версия, например установленная",
+					},
+					"to_version": map[string]interface{}{
+						"type":        "string",
+						"description": "Версия-кандидат",
+					},
+				},
+				"required": []string{"repository_url", "name", "from_version", "to_version"},
+			},
+		},
+		{
+			Name:        "install_from_file",
+			Description: "Устанавливает пакет из архива на локальном диске, минуя репозитории",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Путь к архиву пакета (.criage, .tar.zst, .tar.gz, .tar.xz, .tar.bz2, .zip)",
+					},
+					"global": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Глобальная установка",
+						"default":     false,
+					},
+					"force": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Принудительная переустановка",
+						"default":     false,
+					},
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "dependency_tree",
+			Description: "Показывает дерево зависимостей установленного пакета с установленными версиями, конфликтами и циклами",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя установленного пакета",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "why_installed",
+			Description: "Объясняет, почему пакет установлен: пользователем или как зависимость, и какие пакеты от него зависят",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя установленного пакета",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "autoremove",
+			Description: "Удаляет автоматически установленные зависимости, которые больше не нужны ни одному пакету, установленному пользователем",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Только показать пакеты, которые будут удалены",
+						"default":     false,
+					},
+				},
+			},
+		},
+		{
+			Name:        "config_get",
+			Description: "Показывает текущую конфигурацию (токены репозиториев скрыты) или значение одного параметра",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"key": map[string]interface{}{
+						"type":        "string",
+						"description": "Ключ параметра в config.json (например timeout); без ключа возвращается вся конфигурация",
+					},
+				},
+			},
+		},
+		{
+			Name:        "config_set",
+			Description: "Изменяет параметр конфигурации с проверкой допустимых значений и сохраняет config.json",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"key": map[string]interface{}{
+						"type":        "string",
+						"description": "Ключ параметра в config.json",
+						"enum":        sortedKeys(configSettings),
+					},
+					"value": map[string]interface{}{
+						"type":        []string{"string", "number", "boolean", "object"},
+						"description": "Новое значение (для arch_compatibility — объект «архитектура → список совместимых архитектур»)",
+					},
+				},
+				"required": []string{"key", "value"},
+			},
+		},
+		{
+			Name:        "ping",
+			Description: "Диагностика связи: версия и время работы сервера, доступность и задержка включенных репозиториев, доступность рабочих каталогов для записи",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "server_info",
+			Description: "Возможности сервера: поддерживаемые форматы архивов, алгоритмы сжатия и диапазоны уровней, версия протокола, включенные репозитории",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "restore_config",
+			Description: "Восстанавливает файлы конфигурации, сохраненные при удалении пакета без purge, в каталог установленного пакета",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        "run_script",
+			Description: "Выполняет именованный скрипт из манифеста установленного пакета в каталоге установки и возвращает общий вывод stdout и stderr",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя пакета",
+					},
+					"script": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя скрипта из раздела scripts манифеста",
+					},
+					"timeout": map[string]interface{}{
+						"type":        "integer",
+						"description": "Ограничение времени выполнения в секундах (по умолчанию 300, не более 3600)",
+						"minimum":     1,
+						"maximum":     3600,
+					},
+				},
+				"required": []string{"name", "script"},
+			},
+		},
+		{
+			Name:        "export_state",
+			Description: "Возвращает снимок всех установленных пакетов глобальной и локальной областей в JSON: область, даты установки, размеры и зависимости каждого пакета",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "import_state",
+			Description: "Восстанавливает packages.json обеих областей из снимка export_state, не устанавливая и не удаляя файлы пакетов",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"state": map[string]interface{}{
+						"type":        "object",
+						"description": "Снимок в формате export_state",
+						"properties": map[string]interface{}{
+							"version":  map[string]interface{}{"type": "integer"},
+							"packages": map[string]interface{}{"type": "array"},
+						},
+						"required": []string{"version", "packages"},
+					},
+				},
+				"required": []string{"state"},
+			},
+		},
+		{
+			Name:        "metrics",
+			Description: "Счетчики установок с момента запуска сервера: число установок и сбоев, скачано байт, среднее время установки",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "sync_repository",
+			Description: "Сохраняет локальную копию полного списка пакетов репозитория для поиска по индексу и автономного режима",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя репозитория (по умолчанию все включенные)",
+					},
+				},
+			},
+		},
+		{
+			Name:        "resolved_constraints",
+			Description: "Показывает для каждой транзитивной зависимости ограничения всех требующих ее пакетов и выбранную версию",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Имя корневого пакета в репозитории",
+					},
+					"version": map[string]interface{}{
+						"type":        "string",
+						"description": "Версия корневого пакета (по умолчанию последняя)",
+					},
+					"manifest_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Путь к манифесту или каталогу пакета вместо пакета из репозитория",
+					},
+				},
+			},
+		},
+	}
+
+	result := map[string]interface{}{
+		"tools": tools,
+	}
+
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      message.ID,
+		Result:  result,
+	}
+}
+
+// handleResourcesList перечисляет установленные пакеты как ресурсы criage://packages/<name>
+func (s *MCPServer) handleResourcesList(message MCPMessage) *MCPMessage {
+	packages := s.packageManager.InstalledPackages()
+
+	resources := make([]Resource, 0, len(packages))
+	for _, pkg := range packages {
+		resources = append(resources, Resource{
+			URI:         packageResourcePrefix + pkg.Name,
+			Name:        fmt.Sprintf("%s (%s)", pkg.Name, pkg.Version),
+			Description: pkg.Description,
+			MimeType:    "application/json",
+		})
+	}
+
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      message.ID,
+		Result: map[string]interface{}{
+			"resources": resources,
+		},
+	}
+}
+
+// handleResourcesRead возвращает манифест и список файлов установленного пакета в формате JSON
+func (s *MCPServer) handleResourcesRead(message MCPMessage) *MCPMessage {
+	var params ReadResourceParams
+	paramBytes, _ := json.Marshal(message.Params)
+	if err := json.Unmarshal(paramBytes, &params); err != nil || params.URI == "" {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      message.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: "Неверные параметры",
+			},
+		}
+	}
+
+	name := strings.TrimPrefix(params.URI, packageResourcePrefix)
+	if name == params.URI || name == "" {
+		return resourceNotFound(message.ID, params.URI)
+	}
+
+	resource, err := s.packageManager.PackageResource(name)
+	if err != nil {
+		return resourceNotFound(message.ID, params.URI)
+	}
+
+	data, err := json.MarshalIndent(resource, "", "  ")
+	if err != nil {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      message.ID,
+			Error: &MCPError{
+				Code:    -32603,
+				Message: "Ошибка сериализации ресурса",
+				Data:    redactSecrets(err.Error()),
+			},
+		}
+	}
+
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      message.ID,
+		Result: map[string]interface{}{
+			"contents": []ResourceContents{{
+				URI:      params.URI,
+				MimeType: "application/json",
+				Text:     string(data),
+			}},
+		},
+	}
+}
+
+// resourceNotFound формирует ошибку для неизвестного URI ресурса
+func resourceNotFound(id interface{}, uri string) *MCPMessage {
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &MCPError{
+			Code:    -32002,
+			Message: "Ресурс не найден",
+			Data:    map[string]interface{}{"uri": uri},
+		},
+	}
+}
+
+func (s *MCPServer) handleToolsCall(ctx context.Context, message MCPMessage) *MCPMessage {
+	var params CallToolParams
+	paramBytes, _ := json.Marshal(message.Params)
+	if err := json.Unmarshal(paramBytes, &params); err != nil {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      message.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: "Неверные параметры",
+				Data:    redactSecrets(err.Error()),
+			},
+		}
+	}
+
+	// Клиент запрашивает прогресс, передавая progressToken в _meta вызова
+	if params.Meta != nil && params.Meta.ProgressToken != nil {
+		ctx = withProgress(ctx, s.progressNotifier(params.Meta.ProgressToken))
+	}
+
+	result, err := s.callToolSafely(ctx, params.Name, params.Arguments)
+	if panicErr, ok := err.(*toolPanicError); ok {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      message.ID,
+			Error: &MCPError{
+				Code:    -32603,
+				Message: "Внутренняя ошибка",
+				Data:    redactSecrets(panicErr.Error()),
+			},
+		}
+	}
+	if ctx.Err() != nil {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      message.ID,
+			Error: &MCPError{
+				Code:    -32800,
+				Message: "Запрос отменен",
+				Data:    ctx.Err().Error(),
+			},
+		}
+	}
+	if err != nil {
+		// Ошибка инструмента — результат с isError (ее видит модель), а код категории
+		// в structuredContent позволяет клиенту обработать ее программно
+		text := redactSecrets(err.Error())
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      message.ID,
+			Result: CallToolResult{
+				Content: []ContentItem{{
+					Type: "text",
+					Text: "Ошибка: " + text,
+				}},
+				StructuredContent: map[string]interface{}{
+					"error": ErrorData{Code: errorCode(err), Message: text},
+				},
+				IsError: true,
+			},
+		}
+	}
+
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      message.ID,
+		Result:  result,
+	}
+}
+
+// toolPanicError паника, перехваченная при выполнении инструмента
+type toolPanicError struct {
+	tool  string
+	value interface{}
+}
+
+func (e *toolPanicError) Error() string {
+	return fmt.Sprintf("паника в инструменте %s: %v", e.tool, e.value)
+}
+
+// callToolSafely вызывает инструмент, превращая панику в ошибку, чтобы один
+// неудачный вызов не завершал весь сервер
+func (s *MCPServer) callToolSafely(ctx context.Context, name string, args map[string]interface{}) (result CallToolResult, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.Errorf("Паника в инструменте %s: %v\n%s", name, recovered, debug.Stack())
+			result, err = CallToolResult{}, &toolPanicError{tool: name, value: recovered}
+		}
+	}()
+
+	return s.callTool(ctx, name, args)
+}
+
+func (s *MCPServer) callTool(ctx context.Context, name string, args map[string]interface{}) (CallToolResult, error) {
+	logger.Debugf("Вызов инструмента %s", name)
+
+	switch name {
+	case "install_package":
+		return s.installPackage(ctx, args)
+	case "uninstall_package":
+		return s.uninstallPackage(ctx, args)
+	case "search_packages":
+		return s.searchPackages(ctx, args)
+	case "list_packages":
+		return s.listPackages(ctx, args)
+	case "package_info":
+		return s.packageInfo(ctx, args)
+	case "update_package":
+		return s.updatePackage(ctx, args)
+	case "update_all":
+		return s.updateAll(ctx, args)
+	case "create_package":
+		return s.createPackage(ctx, args)
+	case "build_package":
+		return s.buildPackage(ctx, args)
+	case "publish_package":
+		return s.publishPackage(ctx, args)
+	case "repository_info":
+		return s.repositoryInfo(ctx, args)
+	case "refresh_repository_index":
+		return s.refreshRepositoryIndex(ctx, args)
+	case "get_repository_stats":
+		return s.getRepositoryStats(ctx, args)
+	case "install_from_url":
+		return s.installFromURL(ctx, args)
+	case "list_by_category":
+		return s.listByCategory(ctx, args)
+	case "release_notes":
+		return s.releaseNotes(ctx, args)
+	case "verify_package":
+		return s.verifyPackage(ctx, args)
+	case "package_files":
+		return s.packageFiles(ctx, args)
+	case "check_time_sync":
+		return s.checkTimeSync(ctx, args)
+	case "disk_usage":
+		return s.diskUsage(ctx, args)
+	case "clean_cache":
+		return s.cleanCache(ctx, args)
+	case "raw_package_json":
+		return s.rawPackageJSON(ctx, args)
+	case "client_info":
+		return s.clientInfo(ctx, args)
+	case "set_log_level":
+		return s.setLogLevel(ctx, args)
+	case "doctor":
+		return s.doctor(ctx, args)
+	case "audit_environment":
+		return s.auditEnvironment(ctx, args)
+	case "check_archive_naming":
+		return s.checkArchiveNaming(ctx, args)
+	case "build_search_index":
+		return s.buildSearchIndex(ctx, args)
+	case "search_offline":
+		return s.searchOffline(ctx, args)
+	case "resolve_source":
+		return s.resolveSource(ctx, args)
+	case "compact_index":
+		return s.compactIndex(ctx, args)
+	case "repair_state":
+		return s.repairState(ctx, args)
+	case "downgrade_package":
+		return s.downgradePackage(ctx, args)
+	case "replay_plan":
+		return s.replayPlan(ctx, args)
+	case "resolved_constraints":
+		return s.resolvedConstraints(ctx, args)
+	case "package_version_info":
+		return s.packageVersionInfo(ctx, args)
+	case "compare_versions":
+		return s.compareVersions(ctx, args)
+	case "install_from_file":
+		return s.installFromFile(ctx, args)
+	case "dependency_tree":
+		return s.dependencyTree(ctx, args)
+	case "why_installed":
+		return s.whyInstalled(ctx, args)
+	case "autoremove":
+		return s.autoremove(ctx, args)
+	case "config_get":
+		return s.configGet(ctx, args)
+	case "config_set":
+		return s.configSet(ctx, args)
+	case "ping":
+		return s.ping(ctx, args)
+	case "server_info":
+		return s.serverInfo(ctx, args)
+	case "restore_config":
+		return s.restoreConfig(ctx, args)
+	case "run_script":
+		return s.runScript(ctx, args)
+	case "export_state":
+		return s.exportState(ctx, args)
+	case "import_state":
+		return s.importState(ctx, args)
+	case "metrics":
+		return s.metrics(ctx, args)
+	case "sync_repository":
+		return s.syncRepository(ctx, args)
+	case "repository_health":
+		return s.repositoryHealth(ctx, args)
+	case "check_executables":
+		return s.checkExecutables(ctx, args)
+	case "export_network_profile":
+		return s.exportNetworkProfile(ctx, args)
+	case "import_network_profile":
+		return s.importNetworkProfile(ctx, args)
+	case "use_network_profile":
+		return s.useNetworkProfile(ctx, args)
+	case "list_repositories":
+		return s.listRepositories(ctx, args)
+	case "add_repository":
+		return s.addRepository(ctx, args)
+	case "remove_repository":
+		return s.removeRepository(ctx, args)
+	case "list_signing_keys":
+		return s.listSigningKeys(ctx, args)
+	case "add_signing_key":
+		return s.addSigningKey(ctx, args)
+	case "remove_signing_key":
+		return s.removeSigningKey(ctx, args)
+	case "platform_info":
+		return s.platformInfo(ctx, args)
+	case "pin_package":
+		return s.pinPackage(ctx, args)
+	case "unpin_package":
+		return s.unpinPackage(ctx, args)
+	default:
+		return CallToolResult{}, fmt.Errorf("неизвестный инструмент: %s", name)
+	}
+}
+
+func getString(args map[string]interface{}, key string, defaultValue string) string {
+	if val, ok := args[key]; ok {
+		if str, ok := val.(string); ok {
+			return str
+		}
+	}
+	return defaultValue
+}
+
+func getBool(args map[string]interface{}, key string, defaultValue bool) bool {
+	if val, ok := args[key]; ok {
+		if b, ok := val.(bool); ok {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getInt(args map[string]interface{}, key string, defaultValue int) int {
+	if val, ok := args[key]; ok {
+		if i, ok := val.(float64); ok {
+			return int(i)
+		}
+	}
+	return defaultValue
+}
+
+func (s *MCPServer) installPackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	version := getString(args, "version", "")
+	global := getBool(args, "global", false)
+	force := getBool(args, "force", false)
+	arch := getString(args, "arch", "")
+	osName := getString(args, "os", "")
+	dryRun := getBool(args, "dry_run", false)
+	if getBool(args, "offline", false) {
+		ctx = withOffline(ctx)
+	}
+
+	plan, err := s.packageManager.InstallPackage(ctx, name, version, global, force, false, dryRun, arch, osName)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	if dryRun {
+		return CallToolResult{
+			Content: []ContentItem{{
+				Type: "text",
+				Text: formatInstallPlan(plan),
+			}},
+			StructuredContent: plan,
+		}, nil
+	}
+
+	locale := s.locale()
+	text := translate(locale, msgInstalled, name) + formatInstallTiming(locale, plan.Timing)
+	info, exists := s.packageManager.getInstalledPackage(name)
+	if exists {
+		text += formatSkippedSymlinks(locale, info.SkippedSymlinks) + formatPreservedConfig(locale, info)
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: text,
+		}},
+		StructuredContent: map[string]interface{}{"package": info, "timing": plan.Timing},
+	}, nil
+}
+
+// formatInstallTiming описывает длительность этапов установки
+func formatInstallTiming(locale string, timing *InstallTiming) string {
+	if timing == nil {
+		return ""
+	}
+	return translate(locale, msgInstallTiming, timing.TotalMs, timing.DownloadMs, timing.ExtractMs, timing.CopyMs)
+}
+
+// formatInstallPlan описывает действия, которые выполнила бы установка
+func formatInstallPlan(plan *InstallPlan) string {
+	var output strings.Builder
+	output.WriteString("🔍 План установки (dry run, изменения не выполнены):\n\n")
+
+	for _, action := range plan.Actions {
+		switch action.Action {
+		case InstallActionInstall:
+			output.WriteString(fmt.Sprintf("would install %s@%s to %s", action.Name, action.Version, action.Path))
+		case InstallActionReplace:
+			output.WriteString(fmt.Sprintf("would install %s@%s to %s (заменит %s)", action.Name, action.Version, action.Path, action.InstalledVersion))
+		case InstallActionSatisfied:
+			output.WriteString(fmt.Sprintf("  зависимость %s@%s уже установлена в %s", action.Name, action.InstalledVersion, action.Path))
+		case InstallActionConflict:
+			output.WriteString(fmt.Sprintf("  зависимость %s установлена в неподходящей версии %s (требуется %s) и не будет заменена", action.Name, action.InstalledVersion, action.Constraint))
+		}
+		switch action.Action {
+		case InstallActionInstall, InstallActionReplace:
+			if action.Dependency {
+				output.WriteString(" как зависимость")
+			}
+			if action.Cached {
+				output.WriteString(" — архив в кеше")
+			} else {
+				output.WriteString(" — архив будет скачан")
+			}
+		}
+		output.WriteString("\n")
+	}
+
+	for _, warning := range plan.Warnings {
+		output.WriteString(fmt.Sprintf("\n⚠️ %s\n", warning))
+	}
+	return output.String()
+}
+
+// formatChecksumNote сообщает, сверялся ли скачанный архив с контрольной суммой
+func formatChecksumNote(locale, checksum string) string {
+	if checksum == "" {
+		return translate(locale, msgChecksumMissing)
+	}
+	return translate(locale, msgChecksumMatched, normalizeChecksum(checksum))
+}
+
+// formatSkippedSymlinks описывает пропущенные при установке символические ссылки
+func formatSkippedSymlinks(locale string, links []string) string {
+	if len(links) == 0 {
+		return ""
+	}
+
+	var output strings.Builder
+	output.WriteString(translate(locale, msgSkippedSymlinks, len(links)))
+	for _, link := range links {
+		output.WriteString(fmt.Sprintf("  - %s\n", link))
+	}
+	return output.String()
+}
+
+// formatPreservedConfig предлагает восстановить конфигурацию, сохраненную при прошлом удалении пакета
+func formatPreservedConfig(locale string, info *PackageInfo) string {
+	if len(info.PreservedConfig) == 0 {
+		return ""
+	}
+	return translate(locale, msgPreservedConfigFound, strings.Join(info.PreservedConfig, ", "))
+}
+
+func (s *MCPServer) installFromURL(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	url := getString(args, "url", "")
+	if url == "" {
+		return CallToolResult{}, fmt.Errorf("URL архива обязателен")
+	}
+
+	// checksum оставлен для совместимости с ранними клиентами
+	checksum := getString(args, "expected_checksum", "")
+	if legacy := getString(args, "checksum", ""); legacy != "" {
+		if checksum != "" && normalizeChecksum(checksum) != normalizeChecksum(legacy) {
+			return CallToolResult{}, fmt.Errorf("expected_checksum и checksum различаются")
+		}
+		checksum = legacy
+	}
+	global := getBool(args, "global", false)
+	force := getBool(args, "force", false)
+
+	info, err := s.packageManager.InstallFromURL(ctx, url, checksum, global, force)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	locale := s.locale()
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: translate(locale, msgInstalledFrom, info.Name, info.Version, url) + formatChecksumNote(locale, checksum) + formatSkippedSymlinks(locale, info.SkippedSymlinks) + formatPreservedConfig(locale, info),
+		}},
+		StructuredContent: map[string]interface{}{"package": info, "verified": checksum != ""},
+	}, nil
+}
+
+func (s *MCPServer) uninstallPackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	global := getBool(args, "global", false)
+	purge := getBool(args, "purge", false)
+
+	info, _ := s.packageManager.getInstalledPackage(name)
+	err := s.packageManager.UninstallPackage(name, global, purge)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	text := s.message(msgUninstalled, name)
+	var preserved []string
+	if !purge && info != nil {
+		preserved = s.packageManager.PreservedConfig(name, info.Global)
+	}
+	if len(preserved) > 0 {
+		text += s.message(msgConfigPreserved, strings.Join(preserved, ", "))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: text,
+		}},
+		StructuredContent: map[string]interface{}{"name": name, "global": global, "purged": purge, "preserved_config": preserved},
+	}, nil
+}
+
+func (s *MCPServer) searchPackages(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	query := getString(args, "query", "")
+	if query == "" {
+		return CallToolResult{}, fmt.Errorf("поисковый запрос обязателен")
+	}
+
+	filter := SearchFilter{
+		Author:       getString(args, "author", ""),
+		License:      getString(args, "license", ""),
+		MinDownloads: int64(getInt(args, "min_downloads", 0)),
+	}
+	if filter.MinDownloads < 0 {
+		return CallToolResult{}, fmt.Errorf("min_downloads не может быть отрицательным")
+	}
+
+	page := getInt(args, "page", 1)
+	limit := getInt(args, "limit", 20)
+
+	search := s.packageManager.SearchPackages
+	if getBool(args, "from_index", false) {
+		search = s.packageManager.SearchIndexed
+	}
+	results, err := search(ctx, query, filter, page, limit)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	locale := s.locale()
+	var output strings.Builder
+	output.WriteString(translate(locale, msgSearchFound, results.Total, results.Page, results.TotalPages, results.Limit))
+	if len(results.Results) == 0 && results.Total > 0 {
+		output.WriteString(translate(locale, msgSearchEmptyPage))
+	}
+	for _, warning := range results.Warnings {
+		output.WriteString(fmt.Sprintf("⚠️ %s\n", warning))
+	}
+	if len(results.Suggestions) > 0 {
+		output.WriteString(translate(locale, msgSearchSuggestions))
+		for _, suggestion := range results.Suggestions {
+			output.WriteString(translate(locale, msgSearchSuggestion, suggestion.Name, suggestion.Distance))
+		}
+	}
+
+	for _, result := range results.Results {
+		output.WriteString(translate(locale, msgSearchResult, highlightMatches(result.Name, query), result.Version, formatScore(result.Score)))
+		output.WriteString(translate(locale, msgSearchDescription, highlightMatches(result.Description, query)))
+		output.WriteString(translate(locale, msgSearchAuthor, result.Author))
+		if result.License != "" {
+			output.WriteString(translate(locale, msgSearchLicense, result.License))
+		}
+		if result.Repository != "" {
+			output.WriteString(translate(locale, msgSearchRepository, result.Repository))
+		}
+		output.WriteString(translate(locale, msgSearchDownloads, result.Downloads))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: results,
+	}, nil
+}
+
+func (s *MCPServer) listPackages(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	global := getBool(args, "global", false)
+	outdated := getBool(args, "outdated", false)
+
+	packages, err := s.packageManager.ListPackages(ctx, global, outdated)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	if outdated {
+		output.WriteString(fmt.Sprintf("Устаревших пакетов: %d\n\n", len(packages)))
+	} else {
+		output.WriteString(fmt.Sprintf("Установленных пакетов: %d\n\n", len(packages)))
+	}
+
+	for _, pkg := range packages {
+		if pkg.AvailableVersion != "" {
+			output.WriteString(fmt.Sprintf("📦 %s (%s → %s)\n", pkg.Name, pkg.Version, pkg.AvailableVersion))
+		} else {
+			output.WriteString(fmt.Sprintf("📦 %s (%s)\n", pkg.Name, pkg.Version))
+		}
+		output.WriteString(fmt.Sprintf("   Путь: %s\n", pkg.InstallPath))
+		output.WriteString(fmt.Sprintf("   Размер: %s\n", formatSize(pkg.Size)))
+		output.WriteString(fmt.Sprintf("   Дата установки: %s\n\n", pkg.InstallDate.Format("2006-01-02 15:04:05")))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"packages": packages},
+	}, nil
+}
+
+func (s *MCPServer) listByCategory(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	keyword := getString(args, "keyword", "")
+
+	groups := s.packageManager.PackagesByCategory(keyword)
+
+	categories := make([]string, 0, len(groups))
+	for category := range groups {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var output strings.Builder
+	if keyword != "" && len(groups) == 0 {
+		output.WriteString(fmt.Sprintf("Пакеты с ключевым словом %q не найдены\n", keyword))
+	} else {
+		output.WriteString(fmt.Sprintf("Категорий: %d\n\n", len(categories)))
+	}
+
+	for _, category := range categories {
+		title := category
+		if title == uncategorizedKey {
+			title = "без категории"
+		}
+		output.WriteString(fmt.Sprintf("📂 %s (%d)\n", title, len(groups[category])))
+		for _, pkg := range groups[category] {
+			output.WriteString(fmt.Sprintf("   • %s (%s)\n", pkg.Name, pkg.Version))
+		}
+		output.WriteString("\n")
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"categories": groups},
+	}, nil
+}
+
+func (s *MCPServer) packageInfo(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	info, err := s.packageManager.GetPackageInfo(name)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("📦 Информация о пакете: %s\n\n", info.Name))
+	output.WriteString(fmt.Sprintf("Версия: %s\n", info.Version))
+	if info.Pinned {
+		output.WriteString(fmt.Sprintf("Закреплен на версии: %s\n", info.PinnedVersion))
+	}
+	if info.ResolvedVersion != "" && info.ResolvedVersion != info.Version {
+		output.WriteString(fmt.Sprintf("Точная версия: %s\n", info.ResolvedVersion))
+	}
+	if info.Checksum != "" {
+		output.WriteString(fmt.Sprintf("Контрольная сумма: %s\n", info.Checksum))
+	}
+	output.WriteString(fmt.Sprintf("Описание: %s\n", info.Description))
+	output.WriteString(fmt.Sprintf("Автор: %s\n", info.Author))
+	output.WriteString(fmt.Sprintf("Размер: %s\n", formatSize(info.Size)))
+	output.WriteString(fmt.Sprintf("Путь установки: %s\n", info.InstallPath))
+	output.WriteString(fmt.Sprintf("Дата установки: %s\n", info.InstallDate.Format("2006-01-02 15:04:05")))
+
+	if len(info.Dependencies) > 0 {
+		output.WriteString("\nЗависимости:\n")
+		for name, version := range info.Dependencies {
+			output.WriteString(fmt.Sprintf("  - %s: %s\n", name, version))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: info,
+	}, nil
+}
+
+func (s *MCPServer) verifyPackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	result, err := s.packageManager.VerifyPackage(name)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	verdict := "✅ OK"
+	if !result.OK {
+		verdict = "❌ FAILED"
+	}
+	output.WriteString(fmt.Sprintf("🔍 Проверка пакета %s (%s): %s\n\n", result.Name, result.Version, verdict))
+	output.WriteString(fmt.Sprintf("Путь установки: %s\n", result.InstallPath))
+	output.WriteString(fmt.Sprintf("Проверено файлов: %d\n", result.CheckedFiles))
+
+	if result.ManifestPresent {
+		output.WriteString("Манифест: найден\n")
+	} else {
+		output.WriteString("Манифест: отсутствует\n")
+	}
+
+	if result.ActualSize == result.ExpectedSize {
+		output.WriteString(fmt.Sprintf("Размер: %s\n", formatSize(result.ActualSize)))
+	} else {
+		output.WriteString(fmt.Sprintf("Размер: %s (ожидалось %s)\n", formatSize(result.ActualSize), formatSize(result.ExpectedSize)))
+	}
+
+	if len(result.MissingFiles) > 0 {
+		output.WriteString("\nОтсутствующие файлы:\n")
+		for _, file := range result.MissingFiles {
+			output.WriteString(fmt.Sprintf("  - %s\n", file))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+		IsError:           !result.OK,
+	}, nil
+}
+
+func (s *MCPServer) packageFiles(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	result, err := s.packageManager.PackageFiles(name)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("📄 Файлы пакета %s (%s): %d, всего %s\n", result.Name, result.Version, len(result.Files), formatSize(result.TotalSize)))
+	output.WriteString(fmt.Sprintf("Путь установки: %s\n\n", result.InstallPath))
+	if len(result.Files) == 0 {
+		output.WriteString("Пакет не записал список файлов\n")
+	}
+	for _, file := range result.Files {
+		switch {
+		case !file.Exists:
+			output.WriteString(fmt.Sprintf("  ❌ %s — отсутствует\n", file.Path))
+		case file.Dir:
+			output.WriteString(fmt.Sprintf("  📁 %s (%s)\n", file.Path, formatSize(file.Size)))
+		default:
+			output.WriteString(fmt.Sprintf("  ✅ %s (%s)\n", file.Path, formatSize(file.Size)))
+		}
+	}
+	if result.Missing > 0 {
+		output.WriteString(fmt.Sprintf("\n⚠️ Отсутствует файлов: %d\n", result.Missing))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+	}, nil
+}
+
+func (s *MCPServer) updatePackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	result, err := s.packageManager.UpdatePackage(ctx, name)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	text := s.message(msgUpdated, name, result.FromVersion, result.ToVersion)
+	if result.Skipped {
+		text = s.message(msgUpdatePinned, name, result.FromVersion)
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: text,
+		}},
+		StructuredContent: result,
+	}, nil
+}
+
+func (s *MCPServer) updateAll(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	global := getBool(args, "global", false)
+
+	result, err := s.packageManager.UpdateAll(ctx, global)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	locale := s.locale()
+	var output strings.Builder
+	if len(result.Packages) == 0 {
+		output.WriteString(translate(locale, msgAllUpToDate))
+	} else {
+		output.WriteString(translate(locale, msgUpdateSummary, result.Updated, result.Skipped, result.Failed))
+	}
+
+	for _, pkg := range result.Packages {
+		if pkg.Success {
+			output.WriteString(fmt.Sprintf("✅ %s: %s → %s\n", pkg.Name, pkg.FromVersion, pkg.ToVersion))
+		} else if pkg.Skipped {
+			output.WriteString(translate(locale, msgUpdateItemPinned, pkg.Name, pkg.FromVersion, pkg.ToVersion))
+		} else {
+			output.WriteString(fmt.Sprintf("❌ %s: %s → %s: %s\n", pkg.Name, pkg.FromVersion, pkg.ToVersion, pkg.Error))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+		IsError:           result.Failed > 0,
+	}, nil
+}
+
+func (s *MCPServer) releaseNotes(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	from, to, versions, err := s.packageManager.ReleaseNotes(ctx, name, getString(args, "from", ""), getString(args, "to", ""))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	if from == "" {
+		output.WriteString(fmt.Sprintf("📝 История изменений %s до версии %s\n\n", name, to))
+	} else {
+		output.WriteString(fmt.Sprintf("📝 Изменения %s: %s → %s\n\n", name, from, to))
+	}
+
+	if len(versions) == 0 {
+		output.WriteString("Нет версий между указанными\n")
+	}
+
+	for _, v := range versions {
+		output.WriteString(fmt.Sprintf("## %s", v.Version))
+		if !v.Uploaded.IsZero() {
+			output.WriteString(fmt.Sprintf(" (%s)", v.Uploaded.Format("2006-01-02")))
+		}
+		output.WriteString("\n")
+
+		notes := strings.TrimSpace(v.Changelog)
+		if notes == "" {
+			notes = strings.TrimSpace(v.Description)
+		}
+		if notes == "" {
+			notes = "Описание изменений отсутствует"
+		}
+		output.WriteString(notes + "\n\n")
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"name": name, "from": from, "to": to, "versions": versions},
+	}, nil
+}
+
+func (s *MCPServer) createPackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	template := getString(args, "template", "basic")
+	author := getString(args, "author", "")
+	description := getString(args, "description", "")
+
+	err := s.packageManager.CreatePackage(name, template, author, description)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("Пакет %s успешно создан", name),
+		}},
+		StructuredContent: map[string]interface{}{"name": name, "template": template},
+	}, nil
+}
+
+func (s *MCPServer) buildPackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	outputPath := getString(args, "output_path", "")
+	format := getString(args, "format", "criage")
+	compressionLevel := getInt(args, "compression_level", recommendedCompressionLevel(format))
+
+	err := s.packageManager.BuildPackage(outputPath, format, compressionLevel)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: "Пакет успешно собран",
+		}},
+		StructuredContent: map[string]interface{}{"output_path": outputPath, "format": format, "compression_level": compressionLevel},
+	}, nil
+}
+
+func (s *MCPServer) publishPackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	registryURL := getString(args, "registry_url", "")
+	token := getString(args, "token", "")
+
+	err := s.packageManager.PublishPackage(ctx, registryURL, token)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: "Пакет успешно опубликован",
+		}},
+		StructuredContent: map[string]interface{}{"registry_url": registryURL},
+	}, nil
+}
+
+func (s *MCPServer) repositoryInfo(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	url := getString(args, "url", "")
+	if url == "" {
+		return CallToolResult{}, fmt.Errorf("URL репозитория обязателен")
+	}
+
+	info, err := s.packageManager.GetRepositoryInfo(ctx, url)
+	if err != nil {
+		return CallToolResult{
+			Content: []ContentItem{{
+				Type: "text",
+				Text: fmt.Sprintf("❌ Ошибка получения информации о репозитории: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("📊 Информация о репозитории: %s\n\n", url))
+
+	if name, ok := info["name"].(string); ok {
+		output.WriteString(fmt.Sprintf("Название: %s\n", name))
+	}
+	if version, ok := info["version"].(string); ok {
+		output.WriteString(fmt.Sprintf("Версия: %s\n", version))
+	}
+	if lastUpdated, ok := info["last_updated"].(string); ok {
+		output.WriteString(fmt.Sprintf("Последнее обновление: %s\n", lastUpdated))
+	}
+	if totalPackages, ok := info["total_packages"].(float64); ok {
+		output.WriteString(fmt.Sprintf("Всего пакетов: %.0f\n", totalPackages))
+	}
+	if formats, ok := info["formats"].([]interface{}); ok {
+		output.WriteString("Поддерживаемые форматы: ")
+		for i, format := range formats {
+			if i > 0 {
+				output.WriteString(", ")
+			}
+			output.WriteString(fmt.Sprintf("%v", format))
+		}
+		output.WriteString("\n")
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: info,
+	}, nil
+}
+
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// refreshRepositoryIndex принудительно обновляет индекс пакетов в репозитории
+func (s *MCPServer) refreshRepositoryIndex(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	repositoryURL := getString(args, "repository_url", "")
+	if repositoryURL == "" {
+		return CallToolResult{}, fmt.Errorf("URL репозитория обязателен")
+	}
+
+	authToken := getString(args, "auth_token", "")
+	if authToken == "" {
+		return CallToolResult{}, fmt.Errorf("токен авторизации обязателен")
+	}
+
+	err := s.packageManager.RefreshRepositoryIndex(ctx, repositoryURL, authToken)
+	if err != nil {
+		return CallToolResult{
+			Content: []ContentItem{{
+				Type: "text",
+				Text: fmt.Sprintf("❌ Ошибка обновления индекса репозитория: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("✅ Индекс репозитория %s успешно обновлен", repositoryURL),
+		}},
+		StructuredContent: map[string]interface{}{"repository_url": repositoryURL},
+	}, nil
+}
+
+// getRepositoryStats получает детальную статистику репозитория
+func (s *MCPServer) getRepositoryStats(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	repositoryURL := getString(args, "repository_url", "")
+	if repositoryURL == "" {
+		return CallToolResult{}, fmt.Errorf("URL репозитория обязателен")
+	}
+
+	stats, cached, err := s.packageManager.GetRepositoryStats(ctx, repositoryURL, getBool(args, "force_refresh", false))
+	if err != nil {
+		return CallToolResult{
+			Content: []ContentItem{{
+				Type: "text",
+				Text: fmt.Sprintf("❌ Ошибка получения статистики репозитория: %v", err),
+			}},
+			IsError: true,
+		}, nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("📊 Статистика репозитория: %s\n\n", repositoryURL))
+	output.WriteString(fmt.Sprintf("📦 Всего пакетов: %d\n", stats.TotalPackages))
+	output.WriteString(fmt.Sprintf("⬇️ Всего загрузок: %d\n", stats.TotalDownloads))
+	output.WriteString(fmt.Sprintf("🕒 Последнее обновление: %s\n", stats.LastUpdated.Format("2006-01-02 15:04:05")))
+	if cached {
+		output.WriteString("💾 Данные из кеша (force_refresh=true запросит репозиторий)\n")
+	}
+	output.WriteString("\n")
+
+	if len(stats.PopularPackages) > 0 {
+		output.WriteString("🔥 Популярные пакеты:\n")
+		writeTopN(&output, stats.PopularPackages, 10)
+		output.WriteString("\n")
+	}
+
+	if len(stats.PackagesByLicense) > 0 {
+		output.WriteString("📜 Распределение по лицензиям:\n")
+		for _, license := range rankByCount(stats.PackagesByLicense) {
+			output.WriteString(fmt.Sprintf("   • %s: %d пакетов\n", license.Name, license.Count))
+		}
+		output.WriteString("\n")
+	}
+
+	if len(stats.PackagesByAuthor) > 0 {
+		output.WriteString("👥 Топ авторы:\n")
+		authors := rankByCount(stats.PackagesByAuthor)
+		lines := make([]string, len(authors))
+		for i, author := range authors {
+			lines[i] = fmt.Sprintf("%s: %d пакетов", author.Name, author.Count)
+		}
+		writeTopN(&output, lines, 5)
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: stats,
+	}, nil
+}
+
+// checkTimeSync проверяет расхождение часов с репозиториями
+func (s *MCPServer) checkTimeSync(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	threshold := time.Duration(getInt(args, "threshold_seconds", 30)) * time.Second
+
+	var urls []string
+	if repositoryURL := getString(args, "repository_url", ""); repositoryURL != "" {
+		urls = append(urls, repositoryURL)
+	} else {
+		for _, repo := range s.packageManager.getConfig().Repositories {
+			if repo.Enabled {
+				urls = append(urls, repo.URL)
+			}
+		}
+	}
+
+	if len(urls) == 0 {
+		return CallToolResult{}, fmt.Errorf("нет включенных репозиториев для проверки")
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🕒 Проверка синхронизации времени (порог: %s)\n\n", threshold))
+
+	hasProblems := false
+	checks := make([]map[string]interface{}, 0, len(urls))
+	for _, url := range urls {
+		result, err := s.packageManager.CheckTimeSync(ctx, url)
+		if err != nil {
+			hasProblems = true
+			output.WriteString(fmt.Sprintf("❌ %s: %v\n", url, err))
+			checks = append(checks, map[string]interface{}{"repository_url": url, "error": err.Error()})
+			continue
+		}
+
+		skew := result.Skew
+		if skew < 0 {
+			skew = -skew
+		}
+
+		status := "✅"
+		if skew > threshold {
+			status = "⚠️"
+			hasProblems = true
+		}
+
+		checks = append(checks, map[string]interface{}{
+			"repository_url": url,
+			"server_time":    result.ServerTime,
+			"local_time":     result.LocalTime,
+			"skew_seconds":   result.Skew.Seconds(),
+			"ok":             skew <= threshold,
+		})
+
+		output.WriteString(fmt.Sprintf("%s %s\n", status, url))
+		output.WriteString(fmt.Sprintf("   Время репозитория: %s\n", result.ServerTime.Format(time.RFC1123)))
+		output.WriteString(fmt.Sprintf("   Локальное время: %s\n", result.LocalTime.Format(time.RFC1123)))
+		output.WriteString(fmt.Sprintf("   Расхождение: %s\n", result.Skew.Round(time.Second)))
+		if skew > threshold {
+			output.WriteString("   Предупреждение: расхождение превышает порог, возможны ошибки проверки подписей и срока действия кеша\n")
+		}
+		output.WriteString("\n")
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"threshold_seconds": int(threshold / time.Second), "repositories": checks},
+		IsError:           hasProblems,
+	}, nil
+}
+
+func (s *MCPServer) cleanCache(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	days := getInt(args, "older_than_days", 0)
+	if days < 0 {
+		return CallToolResult{}, fmt.Errorf("older_than_days не может быть отрицательным")
+	}
+	dryRun := getBool(args, "dry_run", false)
+
+	result, err := s.packageManager.CleanCache(time.Duration(days)*24*time.Hour, dryRun)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	if dryRun {
+		output.WriteString(fmt.Sprintf("🧹 Будет удалено из кеша: %d файлов (%s)\n", result.Files, formatSize(result.Bytes)))
+		for _, path := range result.Paths {
+			output.WriteString(fmt.Sprintf("  - %s\n", path))
+		}
+	} else {
+		output.WriteString(fmt.Sprintf("🧹 Удалено из кеша: %d файлов (%s)\n", result.Files, formatSize(result.Bytes)))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+	}, nil
+}
+
+func (s *MCPServer) diskUsage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	top := getInt(args, "top", defaultDiskUsageTop)
+	if top < 1 || top > 100 {
+		return CallToolResult{}, fmt.Errorf("top должен быть от 1 до 100")
+	}
+
+	result := s.packageManager.DiskUsage(top)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("💽 Всего занято: %s\n\n", formatSize(result.Total)))
+	output.WriteString(fmt.Sprintf("Глобальные пакеты: %s (%d) — %s\n", formatSize(result.Global.Size), result.Global.Packages, result.Global.Path))
+	output.WriteString(fmt.Sprintf("Локальные пакеты: %s (%d) — %s\n", formatSize(result.Local.Size), result.Local.Packages, result.Local.Path))
+	output.WriteString(fmt.Sprintf("Кеш: %s — %s\n", formatSize(result.Cache.Size), result.Cache.Path))
+	output.WriteString(fmt.Sprintf("Временные файлы: %s — %s\n", formatSize(result.Temp.Size), result.Temp.Path))
+
+	if len(result.Largest) > 0 {
+		output.WriteString("\n📦 Крупнейшие пакеты:\n")
+		for _, pkg := range result.Largest {
+			scope := "локальный"
+			if pkg.Global {
+				scope = "глобальный"
+			}
+			output.WriteString(fmt.Sprintf("  - %s (%s, %s): %s\n", pkg.Name, pkg.Version, scope, formatSize(pkg.Size)))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+	}, nil
+}
+
+func (s *MCPServer) rawPackageJSON(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	repositoryURL := getString(args, "repository_url", "")
+	if repositoryURL == "" {
+		return CallToolResult{}, fmt.Errorf("URL репозитория обязателен")
+	}
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	body, truncated, err := s.packageManager.RawPackageJSON(ctx, repositoryURL, name)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	// Форматируем с сохранением порядка полей; обрезанный или некорректный JSON выводим как есть
+	text := string(body)
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err == nil {
+		text = pretty.String()
+	}
+	text = redactJSONSecrets(text)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("📄 Сырой ответ %s/api/v1/packages/%s\n\n", strings.TrimRight(repositoryURL, "/"), name))
+	output.WriteString(text)
+	output.WriteString("\n")
+	if truncated {
+		output.WriteString(fmt.Sprintf("\n⚠️ Ответ обрезан до %s\n", formatSize(maxRawJSONSize)))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"repository_url": repositoryURL, "name": name, "body": text, "truncated": truncated},
+	}, nil
+}
+
+func (s *MCPServer) clientInfo(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	client := s.clientParams()
+	if client == nil {
+		return CallToolResult{}, fmt.Errorf("клиент еще не выполнил initialize")
+	}
+
+	var output strings.Builder
+	output.WriteString("🔌 Подключенный клиент\n\n")
+	output.WriteString(fmt.Sprintf("Имя: %s\n", client.ClientInfo.Name))
+	output.WriteString(fmt.Sprintf("Версия: %s\n", client.ClientInfo.Version))
+	output.WriteString(fmt.Sprintf("Версия протокола: %s\n", client.ProtocolVersion))
+
+	if len(client.Capabilities) == 0 {
+		output.WriteString("Возможности: не объявлены\n")
+	} else {
+		capabilities, err := json.MarshalIndent(client.Capabilities, "", "  ")
+		if err != nil {
+			return CallToolResult{}, fmt.Errorf("ошибка сериализации возможностей: %w", err)
+		}
+		output.WriteString(fmt.Sprintf("Возможности:\n%s\n", capabilities))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: client,
+	}, nil
+}
+
+func (s *MCPServer) setLogLevel(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "level", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("уровень журнала обязателен")
+	}
+
+	level, err := parseLogLevel(name)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	previous := logger.Level()
+	logger.SetLevel(level)
+	logger.Infof("Уровень журнала изменен: %s -> %s", previous, level)
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("Уровень журнала: %s (был %s)", level, previous),
+		}},
+		StructuredContent: map[string]interface{}{"level": level.String(), "previous": previous.String()},
+	}, nil
+}
+
+func (s *MCPServer) doctor(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	report := s.packageManager.Doctor(ctx)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🩺 Диагностика: пройдено %d, предупреждений %d, ошибок %d\n\n", report.Passed, report.Warnings, report.Failures))
+
+	icons := map[DiagnosticStatus]string{
+		DiagnosticPass: "✅",
+		DiagnosticWarn: "⚠️",
+		DiagnosticFail: "❌",
+	}
+	for _, diagnostic := range report.Diagnostics {
+		subject := ""
+		if diagnostic.Subject != "" {
+			subject = diagnostic.Subject + ": "
+		}
+		output.WriteString(fmt.Sprintf("%s [%s] %s%s\n", icons[diagnostic.Status], diagnostic.Check, subject, diagnostic.Message))
+		if diagnostic.Fix != "" {
+			output.WriteString(fmt.Sprintf("   → %s\n", diagnostic.Fix))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: report,
+		IsError:           report.Failures > 0,
+	}, nil
+}
+
+func (s *MCPServer) auditEnvironment(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	report, err := s.packageManager.AuditEnvironment(ctx)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🩺 Аудит окружения: ошибок %d, предупреждений %d, сведений %d\n", report.Errors, report.Warnings, report.Info))
+
+	icons := map[string]string{
+		SeverityError:   "❌",
+		SeverityWarning: "⚠️",
+		SeverityInfo:    "ℹ️",
+	}
+	for _, finding := range report.Findings {
+		subject := ""
+		if finding.Subject != "" {
+			subject = finding.Subject + ": "
+		}
+		output.WriteString(fmt.Sprintf("%s [%s] %s%s\n", icons[finding.Severity], finding.Check, subject, finding.Message))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: report,
+		IsError:           report.Errors > 0,
+	}, nil
+}
+
+func (s *MCPServer) checkArchiveNaming(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	archivePath := getString(args, "archive_path", "")
+	if archivePath == "" {
+		return CallToolResult{}, fmt.Errorf("путь к архиву обязателен")
+	}
+
+	result, err := s.packageManager.CheckArchiveNaming(archivePath)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	verdict := "✅ OK"
+	if !result.OK {
+		verdict = "❌ FAILED"
+	}
+	output.WriteString(fmt.Sprintf("🏷️ Проверка имени архива %s: %s\n\n", result.Filename, verdict))
+	output.WriteString(fmt.Sprintf("Пакет: %s (%s)\n", result.Name, result.Version))
+	if result.OS != "" || result.Arch != "" {
+		output.WriteString(fmt.Sprintf("Платформа: %s/%s\n", result.OS, result.Arch))
+	}
+	output.WriteString(fmt.Sprintf("Формат: %s\n", result.Format))
+	if result.Expected != "" && result.Expected != result.Filename {
+		output.WriteString(fmt.Sprintf("Ожидаемое имя: %s\n", result.Expected))
+	}
+
+	if len(result.Problems) > 0 {
+		output.WriteString("\nНесоответствия:\n")
+		for _, problem := range result.Problems {
+			output.WriteString(fmt.Sprintf("  - %s\n", problem))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+		IsError:           !result.OK,
+	}, nil
+}
+
+func (s *MCPServer) buildSearchIndex(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	var urls []string
+	if repositoryURL := getString(args, "repository_url", ""); repositoryURL != "" {
+		urls = []string{repositoryURL}
+	} else {
+		for _, repo := range s.packageManager.getConfig().Repositories {
+			if repo.Enabled {
+				urls = append(urls, repo.URL)
+			}
+		}
+	}
+	if len(urls) == 0 {
+		return CallToolResult{}, fmt.Errorf("нет включенных репозиториев")
+	}
+
+	var output strings.Builder
+	output.WriteString("🗂️ Построение поискового индекса\n\n")
+
+	failed := 0
+	indexes := make([]map[string]interface{}, 0, len(urls))
+	for _, url := range urls {
+		index, err := s.packageManager.BuildSearchIndex(ctx, url)
+		if err != nil {
+			if ctx.Err() != nil {
+				return CallToolResult{}, ctx.Err()
+			}
+			failed++
+			output.WriteString(fmt.Sprintf("❌ %s: %v\n", url, err))
+			indexes = append(indexes, map[string]interface{}{"repository_url": url, "error": err.Error()})
+			continue
+		}
+		output.WriteString(fmt.Sprintf("✅ %s: пакетов %d, термов %d\n", url, len(index.Packages), len(index.Terms)))
+		indexes = append(indexes, map[string]interface{}{
+			"repository_url": url,
+			"built_at":       index.BuiltAt,
+			"packages":       len(index.Packages),
+			"terms":          len(index.Terms),
+		})
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"repositories": indexes},
+		IsError:           failed == len(urls),
+	}, nil
+}
+
+func (s *MCPServer) searchOffline(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	query := getString(args, "query", "")
+	if query == "" {
+		return CallToolResult{}, fmt.Errorf("поисковый запрос обязателен")
+	}
+
+	results, err := s.packageManager.SearchOffline(query, getString(args, "repository_url", ""))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Найдено пакетов в локальном индексе: %d\n\n", len(results)))
+
+	for _, result := range results {
+		output.WriteString(fmt.Sprintf("📦 %s (%s)\n", result.Name, result.Version))
+		output.WriteString(fmt.Sprintf("   Описание: %s\n", result.Description))
+		output.WriteString(fmt.Sprintf("   Автор: %s\n", result.Author))
+		output.WriteString(fmt.Sprintf("   Загрузок: %d\n\n", result.Downloads))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"results": results},
+	}, nil
+}
+
+func (s *MCPServer) resolveSource(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	source, err := s.packageManager.ResolveSource(ctx, name, getString(args, "version", ""), getString(args, "arch", ""), getString(args, "os", ""))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🧭 Источник пакета %s (%s) для %s/%s\n\n", source.Name, source.Version, source.OS, source.Arch))
+	output.WriteString(fmt.Sprintf("Репозиторий: %s (%s)\n", source.Repository, source.RepositoryURL))
+	output.WriteString(fmt.Sprintf("Приоритет: %d\n", source.Priority))
+	output.WriteString(fmt.Sprintf("Файл: %s\n", source.Filename))
+	if source.Format != "" {
+		output.WriteString(fmt.Sprintf("Формат: %s\n", source.Format))
+	}
+	output.WriteString(fmt.Sprintf("Размер: %s\n", formatSize(source.Size)))
+	output.WriteString(fmt.Sprintf("Контрольная сумма: %s\n", source.Checksum))
+	output.WriteString(fmt.Sprintf("URL: %s\n", source.DownloadURL))
+
+	if len(source.Skipped) > 0 {
+		output.WriteString("\nПропущенные репозитории:\n")
+		for _, attempt := range source.Skipped {
+			output.WriteString(fmt.Sprintf("  - %s: %s\n", attempt.Repository, attempt.Error))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: source,
+	}, nil
+}
+
+func (s *MCPServer) compactIndex(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	result, err := s.packageManager.CompactIndex()
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🗜️ Индекс уплотнен: записей %d, удалено %d, исправлено %d\n", result.Kept, len(result.Removed), len(result.Fixed)))
+
+	if len(result.Removed) > 0 {
+		output.WriteString("\nУдалено:\n")
+		for _, change := range result.Removed {
+			output.WriteString(fmt.Sprintf("  - [%s] %s: %s\n", change.Scope, change.Name, change.Reason))
+		}
+	}
+	if len(result.Fixed) > 0 {
+		output.WriteString("\nИсправлено:\n")
+		for _, change := range result.Fixed {
+			output.WriteString(fmt.Sprintf("  - [%s] %s: %s\n", change.Scope, change.Name, change.Reason))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+	}, nil
+}
+
+func (s *MCPServer) repairState(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	result, err := s.packageManager.RepairState(getBool(args, "force", false))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	if len(result.Repaired) == 0 {
+		output.WriteString("✅ Файлы packages.json не повреждены, восстановление не требуется\n")
+	} else {
+		output.WriteString(fmt.Sprintf("🛠️ Восстановлено файлов: %d, пакетов: %d\n", len(result.Repaired), len(result.Recovered)))
+		for _, path := range result.Repaired {
+			output.WriteString(fmt.Sprintf("  - %s\n", path))
+		}
+	}
+
+	if len(result.Recovered) > 0 {
+		output.WriteString("\nВосстановлены пакеты:\n")
+		for _, pkg := range result.Recovered {
+			output.WriteString(fmt.Sprintf("  - [%s] %s (%s)\n", pkg.Scope, pkg.Name, pkg.Version))
+		}
+	}
+	if len(result.Skipped) > 0 {
+		output.WriteString("\nПропущены каталоги:\n")
+		for _, change := range result.Skipped {
+			output.WriteString(fmt.Sprintf("  - [%s] %s: %s\n", change.Scope, change.Name, change.Reason))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+	}, nil
+}
+
+func (s *MCPServer) downgradePackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+	version := getString(args, "version", "")
+	if version == "" {
+		return CallToolResult{}, fmt.Errorf("целевая версия обязательна")
+	}
+
+	result, err := s.packageManager.DowngradePackage(ctx, name, version, getBool(args, "allow_same", false))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("⏪ Пакет %s откачен: %s → %s", result.Name, result.FromVersion, result.ToVersion),
+		}},
+		StructuredContent: result,
+	}, nil
+}
+
+func (s *MCPServer) replayPlan(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	var plan []PlanEntry
+	data, _ := json.Marshal(args["plan"])
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return CallToolResult{}, fmt.Errorf("некорректный план: %w", err)
+	}
+
+	result, err := s.packageManager.ReplayPlan(ctx, plan)
+	if result == nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("▶️ Выполнение плана: установлено %d, ошибок %d\n\n", result.Installed, result.Failed))
+	for _, step := range result.Steps {
+		scope := step.Scope
+		if scope == "" {
+			scope = scopeLocal
+		}
+		switch step.Status {
+		case ReplayInstalled:
+			output.WriteString(fmt.Sprintf("✅ %s %s (%s)\n", step.Name, step.Version, scope))
+		case ReplaySkipped:
+			output.WriteString(fmt.Sprintf("⏭️ %s %s (%s): уже установлен\n", step.Name, step.Version, scope))
+		default:
+			output.WriteString(fmt.Sprintf("❌ %s %s (%s): %s\n", step.Name, step.Version, scope, step.Error))
+		}
+	}
+	if err != nil {
+		output.WriteString(fmt.Sprintf("\nВыполнение прервано: %v\n", err))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+		IsError:           err != nil,
+	}, nil
+}
+
+func (s *MCPServer) resolvedConstraints(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	report, err := s.packageManager.ResolvedConstraints(ctx, getString(args, "name", ""), getString(args, "version", ""), getString(args, "manifest_path", ""))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🔗 Разрешенные зависимости %s: %d\n", report.Root, len(report.Dependencies)))
+
+	for _, dep := range report.Dependencies {
+		output.WriteString(fmt.Sprintf("\n%s → %s\n", dep.Name, dep.Version))
+		output.WriteString(fmt.Sprintf("  Итоговое ограничение: %s\n", dep.Combined))
+		for _, req := range dep.Constraints {
+			output.WriteString(fmt.Sprintf("  - %s: %s\n", req.Requirer, normalizedConstraint(req.Constraint)))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: report,
+	}, nil
+}
+
+func (s *MCPServer) pinPackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	info, err := s.packageManager.PinPackage(name, getString(args, "version", ""))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("📌 Пакет %s закреплен на версии %s", info.Name, info.PinnedVersion),
+		}},
+		StructuredContent: info,
+	}, nil
+}
+
+func (s *MCPServer) unpinPackage(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	info, err := s.packageManager.UnpinPackage(name)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("Закрепление пакета %s снято", info.Name),
+		}},
+		StructuredContent: info,
+	}, nil
+}
+
+func (s *MCPServer) platformInfo(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	info := s.packageManager.Platform(getString(args, "arch", ""), getString(args, "os", ""))
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🖥️ Целевая платформа: %s/%s\n\n", info.OS, info.Arch))
+	output.WriteString(fmt.Sprintf("Определена при запуске: %s/%s\n", info.DetectedOS, info.DetectedArch))
+	if info.DefaultOS != "" || info.DefaultArch != "" {
+		output.WriteString(fmt.Sprintf("По умолчанию из конфигурации: os=%s arch=%s\n", info.DefaultOS, info.DefaultArch))
 	}
+	output.WriteString(fmt.Sprintf("Источник ОС: %s\n", info.OSSource))
+	output.WriteString(fmt.Sprintf("Источник архитектуры: %s\n", info.ArchSource))
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: info,
+	}, nil
 }
 
-func getString(args map[string]interface{}, key string, defaultValue string) string {
-	if val, ok := args[key]; ok {
-		if str, ok := val.(string); ok {
-			return str
+func (s *MCPServer) listRepositories(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	repositories := s.packageManager.ListRepositories()
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🗄️ Репозиториев: %d\n\n", len(repositories)))
+	for i, repo := range repositories {
+		status := "включен"
+		if !repo.Enabled {
+			status = "выключен"
 		}
+		output.WriteString(fmt.Sprintf("%s (%s)\n", repo.Name, repo.URL))
+		output.WriteString(fmt.Sprintf("   Приоритет: %d, %s", repo.Priority, status))
+		if repo.AuthToken != "" {
+			output.WriteString(", токен задан")
+		}
+		output.WriteString("\n")
+		repositories[i] = redactedRepository(repo)
 	}
-	return defaultValue
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"repositories": repositories},
+	}, nil
 }
 
-func getBool(args map[string]interface{}, key string, defaultValue bool) bool {
-	if val, ok := args[key]; ok {
-		if b, ok := val.(bool); ok {
-			return b
+func (s *MCPServer) addRepository(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	repo, err := s.packageManager.AddRepository(Repository{
+		Name:      getString(args, "name", ""),
+		URL:       getString(args, "url", ""),
+		Priority:  getInt(args, "priority", 0),
+		Enabled:   getBool(args, "enabled", true),
+		AuthToken: getString(args, "auth_token", ""),
+		Timeout:   getInt(args, "timeout", 0),
+		RateLimit: getInt(args, "rate_limit", 0),
+		PublicKey: getString(args, "public_key", ""),
+	})
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("✅ Репозиторий %s (%s) добавлен с приоритетом %d", repo.Name, repo.URL, repo.Priority),
+		}},
+		StructuredContent: redactedRepository(*repo),
+	}, nil
+}
+
+func (s *MCPServer) removeRepository(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя репозитория обязательно")
+	}
+
+	repo, err := s.packageManager.RemoveRepository(name)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("🗑️ Репозиторий %s (%s) удален", repo.Name, repo.URL),
+		}},
+		StructuredContent: redactedRepository(*repo),
+	}, nil
+}
+
+func (s *MCPServer) listSigningKeys(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	keys := s.packageManager.ListSigningKeys()
+
+	var output strings.Builder
+	if len(keys) == 0 {
+		output.WriteString("🔑 Ключи подписи не заданы: архивы устанавливаются без проверки подписи")
+	} else {
+		output.WriteString(fmt.Sprintf("🔑 Ключи подписи (%d):\n\n", len(keys)))
+		for _, key := range keys {
+			output.WriteString(fmt.Sprintf("• %s: ключ %s\n", key.Repository, key.KeyID))
 		}
 	}
-	return defaultValue
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"keys": keys},
+	}, nil
 }
 
-func getInt(args map[string]interface{}, key string, defaultValue int) int {
-	if val, ok := args[key]; ok {
-		if i, ok := val.(float64); ok {
-			return int(i)
+func (s *MCPServer) addSigningKey(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	repository := getString(args, "repository", "")
+	if repository == "" {
+		return CallToolResult{}, fmt.Errorf("имя репозитория обязательно")
+	}
+
+	key, err := s.packageManager.AddSigningKey(repository, getString(args, "public_key", ""))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("🔑 Для репозитория %s добавлен ключ подписи %s; неподписанные архивы устанавливаться не будут", key.Repository, key.KeyID),
+		}},
+		StructuredContent: key,
+	}, nil
+}
+
+func (s *MCPServer) removeSigningKey(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	repository := getString(args, "repository", "")
+	if repository == "" {
+		return CallToolResult{}, fmt.Errorf("имя репозитория обязательно")
+	}
+
+	key, err := s.packageManager.RemoveSigningKey(repository)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("🗑️ Ключ подписи %s репозитория %s удален", key.KeyID, key.Repository),
+		}},
+		StructuredContent: key,
+	}, nil
+}
+
+func (s *MCPServer) exportNetworkProfile(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	profile, err := s.packageManager.ExportNetworkProfile(getString(args, "name", ""))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("🌐 Сетевой профиль %s сохранен:\n\n%s\n", profile.Name, data),
+		}},
+		StructuredContent: profile,
+	}, nil
+}
+
+func (s *MCPServer) importNetworkProfile(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	raw, ok := args["profile"]
+	if !ok {
+		return CallToolResult{}, fmt.Errorf("профиль обязателен")
+	}
+
+	// Профиль принимается объектом или JSON-строкой
+	data, isString := raw.(string)
+	if !isString {
+		encoded, err := json.Marshal(raw)
+		if err != nil {
+			return CallToolResult{}, fmt.Errorf("некорректный профиль: %w", err)
 		}
+		data = string(encoded)
 	}
-	return defaultValue
+
+	var profile NetworkProfile
+	if err := json.Unmarshal([]byte(data), &profile); err != nil {
+		return CallToolResult{}, fmt.Errorf("некорректный профиль: %w", err)
+	}
+
+	imported, err := s.packageManager.ImportNetworkProfile(profile)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("🌐 Сетевой профиль %s импортирован", imported.Name),
+		}},
+		StructuredContent: imported,
+	}, nil
+}
+
+func (s *MCPServer) useNetworkProfile(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя профиля обязательно")
+	}
+
+	profile, err := s.packageManager.UseNetworkProfile(name)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🌐 Применен сетевой профиль %s\n\n", profile.Name))
+	output.WriteString(fmt.Sprintf("Запросов в секунду: %d\n", profile.RequestsPerSecond))
+	output.WriteString(fmt.Sprintf("Таймаут: %d с\n", profile.Timeout))
+	output.WriteString(fmt.Sprintf("Повторов после 429: %d\n", profile.MaxRetries))
+	output.WriteString(fmt.Sprintf("Пул соединений: всего %d, на хост %d, простой %d с\n", profile.MaxIdleConns, profile.MaxIdleConnsPerHost, profile.IdleConnTimeout))
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: profile,
+	}, nil
+}
+
+func (s *MCPServer) checkExecutables(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+
+	result, err := s.packageManager.CheckExecutables(ctx, name, getBool(args, "run", false))
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	if len(result.Executables) == 0 {
+		output.WriteString(fmt.Sprintf("Пакет %s (%s) не объявляет исполняемых файлов\n", result.Name, result.Version))
+	} else {
+		output.WriteString(fmt.Sprintf("⚙️ Исполняемые файлы %s (%s): %d, с ошибками: %d\n\n", result.Name, result.Version, len(result.Executables), result.Failed))
+	}
+
+	for _, check := range result.Executables {
+		switch {
+		case !check.OK:
+			output.WriteString(fmt.Sprintf("❌ %s: %s\n", check.Path, check.Error))
+		case check.Ran:
+			output.WriteString(fmt.Sprintf("✅ %s: %s\n", check.Path, check.Output))
+		default:
+			output.WriteString(fmt.Sprintf("✅ %s\n", check.Path))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: result,
+		IsError:           result.Failed > 0,
+	}, nil
+}
+
+func (s *MCPServer) repositoryHealth(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	health := s.packageManager.RepositoryHealth()
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🩺 Состояние репозиториев: %d\n\n", len(health)))
+	for _, repo := range health {
+		switch {
+		case repo.Demoted:
+			output.WriteString(fmt.Sprintf("⛔ %s (%s): понижен до %s\n", repo.Name, repo.URL, repo.DemotedUntil.Format("15:04:05")))
+		case !repo.Healthy:
+			output.WriteString(fmt.Sprintf("⚠️ %s (%s): сбоев подряд %d\n", repo.Name, repo.URL, repo.ConsecutiveFailures))
+		default:
+			output.WriteString(fmt.Sprintf("✅ %s (%s)\n", repo.Name, repo.URL))
+		}
+		if repo.LastError != "" {
+			output.WriteString(fmt.Sprintf("   Последняя ошибка: %s\n", repo.LastError))
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: map[string]interface{}{"repositories": health},
+	}, nil
+}
+
+func (s *MCPServer) packageVersionInfo(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	repositoryURL := getString(args, "repository_url", "")
+	if repositoryURL == "" {
+		return CallToolResult{}, fmt.Errorf("URL репозитория обязателен")
+	}
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+	version := getString(args, "version", "")
+	if version == "" {
+		return CallToolResult{}, fmt.Errorf("версия пакета обязательна")
+	}
+
+	info, err := s.packageManager.GetPackageVersionInfo(ctx, repositoryURL, name, version)
+	if errors.Is(err, ErrVersionNotFound) {
+		return CallToolResult{
+			Content: []ContentItem{{
+				Type: "text",
+				Text: fmt.Sprintf("❌ Версия %s пакета %s не найдена в репозитории %s. Доступные версии покажет package_info или release_notes.", version, name, repositoryURL),
+			}},
+			IsError: true,
+		}, nil
+	}
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("📦 %s %s\n\n", name, info.Version))
+	if info.Description != "" {
+		output.WriteString(fmt.Sprintf("Описание: %s\n", info.Description))
+	}
+	output.WriteString(fmt.Sprintf("Размер: %s\n", formatSize(info.Size)))
+	if info.Checksum != "" {
+		output.WriteString(fmt.Sprintf("Контрольная сумма: %s\n", info.Checksum))
+	}
+	if !info.Uploaded.IsZero() {
+		output.WriteString(fmt.Sprintf("Загружена: %s\n", info.Uploaded.Format("2006-01-02 15:04:05")))
+	}
+	output.WriteString(fmt.Sprintf("Загрузок: %d\n", info.Downloads))
+
+	writeDeps := func(title string, deps map[string]string) {
+		if len(deps) == 0 {
+			return
+		}
+		output.WriteString(fmt.Sprintf("\n%s:\n", title))
+		for _, dep := range sortedKeys(deps) {
+			output.WriteString(fmt.Sprintf("  - %s %s\n", dep, normalizedConstraint(deps[dep])))
+		}
+	}
+	writeDeps("🔗 Зависимости", info.Dependencies)
+	writeDeps("🛠️ Зависимости разработки", info.DevDeps)
+
+	if len(info.Files) > 0 {
+		output.WriteString("\n💾 Файлы:\n")
+		for _, file := range info.Files {
+			output.WriteString(fmt.Sprintf("  - %s/%s: %s (%s", file.OS, file.Arch, file.Filename, formatSize(file.Size)))
+			if file.Format != "" {
+				output.WriteString(", " + file.Format)
+			}
+			output.WriteString(")\n")
+			if file.Checksum != "" {
+				output.WriteString(fmt.Sprintf("    %s\n", file.Checksum))
+			}
+		}
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: info,
+	}, nil
+}
+
+func (s *MCPServer) compareVersions(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	repositoryURL := getString(args, "repository_url", "")
+	if repositoryURL == "" {
+		return CallToolResult{}, fmt.Errorf("URL репозитория обязателен")
+	}
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
+	fromVersion := getString(args, "from_version", "")
+	toVersion := getString(args, "to_version", "")
+	if fromVersion == "" || toVersion == "" {
+		return CallToolResult{}, fmt.Errorf("обе версии для сравнения обязательны")
+	}
+
+	diff, err := s.packageManager.DiffVersions(ctx, repositoryURL, name, fromVersion, toVersion)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🔀 %s: %s → %s\n\n", diff.Name, diff.FromVersion, diff.ToVersion))
+
+	output.WriteString("🔗 Зависимости:\n")
+	if len(diff.AddedDependencies)+len(diff.RemovedDependencies)+len(diff.ChangedDependencies) == 0 {
+		output.WriteString("  без изменений\n")
+	}
+	for _, dep := range diff.AddedDependencies {
+		output.WriteString(fmt.Sprintf("  + %s %s\n", dep.Name, normalizedConstraint(dep.To)))
+	}
+	for _, dep := range diff.RemovedDependencies {
+		output.WriteString(fmt.Sprintf("  - %s %s\n", dep.Name, normalizedConstraint(dep.From)))
+	}
+	for _, dep := range diff.ChangedDependencies {
+		output.WriteString(fmt.Sprintf("  ~ %s %s → %s\n", dep.Name, normalizedConstraint(dep.From), normalizedConstraint(dep.To)))
+	}
+
+	sign := "+"
+	delta := diff.SizeDelta
+	if delta < 0 {
+		sign = "-"
+		delta = -delta
+	}
+	output.WriteString(fmt.Sprintf("\n📏 Размер: %s → %s (%s%s)\n", formatSize(diff.FromSize), formatSize(diff.ToSize), sign, formatSize(delta)))
+
+	output.WriteString("\n💾 Файлы платформ:\n")
+	if len(diff.AddedFiles)+len(diff.RemovedFiles)+len(diff.ChangedFiles) == 0 {
+		output.WriteString("  без изменений\n")
+	}
+	for _, file := range diff.AddedFiles {
+		output.WriteString(fmt.Sprintf("  + %s: %s\n", file.Platform, file.To.Filename))
+	}
+	for _, file := range diff.RemovedFiles {
+		output.WriteString(fmt.Sprintf("  - %s: %s\n", file.Platform, file.From.Filename))
+	}
+	for _, file := range diff.ChangedFiles {
+		output.WriteString(fmt.Sprintf("  ~ %s: %s (%s) → %s (%s)\n", file.Platform, file.From.Filename, formatSize(file.From.Size), file.To.Filename, formatSize(file.To.Size)))
+	}
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: diff,
+	}, nil
 }
 
-func (s *MCPServer) installPackage(args map[string]interface{}) (CallToolResult, error) {
-	name := getString(args, "name", "")
-	if name == "" {
-		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+func (s *MCPServer) installFromFile(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	path := getString(args, "path", "")
+	if path == "" {
+		return CallToolResult{}, fmt.Errorf("путь к архиву обязателен")
 	}
 
-	version := getString(args, "version", "")
 	global := getBool(args, "global", false)
 	force := getBool(args, "force", false)
-	arch := getString(args, "arch", "")
-	osName := getString(args, "os", "")
 
-	err := s.packageManager.InstallPackage(name, version, global, force, false, arch, osName)
+	info, verified, err := s.packageManager.InstallFromFile(ctx, path, global, force)
 	if err != nil {
 		return CallToolResult{}, err
 	}
 
+	locale := s.locale()
+	text := translate(locale, msgInstalledFrom, info.Name, info.Version, path)
+	if verified {
+		text += translate(locale, msgArchiveVerified)
+	} else {
+		text += translate(locale, msgArchiveUnverified)
+	}
+
 	return CallToolResult{
 		Content: []ContentItem{{
 			Type: "text",
-			Text: fmt.Sprintf("Пакет %s успешно установлен", name),
+			Text: text + formatSkippedSymlinks(locale, info.SkippedSymlinks) + formatPreservedConfig(locale, info),
 		}},
+		StructuredContent: map[string]interface{}{"package": info, "verified": verified},
 	}, nil
 }
 
-func (s *MCPServer) uninstallPackage(args map[string]interface{}) (CallToolResult, error) {
+func (s *MCPServer) dependencyTree(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
 	name := getString(args, "name", "")
 	if name == "" {
 		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
 	}
 
-	global := getBool(args, "global", false)
-	purge := getBool(args, "purge", false)
-
-	err := s.packageManager.UninstallPackage(name, global, purge)
+	tree, err := s.packageManager.DependencyTree(ctx, name)
 	if err != nil {
 		return CallToolResult{}, err
 	}
@@ -538,30 +4000,42 @@ func (s *MCPServer) uninstallPackage(args map[string]interface{}) (CallToolResul
 	return CallToolResult{
 		Content: []ContentItem{{
 			Type: "text",
-			Text: fmt.Sprintf("Пакет %s успешно удален", name),
+			Text: "🌳 Дерево зависимостей\n\n" + renderDependencyTree(tree),
 		}},
+		StructuredContent: tree,
 	}, nil
 }
 
-func (s *MCPServer) searchPackages(args map[string]interface{}) (CallToolResult, error) {
-	query := getString(args, "query", "")
-	if query == "" {
-		return CallToolResult{}, fmt.Errorf("поисковый запрос обязателен")
+func (s *MCPServer) whyInstalled(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
 	}
 
-	results, err := s.packageManager.SearchPackages(query)
+	result, err := s.packageManager.WhyInstalled(name)
 	if err != nil {
 		return CallToolResult{}, err
 	}
 
 	var output strings.Builder
-	output.WriteString(fmt.Sprintf("Найдено пакетов: %d\n\n", len(results)))
+	output.WriteString(fmt.Sprintf("📦 %s@%s\n", result.Name, result.Version))
+	if result.Automatic {
+		output.WriteString("Установлен автоматически как зависимость\n")
+	} else {
+		output.WriteString("Установлен пользователем\n")
+	}
 
-	for _, result := range results {
-		output.WriteString(fmt.Sprintf("📦 %s (%s)\n", result.Name, result.Version))
-		output.WriteString(fmt.Sprintf("   Описание: %s\n", result.Description))
-		output.WriteString(fmt.Sprintf("   Автор: %s\n", result.Author))
-		output.WriteString(fmt.Sprintf("   Загрузок: %d\n\n", result.Downloads))
+	if len(result.Dependents) == 0 {
+		output.WriteString("\nОт пакета не зависит ни один установленный пакет\n")
+	} else {
+		output.WriteString(fmt.Sprintf("\nЗависят от пакета (%d):\n", len(result.Dependents)))
+		for _, dep := range result.Dependents {
+			output.WriteString(fmt.Sprintf("  - %s@%s требует %s", dep.Name, dep.Version, dep.Constraint))
+			if !dep.Satisfied {
+				output.WriteString(" ⚠️ не удовлетворено")
+			}
+			output.WriteString("\n")
+		}
 	}
 
 	return CallToolResult{
@@ -569,26 +4043,29 @@ func (s *MCPServer) searchPackages(args map[string]interface{}) (CallToolResult,
 			Type: "text",
 			Text: output.String(),
 		}},
+		StructuredContent: result,
 	}, nil
 }
 
-func (s *MCPServer) listPackages(args map[string]interface{}) (CallToolResult, error) {
-	global := getBool(args, "global", false)
-	outdated := getBool(args, "outdated", false)
+func (s *MCPServer) autoremove(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	dryRun := getBool(args, "dry_run", false)
 
-	packages, err := s.packageManager.ListPackages(global, outdated)
+	removed, err := s.packageManager.Autoremove(dryRun)
 	if err != nil {
 		return CallToolResult{}, err
 	}
 
 	var output strings.Builder
-	output.WriteString(fmt.Sprintf("Установленных пакетов: %d\n\n", len(packages)))
-
-	for _, pkg := range packages {
-		output.WriteString(fmt.Sprintf("📦 %s (%s)\n", pkg.Name, pkg.Version))
-		output.WriteString(fmt.Sprintf("   Путь: %s\n", pkg.InstallPath))
-		output.WriteString(fmt.Sprintf("   Размер: %s\n", formatSize(pkg.Size)))
-		output.WriteString(fmt.Sprintf("   Дата установки: %s\n\n", pkg.InstallDate.Format("2006-01-02 15:04:05")))
+	switch {
+	case len(removed) == 0:
+		output.WriteString("✅ Ненужных зависимостей нет\n")
+	case dryRun:
+		output.WriteString(fmt.Sprintf("🔍 Будут удалены ненужные зависимости (%d):\n", len(removed)))
+	default:
+		output.WriteString(fmt.Sprintf("🗑️ Удалены ненужные зависимости (%d):\n", len(removed)))
+	}
+	for _, pkg := range removed {
+		output.WriteString(fmt.Sprintf("  - %s@%s\n", pkg.Name, pkg.Version))
 	}
 
 	return CallToolResult{
@@ -596,162 +4073,253 @@ func (s *MCPServer) listPackages(args map[string]interface{}) (CallToolResult, e
 			Type: "text",
 			Text: output.String(),
 		}},
+		StructuredContent: map[string]interface{}{"packages": removed, "dry_run": dryRun},
 	}, nil
 }
 
-func (s *MCPServer) packageInfo(args map[string]interface{}) (CallToolResult, error) {
-	name := getString(args, "name", "")
-	if name == "" {
-		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+func (s *MCPServer) configGet(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	if key := getString(args, "key", ""); key != "" {
+		value, err := s.packageManager.ConfigValue(key)
+		if err != nil {
+			return CallToolResult{}, err
+		}
+		data, _ := json.Marshal(value)
+
+		return CallToolResult{
+			Content: []ContentItem{{
+				Type: "text",
+				Text: fmt.Sprintf("⚙️ %s = %s\n", key, data),
+			}},
+			StructuredContent: map[string]interface{}{"key": key, "value": value},
+		}, nil
 	}
 
-	info, err := s.packageManager.GetPackageInfo(name)
+	config := s.packageManager.ConfigSnapshot()
+	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return CallToolResult{}, err
 	}
 
-	var output strings.Builder
-	output.WriteString(fmt.Sprintf("📦 Информация о пакете: %s\n\n", info.Name))
-	output.WriteString(fmt.Sprintf("Версия: %s\n", info.Version))
-	output.WriteString(fmt.Sprintf("Описание: %s\n", info.Description))
-	output.WriteString(fmt.Sprintf("Автор: %s\n", info.Author))
-	output.WriteString(fmt.Sprintf("Размер: %s\n", formatSize(info.Size)))
-	output.WriteString(fmt.Sprintf("Путь установки: %s\n", info.InstallPath))
-	output.WriteString(fmt.Sprintf("Дата установки: %s\n", info.InstallDate.Format("2006-01-02 15:04:05")))
-
-	if len(info.Dependencies) > 0 {
-		output.WriteString("\nЗависимости:\n")
-		for name, version := range info.Dependencies {
-			output.WriteString(fmt.Sprintf("  - %s: %s\n", name, version))
-		}
-	}
-
 	return CallToolResult{
 		Content: []ContentItem{{
 			Type: "text",
-			Text: output.String(),
+			Text: fmt.Sprintf("⚙️ Конфигурация:\n\n%s\n", data),
 		}},
+		StructuredContent: config,
 	}, nil
 }
 
-func (s *MCPServer) updatePackage(args map[string]interface{}) (CallToolResult, error) {
-	name := getString(args, "name", "")
-	if name == "" {
-		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+func (s *MCPServer) configSet(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	key := getString(args, "key", "")
+	if key == "" {
+		return CallToolResult{}, fmt.Errorf("ключ параметра обязателен")
+	}
+	value, ok := args["value"]
+	if !ok {
+		return CallToolResult{}, fmt.Errorf("значение параметра обязательно")
 	}
 
-	err := s.packageManager.UpdatePackage(name)
+	change, err := s.packageManager.SetConfigValue(key, value)
 	if err != nil {
 		return CallToolResult{}, err
 	}
 
+	previous, _ := json.Marshal(change.Previous)
+	updated, _ := json.Marshal(change.Value)
+
 	return CallToolResult{
 		Content: []ContentItem{{
 			Type: "text",
-			Text: fmt.Sprintf("Пакет %s успешно обновлен", name),
+			Text: fmt.Sprintf("⚙️ %s: %s -> %s\n", change.Key, previous, updated),
 		}},
+		StructuredContent: change,
 	}, nil
 }
 
-func (s *MCPServer) createPackage(args map[string]interface{}) (CallToolResult, error) {
-	name := getString(args, "name", "")
-	if name == "" {
-		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+// rankedCount имя с числом пакетов для вывода рейтингов
+type rankedCount struct {
+	Name  string
+	Count int
+}
+
+// rankByCount упорядочивает счетчики по убыванию, при равенстве — по имени
+func rankByCount(counts map[string]int) []rankedCount {
+	ranked := make([]rankedCount, 0, len(counts))
+	for name, count := range counts {
+		ranked = append(ranked, rankedCount{Name: name, Count: count})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	return ranked
+}
+
+// writeTopN выводит первые n строк нумерованным списком
+func writeTopN(output *strings.Builder, lines []string, n int) {
+	for i, line := range lines[:min(n, len(lines))] {
+		output.WriteString(fmt.Sprintf("   %d. %s\n", i+1, line))
 	}
+}
 
-	template := getString(args, "template", "basic")
-	author := getString(args, "author", "")
-	description := getString(args, "description", "")
+func (s *MCPServer) ping(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	report := s.packageManager.Ping(ctx)
+	uptime := time.Since(processStarted).Round(time.Second)
 
-	err := s.packageManager.CreatePackage(name, template, author, description)
-	if err != nil {
-		return CallToolResult{}, err
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🏓 %s %s, работает %s\n\n", ServerName, ServerVersion, uptime))
+
+	output.WriteString("Репозитории:\n")
+	if len(report.Repositories) == 0 {
+		output.WriteString("  нет включенных репозиториев\n")
+	}
+	for _, repo := range report.Repositories {
+		if repo.Reachable {
+			output.WriteString(fmt.Sprintf("  ✅ %s (%s): %d, %.1f мс\n", repo.Name, repo.URL, repo.StatusCode, repo.LatencyMs))
+		} else {
+			output.WriteString(fmt.Sprintf("  ❌ %s (%s): %s\n", repo.Name, repo.URL, repo.Error))
+		}
+	}
+
+	output.WriteString("\nКаталоги:\n")
+	for _, path := range report.Paths {
+		if path.Writable {
+			output.WriteString(fmt.Sprintf("  ✅ %s: %s\n", path.Name, path.Path))
+		} else {
+			output.WriteString(fmt.Sprintf("  ❌ %s: %s — %s\n", path.Name, path.Path, path.Error))
+		}
 	}
 
 	return CallToolResult{
 		Content: []ContentItem{{
 			Type: "text",
-			Text: fmt.Sprintf("Пакет %s успешно создан", name),
+			Text: output.String(),
 		}},
+		StructuredContent: map[string]interface{}{
+			"server":         ServerName,
+			"version":        ServerVersion,
+			"uptime_seconds": int64(uptime.Seconds()),
+			"repositories":   report.Repositories,
+			"paths":          report.Paths,
+		},
 	}, nil
 }
 
-func (s *MCPServer) buildPackage(args map[string]interface{}) (CallToolResult, error) {
-	outputPath := getString(args, "output_path", "")
-	format := getString(args, "format", "criage")
-	compressionLevel := getInt(args, "compression_level", 3)
+// describeServer собирает описание возможностей сервера
+func (s *MCPServer) describeServer() *ServerDescription {
+	s.clientMutex.RLock()
+	protocolVersion := s.protocolVersion
+	s.clientMutex.RUnlock()
 
-	err := s.packageManager.BuildPackage(outputPath, format, compressionLevel)
-	if err != nil {
-		return CallToolResult{}, err
+	description := &ServerDescription{
+		Name:                      ServerName,
+		Version:                   ServerVersion,
+		ProtocolVersion:           protocolVersion,
+		SupportedProtocolVersions: supportedProtocolVersions,
+		ArchiveFormats:            supportedFormats,
+		Compression:               compressionCodecs,
+		Repositories:              []ServerRepository{},
+	}
+	for _, repo := range s.packageManager.ListRepositories() {
+		if repo.Enabled {
+			description.Repositories = append(description.Repositories, ServerRepository{Name: repo.Name, URL: repo.URL, Priority: repo.Priority})
+		}
+	}
+	return description
+}
+
+func (s *MCPServer) serverInfo(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	description := s.describeServer()
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("🧭 %s %s\n\n", description.Name, description.Version))
+	if description.ProtocolVersion != "" {
+		output.WriteString(fmt.Sprintf("Версия протокола: %s\n", description.ProtocolVersion))
+	} else {
+		output.WriteString("Версия протокола: не согласована (initialize не выполнен)\n")
+	}
+	output.WriteString(fmt.Sprintf("Поддерживаемые версии протокола: %s\n", strings.Join(description.SupportedProtocolVersions, ", ")))
+	output.WriteString(fmt.Sprintf("Форматы архивов: %s\n", strings.Join(description.ArchiveFormats, ", ")))
+
+	output.WriteString("\nСжатие:\n")
+	for _, codec := range description.Compression {
+		output.WriteString(fmt.Sprintf("  %s (%s): уровни %d-%d, по умолчанию %d\n", codec.Name, strings.Join(codec.Formats, ", "), codec.MinLevel, codec.MaxLevel, codec.DefaultLevel))
+	}
+
+	output.WriteString("\nВключенные репозитории:\n")
+	if len(description.Repositories) == 0 {
+		output.WriteString("  нет\n")
+	}
+	for _, repo := range description.Repositories {
+		output.WriteString(fmt.Sprintf("  %s: %s (приоритет %d)\n", repo.Name, repo.URL, repo.Priority))
 	}
 
 	return CallToolResult{
 		Content: []ContentItem{{
 			Type: "text",
-			Text: "Пакет успешно собран",
+			Text: output.String(),
 		}},
+		StructuredContent: description,
 	}, nil
 }
 
-func (s *MCPServer) publishPackage(args map[string]interface{}) (CallToolResult, error) {
-	registryURL := getString(args, "registry_url", "")
-	token := getString(args, "token", "")
+func (s *MCPServer) restoreConfig(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
+	}
 
-	err := s.packageManager.PublishPackage(registryURL, token)
+	files, err := s.packageManager.RestoreConfig(name)
 	if err != nil {
 		return CallToolResult{}, err
 	}
 
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("♻️ Восстановлена конфигурация пакета %s (%d):\n", name, len(files)))
+	for _, file := range files {
+		output.WriteString(fmt.Sprintf("  %s\n", file))
+	}
+
 	return CallToolResult{
 		Content: []ContentItem{{
 			Type: "text",
-			Text: "Пакет успешно опубликован",
+			Text: output.String(),
 		}},
+		StructuredContent: map[string]interface{}{"name": name, "restored": files},
 	}, nil
 }
 
-func (s *MCPServer) repositoryInfo(args map[string]interface{}) (CallToolResult, error) {
-	url := getString(args, "url", "")
-	if url == "" {
-		return CallToolResult{}, fmt.Errorf("URL репозитория обязателен")
+func (s *MCPServer) runScript(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+	if name == "" {
+		return CallToolResult{}, fmt.Errorf("имя пакета обязательно")
 	}
+	script := getString(args, "script", "")
+	if script == "" {
+		return CallToolResult{}, fmt.Errorf("имя скрипта обязательно")
+	}
+	timeout := time.Duration(getInt(args, "timeout", 0)) * time.Second
 
-	info, err := s.packageManager.GetRepositoryInfo(url)
+	result, err := s.packageManager.RunScript(ctx, name, script, timeout)
 	if err != nil {
-		return CallToolResult{
-			Content: []ContentItem{{
-				Type: "text",
-				Text: fmt.Sprintf("❌ Ошибка получения информации о репозитории: %v", err),
-			}},
-			IsError: true,
-		}, nil
+		return CallToolResult{}, err
 	}
 
 	var output strings.Builder
-	output.WriteString(fmt.Sprintf("📊 Информация о репозитории: %s\n\n", url))
-
-	if name, ok := info["name"].(string); ok {
-		output.WriteString(fmt.Sprintf("Название: %s\n", name))
-	}
-	if version, ok := info["version"].(string); ok {
-		output.WriteString(fmt.Sprintf("Версия: %s\n", version))
-	}
-	if lastUpdated, ok := info["last_updated"].(string); ok {
-		output.WriteString(fmt.Sprintf("Последнее обновление: %s\n", lastUpdated))
-	}
-	if totalPackages, ok := info["total_packages"].(float64); ok {
-		output.WriteString(fmt.Sprintf("Всего пакетов: %.0f\n", totalPackages))
+	switch {
+	case result.TimedOut:
+		output.WriteString(fmt.Sprintf("⏱️ Скрипт %s пакета %s прерван: превышено время выполнения\n", script, name))
+	case result.ExitCode != 0:
+		output.WriteString(fmt.Sprintf("❌ Скрипт %s пакета %s завершился с кодом %d\n", script, name, result.ExitCode))
+	default:
+		output.WriteString(fmt.Sprintf("✅ Скрипт %s пакета %s выполнен за %d мс\n", script, name, result.DurationMs))
 	}
-	if formats, ok := info["formats"].([]interface{}); ok {
-		output.WriteString("Поддерживаемые форматы: ")
-		for i, format := range formats {
-			if i > 0 {
-				output.WriteString(", ")
-			}
-			output.WriteString(fmt.Sprintf("%v", format))
-		}
-		output.WriteString("\n")
+	output.WriteString(fmt.Sprintf("$ %s\n\n", result.Command))
+	output.WriteString(result.Output)
+	if result.Truncated {
+		output.WriteString(fmt.Sprintf("\n⚠️ Вывод обрезан до %s\n", formatSize(maxScriptOutput)))
 	}
 
 	return CallToolResult{
@@ -759,122 +4327,130 @@ func (s *MCPServer) repositoryInfo(args map[string]interface{}) (CallToolResult,
 			Type: "text",
 			Text: output.String(),
 		}},
+		StructuredContent: result,
+		IsError:           result.TimedOut || result.ExitCode != 0,
 	}, nil
 }
 
-func formatSize(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+func (s *MCPServer) exportState(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	snapshot, err := s.packageManager.ExportState()
+	if err != nil {
+		return CallToolResult{}, err
 	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return CallToolResult{}, err
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: fmt.Sprintf("📸 Снимок состояния: %d пакетов\n\n%s\n", len(snapshot.Packages), data),
+		}},
+		StructuredContent: snapshot,
+	}, nil
 }
 
-// refreshRepositoryIndex принудительно обновляет индекс пакетов в репозитории
-func (s *MCPServer) refreshRepositoryIndex(args map[string]interface{}) (CallToolResult, error) {
-	repositoryURL := getString(args, "repository_url", "")
-	if repositoryURL == "" {
-		return CallToolResult{}, fmt.Errorf("URL репозитория обязателен")
+func (s *MCPServer) importState(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	raw, ok := args["state"]
+	if !ok {
+		return CallToolResult{}, fmt.Errorf("снимок состояния обязателен")
 	}
 
-	authToken := getString(args, "auth_token", "")
-	if authToken == "" {
-		return CallToolResult{}, fmt.Errorf("токен авторизации обязателен")
+	// Снимок принимается объектом или JSON-строкой
+	data, isString := raw.(string)
+	if !isString {
+		encoded, err := json.Marshal(raw)
+		if err != nil {
+			return CallToolResult{}, fmt.Errorf("некорректный снимок состояния: %w", err)
+		}
+		data = string(encoded)
 	}
 
-	err := s.packageManager.RefreshRepositoryIndex(repositoryURL, authToken)
+	var snapshot StateSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return CallToolResult{}, fmt.Errorf("некорректный снимок состояния: %w", err)
+	}
+
+	result, err := s.packageManager.ImportState(&snapshot)
 	if err != nil {
-		return CallToolResult{
-			Content: []ContentItem{{
-				Type: "text",
-				Text: fmt.Sprintf("❌ Ошибка обновления индекса репозитория: %v", err),
-			}},
-			IsError: true,
-		}, nil
+		return CallToolResult{}, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("📥 Состояние восстановлено: глобальных пакетов %d, локальных %d\n", result.Global, result.Local))
+	if len(result.Missing) > 0 {
+		output.WriteString(fmt.Sprintf("\n⚠️ Каталоги установки не найдены (файлы не восстанавливаются): %s\n", strings.Join(result.Missing, ", ")))
 	}
 
 	return CallToolResult{
 		Content: []ContentItem{{
 			Type: "text",
-			Text: fmt.Sprintf("✅ Индекс репозитория %s успешно обновлен", repositoryURL),
+			Text: output.String(),
 		}},
+		StructuredContent: result,
 	}, nil
 }
 
-// getRepositoryStats получает детальную статистику репозитория
-func (s *MCPServer) getRepositoryStats(args map[string]interface{}) (CallToolResult, error) {
-	repositoryURL := getString(args, "repository_url", "")
-	if repositoryURL == "" {
-		return CallToolResult{}, fmt.Errorf("URL репозитория обязателен")
-	}
-
-	stats, err := s.packageManager.GetRepositoryStats(repositoryURL)
-	if err != nil {
-		return CallToolResult{
-			Content: []ContentItem{{
-				Type: "text",
-				Text: fmt.Sprintf("❌ Ошибка получения статистики репозитория: %v", err),
-			}},
-			IsError: true,
-		}, nil
-	}
+func (s *MCPServer) metrics(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	metrics := s.packageManager.Metrics()
 
 	var output strings.Builder
-	output.WriteString(fmt.Sprintf("📊 Статистика репозитория: %s\n\n", repositoryURL))
-	output.WriteString(fmt.Sprintf("📦 Всего пакетов: %d\n", stats.TotalPackages))
-	output.WriteString(fmt.Sprintf("⬇️ Всего загрузок: %d\n", stats.TotalDownloads))
-	output.WriteString(fmt.Sprintf("🕒 Последнее обновление: %s\n\n", stats.LastUpdated.Format("2006-01-02 15:04:05")))
+	output.WriteString(fmt.Sprintf("📊 Установки с %s\n\n", metrics.Since.Format("2006-01-02 15:04:05")))
+	output.WriteString(fmt.Sprintf("Установлено пакетов: %d\n", metrics.Installs))
+	output.WriteString(fmt.Sprintf("Неудачных установок: %d\n", metrics.Failures))
+	output.WriteString(fmt.Sprintf("Скачано: %s\n", formatSize(metrics.BytesDownloaded)))
+	output.WriteString(fmt.Sprintf("Среднее время установки: %.0f мс\n", metrics.AverageInstallMs))
 
-	if len(stats.PopularPackages) > 0 {
-		output.WriteString("🔥 Популярные пакеты:\n")
-		for i, pkg := range stats.PopularPackages {
-			if i >= 10 { // Показываем только топ-10
-				break
-			}
-			output.WriteString(fmt.Sprintf("   %d. %s\n", i+1, pkg))
+	return CallToolResult{
+		Content: []ContentItem{{
+			Type: "text",
+			Text: output.String(),
+		}},
+		StructuredContent: metrics,
+	}, nil
+}
+
+func (s *MCPServer) syncRepository(ctx context.Context, args map[string]interface{}) (CallToolResult, error) {
+	name := getString(args, "name", "")
+
+	var repositories []Repository
+	for _, repo := range s.packageManager.ListRepositories() {
+		if name == "" && repo.Enabled || name != "" && strings.EqualFold(repo.Name, name) {
+			repositories = append(repositories, repo)
 		}
-		output.WriteString("\n")
 	}
-
-	if len(stats.PackagesByLicense) > 0 {
-		output.WriteString("📜 Распределение по лицензиям:\n")
-		for license, count := range stats.PackagesByLicense {
-			output.WriteString(fmt.Sprintf("   • %s: %d пакетов\n", license, count))
+	if len(repositories) == 0 {
+		if name != "" {
+			return CallToolResult{}, fmt.Errorf("репозиторий %s не найден", name)
 		}
-		output.WriteString("\n")
+		return CallToolResult{}, fmt.Errorf("нет включенных репозиториев")
 	}
 
-	if len(stats.PackagesByAuthor) > 0 {
-		output.WriteString("👥 Топ авторы:\n")
-		// Преобразуем в слайс для сортировки
-		type authorStat struct {
-			name  string
-			count int
-		}
-		var authors []authorStat
-		for author, count := range stats.PackagesByAuthor {
-			authors = append(authors, authorStat{author, count})
-		}
-		// Сортируем по количеству пакетов
-		for i := 0; i < len(authors)-1; i++ {
-			for j := i + 1; j < len(authors); j++ {
-				if authors[i].count < authors[j].count {
-					authors[i], authors[j] = authors[j], authors[i]
-				}
-			}
-		}
-		// Показываем топ-5 авторов
-		for i, author := range authors {
-			if i >= 5 {
-				break
+	var output strings.Builder
+	output.WriteString("🔄 Синхронизация индексов репозиториев\n\n")
+
+	results := make([]map[string]interface{}, 0, len(repositories))
+	failed := 0
+	for _, repo := range repositories {
+		result, err := s.packageManager.SyncRepositoryIndex(ctx, repo)
+		if err != nil {
+			if ctx.Err() != nil {
+				return CallToolResult{}, ctx.Err()
 			}
-			output.WriteString(fmt.Sprintf("   %d. %s: %d пакетов\n", i+1, author.name, author.count))
+			failed++
+			output.WriteString(fmt.Sprintf("❌ %s: %v\n", repo.Name, err))
+			results = append(results, map[string]interface{}{"repository": repo.Name, "url": repo.URL, "error": err.Error()})
+			continue
 		}
+		output.WriteString(fmt.Sprintf("✅ %s: пакетов %d\n", result.Repository, result.Packages))
+		results = append(results, map[string]interface{}{
+			"repository": result.Repository,
+			"url":        result.URL,
+			"packages":   result.Packages,
+			"synced_at":  result.SyncedAt,
+		})
 	}
 
 	return CallToolResult{
@@ -882,5 +4458,7 @@ func (s *MCPServer) getRepositoryStats(args map[string]interface{}) (CallToolRes
 			Type: "text",
 			Text: output.String(),
 		}},
+		StructuredContent: map[string]interface{}{"repositories": results},
+		IsError:           failed == len(repositories),
 	}, nil
 }
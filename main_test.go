@@ -0,0 +1,1059 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer создает MCP сервер поверх указанного пакетного менеджера
+func newTestServer(t *testing.T, pm *PackageManager) *MCPServer {
+	t.Helper()
+
+	return &MCPServer{
+		packageManager: pm,
+		activeCalls:    make(map[string]context.CancelFunc),
+	}
+}
+
+// testSession сеанс обмена сообщениями с сервером через каналы в памяти
+type testSession struct {
+	t       *testing.T
+	encoder *json.Encoder
+	decoder *json.Decoder
+	done    chan error
+}
+
+// startTestSession запускает Serve в горутине и возвращает сеанс для обмена сообщениями
+func startTestSession(t *testing.T, s *MCPServer) *testSession {
+	t.Helper()
+	return startTestSessionContext(t, context.Background(), s)
+}
+
+// startTestSessionContext запускает ServeContext с ctx; отмена ctx имитирует сигнал завершения
+func startTestSessionContext(t *testing.T, ctx context.Context, s *MCPServer) *testSession {
+	t.Helper()
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+
+	session := &testSession{
+		t:       t,
+		encoder: json.NewEncoder(inWriter),
+		decoder: json.NewDecoder(outReader),
+		done:    make(chan error, 1),
+	}
+
+	go func() {
+		session.done <- s.ServeContext(ctx, inReader, outWriter)
+		outWriter.Close()
+	}()
+
+	t.Cleanup(func() {
+		inWriter.Close()
+		go io.Copy(io.Discard, outReader)
+		<-session.done
+	})
+
+	return session
+}
+
+// send отправляет сообщение серверу
+func (ts *testSession) send(message interface{}) {
+	ts.t.Helper()
+
+	if err := ts.encoder.Encode(message); err != nil {
+		ts.t.Fatalf("Failed to send message: %v", err)
+	}
+}
+
+// receiveRaw читает очередное значение JSON из потока сервера, ожидая не дольше timeout
+func (ts *testSession) receiveRaw(timeout time.Duration) json.RawMessage {
+	ts.t.Helper()
+
+	result := make(chan json.RawMessage, 1)
+	errs := make(chan error, 1)
+	go func() {
+		var raw json.RawMessage
+		if err := ts.decoder.Decode(&raw); err != nil {
+			errs <- err
+			return
+		}
+		result <- raw
+	}()
+
+	select {
+	case raw := <-result:
+		return raw
+	case err := <-errs:
+		ts.t.Fatalf("Failed to receive message: %v", err)
+	case <-time.After(timeout):
+		ts.t.Fatalf("No message received within %v", timeout)
+	}
+	return nil
+}
+
+// receive читает очередное сообщение сервера, ожидая не дольше timeout
+func (ts *testSession) receive(timeout time.Duration) MCPMessage {
+	ts.t.Helper()
+
+	var message MCPMessage
+	if err := json.Unmarshal(ts.receiveRaw(timeout), &message); err != nil {
+		ts.t.Fatalf("Failed to decode message: %v", err)
+	}
+	return message
+}
+
+// TestCancelInFlightToolCall проверяет отмену выполняющегося вызова уведомлением notifications/cancelled
+func TestCancelInFlightToolCall(t *testing.T) {
+	started := make(chan struct{}, 1)
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Медленный репозиторий: отвечает только после отмены запроса клиентом
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer repo.Close()
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{{Name: "slow", URL: repo.URL, Enabled: true}}
+	pm.getConfig().Timeout = 60
+
+	session := startTestSession(t, newTestServer(t, pm))
+	session.send(MCPMessage{
+		JSONRPC: "2.0",
+		ID:      7,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "install_package",
+			"arguments": map[string]interface{}{"name": "slow-package"},
+		},
+	})
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Tool call did not reach the repository")
+	}
+
+	// Цикл сервера должен оставаться отзывчивым во время выполнения вызова
+	session.send(MCPMessage{JSONRPC: "2.0", ID: 8, Method: "tools/list"})
+	if response := session.receive(2 * time.Second); requestKey(response.ID) != "8" {
+		t.Fatalf("Expected tools/list response while call is in flight, got id %v", response.ID)
+	}
+
+	start := time.Now()
+	session.send(MCPMessage{
+		JSONRPC: "2.0",
+		Method:  "notifications/cancelled",
+		Params:  map[string]interface{}{"requestId": 7, "reason": "test"},
+	})
+
+	response := session.receive(2 * time.Second)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Cancelled call took too long to return: %v", elapsed)
+	}
+
+	if requestKey(response.ID) != "7" {
+		t.Fatalf("Expected response for request 7, got %v", response.ID)
+	}
+	if response.Error == nil || response.Error.Code != -32800 {
+		t.Fatalf("Expected cancellation error, got %+v", response)
+	}
+}
+
+// TestCancelUnknownRequestIgnored проверяет, что отмена неизвестного запроса не порождает ответ
+func TestCancelUnknownRequestIgnored(t *testing.T) {
+	s := newTestServer(t, newTestPackageManager(t))
+
+	response := s.handleMessage(context.Background(), MCPMessage{
+		JSONRPC: "2.0",
+		Method:  "notifications/cancelled",
+		Params:  map[string]interface{}{"requestId": 42},
+	})
+	if response != nil {
+		t.Errorf("Cancellation notification should not produce a response, got %+v", response)
+	}
+}
+
+// initializeSession выполняет initialize с указанными возможностями клиента
+func initializeSession(session *testSession, capabilities map[string]interface{}) {
+	session.t.Helper()
+
+	session.send(MCPMessage{
+		JSONRPC: "2.0",
+		ID:      0,
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"protocolVersion": MCPVersion,
+			"clientInfo":      map[string]interface{}{"name": "test-client", "version": "1.0.0"},
+			"capabilities":    capabilities,
+		},
+	})
+	if response := session.receive(5 * time.Second); response.Error != nil {
+		session.t.Fatalf("initialize failed: %+v", response.Error)
+	}
+}
+
+// installWithProgress устанавливает крупный пакет, передавая progressToken, если он не nil,
+// и возвращает полученные уведомления о прогрессе
+func installWithProgress(t *testing.T, progressToken interface{}) []ProgressParams {
+	t.Helper()
+
+	pm := newTestPackageManager(t)
+
+	// Несжимаемое содержимое, чтобы архив занимал несколько шагов прогресса
+	payload := make([]byte, 4*progressStep)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatalf("Failed to generate payload: %v", err)
+	}
+	manifest := PackageManifest{Name: "big", Version: "1.0.0"}
+	archivePath := buildTestArchive(t, pm, manifest, map[string]string{"data.bin": string(payload)}, FormatTarGz)
+	_, archiveURL := serveFile(t, archivePath)
+
+	session := startTestSession(t, newTestServer(t, pm))
+	initializeSession(session, map[string]interface{}{})
+	params := map[string]interface{}{
+		"name":      "install_from_url",
+		"arguments": map[string]interface{}{"url": archiveURL},
+	}
+	if progressToken != nil {
+		params["_meta"] = map[string]interface{}{"progressToken": progressToken}
+	}
+	session.send(MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params:  params,
+	})
+
+	var notifications []ProgressParams
+	for {
+		message := session.receive(5 * time.Second)
+		if message.ID != nil {
+			if message.Error != nil {
+				t.Fatalf("Tool call failed: %+v", message.Error)
+			}
+			break
+		}
+		if message.Method != "notifications/progress" {
+			t.Fatalf("Unexpected notification: %s", message.Method)
+		}
+
+		var params ProgressParams
+		data, _ := json.Marshal(message.Params)
+		if err := json.Unmarshal(data, &params); err != nil {
+			t.Fatalf("Failed to decode progress params: %v", err)
+		}
+		notifications = append(notifications, params)
+	}
+
+	return notifications
+}
+
+// TestProgressNotifications проверяет отправку notifications/progress при скачивании и извлечении
+func TestProgressNotifications(t *testing.T) {
+	notifications := installWithProgress(t, "install-1")
+
+	if len(notifications) < 2 {
+		t.Fatalf("Expected several progress notifications, got %d", len(notifications))
+	}
+
+	phases := make(map[string]bool)
+	var last int64
+	for _, n := range notifications {
+		if n.ProgressToken != "install-1" {
+			t.Errorf("Unexpected progress token: %v", n.ProgressToken)
+		}
+		if n.Progress <= last {
+			t.Errorf("Progress must increase: %d after %d", n.Progress, last)
+		}
+		if n.Total != 0 && n.Progress > n.Total {
+			t.Errorf("Progress %d exceeds total %d", n.Progress, n.Total)
+		}
+		last = n.Progress
+		phases[progressPhase(n.Message)] = true
+		if strings.HasPrefix(n.Message, downloadPhase+": ") && !strings.Contains(n.Message, "/с") {
+			t.Errorf("Expected download rate in message, got %q", n.Message)
+		}
+	}
+
+	if !phases["Скачивание"] || !phases["Извлечение"] {
+		t.Errorf("Expected download and extraction phases, got %v", phases)
+	}
+}
+
+// TestProgressNotificationsRequireToken проверяет, что прогресс отправляется только
+// для вызовов, в _meta которых клиент передал progressToken
+func TestProgressNotificationsRequireToken(t *testing.T) {
+	if notifications := installWithProgress(t, nil); len(notifications) != 0 {
+		t.Errorf("Expected no progress notifications without progressToken, got %d", len(notifications))
+	}
+
+	notifications := installWithProgress(t, 7.0)
+	if len(notifications) == 0 {
+		t.Fatal("Expected progress notifications with numeric progressToken")
+	}
+	if notifications[0].ProgressToken != 7.0 {
+		t.Errorf("Unexpected progress token: %v", notifications[0].ProgressToken)
+	}
+}
+
+// TestLoggingNotifications проверяет, что сервер объявляет возможность logging и отправляет
+// notifications/message только после logging/setLevel и не ниже заданного уровня
+func TestLoggingNotifications(t *testing.T) {
+	session := startTestSession(t, newTestServer(t, newTestPackageManager(t)))
+	session.send(MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"protocolVersion": MCPVersion,
+			"clientInfo":      map[string]interface{}{"name": "test-client", "version": "1.0.0"},
+			"capabilities":    map[string]interface{}{},
+		},
+	})
+	response := session.receive(5 * time.Second)
+	capabilities, _ := response.Result.(map[string]interface{})["capabilities"].(map[string]interface{})
+	if _, ok := capabilities["logging"]; !ok {
+		t.Fatalf("Expected logging capability, got %v", capabilities)
+	}
+
+	// Уведомление пишется в поток вывода синхронно, поэтому журнал вызывается в отдельной горутине
+	logAsync := func(log func(string, ...interface{}), message string) <-chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			log(message)
+			close(done)
+		}()
+		return done
+	}
+	notForwarded := func(log func(string, ...interface{}), message string) {
+		t.Helper()
+		select {
+		case <-logAsync(log, message):
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Log message %q was sent to the client", message)
+		}
+	}
+
+	notForwarded(logger.Warnf, "before setLevel")
+
+	session.send(MCPMessage{JSONRPC: "2.0", ID: 3, Method: "logging/setLevel", Params: map[string]interface{}{"level": "verbose"}})
+	if response := session.receive(5 * time.Second); response.Error == nil || response.Error.Code != -32602 {
+		t.Fatalf("Expected invalid params for unknown level, got %+v", response)
+	}
+	session.send(MCPMessage{JSONRPC: "2.0", ID: 4, Method: "logging/setLevel", Params: map[string]interface{}{"level": "warning"}})
+	if response := session.receive(5 * time.Second); response.Error != nil {
+		t.Fatalf("logging/setLevel failed: %+v", response.Error)
+	}
+
+	notForwarded(logger.Infof, "below threshold")
+
+	done := logAsync(logger.Warnf, "Authorization: Bearer secret-value in warning")
+	message := session.receive(5 * time.Second)
+	<-done
+	if message.Method != "notifications/message" {
+		t.Fatalf("Expected notifications/message, got %+v", message)
+	}
+	params, _ := message.Params.(map[string]interface{})
+	if params["level"] != "warning" || params["logger"] != ServerName {
+		t.Errorf("Unexpected log notification params: %v", params)
+	}
+	if data, _ := params["data"].(string); !strings.Contains(data, "in warning") || strings.Contains(data, "secret-value") {
+		t.Errorf("Expected redacted log message, got %q", data)
+	}
+}
+
+// callToolText вызывает инструмент напрямую и возвращает текст результата
+func callToolText(t *testing.T, s *MCPServer, name string, args map[string]interface{}) (string, error) {
+	t.Helper()
+
+	result, err := s.callTool(context.Background(), name, args)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Content) == 0 {
+		t.Fatalf("Tool %s returned no content", name)
+	}
+
+	return result.Content[0].Text, nil
+}
+
+// TestReleaseNotes проверяет сборку описания изменений по нескольким версиям
+func TestReleaseNotes(t *testing.T) {
+	repo := newMockRepository(t, &RepositoryPackage{
+		Name: "lib",
+		Versions: []RepositoryVersion{
+			{Version: "2.0.0"},
+			{Version: "1.0.0", Changelog: "Первый выпуск"},
+			{Version: "1.10.0", Changelog: "Поддержка зеркал"},
+			{Version: "1.2.0", Description: "Исправления ошибок"},
+		},
+	})
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{repo.repository("main", 1)}
+	pm.installedPackages["lib"] = &PackageInfo{Name: "lib", Version: "1.0.0"}
+	s := newTestServer(t, pm)
+
+	text, err := callToolText(t, s, "release_notes", map[string]interface{}{"name": "lib"})
+	if err != nil {
+		t.Fatalf("release_notes failed: %v", err)
+	}
+
+	if !strings.Contains(text, "1.0.0 → 2.0.0") {
+		t.Errorf("Header should show installed and latest versions:\n%s", text)
+	}
+	if strings.Contains(text, "Первый выпуск") {
+		t.Errorf("Installed version notes should be excluded:\n%s", text)
+	}
+
+	i12 := strings.Index(text, "## 1.2.0")
+	i110 := strings.Index(text, "## 1.10.0")
+	i200 := strings.Index(text, "## 2.0.0")
+	if i12 < 0 || i110 < 0 || i200 < 0 || !(i12 < i110 && i110 < i200) {
+		t.Errorf("Versions should be listed in semantic order:\n%s", text)
+	}
+	if !strings.Contains(text, "Исправления ошибок") || !strings.Contains(text, "Поддержка зеркал") {
+		t.Errorf("Notes should fall back to description:\n%s", text)
+	}
+	if !strings.Contains(text, "Описание изменений отсутствует") {
+		t.Errorf("Missing changelog should be reported gracefully:\n%s", text)
+	}
+
+	text, err = callToolText(t, s, "release_notes", map[string]interface{}{"name": "lib", "from": "1.2.0", "to": "1.10.0"})
+	if err != nil {
+		t.Fatalf("release_notes with range failed: %v", err)
+	}
+	if strings.Contains(text, "## 1.2.0") || !strings.Contains(text, "## 1.10.0") || strings.Contains(text, "## 2.0.0") {
+		t.Errorf("Explicit range not respected:\n%s", text)
+	}
+}
+
+// TestVerifyPackageTool проверяет вердикт инструмента verify_package
+func TestVerifyPackageTool(t *testing.T) {
+	pm := newTestPackageManager(t)
+	info := installTestArchive(t, pm, PackageManifest{Name: "checked", Version: "1.0.0", Files: []string{"src/main.txt"}}, false)
+	s := newTestServer(t, pm)
+
+	result, err := s.callTool(context.Background(), "verify_package", map[string]interface{}{"name": "checked"})
+	if err != nil || result.IsError || !strings.Contains(result.Content[0].Text, "OK") {
+		t.Fatalf("Expected OK verdict, got %+v, %v", result, err)
+	}
+
+	os.RemoveAll(filepath.Join(info.InstallPath, "src"))
+
+	result, err = s.callTool(context.Background(), "verify_package", map[string]interface{}{"name": "checked"})
+	if err != nil {
+		t.Fatalf("verify_package failed: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "FAILED") || !strings.Contains(result.Content[0].Text, "src/main.txt") {
+		t.Errorf("Expected FAILED verdict listing missing file, got:\n%s", result.Content[0].Text)
+	}
+}
+
+// newDateServer поднимает сервер, возвращающий заголовок Date со смещением offset
+func newDateServer(t *testing.T, offset time.Duration) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// TestCheckTimeSync проверяет обнаружение расхождения часов с репозиторием
+func TestCheckTimeSync(t *testing.T) {
+	skewed := newDateServer(t, 2*time.Hour)
+	synced := newDateServer(t, 0)
+
+	pm := newTestPackageManager(t)
+	result, err := pm.CheckTimeSync(context.Background(), skewed.URL)
+	if err != nil {
+		t.Fatalf("CheckTimeSync failed: %v", err)
+	}
+	if diff := result.Skew - 2*time.Hour; diff < -2*time.Second || diff > 2*time.Second {
+		t.Errorf("Expected skew of about 2h, got %v", result.Skew)
+	}
+
+	pm.getConfig().Repositories = []Repository{
+		{Name: "skewed", URL: skewed.URL, Enabled: true},
+		{Name: "synced", URL: synced.URL, Enabled: true},
+	}
+	s := newTestServer(t, pm)
+
+	toolResult, err := s.callTool(context.Background(), "check_time_sync", map[string]interface{}{"threshold_seconds": float64(60)})
+	if err != nil {
+		t.Fatalf("check_time_sync failed: %v", err)
+	}
+	text := toolResult.Content[0].Text
+	if !toolResult.IsError || !strings.Contains(text, "⚠️ "+skewed.URL) || !strings.Contains(text, "Предупреждение") {
+		t.Errorf("Skewed repository should produce a warning:\n%s", text)
+	}
+	if !strings.Contains(text, "✅ "+synced.URL) {
+		t.Errorf("Synced repository should pass:\n%s", text)
+	}
+
+	toolResult, _ = s.callTool(context.Background(), "check_time_sync", map[string]interface{}{"repository_url": synced.URL})
+	if toolResult.IsError {
+		t.Errorf("Synced repository alone should not report problems:\n%s", toolResult.Content[0].Text)
+	}
+}
+
+// TestRawPackageJSON проверяет, что инструмент возвращает сырой ответ со скрытыми токенами
+func TestRawPackageJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/packages/raw" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{"success":true,"data":{"name":"raw","unexpected_field":42,"upload_token":"s3cr3t"}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	s := newTestServer(t, newTestPackageManager(t))
+	text, err := callToolText(t, s, "raw_package_json", map[string]interface{}{
+		"repository_url": server.URL,
+		"name":           "raw",
+	})
+	if err != nil {
+		t.Fatalf("raw_package_json failed: %v", err)
+	}
+
+	if !strings.Contains(text, `"unexpected_field": 42`) {
+		t.Errorf("expected pretty-printed raw body, got:\n%s", text)
+	}
+	if strings.Contains(text, "s3cr3t") || !strings.Contains(text, `"upload_token": "***"`) {
+		t.Errorf("expected token to be redacted, got:\n%s", text)
+	}
+	if strings.Contains(text, "обрезан") {
+		t.Errorf("small body must not be reported as truncated")
+	}
+}
+
+// TestPackageVersionInfoTool проверяет вывод сведений о версии и сообщение о несуществующей версии
+func TestPackageVersionInfoTool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/packages/tool/1.2.0" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"success":false,"error":"version not found"}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": RepositoryVersion{
+			Version:      "1.2.0",
+			Dependencies: map[string]string{"lib": "^1.0.0"},
+			DevDeps:      map[string]string{"testkit": ""},
+			Size:         2048,
+			Checksum:     "sha256:abc",
+			Uploaded:     time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+			Downloads:    42,
+			Files: []RepositoryFile{
+				{OS: "linux", Arch: "amd64", Format: FormatTarZst, Filename: "tool-1.2.0-linux-amd64.tar.zst", Size: 1024, Checksum: "sha256:def"},
+			},
+		}})
+	}))
+	t.Cleanup(server.Close)
+
+	s := newTestServer(t, newTestPackageManager(t))
+	result, err := s.callTool(context.Background(), "package_version_info", map[string]interface{}{
+		"repository_url": server.URL, "name": "tool", "version": "1.2.0",
+	})
+	if err != nil {
+		t.Fatalf("package_version_info failed: %v", err)
+	}
+	text := result.Content[0].Text
+	for _, want := range []string{"tool 1.2.0", "2.0 KB", "sha256:abc", "2024-05-06 07:08:09", "Загрузок: 42", "lib ^1.0.0", "testkit *", "linux/amd64: tool-1.2.0-linux-amd64.tar.zst (1.0 KB, tar.zst)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in output:\n%s", want, text)
+		}
+	}
+	if info, ok := result.StructuredContent.(*RepositoryVersion); !ok || info.Version != "1.2.0" {
+		t.Errorf("unexpected structured content %+v", result.StructuredContent)
+	}
+
+	result, err = s.callTool(context.Background(), "package_version_info", map[string]interface{}{
+		"repository_url": server.URL, "name": "tool", "version": "9.9.9",
+	})
+	if err != nil {
+		t.Fatalf("expected friendly tool error, got %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "Версия 9.9.9 пакета tool не найдена") {
+		t.Errorf("expected not-found message, got %+v", result)
+	}
+}
+
+// TestClientInfoRetained проверяет, что сведения о клиенте из initialize сохраняются между запросами
+func TestClientInfoRetained(t *testing.T) {
+	s := newTestServer(t, newTestPackageManager(t))
+
+	if _, err := callToolText(t, s, "client_info", nil); err == nil {
+		t.Error("expected error before initialize")
+	}
+
+	s.handleMessage(context.Background(), MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"protocolVersion": "2024-11-05",
+			"clientInfo":      map[string]interface{}{"name": "test-client", "version": "0.3.1"},
+			"capabilities": map[string]interface{}{
+				"roots": map[string]interface{}{"listChanged": true},
+			},
+		},
+	})
+	s.handleMessage(context.Background(), MCPMessage{JSONRPC: "2.0", ID: 2, Method: "tools/list"})
+
+	text, err := callToolText(t, s, "client_info", nil)
+	if err != nil {
+		t.Fatalf("client_info failed: %v", err)
+	}
+	for _, want := range []string{"test-client", "0.3.1", "2024-11-05", `"listChanged": true`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in client_info output:\n%s", want, text)
+		}
+	}
+}
+
+// TestResources проверяет перечисление установленных пакетов как ресурсов и чтение одного из них
+func TestResources(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "alpha", Version: "1.0.0", Description: "Первый"}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "beta", Version: "2.0.0"}, false)
+	s := newTestServer(t, pm)
+
+	initResponse := s.handleMessage(context.Background(), MCPMessage{JSONRPC: "2.0", ID: 1, Method: "initialize"})
+	capabilities := initResponse.Result.(InitializeResult).Capabilities
+	if _, ok := capabilities["resources"]; !ok {
+		t.Errorf("expected resources capability, got %v", capabilities)
+	}
+
+	listResponse := s.handleMessage(context.Background(), MCPMessage{JSONRPC: "2.0", ID: 2, Method: "resources/list"})
+	resources := listResponse.Result.(map[string]interface{})["resources"].([]Resource)
+	if len(resources) != 2 || resources[0].URI != "criage://packages/alpha" || resources[1].URI != "criage://packages/beta" {
+		t.Fatalf("unexpected resources: %+v", resources)
+	}
+
+	readResponse := s.handleMessage(context.Background(), MCPMessage{
+		JSONRPC: "2.0",
+		ID:      3,
+		Method:  "resources/read",
+		Params:  map[string]interface{}{"uri": "criage://packages/alpha"},
+	})
+	if readResponse.Error != nil {
+		t.Fatalf("resources/read failed: %+v", readResponse.Error)
+	}
+	contents := readResponse.Result.(map[string]interface{})["contents"].([]ResourceContents)
+	if len(contents) != 1 || contents[0].MimeType != "application/json" {
+		t.Fatalf("unexpected contents: %+v", contents)
+	}
+
+	var resource PackageResource
+	if err := json.Unmarshal([]byte(contents[0].Text), &resource); err != nil {
+		t.Fatalf("resource is not valid JSON: %v", err)
+	}
+	if resource.Manifest == nil || resource.Manifest.Description != "Первый" {
+		t.Errorf("expected manifest in resource, got %+v", resource.Manifest)
+	}
+	if !strings.Contains(strings.Join(resource.Files, ","), "src/main.txt") {
+		t.Errorf("expected file listing to contain src/main.txt, got %v", resource.Files)
+	}
+
+	missing := s.handleMessage(context.Background(), MCPMessage{
+		JSONRPC: "2.0",
+		ID:      4,
+		Method:  "resources/read",
+		Params:  map[string]interface{}{"uri": "criage://packages/missing"},
+	})
+	if missing.Error == nil || missing.Error.Code != -32002 {
+		t.Errorf("expected resource not found error, got %+v", missing.Error)
+	}
+}
+
+// TestStructuredContentRoundTrip проверяет, что данные инструмента передаются в structuredContent
+// и восстанавливаются из JSON ответа без потерь
+func TestStructuredContentRoundTrip(t *testing.T) {
+	expected := []SearchResult{
+		{Name: "json-tool", Version: "1.2.0", Description: "JSON formatter", Author: "Alice", Downloads: 42, Updated: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Score: 9.5, Repository: "test"},
+		{Name: "yaml-lint", Version: "0.3.1", Description: "YAML linter", Author: "Bob", Downloads: 7, Updated: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), Score: 1, Repository: "test"},
+	}
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]interface{}{"query": r.URL.Query().Get("q"), "results": expected, "total": len(expected)},
+		})
+	}))
+	defer repo.Close()
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{{Name: "test", URL: repo.URL, Enabled: true}}
+	s := newTestServer(t, pm)
+
+	response := s.handleMessage(context.Background(), MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "search_packages",
+			"arguments": map[string]interface{}{"query": "json"},
+		},
+	})
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		t.Fatalf("Failed to marshal response: %v", err)
+	}
+
+	var decoded struct {
+		Result struct {
+			Content           []ContentItem `json:"content"`
+			StructuredContent struct {
+				Results []SearchResult `json:"results"`
+			} `json:"structuredContent"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(decoded.Result.Content) != 1 || !strings.Contains(decoded.Result.Content[0].Text, "**json**-tool") {
+		t.Errorf("expected human-readable text block, got %+v", decoded.Result.Content)
+	}
+	if !reflect.DeepEqual(decoded.Result.StructuredContent.Results, expected) {
+		t.Errorf("structured results = %+v, want %+v", decoded.Result.StructuredContent.Results, expected)
+	}
+}
+
+// TestStructuredContentForEveryTool проверяет, что успешные вызовы инструментов возвращают structuredContent
+func TestStructuredContentForEveryTool(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "alpha", Version: "1.0.0"}, false)
+	s := newTestServer(t, pm)
+
+	testCases := []struct {
+		tool string
+		args map[string]interface{}
+	}{
+		{"list_packages", nil},
+		{"list_by_category", nil},
+		{"package_info", map[string]interface{}{"name": "alpha"}},
+		{"verify_package", map[string]interface{}{"name": "alpha"}},
+		{"clean_cache", map[string]interface{}{"dry_run": true}},
+		{"set_log_level", map[string]interface{}{"level": "info"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.tool, func(t *testing.T) {
+			result, err := s.callTool(context.Background(), tc.tool, tc.args)
+			if err != nil {
+				t.Fatalf("callTool: %v", err)
+			}
+			if result.StructuredContent == nil {
+				t.Fatal("expected structuredContent")
+			}
+
+			data, err := json.Marshal(result.StructuredContent)
+			if err != nil {
+				t.Fatalf("structuredContent is not serializable: %v", err)
+			}
+			var object map[string]interface{}
+			if err := json.Unmarshal(data, &object); err != nil {
+				t.Errorf("structuredContent must be a JSON object, got %s", data)
+			}
+		})
+	}
+}
+
+// TestProtocolVersionNegotiation проверяет выбор версии протокола при initialize
+func TestProtocolVersionNegotiation(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requested string
+		want      string
+	}{
+		{"exact match", MCPVersion, MCPVersion},
+		{"supported older version", "2024-11-05", "2024-11-05"},
+		{"unknown version between supported", "2025-01-15", "2024-11-05"},
+		{"newer client", "2099-01-01", MCPVersion},
+		{"missing version", "", MCPVersion},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(t, newTestPackageManager(t))
+			response := s.handleMessage(context.Background(), MCPMessage{
+				JSONRPC: "2.0",
+				ID:      1,
+				Method:  "initialize",
+				Params:  map[string]interface{}{"protocolVersion": tc.requested},
+			})
+			if response.Error != nil {
+				t.Fatalf("initialize failed: %+v", response.Error)
+			}
+			if got := response.Result.(InitializeResult).ProtocolVersion; got != tc.want {
+				t.Errorf("protocolVersion = %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	for _, requested := range []string{"2023-01-01", "latest"} {
+		t.Run("unsupported "+requested, func(t *testing.T) {
+			s := newTestServer(t, newTestPackageManager(t))
+			response := s.handleMessage(context.Background(), MCPMessage{
+				JSONRPC: "2.0",
+				ID:      1,
+				Method:  "initialize",
+				Params:  map[string]interface{}{"protocolVersion": requested},
+			})
+			if response.Error == nil || response.Error.Code != -32602 {
+				t.Fatalf("expected invalid params error, got %+v", response)
+			}
+			data, _ := json.Marshal(response.Error.Data)
+			if !strings.Contains(string(data), MCPVersion) {
+				t.Errorf("expected supported versions in error data, got %s", data)
+			}
+			if s.clientParams() != nil {
+				t.Error("client must not be recorded after failed negotiation")
+			}
+		})
+	}
+}
+
+// TestBatchRequests проверяет обработку пакетных запросов JSON-RPC
+func TestBatchRequests(t *testing.T) {
+	session := startTestSession(t, newTestServer(t, newTestPackageManager(t)))
+
+	t.Run("empty batch", func(t *testing.T) {
+		session.send([]interface{}{})
+		response := session.receive(2 * time.Second)
+		if response.Error == nil || response.Error.Code != -32600 {
+			t.Fatalf("expected invalid request error, got %+v", response)
+		}
+	})
+
+	t.Run("calls and notifications", func(t *testing.T) {
+		session.send([]interface{}{
+			MCPMessage{JSONRPC: "2.0", ID: 1, Method: "tools/list"},
+			MCPMessage{JSONRPC: "2.0", Method: "notifications/initialized"},
+			MCPMessage{
+				JSONRPC: "2.0",
+				ID:      2,
+				Method:  "tools/call",
+				Params: map[string]interface{}{
+					"name":      "list_packages",
+					"arguments": map[string]interface{}{},
+				},
+			},
+			MCPMessage{JSONRPC: "2.0", Method: "notifications/cancelled", Params: map[string]interface{}{"requestId": 99}},
+		})
+
+		var responses []MCPMessage
+		if err := json.Unmarshal(session.receiveRaw(5*time.Second), &responses); err != nil {
+			t.Fatalf("expected array of responses: %v", err)
+		}
+		if len(responses) != 2 {
+			t.Fatalf("expected 2 responses, got %d: %+v", len(responses), responses)
+		}
+		if requestKey(responses[0].ID) != "1" || requestKey(responses[1].ID) != "2" {
+			t.Errorf("unexpected response ids: %v, %v", responses[0].ID, responses[1].ID)
+		}
+		for _, response := range responses {
+			if response.Error != nil {
+				t.Errorf("unexpected error for id %v: %+v", response.ID, response.Error)
+			}
+		}
+	})
+
+	t.Run("only notifications", func(t *testing.T) {
+		session.send([]interface{}{
+			MCPMessage{JSONRPC: "2.0", Method: "notifications/initialized"},
+		})
+		// Ответа на пакет из уведомлений нет: следующим приходит ответ на обычный запрос
+		session.send(MCPMessage{JSONRPC: "2.0", ID: 3, Method: "tools/list"})
+		if response := session.receive(2 * time.Second); requestKey(response.ID) != "3" {
+			t.Fatalf("expected response to id 3, got %+v", response)
+		}
+	})
+
+	t.Run("invalid entries", func(t *testing.T) {
+		session.send([]interface{}{1, MCPMessage{JSONRPC: "2.0", ID: 4, Method: "tools/list"}})
+
+		var responses []MCPMessage
+		if err := json.Unmarshal(session.receiveRaw(2*time.Second), &responses); err != nil {
+			t.Fatalf("expected array of responses: %v", err)
+		}
+		if len(responses) != 2 || responses[0].Error == nil || responses[0].Error.Code != -32600 || requestKey(responses[1].ID) != "4" {
+			t.Fatalf("unexpected responses: %+v", responses)
+		}
+	})
+}
+
+// TestToolPanicRecovered проверяет, что паника в инструменте превращается в ошибку
+// JSON-RPC, а сервер продолжает обрабатывать запросы
+func TestToolPanicRecovered(t *testing.T) {
+	var logs bytes.Buffer
+	previous := logger
+	logger = NewLogger(&logs, LevelInfo)
+	t.Cleanup(func() { logger = previous })
+
+	// Сервер без пакетного менеджера: обращение к нему в инструменте вызывает панику
+	session := startTestSession(t, newTestServer(t, nil))
+	session.send(MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "list_packages",
+			"arguments": map[string]interface{}{},
+		},
+	})
+
+	response := session.receive(2 * time.Second)
+	if response.Error == nil || response.Error.Code != -32603 {
+		t.Fatalf("expected internal error, got %+v", response)
+	}
+	if data, _ := response.Error.Data.(string); !strings.Contains(data, "list_packages") {
+		t.Errorf("expected recovered panic message in error data, got %v", response.Error.Data)
+	}
+	if !strings.Contains(logs.String(), "goroutine") {
+		t.Errorf("expected stack trace in log, got:\n%s", logs.String())
+	}
+
+	session.send(MCPMessage{JSONRPC: "2.0", ID: 2, Method: "tools/list"})
+	if response := session.receive(2 * time.Second); requestKey(response.ID) != "2" || response.Error != nil {
+		t.Fatalf("server did not survive the panic: %+v", response)
+	}
+}
+
+// TestRepositoryStatsRanking проверяет детерминированный порядок авторов с равным числом
+// пакетов и ограничение рейтингов
+func TestRepositoryStatsRanking(t *testing.T) {
+	authors := map[string]int{"zoe": 3, "bob": 3, "alice": 3, "carol": 7, "dave": 1, "erin": 2}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data": Statistics{
+				TotalPackages:    19,
+				PackagesByAuthor: authors,
+				PopularPackages:  []string{"p1", "p2", "p3", "p4", "p5", "p6", "p7", "p8", "p9", "p10", "p11"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	s := newTestServer(t, newTestPackageManager(t))
+	text, err := callToolText(t, s, "get_repository_stats", map[string]interface{}{"repository_url": server.URL})
+	if err != nil {
+		t.Fatalf("get_repository_stats: %v", err)
+	}
+
+	want := "👥 Топ авторы:\n" +
+		"   1. carol: 7 пакетов\n" +
+		"   2. alice: 3 пакетов\n" +
+		"   3. bob: 3 пакетов\n" +
+		"   4. zoe: 3 пакетов\n" +
+		"   5. erin: 2 пакетов\n"
+	if !strings.HasSuffix(text, want) {
+		t.Errorf("unexpected author ranking:\n%s", text)
+	}
+	if !strings.Contains(text, "   10. p10\n") || strings.Contains(text, "p11") {
+		t.Errorf("expected top-10 popular packages, got:\n%s", text)
+	}
+
+	// Порядок не зависит от порядка обхода карты
+	for i := 0; i < 20; i++ {
+		ranked := rankByCount(authors)
+		if ranked[1].Name != "alice" || ranked[2].Name != "bob" || ranked[3].Name != "zoe" {
+			t.Fatalf("non-deterministic ranking: %+v", ranked)
+		}
+	}
+}
+
+// TestServerInfo проверяет, что server_info объявляет ровно те форматы и уровни сжатия,
+// которые поддерживает createArchive
+func TestServerInfo(t *testing.T) {
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{
+		{Name: "main", URL: "https://packages.example.com", Priority: 10, Enabled: true, AuthToken: "secret-token"},
+		{Name: "off", URL: "https://off.example.com", Enabled: false},
+	}
+	s := newTestServer(t, pm)
+	s.handleMessage(context.Background(), MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params:  map[string]interface{}{"protocolVersion": "2025-03-26"},
+	})
+
+	result, err := s.callTool(context.Background(), "server_info", nil)
+	if err != nil {
+		t.Fatalf("server_info failed: %v", err)
+	}
+	description, ok := result.StructuredContent.(*ServerDescription)
+	if !ok {
+		t.Fatalf("unexpected structured content: %T", result.StructuredContent)
+	}
+	if description.ProtocolVersion != "2025-03-26" || description.Version != ServerVersion {
+		t.Errorf("unexpected server description: %+v", description)
+	}
+	if len(description.Repositories) != 1 || description.Repositories[0].Name != "main" {
+		t.Errorf("expected only enabled repositories, got %+v", description.Repositories)
+	}
+	if strings.Contains(result.Content[0].Text, "secret-token") {
+		t.Error("server_info must not expose repository tokens")
+	}
+
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// Каждый объявленный формат упаковывается на границах объявленного диапазона и извлекается
+	covered := map[string]bool{}
+	for _, codec := range description.Compression {
+		for _, format := range codec.Formats {
+			covered[format] = true
+			for _, level := range []int{codec.MinLevel, codec.MaxLevel} {
+				archivePath := filepath.Join(t.TempDir(), "pkg"+archiveExtension(format))
+				if err := pm.createArchive(srcDir, archivePath, format, level); err != nil {
+					t.Errorf("createArchive(%s, level %d): %v", format, level, err)
+					continue
+				}
+				if _, err := pm.extractArchive(context.Background(), archivePath, t.TempDir()); err != nil {
+					t.Errorf("extractArchive(%s, level %d): %v", format, level, err)
+				}
+			}
+		}
+	}
+	for _, format := range description.ArchiveFormats {
+		if !covered[format] {
+			t.Errorf("format %s has no advertised compression codec", format)
+		}
+	}
+	if len(covered) != len(description.ArchiveFormats) {
+		t.Errorf("codecs advertise formats %v beyond archive formats %v", covered, description.ArchiveFormats)
+	}
+
+	// Форматы, которые распознаются по расширению, тоже должны быть объявлены
+	for _, e := range archiveExtensions {
+		if !containsString(description.ArchiveFormats, e.format) {
+			t.Errorf("format %s is detected but not advertised", e.format)
+		}
+	}
+	if err := pm.createArchive(srcDir, filepath.Join(t.TempDir(), "pkg.rar"), "rar", 3); err == nil {
+		t.Error("expected createArchive to reject an unadvertised format")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// durationMs переводит длительность в миллисекунды с точностью до микросекунды
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// add прибавляет длительности этапов другой установки
+func (t *InstallTiming) add(other *InstallTiming) {
+	t.DownloadMs += other.DownloadMs
+	t.ExtractMs += other.ExtractMs
+	t.CopyMs += other.CopyMs
+}
+
+// installMetrics счетчики установок с момента запуска сервера. Хранятся только в памяти.
+type installMetrics struct {
+	mu              sync.Mutex
+	since           time.Time
+	installs        int64
+	failures        int64
+	bytesDownloaded int64
+	installMs       float64
+}
+
+func newInstallMetrics() *installMetrics {
+	return &installMetrics{since: time.Now()}
+}
+
+// recordDownload учитывает скачанные из сети байты
+func (m *installMetrics) recordDownload(n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bytesDownloaded += n
+}
+
+// recordInstall учитывает завершенную установку; время неудачных не входит в среднее
+func (m *installMetrics) recordInstall(elapsedMs float64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.failures++
+		return
+	}
+	m.installs++
+	m.installMs += elapsedMs
+}
+
+// snapshot возвращает текущие значения счетчиков
+func (m *installMetrics) snapshot() *InstallMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &InstallMetrics{
+		Since:           m.since,
+		Installs:        m.installs,
+		Failures:        m.failures,
+		BytesDownloaded: m.bytesDownloaded,
+		TotalInstallMs:  m.installMs,
+	}
+	if m.installs > 0 {
+		result.AverageInstallMs = result.TotalInstallMs / float64(m.installs)
+	}
+	return result
+}
+
+// Metrics возвращает счетчики установок с момента запуска
+func (pm *PackageManager) Metrics() *InstallMetrics {
+	return pm.metrics.snapshot()
+}
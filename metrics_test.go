@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"testing"
+)
+
+// TestInstallTimingAndMetrics проверяет длительность этапов установки в плане и
+// накопление счетчиков установок, скачанных байт и среднего времени
+func TestInstallTimingAndMetrics(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	var archiveBytes int64
+	for name, deps := range map[string]map[string]string{
+		"app": {"lib": "^1.0.0"},
+		"lib": nil,
+	} {
+		archivePath := buildTestArchive(t, pm, PackageManifest{Name: name, Version: "1.0.0", Dependencies: deps}, map[string]string{"data.txt": name}, FormatTarGz)
+		stat, err := os.Stat(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archiveBytes += stat.Size()
+		repo.publish(t, name, "1.0.0", archivePath)
+		repo.setDependencies(name, "1.0.0", deps)
+	}
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	checkTiming := func(label string, timing *InstallTiming) {
+		t.Helper()
+		if timing == nil {
+			t.Fatalf("%s: timing is missing", label)
+		}
+		if timing.ExtractMs <= 0 || timing.CopyMs <= 0 || timing.DownloadMs < 0 {
+			t.Errorf("%s: phase durations are not populated: %+v", label, timing)
+		}
+		if sum := timing.DownloadMs + timing.ExtractMs + timing.CopyMs; sum > timing.TotalMs {
+			t.Errorf("%s: phases (%.3f ms) exceed total (%.3f ms)", label, sum, timing.TotalMs)
+		}
+	}
+
+	plan, err := pm.InstallPackage(ctx, "app", "", false, false, false, false, "", "")
+	if err != nil {
+		t.Fatalf("InstallPackage: %v", err)
+	}
+	for _, action := range plan.Actions {
+		checkTiming(action.Name, action.Timing)
+		if action.Timing.DownloadMs <= 0 {
+			t.Errorf("%s: download time must be measured for an uncached archive", action.Name)
+		}
+	}
+	checkTiming("plan", plan.Timing)
+
+	metrics := pm.Metrics()
+	if metrics.Installs != 2 || metrics.Failures != 0 || metrics.BytesDownloaded != archiveBytes {
+		t.Errorf("unexpected metrics after first install: %+v (archives %d bytes)", metrics, archiveBytes)
+	}
+	if metrics.AverageInstallMs <= 0 || math.Abs(metrics.AverageInstallMs*2-metrics.TotalInstallMs) > 1e-6 {
+		t.Errorf("average does not match total: %+v", metrics)
+	}
+
+	// Повторная установка берет архив из кеша: байты не прибавляются, счетчики растут
+	plan, err = pm.InstallPackage(ctx, "app", "", false, true, false, false, "", "")
+	if err != nil {
+		t.Fatalf("InstallPackage: %v", err)
+	}
+	checkTiming("reinstall", plan.Timing)
+	if _, err := pm.InstallPackage(ctx, "missing", "", false, false, false, false, "", ""); err == nil {
+		t.Fatal("expected missing package to fail")
+	}
+
+	again := pm.Metrics()
+	if again.Installs != 3 || again.BytesDownloaded != metrics.BytesDownloaded || again.TotalInstallMs <= metrics.TotalInstallMs {
+		t.Errorf("metrics must grow monotonically: before %+v, after %+v", metrics, again)
+	}
+	if !again.Since.Equal(metrics.Since) {
+		t.Errorf("metrics start time changed: %v -> %v", metrics.Since, again.Since)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// knownOS и knownArch значения GOOS/GOARCH, встречающиеся в именах архивов
+var (
+	knownOS   = []string{"linux", "darwin", "windows", "freebsd", "netbsd", "openbsd", "android", "ios", "aix", "solaris", "illumos", "dragonfly", "plan9", "js", "wasip1"}
+	knownArch = []string{"amd64", "386", "arm", "arm64", "ppc64", "ppc64le", "mips", "mipsle", "mips64", "mips64le", "riscv64", "s390x", "loong64", "wasm"}
+)
+
+// validatePackageName проверяет, что имя пакета можно использовать как имя каталога
+// установки: оно не пустое, не "." и "..", без разделителей пути, имени тома и нулевых байтов
+func validatePackageName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("пустое имя пакета")
+	case name == "." || name == "..":
+		return fmt.Errorf("недопустимое имя пакета %q", name)
+	case strings.ContainsAny(name, `/\:`+"\x00"):
+		return fmt.Errorf("имя пакета %q содержит разделитель пути или недопустимый символ", name)
+	case filepath.IsAbs(name) || filepath.VolumeName(name) != "":
+		return fmt.Errorf("имя пакета %q является путем", name)
+	}
+	return nil
+}
+
+// readArchiveInfo читает из архива манифест пакета и служебные метаданные (если есть)
+func readArchiveInfo(archivePath string) (*PackageManifest, *ArchiveMetadata, error) {
+	tr, closer, err := openArchive(archivePath, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closer()
+
+	var manifest *PackageManifest
+	var metadata *ArchiveMetadata
+	for manifest == nil || metadata == nil {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка чтения архива: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch path.Clean(header.Name) {
+		case archiveMetadataName:
+			metadata = &ArchiveMetadata{}
+			if err := json.NewDecoder(tr).Decode(metadata); err != nil {
+				return nil, nil, fmt.Errorf("ошибка разбора метаданных архива: %w", err)
+			}
+		case manifestFileName:
+			manifest = &PackageManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, fmt.Errorf("ошибка разбора манифеста: %w", err)
+			}
+		}
+	}
+
+	if manifest == nil && metadata != nil && metadata.PackageManifest != nil {
+		manifest = metadata.PackageManifest
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("архив не содержит %s", manifestFileName)
+	}
+
+	return manifest, metadata, nil
+}
+
+// archiveTarget определяет платформу архива по манифесту сборки или полям metadata.os/metadata.arch
+func archiveTarget(manifest *PackageManifest, metadata *ArchiveMetadata) (osName, arch string) {
+	if metadata != nil && metadata.BuildManifest != nil && len(metadata.BuildManifest.Targets) == 1 {
+		target := metadata.BuildManifest.Targets[0]
+		return target.OS, target.Arch
+	}
+
+	if manifest.Metadata != nil {
+		osName, _ = manifest.Metadata["os"].(string)
+		arch, _ = manifest.Metadata["arch"].(string)
+	}
+	return osName, arch
+}
+
+// CheckArchiveNaming проверяет, что имя файла архива соответствует соглашению
+// name-version-os-arch.format и содержимому архива
+func (pm *PackageManager) CheckArchiveNaming(archivePath string) (*ArchiveNamingResult, error) {
+	format, err := detectArchiveFormat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, metadata, err := readArchiveInfo(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := filepath.Base(archivePath)
+	ext := archiveExtension(format)
+	for _, e := range archiveExtensions {
+		if strings.HasSuffix(strings.ToLower(filename), e.ext) {
+			ext = e.ext
+			break
+		}
+	}
+	base := filename[:len(filename)-len(ext)]
+
+	result := &ArchiveNamingResult{
+		Filename: filename,
+		Name:     manifest.Name,
+		Version:  manifest.Version,
+		Format:   format,
+	}
+	result.OS, result.Arch = archiveTarget(manifest, metadata)
+
+	if metadata != nil && metadata.CompressionType != "" && metadata.CompressionType != format {
+		result.Problems = append(result.Problems,
+			fmt.Sprintf("расширение %s не соответствует формату архива %s", ext, metadata.CompressionType))
+	}
+
+	// Имя и версия берутся из манифеста, поэтому сверяем префикс целиком
+	prefix := manifest.Name + "-" + manifest.Version + "-"
+	switch {
+	case !strings.HasPrefix(base, manifest.Name+"-"):
+		result.Problems = append(result.Problems, fmt.Sprintf("имя файла не начинается с имени пакета %q", manifest.Name))
+	case base == manifest.Name+"-"+manifest.Version:
+		result.Problems = append(result.Problems, "имя файла не содержит os-arch")
+	case !strings.HasPrefix(base, prefix):
+		result.Problems = append(result.Problems, fmt.Sprintf("имя файла не содержит версию %q после имени пакета", manifest.Version))
+	default:
+		platform := strings.SplitN(base[len(prefix):], "-", 2)
+		if len(platform) != 2 || platform[0] == "" || platform[1] == "" {
+			result.Problems = append(result.Problems, "имя файла не содержит os-arch")
+			break
+		}
+		fileOS, fileArch := platform[0], platform[1]
+
+		if result.OS == "" {
+			result.OS = fileOS
+			if !containsString(knownOS, fileOS) && !isPlatformWildcard(fileOS) {
+				result.Problems = append(result.Problems, fmt.Sprintf("неизвестная ОС в имени файла: %s", fileOS))
+			}
+		} else if fileOS != result.OS {
+			result.Problems = append(result.Problems, fmt.Sprintf("ОС в имени файла %s, в архиве %s", fileOS, result.OS))
+		}
+
+		if result.Arch == "" {
+			result.Arch = fileArch
+			if !containsString(knownArch, fileArch) && !isPlatformWildcard(fileArch) {
+				result.Problems = append(result.Problems, fmt.Sprintf("неизвестная архитектура в имени файла: %s", fileArch))
+			}
+		} else if fileArch != result.Arch {
+			result.Problems = append(result.Problems, fmt.Sprintf("архитектура в имени файла %s, в архиве %s", fileArch, result.Arch))
+		}
+	}
+
+	if result.OS != "" && result.Arch != "" {
+		result.Expected = fmt.Sprintf("%s%s-%s%s", prefix, result.OS, result.Arch, archiveExtension(format))
+	}
+	result.OK = len(result.Problems) == 0
+
+	return result, nil
+}
+
+// containsString сообщает, содержится ли value в values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
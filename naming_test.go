@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckArchiveNaming проверяет архивы с правильными и неправильными именами
+func TestCheckArchiveNaming(t *testing.T) {
+	pm := newTestPackageManager(t)
+	manifest := PackageManifest{
+		Name:     "my-tool",
+		Version:  "1.2.0-beta.1",
+		Metadata: map[string]interface{}{"os": "linux", "arch": "arm64"},
+	}
+	built := buildTestArchive(t, pm, manifest, map[string]string{"bin/tool": "tool"}, FormatCriage)
+
+	rename := func(name string) string {
+		path := filepath.Join(t.TempDir(), name)
+		data, err := os.ReadFile(built)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	testCases := []struct {
+		filename string
+		problem  string
+	}{
+		{"my-tool-1.2.0-beta.1-linux-arm64.criage", ""},
+		{"other-1.2.0-beta.1-linux-arm64.criage", "имени пакета"},
+		{"my-tool-1.2.0-linux-arm64.criage", "версию"},
+		{"my-tool-1.2.0-beta.1-darwin-arm64.criage", "ОС"},
+		{"my-tool-1.2.0-beta.1-linux-amd64.criage", "архитектура"},
+		{"my-tool-1.2.0-beta.1.criage", "os-arch"},
+	}
+
+	for _, tc := range testCases {
+		result, err := pm.CheckArchiveNaming(rename(tc.filename))
+		if err != nil {
+			t.Fatalf("%s: CheckArchiveNaming failed: %v", tc.filename, err)
+		}
+
+		if tc.problem == "" {
+			if !result.OK {
+				t.Errorf("%s: expected OK, got problems %v", tc.filename, result.Problems)
+			}
+			if result.Expected != tc.filename {
+				t.Errorf("%s: expected name %s", tc.filename, result.Expected)
+			}
+			continue
+		}
+
+		if result.OK || !strings.Contains(strings.Join(result.Problems, "; "), tc.problem) {
+			t.Errorf("%s: expected problem about %q, got %v", tc.filename, tc.problem, result.Problems)
+		}
+	}
+}
+
+// TestCheckArchiveNamingWithoutTarget проверяет архив без сведений о платформе
+func TestCheckArchiveNamingWithoutTarget(t *testing.T) {
+	pm := newTestPackageManager(t)
+	built := buildTestArchive(t, pm, PackageManifest{Name: "plain", Version: "0.1.0"}, nil, FormatTarGz)
+
+	good := filepath.Join(t.TempDir(), "plain-0.1.0-windows-amd64.tar.gz")
+	bad := filepath.Join(t.TempDir(), "plain-0.1.0-winblows-x64.tar.gz")
+	for _, path := range []string{good, bad} {
+		data, _ := os.ReadFile(built)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if result, err := pm.CheckArchiveNaming(good); err != nil || !result.OK {
+		t.Errorf("expected known platform to pass, got %+v (err %v)", result, err)
+	}
+	if result, err := pm.CheckArchiveNaming(bad); err != nil || result.OK || len(result.Problems) != 2 {
+		t.Errorf("expected unknown os and arch to be reported, got %+v (err %v)", result, err)
+	}
+}
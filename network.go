@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// currentNetworkProfile возвращает действующие сетевые настройки в виде профиля
+func (pm *PackageManager) currentNetworkProfile(name string) NetworkProfile {
+	config := pm.getConfig()
+	return NetworkProfile{
+		Name:                name,
+		RequestsPerSecond:   config.RequestsPerSecond,
+		Timeout:             config.Timeout,
+		MaxRetries:          config.MaxRetries,
+		MaxIdleConns:        config.MaxIdleConns,
+		MaxIdleConnsPerHost: config.MaxIdleConnsPerHost,
+		IdleConnTimeout:     config.IdleConnTimeout,
+	}
+}
+
+// validateNetworkProfile проверяет имя и значения профиля
+func validateNetworkProfile(profile NetworkProfile) error {
+	if strings.TrimSpace(profile.Name) == "" {
+		return fmt.Errorf("имя сетевого профиля обязательно")
+	}
+
+	for field, value := range map[string]int{
+		"requests_per_second":     profile.RequestsPerSecond,
+		"timeout":                 profile.Timeout,
+		"max_retries":             profile.MaxRetries,
+		"max_idle_conns":          profile.MaxIdleConns,
+		"max_idle_conns_per_host": profile.MaxIdleConnsPerHost,
+		"idle_conn_timeout":       profile.IdleConnTimeout,
+	} {
+		if value < 0 {
+			return fmt.Errorf("профиль %s: %s не может быть отрицательным", profile.Name, field)
+		}
+	}
+	return nil
+}
+
+// ExportNetworkProfile сохраняет действующие сетевые настройки под указанным именем
+func (pm *PackageManager) ExportNetworkProfile(name string) (*NetworkProfile, error) {
+	pm.configMutex.Lock()
+	defer pm.configMutex.Unlock()
+
+	profile := pm.currentNetworkProfile(strings.TrimSpace(name))
+	if err := validateNetworkProfile(profile); err != nil {
+		return nil, err
+	}
+
+	if err := pm.storeNetworkProfile(profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// ImportNetworkProfile добавляет или заменяет профиль, не применяя его
+func (pm *PackageManager) ImportNetworkProfile(profile NetworkProfile) (*NetworkProfile, error) {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if err := validateNetworkProfile(profile); err != nil {
+		return nil, err
+	}
+
+	pm.configMutex.Lock()
+	defer pm.configMutex.Unlock()
+
+	if err := pm.storeNetworkProfile(profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// storeNetworkProfile записывает профиль в конфигурацию; вызывается под configMutex
+func (pm *PackageManager) storeNetworkProfile(profile NetworkProfile) error {
+	_, err := pm.updateConfig(func(config *Config) error {
+		if config.NetworkProfiles == nil {
+			config.NetworkProfiles = make(map[string]NetworkProfile)
+		}
+		config.NetworkProfiles[profile.Name] = profile
+		return nil
+	})
+	return err
+}
+
+// UseNetworkProfile применяет сохраненный профиль: перенастраивает rate limiter и
+// HTTP-клиент и запоминает профиль как активный
+func (pm *PackageManager) UseNetworkProfile(name string) (*NetworkProfile, error) {
+	pm.configMutex.Lock()
+	defer pm.configMutex.Unlock()
+
+	profile, ok := pm.getConfig().NetworkProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("сетевой профиль %s не найден", name)
+	}
+
+	_, err := pm.updateConfig(func(config *Config) error {
+		config.RequestsPerSecond = profile.RequestsPerSecond
+		config.Timeout = profile.Timeout
+		config.MaxRetries = profile.MaxRetries
+		config.MaxIdleConns = profile.MaxIdleConns
+		config.MaxIdleConnsPerHost = profile.MaxIdleConnsPerHost
+		config.IdleConnTimeout = profile.IdleConnTimeout
+		config.NetworkProfile = profile.Name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := pm.reconfigureNetwork(); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Применен сетевой профиль %s", profile.Name)
+	return &profile, nil
+}
+
+// reconfigureNetwork пересоздает HTTP-клиент и ограничители частоты по текущей конфигурации.
+// Клиент, переданный в NewPackageManagerWithConfig, сохраняется. Запросы, уже получившие
+// клиента, завершаются со старыми настройками.
+func (pm *PackageManager) reconfigureNetwork() error {
+	config := pm.getConfig()
+	client := pm.httpClient
+	if !pm.customClient {
+		transport, err := newHTTPTransport(config, nil)
+		if err != nil {
+			return fmt.Errorf("ошибка настройки HTTP: %w", err)
+		}
+		client = &http.Client{
+			Transport: transport,
+		}
+	}
+	limiter := optionalRateLimiter(config.RequestsPerSecond, config.RateBurst)
+
+	pm.networkMutex.Lock()
+	previous := pm.rateLimiter
+	repositories := pm.rateLimiters
+	pm.httpClient = client
+	pm.rateLimiter = limiter
+	pm.rateLimiters = nil
+	pm.networkMutex.Unlock()
+
+	// Ограничители репозиториев создаются заново по новым настройкам при следующем запросе
+	previous.Close()
+	for _, entry := range repositories {
+		entry.limiter.Close()
+	}
+	return nil
+}
+
+// Close освобождает сетевые ресурсы при завершении работы: останавливает ограничители
+// частоты запросов и закрывает простаивающие соединения. Кеш архивов и packages.json
+// записываются сразу через временный файл, поэтому сбрасывать на диск нечего.
+func (pm *PackageManager) Close() {
+	pm.networkMutex.Lock()
+	limiter := pm.rateLimiter
+	repositories := pm.rateLimiters
+	client := pm.httpClient
+	pm.rateLimiter = nil
+	pm.rateLimiters = nil
+	pm.networkMutex.Unlock()
+
+	limiter.Close()
+	for _, entry := range repositories {
+		entry.limiter.Close()
+	}
+	if client != nil {
+		client.CloseIdleConnections()
+	}
+}
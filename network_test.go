@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestNetworkProfileRoundTrip проверяет экспорт, импорт и применение сетевого профиля
+func TestNetworkProfileRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	pm := newTestPackageManager(t)
+	pm.getConfig().RequestsPerSecond = 2
+	pm.getConfig().MaxRetries = 3
+
+	strict, err := pm.ExportNetworkProfile("strict")
+	if err != nil {
+		t.Fatalf("ExportNetworkProfile: %v", err)
+	}
+	if strict.RequestsPerSecond != 2 || strict.Timeout != 5 || strict.MaxRetries != 3 {
+		t.Errorf("expected current settings in exported profile, got %+v", strict)
+	}
+
+	if _, err := pm.ImportNetworkProfile(NetworkProfile{Name: "broken", Timeout: -1}); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+	fast := NetworkProfile{Name: "fast", RequestsPerSecond: 50, Timeout: 120, MaxIdleConns: 64, MaxIdleConnsPerHost: 16, IdleConnTimeout: 30}
+	if _, err := pm.ImportNetworkProfile(fast); err != nil {
+		t.Fatalf("ImportNetworkProfile: %v", err)
+	}
+
+	limiter := pm.rateLimiter
+	if _, err := pm.UseNetworkProfile("fast"); err != nil {
+		t.Fatalf("UseNetworkProfile: %v", err)
+	}
+	client, current := pm.network()
+	if current == limiter {
+		t.Error("expected rate limiter to be replaced")
+	}
+	if timeout := pm.requestTimeout("https://example.com/api/v1/search"); timeout != 120*time.Second {
+		t.Errorf("expected request timeout 120s, got %s", timeout)
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("unexpected transport %T", client.Transport)
+	}
+	if transport.MaxIdleConns != 64 || transport.MaxIdleConnsPerHost != 16 || transport.IdleConnTimeout != 30*time.Second {
+		t.Errorf("connection pool settings not applied: %d/%d/%s", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if pm.getConfig().MaxRetries != 0 || pm.getConfig().NetworkProfile != "fast" {
+		t.Errorf("expected config to follow the profile, got retries %d, profile %q", pm.getConfig().MaxRetries, pm.getConfig().NetworkProfile)
+	}
+
+	// Профили и выбор активного переживают перезагрузку конфигурации
+	reloaded, err := loadConfigFile(pm.configPath)
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if reloaded.NetworkProfiles["strict"] != *strict || reloaded.NetworkProfiles["fast"] != fast {
+		t.Errorf("profiles not persisted: %+v", reloaded.NetworkProfiles)
+	}
+	if reloaded.NetworkProfile != "fast" || reloaded.RequestsPerSecond != 50 {
+		t.Errorf("active profile not persisted: %q, %d rps", reloaded.NetworkProfile, reloaded.RequestsPerSecond)
+	}
+
+	if _, err := pm.UseNetworkProfile("missing"); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+// fixtureTransport отвечает на запросы к репозиторию заготовленными ответами по пути URL,
+// не открывая соединений; на неизвестный путь отвечает 404
+type fixtureTransport struct {
+	mu       sync.Mutex
+	routes   map[string]fixtureResponse
+	requests []*http.Request
+}
+
+// fixtureResponse заготовленный ответ: статус и тело
+type fixtureResponse struct {
+	status int
+	body   []byte
+}
+
+func (f *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	route, ok := f.routes[req.URL.Path]
+	f.mu.Unlock()
+	if !ok {
+		route = fixtureResponse{status: http.StatusNotFound, body: []byte(`{"success":false,"error":"not found"}`)}
+	}
+
+	return &http.Response{
+		StatusCode:    route.status,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(route.body)),
+		ContentLength: int64(len(route.body)),
+		Request:       req,
+	}, nil
+}
+
+// handleJSON задает ответ {"success": true, "data": data} для пути
+func (f *fixtureTransport) handleJSON(t *testing.T, path string, data interface{}) {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{"success": true, "data": data})
+	if err != nil {
+		t.Fatalf("marshal fixture: %v", err)
+	}
+	f.handle(path, http.StatusOK, body)
+}
+
+// handle задает ответ для пути
+func (f *fixtureTransport) handle(path string, status int, body []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.routes[path] = fixtureResponse{status: status, body: body}
+}
+
+// paths возвращает пути запросов в порядке поступления
+func (f *fixtureTransport) paths() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	paths := make([]string, len(f.requests))
+	for i, req := range f.requests {
+		paths[i] = req.URL.Path
+	}
+	return paths
+}
+
+// newFixtureManager создает менеджер во временном каталоге, все запросы которого
+// обслуживает fixtureTransport, с единственным репозиторием https://repo.test
+func newFixtureManager(t *testing.T) (*PackageManager, *fixtureTransport) {
+	t.Helper()
+
+	dir := t.TempDir()
+	config := defaultConfig(dir)
+	config.LocalPath = filepath.Join(dir, "local")
+	config.RequestsPerSecond = 0
+	config.Repositories = []Repository{{Name: "fixture", URL: "https://repo.test", Priority: 1, Enabled: true, AuthToken: "secret"}}
+
+	transport := &fixtureTransport{routes: make(map[string]fixtureResponse)}
+	pm, err := NewPackageManagerWithConfig(config, &http.Client{Transport: transport})
+	if err != nil {
+		t.Fatalf("NewPackageManagerWithConfig: %v", err)
+	}
+	t.Cleanup(pm.Close)
+	return pm, transport
+}
+
+// TestFindInRepositoryFixtures проверяет выбор версии и файла платформы по заготовленному
+// описанию пакета
+func TestFindInRepositoryFixtures(t *testing.T) {
+	pm, transport := newFixtureManager(t)
+	files := func(version string) []RepositoryFile {
+		return []RepositoryFile{
+			{OS: "linux", Arch: "amd64", Filename: "tool-" + version + "-linux-amd64.tar.gz", Checksum: "sha256:" + version, Size: 100},
+			{OS: "darwin", Arch: "arm64", Filename: "tool-" + version + "-darwin-arm64.tar.gz", Size: 200},
+		}
+	}
+	transport.handleJSON(t, "/api/v1/packages/tool", &RepositoryPackage{
+		Name:        "tool",
+		Description: "fixture tool",
+		Versions: []RepositoryVersion{
+			{Version: "1.0.0", Files: files("1.0.0")},
+			{Version: "1.1.0+build.7", Files: files("1.1.0+build.7")},
+			{Version: "2.0.0", Files: files("2.0.0")},
+		},
+	})
+	repo := pm.getConfig().Repositories[0]
+	ctx := context.Background()
+
+	tests := []struct {
+		version, osName, arch string
+		wantVersion, wantFile string
+		wantErr               error
+	}{
+		{"", "linux", "amd64", "2.0.0", "tool-2.0.0-linux-amd64.tar.gz", nil},
+		{"1.0.0", "darwin", "arm64", "1.0.0", "tool-1.0.0-darwin-arm64.tar.gz", nil},
+		{"1.1.0", "linux", "amd64", "1.1.0+build.7", "tool-1.1.0+build.7-linux-amd64.tar.gz", nil},
+		{"3.0.0", "linux", "amd64", "", "", ErrVersionNotFound},
+		{"2.0.0", "windows", "amd64", "", "", ErrFileUnavailable},
+	}
+	for _, tt := range tests {
+		resolved, err := pm.findInRepository(ctx, repo, "tool", tt.version, tt.arch, tt.osName)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("version %q on %s/%s: expected %v, got %v", tt.version, tt.osName, tt.arch, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("version %q on %s/%s: %v", tt.version, tt.osName, tt.arch, err)
+			continue
+		}
+		if resolved.Info.Version != tt.wantVersion || resolved.File.Filename != tt.wantFile {
+			t.Errorf("version %q on %s/%s: got %s %s", tt.version, tt.osName, tt.arch, resolved.Info.Version, resolved.File.Filename)
+		}
+		wantURL := "https://repo.test/api/v1/download/tool/" + tt.wantVersion + "/" + tt.wantFile
+		if resolved.DownloadURL != wantURL {
+			t.Errorf("expected download URL %s, got %s", wantURL, resolved.DownloadURL)
+		}
+	}
+
+	if _, err := pm.findInRepository(ctx, repo, "missing", "", "amd64", "linux"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("expected ErrPackageNotFound for unknown package, got %v", err)
+	}
+	transport.mu.Lock()
+	auth := transport.requests[0].Header.Get("Authorization")
+	transport.mu.Unlock()
+	if auth != "Bearer secret" {
+		t.Errorf("expected repository token in request, got %q", auth)
+	}
+}
+
+// TestSearchPackagesFixtures проверяет разбор ответа поиска и параметры запроса
+func TestSearchPackagesFixtures(t *testing.T) {
+	pm, transport := newFixtureManager(t)
+	transport.handleJSON(t, "/api/v1/search", map[string]interface{}{
+		"query": "json",
+		"results": []SearchResult{
+			{Name: "json-tool", Version: "1.2.0", Description: "JSON utilities", Downloads: 10, Score: 1},
+			{Name: "yaml-json", Version: "0.3.0", Downloads: 5, Score: 0.5},
+		},
+		"total": 2,
+	})
+
+	page, err := pm.SearchPackages(context.Background(), "json", SearchFilter{}, 1, 10)
+	if err != nil {
+		t.Fatalf("SearchPackages: %v", err)
+	}
+	if page.Total != 2 || len(page.Results) != 2 || page.Results[0].Name != "json-tool" {
+		t.Fatalf("unexpected search page: %+v", page)
+	}
+	if page.Results[0].Repository != "fixture" {
+		t.Errorf("expected repository name in results, got %q", page.Results[0].Repository)
+	}
+
+	transport.mu.Lock()
+	query := transport.requests[0].URL.Query()
+	transport.mu.Unlock()
+	if query.Get("q") != "json" || query.Get("limit") != "10" {
+		t.Errorf("unexpected search query: %v", query)
+	}
+}
+
+// TestInstallPackageFixtures проверяет установку, при которой описание пакета и архив
+// отдает подставленный транспорт, и сохранение переданного клиента при смене сетевых настроек
+func TestInstallPackageFixtures(t *testing.T) {
+	pm, transport := newFixtureManager(t)
+	archivePath := buildTestArchive(t, pm, PackageManifest{Name: "hello", Version: "1.0.0"},
+		map[string]string{"src/main.txt": "hello"}, FormatTarGz)
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	checksum, err := calculateChecksum(archivePath, defaultChecksumAlgorithm)
+	if err != nil {
+		t.Fatalf("checksum: %v", err)
+	}
+
+	transport.handleJSON(t, "/api/v1/packages/hello", &RepositoryPackage{
+		Name: "hello",
+		Versions: []RepositoryVersion{{Version: "1.0.0", Files: []RepositoryFile{{
+			OS: runtime.GOOS, Arch: runtime.GOARCH, Filename: "hello-1.0.0.tar.gz", Checksum: checksum, Size: int64(len(archive)),
+		}}}},
+	})
+	transport.handle("/api/v1/download/hello/1.0.0/hello-1.0.0.tar.gz", http.StatusOK, archive)
+
+	if _, err := pm.InstallPackage(context.Background(), "hello", "", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("InstallPackage: %v (requests: %v)", err, transport.paths())
+	}
+	data, err := os.ReadFile(filepath.Join(pm.getConfig().LocalPath, "hello", "src", "main.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("package not installed: %q (err %v)", data, err)
+	}
+	downloaded := false
+	for _, path := range transport.paths() {
+		downloaded = downloaded || strings.HasPrefix(path, "/api/v1/download/")
+	}
+	if !downloaded {
+		t.Errorf("expected archive download through the transport, got %v", transport.paths())
+	}
+
+	client, _ := pm.network()
+	pm.getConfig().Timeout = 60
+	if err := pm.reconfigureNetwork(); err != nil {
+		t.Fatalf("reconfigureNetwork: %v", err)
+	}
+	if current, _ := pm.network(); current != client {
+		t.Error("expected injected client to survive network reconfiguration")
+	}
+}
+
+// TestFindInRepositoryUnorderedVersions проверяет, что последняя версия выбирается по semver,
+// даже если репозиторий перечисляет версии не по порядку, а файл берется из выбранной версии
+func TestFindInRepositoryUnorderedVersions(t *testing.T) {
+	pm, transport := newFixtureManager(t)
+	version := func(v string) RepositoryVersion {
+		return RepositoryVersion{Version: v, Files: []RepositoryFile{
+			{OS: "linux", Arch: "arm64", Filename: "tool-" + v + "-arm64.tar.gz"},
+			{OS: "linux", Arch: "amd64", Filename: "tool-" + v + "-amd64.tar.gz"},
+		}}
+	}
+	transport.handleJSON(t, "/api/v1/packages/tool", &RepositoryPackage{
+		Name:     "tool",
+		Versions: []RepositoryVersion{version("2.0.0"), version("10.0.0"), version("10.0.0-rc.1"), version("1.0.0")},
+	})
+	repo := pm.getConfig().Repositories[0]
+
+	for requested, want := range map[string]string{"": "10.0.0", "2.0.0": "2.0.0", "10.0.0-rc.1": "10.0.0-rc.1"} {
+		resolved, err := pm.findInRepository(context.Background(), repo, "tool", requested, "amd64", "linux")
+		if err != nil {
+			t.Fatalf("findInRepository(%q): %v", requested, err)
+		}
+		if resolved.Info.Version != want || resolved.Version.Version != want {
+			t.Errorf("findInRepository(%q): expected %s, got %s", requested, want, resolved.Info.Version)
+		}
+		if file := "tool-" + want + "-amd64.tar.gz"; resolved.File.Filename != file {
+			t.Errorf("findInRepository(%q): expected file %s, got %s", requested, file, resolved.File.Filename)
+		}
+	}
+}
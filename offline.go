@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errNotAvailableOffline сообщает, что для операции в автономном режиме нужна сеть
+var errNotAvailableOffline = errors.New("недоступен в автономном режиме")
+
+type offlineKey struct{}
+
+// withOffline возвращает контекст, в котором операции выполняются без обращения к сети
+// независимо от параметра offline конфигурации
+func withOffline(ctx context.Context) context.Context {
+	return context.WithValue(ctx, offlineKey{}, true)
+}
+
+// offline сообщает, запрещены ли обращения к сети: параметром конфигурации или контекстом вызова
+func (pm *PackageManager) offline(ctx context.Context) bool {
+	if pm.getConfig().Offline {
+		return true
+	}
+	offline, _ := ctx.Value(offlineKey{}).(bool)
+	return offline
+}
+
+// indexedPackage возвращает описание пакета из локального индекса репозитория,
+// сохраненного build_search_index; используется вместо API в автономном режиме
+func (pm *PackageManager) indexedPackage(repo Repository, packageName string) (*RepositoryPackage, error) {
+	indexes, err := pm.loadSearchIndexes(repo.URL)
+	if err != nil {
+		return nil, fmt.Errorf("пакет %s %w: нет локального индекса репозитория %s", packageName, errNotAvailableOffline, repo.Name)
+	}
+
+	for _, index := range indexes {
+		if pkg, ok := index.Packages[packageName]; ok && pkg != nil {
+			return pkg, nil
+		}
+	}
+	return nil, fmt.Errorf("пакет %s %w: его нет в локальном индексе репозитория %s", packageName, errNotAvailableOffline, repo.Name)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestOfflineMode проверяет установку из кеша по локальному индексу без обращения
+// к сети, понятную ошибку для архива не из кеша и поиск по локальному индексу
+func TestOfflineMode(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	for _, name := range []string{"tool", "lib"} {
+		manifest := PackageManifest{Name: name, Version: "1.0.0", Description: name + " package"}
+		repo.publish(t, name, "1.0.0", buildTestArchive(t, pm, manifest, map[string]string{"data.txt": name}, FormatTarGz))
+	}
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	// В сети: индекс сохраняется локально, архив tool попадает в кеш при установке
+	if _, err := pm.BuildSearchIndex(ctx, repo.URL); err != nil {
+		t.Fatalf("BuildSearchIndex: %v", err)
+	}
+	if _, err := pm.InstallPackage(ctx, "tool", "", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("InstallPackage: %v", err)
+	}
+	if err := pm.UninstallPackage("tool", false, true); err != nil {
+		t.Fatalf("UninstallPackage: %v", err)
+	}
+
+	// Репозиторий недоступен: любое обращение к сети завершилось бы ошибкой
+	downloads := repo.downloadCount()
+	repo.Close()
+	pm.getConfig().Offline = true
+
+	if _, err := pm.InstallPackage(ctx, "tool", "", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("offline install of cached package: %v", err)
+	}
+	if info, ok := pm.getInstalledPackage("tool"); !ok || info.Version != "1.0.0" {
+		t.Errorf("expected tool to be installed from cache, got %+v", info)
+	}
+	if repo.downloadCount() != downloads {
+		t.Error("offline install must not download")
+	}
+
+	_, err := pm.InstallPackage(ctx, "lib", "", false, false, false, false, "", "")
+	if !errors.Is(err, errNotAvailableOffline) || !strings.Contains(err.Error(), "кеше") {
+		t.Errorf("expected not available offline error for uncached archive, got %v", err)
+	}
+	_, err = pm.InstallPackage(ctx, "unknown", "", false, false, false, false, "", "")
+	if !errors.Is(err, errNotAvailableOffline) || !strings.Contains(err.Error(), "индексе") {
+		t.Errorf("expected not available offline error for unindexed package, got %v", err)
+	}
+
+	page, err := pm.SearchPackages(ctx, "lib", SearchFilter{}, 1, 20)
+	if err != nil {
+		t.Fatalf("offline SearchPackages: %v", err)
+	}
+	if page.Total != 1 || len(page.Results) != 1 || page.Results[0].Name != "lib" {
+		t.Errorf("expected lib from the local index, got %+v", page)
+	}
+}
+
+// TestOfflineInstallArgument проверяет, что аргумент offline инструмента запрещает
+// обращения к сети при выключенном offline в конфигурации
+func TestOfflineInstallArgument(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	repo.publish(t, "tool", "1.0.0", buildTestArchive(t, pm, PackageManifest{Name: "tool", Version: "1.0.0"}, nil, FormatTarGz))
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	s := newTestServer(t, pm)
+
+	_, err := callToolText(t, s, "install_package", map[string]interface{}{"name": "tool", "offline": true})
+	if !errors.Is(err, errNotAvailableOffline) {
+		t.Fatalf("expected offline install without index to fail, got %v", err)
+	}
+	if repo.downloadCount() != 0 {
+		t.Error("offline install must not download")
+	}
+
+	if _, err := callToolText(t, s, "install_package", map[string]interface{}{"name": "tool"}); err != nil {
+		t.Fatalf("online install: %v", err)
+	}
+}
@@ -2,103 +2,96 @@ package main
 
 import (
 	"bytes"
-
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// RateLimiter простой rate limiter для HTTP запросов
-type RateLimiter struct {
-	ticker   *time.Ticker
-	requests chan struct{}
-	done     chan struct{}
-}
-
-// NewRateLimiter создает новый rate limiter с заданной частотой запросов в секунду
-func NewRateLimiter(requestsPerSecond int) *RateLimiter {
-	if requestsPerSecond <= 0 {
-		requestsPerSecond = 10 // по умолчанию 10 запросов в секунду
-	}
-
-	interval := time.Second / time.Duration(requestsPerSecond)
-	ticker := time.NewTicker(interval)
-	requests := make(chan struct{}, 1)
-	// Стартовое «разрешение»
-	requests <- struct{}{}
-
-	rl := &RateLimiter{
-		ticker:   ticker,
-		requests: requests,
-		done:     make(chan struct{}),
-	}
-
-	// Запускаем горутину для пополнения буфера
-	go func() {
-		defer close(requests)
-		for {
-			select {
-			case <-ticker.C:
-				// Тик добавляет одно «разрешение», не накапливая больше одного
-				select {
-				case requests <- struct{}{}:
-				default:
-				}
-			case <-rl.done:
-				return
-			}
-		}
-	}()
-
-	return rl
-}
-
-// Wait ждет разрешения на выполнение запроса
-func (rl *RateLimiter) Wait() {
-	<-rl.requests
-}
-
-// Close останавливает rate limiter
-func (rl *RateLimiter) Close() {
-	rl.ticker.Stop()
-	close(rl.done)
-}
-
 // PackageManager основной менеджер пакетов
 type PackageManager struct {
-	config            *Config
+	config            atomic.Pointer[Config] // опубликованная конфигурация не меняется, см. updateConfig
+	configPath        string                 // файл, в который сохраняются изменения конфигурации
+	configMutex       sync.Mutex             // упорядочивает изменения и сохранение конфигурации
 	installedPackages map[string]*PackageInfo
 	packagesMutex     sync.RWMutex
+	packagesFileMutex sync.Mutex // упорядочивает чтение, изменение и запись packages.json
 	httpClient        *http.Client
-	rateLimiter       *RateLimiter
+	customClient      bool                         // httpClient передан снаружи и не пересоздается по сетевым настройкам
+	rateLimiter       *RateLimiter                 // запросы вне настроенных репозиториев
+	rateLimiters      map[string]repositoryLimiter // по имени репозитория
+	networkMutex      sync.RWMutex                 // защищает httpClient и ограничители частоты при смене сетевых настроек
+	health            *healthTracker
+	stats             *statsCache
+	metrics           *installMetrics
 }
 
-// NewPackageManager создает новый пакетный менеджер
+// NewPackageManager создает пакетный менеджер с конфигурацией из файла (см. configFilePath);
+// изменения конфигурации сохраняются в этот файл
 func NewPackageManager() (*PackageManager, error) {
-	config, err := loadConfig()
+	configPath, err := configFilePath()
+	if err != nil {
+		return nil, err
+	}
+	config, err := loadConfigFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка загрузки конфигурации: %w", err)
 	}
 
-	httpClient := &http.Client{
-		Timeout: time.Duration(config.Timeout) * time.Second,
+	if err := logger.Configure(config.LogLevel, config.LogFile); err != nil {
+		logger.Warnf("Некорректные настройки журнала: %v", err)
+	}
+
+	pm, err := NewPackageManagerWithConfig(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	pm.configPath = configPath
+	return pm, nil
+}
+
+// NewPackageManagerWithConfig создает пакетный менеджер с готовой конфигурацией, не читая
+// файлов из домашнего каталога. client задает HTTP-клиент для запросов к репозиториям
+// (nil — клиент по сетевым настройкам config с таймаутами из конфигурации). Переданный
+// клиент сохраняется и при смене сетевых параметров, поэтому через его Transport тесты
+// подставляют заготовленные ответы. Такой менеджер не сохраняет изменения конфигурации на диск.
+func NewPackageManagerWithConfig(config *Config, client *http.Client) (*PackageManager, error) {
+	customClient := client != nil
+	if client == nil {
+		transport, err := newHTTPTransport(config, nil)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка настройки HTTP: %w", err)
+		}
+
+		// Таймауты задаются контекстом каждого запроса (см. requestTimeout)
+		client = &http.Client{
+			Transport: transport,
+		}
 	}
 
 	pm := &PackageManager{
-		config:            config,
 		installedPackages: make(map[string]*PackageInfo),
-		httpClient:        httpClient,
-		rateLimiter:       NewRateLimiter(5), // 5 запросов в секунду
+		httpClient:        client,
+		customClient:      customClient,
+		rateLimiter:       optionalRateLimiter(config.RequestsPerSecond, config.RateBurst),
+		health:            newHealthTracker(),
+		stats:             newStatsCache(),
+		metrics:           newInstallMetrics(),
 	}
+	pm.config.Store(config)
 
 	// Создаем необходимые директории
 	if err := pm.ensureDirectories(); err != nil {
@@ -113,17 +106,49 @@ func NewPackageManager() (*PackageManager, error) {
 	return pm, nil
 }
 
+// Переменные окружения, позволяющие запускать изолированные экземпляры сервера
+const (
+	envConfigPath = "CRIAGE_CONFIG" // путь к файлу конфигурации
+	envHome       = "CRIAGE_HOME"   // базовый каталог вместо ~/.criage
+)
+
+// criageHome возвращает базовый каталог criage: CRIAGE_HOME или ~/.criage
+func criageHome() (string, error) {
+	if home := os.Getenv(envHome); home != "" {
+		return filepath.Abs(home)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".criage"), nil
+}
+
+// configFilePath возвращает путь к файлу конфигурации: CRIAGE_CONFIG или config.json
+// в базовом каталоге
+func configFilePath() (string, error) {
+	if path := os.Getenv(envConfigPath); path != "" {
+		return filepath.Abs(path)
+	}
+	home, err := criageHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "config.json"), nil
+}
+
 // loadConfig загружает конфигурацию
 func loadConfig() (*Config, error) {
-	homeDir, err := os.UserHomeDir()
+	configPath, err := configFilePath()
 	if err != nil {
 		return nil, err
 	}
+	return loadConfigFile(configPath)
+}
 
-	configPath := filepath.Join(homeDir, ".criage", "config.json")
-
-	// Создаем конфигурацию по умолчанию
-	config := &Config{
+// defaultConfig возвращает конфигурацию по умолчанию с каталогами внутри home
+func defaultConfig(home string) *Config {
+	return &Config{
 		Repositories: []Repository{
 			{
 				Name:     "criage-main",
@@ -132,15 +157,29 @@ func loadConfig() (*Config, error) {
 				Enabled:  true,
 			},
 		},
-		GlobalPath:       filepath.Join(homeDir, ".criage", "packages"),
+		GlobalPath:       filepath.Join(home, "packages"),
 		LocalPath:        "./criage_modules",
-		CachePath:        filepath.Join(homeDir, ".criage", "cache"),
-		TempPath:         filepath.Join(homeDir, ".criage", "temp"),
+		CachePath:        filepath.Join(home, "cache"),
+		TempPath:         filepath.Join(home, "temp"),
 		Timeout:          30,
 		MaxConcurrency:   4,
 		CompressionLevel: 3,
 		ForceHTTPS:       false,
+		MaxCacheSize:     1 << 30,
+		MaxDownloadSize:  2 << 30,
+
+		RequestsPerSecond: 5,
+		MaxRetries:        1,
+	}
+}
+
+// loadConfigFile загружает конфигурацию из файла, создавая его со значениями по умолчанию
+func loadConfigFile(configPath string) (*Config, error) {
+	home, err := criageHome()
+	if err != nil {
+		return nil, err
 	}
+	config := defaultConfig(home)
 
 	// Если файл конфигурации существует, загружаем его
 	if _, err := os.Stat(configPath); err == nil {
@@ -152,33 +191,48 @@ func loadConfig() (*Config, error) {
 		if err := json.Unmarshal(data, config); err != nil {
 			return nil, err
 		}
+
+		// Переписываем конфигурацию, если токены записаны под устаревшим ключом "token"
+		for _, repo := range config.Repositories {
+			if repo.legacyToken {
+				if err := writeConfig(configPath, config); err != nil {
+					logger.Warnf("Не удалось обновить конфигурацию: %v", err)
+				}
+				break
+			}
+		}
 	} else {
 		// Создаем файл конфигурации по умолчанию
-		configDir := filepath.Dir(configPath)
-		if err := os.MkdirAll(configDir, 0755); err != nil {
+		if err := writeConfig(configPath, config); err != nil {
 			return nil, err
 		}
+	}
 
-		data, err := json.MarshalIndent(config, "", "  ")
-		if err != nil {
-			return nil, err
-		}
+	return config, nil
+}
 
-		if err := os.WriteFile(configPath, data, 0644); err != nil {
-			return nil, err
-		}
+// writeConfig сохраняет конфигурацию в файл
+func writeConfig(configPath string, config *Config) error {
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
 	}
 
-	return config, nil
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// В конфигурации хранятся токены репозиториев и учетные данные прокси
+	return writeFileAtomic(configPath, data, 0600)
 }
 
 // ensureDirectories создает необходимые директории
 func (pm *PackageManager) ensureDirectories() error {
 	dirs := []string{
-		pm.config.GlobalPath,
-		pm.config.LocalPath,
-		pm.config.CachePath,
-		pm.config.TempPath,
+		pm.getConfig().GlobalPath,
+		pm.getConfig().LocalPath,
+		pm.getConfig().CachePath,
+		pm.getConfig().TempPath,
 	}
 
 	for _, dir := range dirs {
@@ -190,74 +244,361 @@ func (pm *PackageManager) ensureDirectories() error {
 	return nil
 }
 
-// InstallPackage устанавливает пакет
-func (pm *PackageManager) InstallPackage(packageName, version string, global, force, dev bool, arch, osName string) error {
+// InstallPackage устанавливает пакет вместе с недостающими зависимостями (с dev — и
+// с зависимостями разработки) и возвращает план выполненных действий. Зависимости
+// отмечаются как установленные автоматически. В режиме dryRun план только
+// составляется: архивы не скачиваются и на диск ничего не записывается.
+func (pm *PackageManager) InstallPackage(ctx context.Context, packageName, version string, global, force, dev, dryRun bool, arch, osName string) (*InstallPlan, error) {
+	return pm.installPackage(ctx, packageName, version, global, force, dev, dryRun, false, arch, osName)
+}
+
+// installPackage устанавливает пакет; automatic задает отметку самого пакета
+// (обновление сохраняет отметку, с которой пакет был установлен)
+func (pm *PackageManager) installPackage(ctx context.Context, packageName, version string, global, force, dev, dryRun, automatic bool, arch, osName string) (*InstallPlan, error) {
 	// Проверяем, не установлен ли уже пакет
 	if !force {
 		if info, exists := pm.getInstalledPackage(packageName); exists {
-			if version == "" || info.Version == version {
-				return fmt.Errorf("пакет %s (%s) уже установлен", packageName, info.Version)
+			if version == "" || info.Version == version || info.ResolvedVersion == version {
+				return nil, errorWithCause(ErrAlreadyInstalled, "пакет %s (%s) уже установлен", packageName, info.Version)
 			}
 		}
 	}
 
-	// Определяем архитектуру и ОС
-	if arch == "" {
-		arch = runtime.GOARCH
+	// Поиск пакета в репозиториях для целевой платформы
+	resolved, err := pm.findPackage(ctx, packageName, version, arch, osName)
+	if err != nil {
+		return nil, fmt.Errorf("пакет не найден: %w", err)
+	}
+	resolved.Automatic = automatic
+
+	// Все зависимости разрешаются до установки: если какой-то нет, ничего не ставится
+	actions, sources, err := pm.resolveDependencies(ctx, resolved, global, dev, arch, osName)
+	if err != nil {
+		return nil, err
 	}
-	if osName == "" {
-		osName = runtime.GOOS
+
+	plan := &InstallPlan{DryRun: dryRun, Actions: append([]InstallAction{pm.installAction(resolved, global)}, actions...)}
+	for _, action := range actions {
+		if action.Action == InstallActionConflict {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("зависимость %s: установлена версия %s, требуется %s", action.Name, action.InstalledVersion, action.Constraint))
+		}
+	}
+	if dryRun {
+		return plan, nil
 	}
 
-	// Поиск пакета в репозиториях
-	packageInfo, downloadURL, err := pm.findPackage(packageName, version, arch, osName)
+	started := time.Now()
+	plan.Timing = &InstallTiming{}
+	for _, source := range sources {
+		_, timing, err := pm.installResolved(ctx, source, global, false)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка установки зависимости %s: %w", source.Info.Name, err)
+		}
+		plan.recordTiming(source.Info.Name, timing)
+	}
+	_, timing, err := pm.installResolved(ctx, resolved, global, force)
 	if err != nil {
-		return fmt.Errorf("пакет не найден: %w", err)
+		return nil, err
 	}
+	plan.recordTiming(resolved.Info.Name, timing)
+	plan.Timing.TotalMs = durationMs(time.Since(started))
+	return plan, nil
+}
 
-	// Скачиваем пакет
-	archivePath, err := pm.downloadPackage(downloadURL, packageName, packageInfo.Version)
+// recordTiming запоминает длительность установки пакета в его действии и в сумме плана
+func (plan *InstallPlan) recordTiming(name string, timing *InstallTiming) {
+	for i := range plan.Actions {
+		action := &plan.Actions[i]
+		if action.Name == name && (action.Action == InstallActionInstall || action.Action == InstallActionReplace) {
+			action.Timing = timing
+			break
+		}
+	}
+	plan.Timing.add(timing)
+}
+
+// installAction описывает установку найденного в репозитории пакета
+func (pm *PackageManager) installAction(resolved *resolvedPackage, global bool) InstallAction {
+	action := InstallAction{
+		Name:        resolved.Info.Name,
+		Version:     resolved.Version.Version,
+		Action:      InstallActionInstall,
+		Dependency:  resolved.Automatic,
+		Cached:      pm.inCache(resolved.File.Checksum),
+		DownloadURL: resolved.DownloadURL,
+	}
+	// Имя проверено при поиске в репозитории (findInRepository)
+	action.Path, _ = pm.getInstallPath(resolved.Info.Name, global)
+	if info, exists := pm.getInstalledPackage(resolved.Info.Name); exists {
+		action.Action = InstallActionReplace
+		action.InstalledVersion = info.Version
+	}
+	return action
+}
+
+// resolveDependencies разрешает транзитивные зависимости устанавливаемого пакета.
+// Возвращает действия для каждой зависимости и найденные в репозиториях источники
+// для тех, что еще не установлены. Установленные зависимости не заменяются.
+func (pm *PackageManager) resolveDependencies(ctx context.Context, resolved *resolvedPackage, global, dev bool, arch, osName string) ([]InstallAction, []*resolvedPackage, error) {
+	rootDeps := resolved.Version.Dependencies
+	if dev && len(resolved.Version.DevDeps) > 0 {
+		rootDeps = make(map[string]string, len(resolved.Version.Dependencies)+len(resolved.Version.DevDeps))
+		for name, constraint := range resolved.Version.DevDeps {
+			rootDeps[name] = constraint
+		}
+		for name, constraint := range resolved.Version.Dependencies {
+			rootDeps[name] = constraint
+		}
+	}
+	if len(rootDeps) == 0 {
+		return nil, nil, nil
+	}
+
+	root := resolved.Info.Name + "@" + resolved.Version.Version
+	dependencies, err := newDependencyResolver(pm).resolve(ctx, root, rootDeps)
 	if err != nil {
-		return fmt.Errorf("ошибка скачивания: %w", err)
+		return nil, nil, fmt.Errorf("не удалось разрешить зависимости: %w", err)
+	}
+
+	var actions []InstallAction
+	var sources []*resolvedPackage
+	for _, dep := range dependencies {
+		// Зависимость на сам устанавливаемый пакет замыкает цикл
+		if dep.Name == resolved.Info.Name {
+			continue
+		}
+
+		if info, exists := pm.getInstalledPackage(dep.Name); exists {
+			action := InstallAction{
+				Name:             dep.Name,
+				Version:          dep.Version,
+				Action:           InstallActionSatisfied,
+				Path:             info.InstallPath,
+				Dependency:       true,
+				Constraint:       dep.Combined,
+				InstalledVersion: info.Version,
+			}
+			if !satisfiesAll(info.Version, dep.Constraints) {
+				action.Action = InstallActionConflict
+			}
+			actions = append(actions, action)
+			continue
+		}
+
+		source, err := pm.findPackage(ctx, dep.Name, dep.Version, arch, osName)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			return nil, nil, fmt.Errorf("зависимость %s@%s недоступна: %w", dep.Name, dep.Version, err)
+		}
+		source.Automatic = true
+		action := pm.installAction(source, global)
+		action.Constraint = dep.Combined
+		actions = append(actions, action)
+		sources = append(sources, source)
+	}
+
+	return actions, sources, nil
+}
+
+// satisfiesAll сообщает, удовлетворяет ли версия ограничениям всех требующих пакетов
+func satisfiesAll(version string, reqs []RequirerConstraint) bool {
+	for _, req := range reqs {
+		constraint, err := parseConstraint(req.Constraint)
+		if err != nil || !constraint.satisfies(version) {
+			return false
+		}
+	}
+	return true
+}
+
+// installResolved скачивает найденный в репозитории архив и устанавливает его,
+// возвращая длительность этапов установки
+func (pm *PackageManager) installResolved(ctx context.Context, resolved *resolvedPackage, global, force bool) (*PackageInfo, *InstallTiming, error) {
+	timing := &InstallTiming{}
+
+	// Скачиваем пакет (или берем из кеша по контрольной сумме)
+	started := time.Now()
+	archivePath, temporary, err := pm.fetchResolved(ctx, resolved)
+	if err != nil {
+		pm.metrics.recordInstall(0, err)
+		return nil, nil, err
+	}
+	if temporary {
+		defer os.Remove(archivePath)
+	}
+	timing.DownloadMs = durationMs(time.Since(started))
+
+	// Устанавливаем пакет из скачанного архива
+	info, err := pm.installFromArchiveTimed(ctx, archivePath, global, force, resolved, timing)
+	if err != nil {
+		return nil, nil, err
+	}
+	return info, timing, nil
+}
+
+// fetchResolved возвращает архив найденной версии пакета: из кеша или скачанный.
+// Перед скачиванием наличие архива проверяется запросом HEAD, чтобы отсутствие файла
+// для платформы обнаруживалось до начала загрузки; после — проверяется подпись.
+func (pm *PackageManager) fetchResolved(ctx context.Context, resolved *resolvedPackage) (path string, temporary bool, err error) {
+	size := int64(-1)
+	if !pm.inCache(resolved.File.Checksum) {
+		if size, err = pm.probeDownload(ctx, resolved); err != nil {
+			return "", false, err
+		}
+	}
+
+	path, temporary, err = pm.fetchArchive(ctx, resolved.DownloadURL, resolved.File.Checksum, resolved.Info.Name, resolved.Info.Version, size)
+	if err != nil {
+		return "", false, fmt.Errorf("ошибка скачивания: %w", err)
+	}
+
+	// Подпись проверяется до извлечения, в том числе для архива из кеша
+	if err := verifyResolvedSignature(path, resolved); err != nil {
+		if temporary {
+			os.Remove(path)
+		}
+		return "", false, err
+	}
+	return path, temporary, nil
+}
+
+// InstallFromURL устанавливает пакет из архива по прямой ссылке, минуя API репозитория
+func (pm *PackageManager) InstallFromURL(ctx context.Context, rawURL, checksum string, global, force bool) (*PackageInfo, error) {
+	if err := pm.validateDownloadURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	// Политика URL проверяется и для каждого перенаправления при скачивании
+	ctx = withRedirectPolicy(ctx)
+
+	timing := &InstallTiming{}
+	started := time.Now()
+	archivePath, temporary, err := pm.fetchArchive(ctx, rawURL, checksum, "url", fmt.Sprintf("%d", time.Now().UnixNano()), -1)
+	if err != nil {
+		pm.metrics.recordInstall(0, err)
+		return nil, fmt.Errorf("ошибка скачивания: %w", err)
+	}
+	if temporary {
+		defer os.Remove(archivePath)
+	}
+	timing.DownloadMs = durationMs(time.Since(started))
+
+	return pm.installFromArchiveTimed(ctx, archivePath, global, force, nil, timing)
+}
+
+// InstallFromFile устанавливает пакет из архива на диске, минуя репозитории. Если архив
+// содержит контрольные суммы файлов, содержимое сверяется с ними до установки;
+// verified сообщает, была ли выполнена такая проверка.
+func (pm *PackageManager) InstallFromFile(ctx context.Context, archivePath string, global, force bool) (info *PackageInfo, verified bool, err error) {
+	stat, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, false, fmt.Errorf("архив недоступен: %w", err)
+	}
+	if stat.IsDir() {
+		return nil, false, fmt.Errorf("%s является каталогом, а не архивом", archivePath)
 	}
-	defer os.Remove(archivePath)
+
+	metadata, err := verifyArchiveContents(archivePath)
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка проверки архива: %w", err)
+	}
+	if metadata != nil && metadata.PackageManifest != nil {
+		logger.Debugf("Установка %s (%s) из файла %s", metadata.PackageManifest.Name, metadata.PackageManifest.Version, archivePath)
+	}
+
+	info, err = pm.installFromArchive(ctx, archivePath, global, force, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	return info, metadata != nil && len(metadata.Checksums) > 0, nil
+}
+
+// installFromArchive извлекает архив, читает встроенный манифест и устанавливает пакет.
+// source описывает найденную в репозитории версию (nil при установке не из репозитория).
+func (pm *PackageManager) installFromArchive(ctx context.Context, archivePath string, global, force bool, source *resolvedPackage) (*PackageInfo, error) {
+	return pm.installFromArchiveTimed(ctx, archivePath, global, force, source, &InstallTiming{})
+}
+
+// installFromArchiveTimed устанавливает пакет из архива, записывая в timing длительность
+// извлечения и копирования и общее время с учетом скачивания, измеренного вызывающим.
+// Установка учитывается в счетчиках metrics.
+func (pm *PackageManager) installFromArchiveTimed(ctx context.Context, archivePath string, global, force bool, source *resolvedPackage, timing *InstallTiming) (info *PackageInfo, err error) {
+	started := time.Now()
+	defer func() {
+		timing.TotalMs = timing.DownloadMs + durationMs(time.Since(started))
+		pm.metrics.recordInstall(timing.TotalMs, err)
+	}()
 
 	// Извлекаем архив
-	tempDir := filepath.Join(pm.config.TempPath, fmt.Sprintf("install_%s_%d", packageName, time.Now().Unix()))
+	tempDir := filepath.Join(pm.getConfig().TempPath, fmt.Sprintf("install_%d", time.Now().UnixNano()))
 	defer os.RemoveAll(tempDir)
 
-	if err := pm.extractArchive(archivePath, tempDir); err != nil {
-		return fmt.Errorf("ошибка извлечения: %w", err)
+	skippedSymlinks, err := pm.extractArchive(ctx, archivePath, tempDir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка извлечения: %w", err)
 	}
+	timing.ExtractMs = durationMs(time.Since(started))
 
 	// Загружаем манифест пакета
 	manifest, err := pm.loadManifestFromDir(tempDir)
 	if err != nil {
-		return fmt.Errorf("ошибка загрузки манифеста: %w", err)
+		return nil, fmt.Errorf("ошибка загрузки манифеста: %w", err)
+	}
+	if manifest.Name == "" {
+		return nil, fmt.Errorf("манифест не содержит имя пакета")
+	}
+	// Путь установки строится из имени, поэтому оно проверяется до блокировки и замены каталога
+	installPath, err := pm.getInstallPath(manifest.Name, global)
+	if err != nil {
+		return nil, fmt.Errorf("недопустимый манифест: %w", err)
 	}
 
-	// Определяем путь установки
-	installPath := pm.getInstallPath(packageName, global)
+	// Параллельные установки одного пакета выполняются по очереди
+	lock, err := pm.lockPackage(manifest.Name, global)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
 
-	// Удаляем старую версию, если она есть
-	if force {
-		if err := os.RemoveAll(installPath); err != nil {
-			return fmt.Errorf("ошибка удаления старой версии: %w", err)
+	// Проверяем, не установлен ли уже пакет
+	if !force {
+		if info, exists := pm.getInstalledPackage(manifest.Name); exists {
+			// Сборки одной версии различаются по точной версии из репозитория
+			sameVersion := info.Version == manifest.Version
+			if source != nil && info.ResolvedVersion != "" {
+				sameVersion = info.ResolvedVersion == source.Version.Version
+			}
+			if sameVersion {
+				return nil, errorWithCause(ErrAlreadyInstalled, "пакет %s (%s) уже установлен", manifest.Name, info.Version)
+			}
 		}
 	}
 
+	// Изменения файловой системы записываются в журнал: при любой ошибке
+	// они отменяются, и на диске не остается частично установленного пакета
+	journal := &installJournal{}
+
+	// Старая версия переносится в резервную копию и удаляется только после успешной установки
+	if err := journal.replaceDir(installPath); err != nil {
+		return nil, journal.rollback(fmt.Errorf("ошибка удаления старой версии: %w", err))
+	}
+
 	// Создаем директорию установки
-	if err := os.MkdirAll(installPath, 0755); err != nil {
-		return fmt.Errorf("ошибка создания директории: %w", err)
+	if err := journal.mkdirAll(installPath, 0755); err != nil {
+		return nil, journal.rollback(fmt.Errorf("ошибка создания директории: %w", err))
 	}
 
 	// Копируем файлы
+	copyStarted := time.Now()
 	if err := pm.copyFiles(tempDir, installPath); err != nil {
-		return fmt.Errorf("ошибка копирования файлов: %w", err)
+		return nil, journal.rollback(fmt.Errorf("ошибка копирования файлов: %w", err))
 	}
+	timing.CopyMs = durationMs(time.Since(copyStarted))
 
 	// Создаем информацию о пакете
-	packageInfo = &PackageInfo{
+	packageInfo := &PackageInfo{
 		Name:         manifest.Name,
 		Version:      manifest.Version,
 		Description:  manifest.Description,
@@ -270,19 +611,34 @@ func (pm *PackageManager) InstallPackage(packageName, version string, global, fo
 		Size:         pm.calculateDirSize(installPath),
 		Files:        manifest.Files,
 		Scripts:      manifest.Scripts,
+		Keywords:     manifest.Keywords,
+		Config:       manifest.Config,
+
+		SkippedSymlinks: skippedSymlinks,
+		PreservedConfig: pm.PreservedConfig(manifest.Name, global),
+	}
+
+	// Запоминаем точную версию из репозитория, включая метаданные сборки
+	if source != nil {
+		packageInfo.ResolvedVersion = source.Version.Version
+		packageInfo.Checksum = source.File.Checksum
+		packageInfo.Automatic = source.Automatic
 	}
 
 	// Сохраняем информацию о пакете
 	if err := pm.savePackageInfo(packageInfo); err != nil {
-		return fmt.Errorf("ошибка сохранения информации о пакете: %w", err)
+		return nil, journal.rollback(fmt.Errorf("ошибка сохранения информации о пакете: %w", err))
 	}
+	journal.commit()
 
 	// Обновляем кеш установленных пакетов
 	pm.packagesMutex.Lock()
-	pm.installedPackages[packageName] = packageInfo
+	pm.installedPackages[packageInfo.Name] = packageInfo
 	pm.packagesMutex.Unlock()
 
-	return nil
+	logger.Infof("Пакет %s (%s) установлен в %s", packageInfo.Name, packageInfo.Version, installPath)
+
+	return packageInfo, nil
 }
 
 // UninstallPackage удаляет пакет
@@ -290,7 +646,24 @@ func (pm *PackageManager) UninstallPackage(packageName string, global, purge boo
 	// Проверяем, установлен ли пакет
 	packageInfo, exists := pm.getInstalledPackage(packageName)
 	if !exists {
-		return fmt.Errorf("пакет %s не установлен", packageName)
+		return errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	lock, err := pm.lockPackage(packageName, global)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Без purge файлы конфигурации сохраняются для следующей установки, с purge удаляется и ранее сохраненная
+	if purge {
+		if err := os.RemoveAll(pm.configBackupPath(packageName, packageInfo.Global)); err != nil {
+			return fmt.Errorf("ошибка удаления сохраненной конфигурации: %w", err)
+		}
+	} else if len(packageInfo.Config) > 0 {
+		if _, err := pm.preserveConfig(packageInfo); err != nil {
+			return fmt.Errorf("ошибка сохранения конфигурации: %w", err)
+		}
 	}
 
 	// Удаляем файлы пакета
@@ -311,59 +684,322 @@ func (pm *PackageManager) UninstallPackage(packageName string, global, purge boo
 	return nil
 }
 
-// UpdatePackage обновляет пакет
-func (pm *PackageManager) UpdatePackage(packageName string) error {
+// Platform сообщает определенную платформу, значения по умолчанию из конфигурации и
+// действующую платформу с учетом переданных arch и osName.
+// Приоритет: аргумент вызова, затем default_arch/default_os, затем runtime.
+func (pm *PackageManager) Platform(arch, osName string) *PlatformInfo {
+	info := &PlatformInfo{
+		DetectedOS:   runtime.GOOS,
+		DetectedArch: runtime.GOARCH,
+		DefaultOS:    pm.getConfig().DefaultOS,
+		DefaultArch:  pm.getConfig().DefaultArch,
+	}
+	info.OS, info.OSSource = choosePlatformValue(osName, pm.getConfig().DefaultOS, runtime.GOOS)
+	info.Arch, info.ArchSource = choosePlatformValue(arch, pm.getConfig().DefaultArch, runtime.GOARCH)
+	return info
+}
+
+// targetPlatform возвращает действующие архитектуру и ОС
+func (pm *PackageManager) targetPlatform(arch, osName string) (string, string) {
+	info := pm.Platform(arch, osName)
+	return info.Arch, info.OS
+}
+
+// choosePlatformValue выбирает первое непустое значение и его источник
+func choosePlatformValue(argument, configured, detected string) (string, string) {
+	switch {
+	case argument != "":
+		return argument, PlatformFromArgument
+	case configured != "":
+		return configured, PlatformFromConfig
+	default:
+		return detected, PlatformFromRuntime
+	}
+}
+
+// UpdatePackage обновляет пакет. Закрепленный пакет не обновляется и возвращается как пропущенный.
+func (pm *PackageManager) UpdatePackage(ctx context.Context, packageName string) (*PackageUpdateResult, error) {
 	// Проверяем, установлен ли пакет
 	currentInfo, exists := pm.getInstalledPackage(packageName)
 	if !exists {
-		return fmt.Errorf("пакет %s не установлен", packageName)
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	result := &PackageUpdateResult{Name: packageName, FromVersion: currentInfo.Version}
+	if currentInfo.Pinned {
+		result.ToVersion = currentInfo.Version
+		result.Skipped = true
+		return result, nil
 	}
 
 	// Ищем последнюю версию
-	latestInfo, _, err := pm.findPackage(packageName, "", runtime.GOARCH, runtime.GOOS)
+	latest, err := pm.findPackage(ctx, packageName, "", "", "")
 	if err != nil {
-		return fmt.Errorf("не удалось найти обновления: %w", err)
+		return nil, fmt.Errorf("не удалось найти обновления: %w", err)
 	}
+	latestInfo := latest.Info
 
-	// Проверяем, нужно ли обновление
-	if currentInfo.Version == latestInfo.Version {
-		return fmt.Errorf("пакет %s уже имеет последнюю версию (%s)", packageName, currentInfo.Version)
+	// Проверяем, нужно ли обновление: версии сравниваются по semver, поэтому "1.0" равна
+	// "1.0.0", а установленная версия новее репозиторной не откатывается
+	switch cmp := compareVersions(latestInfo.Version, currentInfo.Version); {
+	case cmp == 0:
+		return nil, fmt.Errorf("пакет %s уже имеет последнюю версию (%s)", packageName, currentInfo.Version)
+	case cmp < 0:
+		return nil, fmt.Errorf("установленная версия %s пакета %s новее последней в репозиториях (%s); для отката используйте downgrade_package",
+			currentInfo.Version, packageName, latestInfo.Version)
 	}
 
 	// Устанавливаем новую версию
-	return pm.InstallPackage(packageName, latestInfo.Version, currentInfo.Global, true, false, "", "")
+	if _, err := pm.installPackage(ctx, packageName, latestInfo.Version, currentInfo.Global, true, false, false, currentInfo.Automatic, "", ""); err != nil {
+		return nil, err
+	}
+
+	result.ToVersion = latestInfo.Version
+	result.Success = true
+	return result, nil
 }
 
-// SearchPackages выполняет поиск пакетов
-func (pm *PackageManager) SearchPackages(query string) ([]SearchResult, error) {
-	var allResults []SearchResult
+// PinPackage закрепляет установленный пакет, чтобы update_package и update_all его не трогали.
+// Если указана версия, она должна совпадать с установленной.
+func (pm *PackageManager) PinPackage(packageName, version string) (*PackageInfo, error) {
+	return pm.setPinned(packageName, version, true)
+}
+
+// UnpinPackage снимает закрепление пакета
+func (pm *PackageManager) UnpinPackage(packageName string) (*PackageInfo, error) {
+	return pm.setPinned(packageName, "", false)
+}
+
+// setPinned меняет закрепление пакета и сохраняет его в packages.json
+func (pm *PackageManager) setPinned(packageName, version string, pinned bool) (*PackageInfo, error) {
+	info, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+	if version != "" && compareVersions(version, info.Version) != 0 {
+		return nil, fmt.Errorf("установлена версия %s, а не %s; сначала установите нужную версию", info.Version, version)
+	}
+
+	// Запись заменяется копией: ранее выданные указатели на PackageInfo не меняются
+	updated := *info
+	updated.Pinned = pinned
+	updated.PinnedVersion = ""
+	if pinned {
+		updated.PinnedVersion = info.Version
+	}
+	pm.packagesMutex.Lock()
+	pm.installedPackages[packageName] = &updated
+	pm.packagesMutex.Unlock()
+
+	if err := pm.savePackageInfo(&updated); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения информации о пакете: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// DowngradePackage откатывает установленный пакет на более старую версию.
+// Переустановка той же версии допускается только с allowSame; более новая версия отклоняется.
+func (pm *PackageManager) DowngradePackage(ctx context.Context, packageName, version string, allowSame bool) (*PackageUpdateResult, error) {
+	currentInfo, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+	if version == "" {
+		return nil, fmt.Errorf("целевая версия обязательна")
+	}
+
+	switch cmp := compareVersions(version, currentInfo.Version); {
+	case cmp > 0:
+		return nil, fmt.Errorf("версия %s новее установленной %s, используйте update_package", version, currentInfo.Version)
+	case cmp == 0 && !allowSame:
+		return nil, errorWithCause(ErrAlreadyInstalled, "версия %s уже установлена, для переустановки укажите allow_same", currentInfo.Version)
+	}
 
-	for _, repo := range pm.config.Repositories {
-		if !repo.Enabled {
+	// Версия ищется до удаления текущей, поэтому при ее отсутствии пакет остается нетронутым
+	if _, err := pm.installPackage(ctx, packageName, version, currentInfo.Global, true, false, false, currentInfo.Automatic, "", ""); err != nil {
+		return nil, err
+	}
+
+	result := &PackageUpdateResult{
+		Name:        packageName,
+		FromVersion: currentInfo.Version,
+		ToVersion:   version,
+		Success:     true,
+	}
+	if info, exists := pm.getInstalledPackage(packageName); exists {
+		result.ToVersion = info.Version
+	}
+
+	return result, nil
+}
+
+// UpdateAll обновляет все устаревшие пакеты. Поиск и скачивание выполняются параллельно
+// (не более MaxConcurrency одновременно), установка — последовательно. Ошибка обновления
+// отдельного пакета не прерывает обновление остальных.
+func (pm *PackageManager) UpdateAll(ctx context.Context, global bool) (*UpdateAllResult, error) {
+	outdated, err := pm.ListPackages(ctx, global, true)
+	if err != nil {
+		return nil, err
+	}
+
+	type download struct {
+		resolved  *resolvedPackage
+		path      string
+		temporary bool
+		err       error
+	}
+
+	result := &UpdateAllResult{}
+
+	// Закрепленные пакеты не скачиваются и отмечаются как пропущенные
+	pending := outdated[:0]
+	for _, pkg := range outdated {
+		if !pkg.Pinned {
+			pending = append(pending, pkg)
 			continue
 		}
+		result.Packages = append(result.Packages, PackageUpdateResult{
+			Name:        pkg.Name,
+			FromVersion: pkg.Version,
+			ToVersion:   pkg.AvailableVersion,
+			Skipped:     true,
+		})
+		result.Skipped++
+	}
+	outdated = pending
+
+	downloads := make([]download, len(outdated))
+	pm.runConcurrently(len(outdated), func(i int) {
+		pkg := outdated[i]
+		resolved, err := pm.findPackage(ctx, pkg.Name, pkg.AvailableVersion, "", "")
+		if err != nil {
+			downloads[i].err = fmt.Errorf("пакет не найден: %w", err)
+			return
+		}
+
+		path, temporary, err := pm.fetchResolved(ctx, resolved)
+		if err != nil {
+			downloads[i].err = err
+			return
+		}
+		downloads[i] = download{resolved: resolved, path: path, temporary: temporary}
+	})
+
+	for i, pkg := range outdated {
+		update := PackageUpdateResult{
+			Name:        pkg.Name,
+			FromVersion: pkg.Version,
+			ToVersion:   pkg.AvailableVersion,
+		}
+
+		err := downloads[i].err
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil {
+			_, err = pm.installFromArchive(ctx, downloads[i].path, pkg.Global, true, downloads[i].resolved)
+		}
+		if downloads[i].temporary {
+			os.Remove(downloads[i].path)
+		}
+
+		if err != nil {
+			update.Error = err.Error()
+			result.Failed++
+		} else {
+			update.Success = true
+			result.Updated++
+		}
+		result.Packages = append(result.Packages, update)
+	}
+
+	return result, nil
+}
 
-		results, err := pm.searchInRepository(repo, query)
+// searchPageBounds приводит номер страницы и размер страницы поиска к допустимым значениям
+func searchPageBounds(page, limit int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// SearchPackages выполняет поиск пакетов и возвращает указанную страницу результатов,
+// объединенных из всех репозиториев и отсортированных по релевантности
+func (pm *PackageManager) SearchPackages(ctx context.Context, query string, filter SearchFilter, page, limit int) (*SearchPage, error) {
+	page, limit = searchPageBounds(page, limit)
+
+	// В автономном режиме поиск идет по локальным копиям индексов
+	if pm.offline(ctx) {
+		return pm.searchIndex(ctx, query, filter, page, limit)
+	}
+
+	var allResults []SearchResult
+	total := 0
+
+	// Репозитории перебираются по убыванию приоритета, поэтому устойчивая
+	// сортировка при равной релевантности оставляет выше более приоритетный
+	for _, repo := range pm.repositoriesByPriority() {
+		// Страница объединенного списка может состоять из первых результатов любого
+		// репозитория, поэтому у каждого запрашиваются все результаты до ее конца
+		results, repoTotal, err := pm.searchInRepository(ctx, repo, query, filter, page*limit)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			continue // Игнорируем ошибки отдельных репозиториев
 		}
 
-		allResults = append(allResults, results...)
+		// Фильтры повторяются на клиенте для репозиториев, которые их не поддерживают
+		kept := 0
+		for _, result := range results {
+			if filter.matches(result) {
+				result.Repository = repo.Name
+				if result.Score == 0 {
+					result.Score = localRelevance(query, result)
+				}
+				allResults = append(allResults, result)
+				kept++
+			}
+		}
+
+		// Итог репозитория учитывается, только если он применил фильтры сам;
+		// иначе он вернул все результаты и их число известно точно
+		if kept == len(results) && repoTotal > kept {
+			kept = repoTotal
+		}
+		total += kept
 	}
 
 	// Сортируем по релевантности
-	sort.Slice(allResults, func(i, j int) bool {
+	sort.SliceStable(allResults, func(i, j int) bool {
 		return allResults[i].Score > allResults[j].Score
 	})
 
-	return allResults, nil
+	result := &SearchPage{
+		Results:    []SearchResult{},
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (total + limit - 1) / limit,
+	}
+	if start := (page - 1) * limit; start < len(allResults) {
+		result.Results = allResults[start:min(start+limit, len(allResults))]
+	}
+	if total == 0 {
+		result.Suggestions = pm.suggestPackages(ctx, query)
+	}
+
+	return result, nil
 }
 
 // ListPackages возвращает список установленных пакетов
-func (pm *PackageManager) ListPackages(global, outdated bool) ([]*PackageInfo, error) {
+func (pm *PackageManager) ListPackages(ctx context.Context, global, outdated bool) ([]*PackageInfo, error) {
 	pm.packagesMutex.RLock()
-	defer pm.packagesMutex.RUnlock()
-
 	var packages []*PackageInfo
 	for _, pkg := range pm.installedPackages {
 		if global && !pkg.Global {
@@ -375,22 +1011,289 @@ func (pm *PackageManager) ListPackages(global, outdated bool) ([]*PackageInfo, e
 
 		packages = append(packages, pkg)
 	}
+	pm.packagesMutex.RUnlock()
 
 	// Сортируем по имени
 	sort.Slice(packages, func(i, j int) bool {
 		return packages[i].Name < packages[j].Name
 	})
 
-	return packages, nil
+	if !outdated {
+		return packages, nil
+	}
+
+	// Запрашиваем последние версии параллельно, не более MaxConcurrency запросов одновременно
+	available := make([]string, len(packages))
+	pm.runConcurrently(len(packages), func(i int) {
+		if repoPkg, _, err := pm.findRepositoryPackage(ctx, packages[i].Name); err == nil {
+			available[i] = latestVersion(repoPkg)
+		}
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// Оставляем только пакеты, для которых в репозитории есть более новая версия
+	var stale []*PackageInfo
+	for i, pkg := range packages {
+		if available[i] == "" || compareVersions(pkg.Version, available[i]) >= 0 {
+			continue
+		}
+
+		// Копия, чтобы не менять сохраненную информацию о пакете
+		info := *pkg
+		info.AvailableVersion = available[i]
+		stale = append(stale, &info)
+	}
+
+	return stale, nil
+}
+
+// runConcurrently вызывает fn для индексов 0..n-1, выполняя не более MaxConcurrency вызовов одновременно
+func (pm *PackageManager) runConcurrently(n int, fn func(i int)) {
+	limit := pm.getConfig().MaxConcurrency
+	if limit <= 0 {
+		limit = 1
+	}
+
+	semaphore := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// latestVersion возвращает наибольшую по semver версию пакета в репозитории
+func latestVersion(pkg *RepositoryPackage) string {
+	if newest := newestVersion(pkg.Versions); newest != nil {
+		return newest.Version
+	}
+	return ""
+}
+
+// InstalledPackages возвращает все установленные пакеты, отсортированные по имени
+func (pm *PackageManager) InstalledPackages() []*PackageInfo {
+	pm.packagesMutex.RLock()
+	packages := make([]*PackageInfo, 0, len(pm.installedPackages))
+	for _, pkg := range pm.installedPackages {
+		packages = append(packages, pkg)
+	}
+	pm.packagesMutex.RUnlock()
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+
+	return packages
+}
+
+// PackageResource собирает манифест и список файлов установленного пакета
+func (pm *PackageManager) PackageResource(packageName string) (*PackageResource, error) {
+	info, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	resource := &PackageResource{Package: info}
+
+	// Манифест может отсутствовать у поврежденной установки
+	if manifest, err := pm.loadManifestFromDir(info.InstallPath); err == nil {
+		resource.Manifest = manifest
+	}
+
+	err := filepath.Walk(info.InstallPath, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(info.InstallPath, path)
+		if err != nil {
+			return err
+		}
+		resource.Files = append(resource.Files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("ошибка чтения файлов пакета: %w", err)
+	}
+
+	return resource, nil
 }
 
 // GetPackageInfo возвращает информацию о пакете
 func (pm *PackageManager) GetPackageInfo(packageName string) (*PackageInfo, error) {
 	info, exists := pm.getInstalledPackage(packageName)
 	if !exists {
-		return nil, fmt.Errorf("пакет %s не установлен", packageName)
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+	return info, nil
+}
+
+// uncategorizedKey ключ группы для пакетов без ключевых слов
+const uncategorizedKey = ""
+
+// PackagesByCategory группирует установленные пакеты по ключевым словам.
+// Если задан keyword, возвращается только соответствующая ему группа.
+func (pm *PackageManager) PackagesByCategory(keyword string) map[string][]*PackageInfo {
+	pm.packagesMutex.RLock()
+	defer pm.packagesMutex.RUnlock()
+
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	groups := make(map[string][]*PackageInfo)
+
+	for _, pkg := range pm.installedPackages {
+		if len(pkg.Keywords) == 0 {
+			if keyword == "" {
+				groups[uncategorizedKey] = append(groups[uncategorizedKey], pkg)
+			}
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, kw := range pkg.Keywords {
+			kw = strings.ToLower(strings.TrimSpace(kw))
+			if kw == "" || seen[kw] || (keyword != "" && kw != keyword) {
+				continue
+			}
+			seen[kw] = true
+			groups[kw] = append(groups[kw], pkg)
+		}
+	}
+
+	for _, pkgs := range groups {
+		sort.Slice(pkgs, func(i, j int) bool {
+			return pkgs[i].Name < pkgs[j].Name
+		})
+	}
+
+	return groups
+}
+
+// ReleaseNotes возвращает версии пакета в диапазоне (from, to] по возрастанию.
+// По умолчанию from — установленная версия, to — последняя доступная.
+func (pm *PackageManager) ReleaseNotes(ctx context.Context, packageName, from, to string) (string, string, []RepositoryVersion, error) {
+	if from == "" {
+		if info, exists := pm.getInstalledPackage(packageName); exists {
+			from = info.Version
+		}
+	}
+
+	pkg, _, err := pm.findRepositoryPackage(ctx, packageName)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if to == "" {
+		to = latestVersion(pkg)
+		if to == "" {
+			return "", "", nil, fmt.Errorf("у пакета %s нет опубликованных версий", packageName)
+		}
+	}
+
+	if from != "" && compareVersions(from, to) >= 0 {
+		return from, to, nil, nil
+	}
+
+	var versions []RepositoryVersion
+	for _, v := range pkg.Versions {
+		if from != "" && compareVersions(v.Version, from) <= 0 {
+			continue
+		}
+		if compareVersions(v.Version, to) > 0 {
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i].Version, versions[j].Version) < 0
+	})
+
+	return from, to, versions, nil
+}
+
+// VerifyPackage проверяет целостность установленного пакета: наличие записанных
+// файлов и манифеста, а также совпадение размера директории с сохраненным
+func (pm *PackageManager) VerifyPackage(packageName string) (*VerifyResult, error) {
+	info, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	result := &VerifyResult{
+		Name:         info.Name,
+		Version:      info.Version,
+		InstallPath:  info.InstallPath,
+		ExpectedSize: info.Size,
+	}
+
+	for _, file := range info.Files {
+		result.CheckedFiles++
+		path := filepath.Join(info.InstallPath, filepath.FromSlash(file))
+		if _, err := os.Stat(path); err != nil {
+			result.MissingFiles = append(result.MissingFiles, file)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(info.InstallPath, manifestFileName)); err == nil {
+		result.ManifestPresent = true
+	}
+
+	result.ActualSize = pm.calculateDirSize(info.InstallPath)
+	result.OK = len(result.MissingFiles) == 0 &&
+		result.ManifestPresent &&
+		result.ActualSize == result.ExpectedSize
+
+	return result, nil
+}
+
+// PackageFiles возвращает записанные за установленным пакетом файлы с размерами
+// и признаком того, существуют ли они сейчас в каталоге установки
+func (pm *PackageManager) PackageFiles(packageName string) (*PackageFilesResult, error) {
+	info, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	result := &PackageFilesResult{
+		Name:        info.Name,
+		Version:     info.Version,
+		InstallPath: info.InstallPath,
+		Files:       []PackageFile{},
+	}
+	for _, rel := range info.Files {
+		file := PackageFile{Path: rel}
+		if path, err := safeJoin(info.InstallPath, rel); err == nil {
+			if stat, err := os.Lstat(path); err == nil {
+				file.Exists = true
+				file.Dir = stat.IsDir()
+				file.Size = stat.Size()
+				if file.Dir {
+					file.Size = pm.calculateDirSize(path)
+				}
+			}
+		}
+
+		if file.Exists {
+			if !file.Dir {
+				result.TotalSize += file.Size
+			}
+		} else {
+			result.Missing++
+		}
+		result.Files = append(result.Files, file)
 	}
-	return info, nil
+
+	return result, nil
 }
 
 // CreatePackage создает новый пакет
@@ -442,8 +1345,12 @@ func (pm *PackageManager) CreatePackage(name, template, author, description stri
 	return nil
 }
 
-// BuildPackage собирает пакет
+// BuildPackage собирает пакет. Формат и уровень сжатия проверяются до начала сборки.
 func (pm *PackageManager) BuildPackage(outputPath, format string, compressionLevel int) error {
+	if err := validateCompressionLevel(format, compressionLevel); err != nil {
+		return err
+	}
+
 	// Загружаем манифест
 	manifest, err := pm.loadManifestFromDir(".")
 	if err != nil {
@@ -464,7 +1371,7 @@ func (pm *PackageManager) BuildPackage(outputPath, format string, compressionLev
 }
 
 // PublishPackage публикует пакет в репозиторий
-func (pm *PackageManager) PublishPackage(registryURL, token string) error {
+func (pm *PackageManager) PublishPackage(ctx context.Context, registryURL, token string) error {
 	// Загружаем манифест
 	manifest, err := pm.loadManifestFromDir(".")
 	if err != nil {
@@ -473,17 +1380,17 @@ func (pm *PackageManager) PublishPackage(registryURL, token string) error {
 
 	// Строим пакет
 	archivePath := fmt.Sprintf("%s-%s.criage", manifest.Name, manifest.Version)
-	if err := pm.BuildPackage(archivePath, "criage", pm.config.CompressionLevel); err != nil {
+	if err := pm.BuildPackage(archivePath, "criage", pm.getConfig().CompressionLevel); err != nil {
 		return fmt.Errorf("ошибка сборки пакета: %w", err)
 	}
 	defer os.Remove(archivePath)
 
 	// Загружаем в репозиторий
 	if registryURL == "" {
-		registryURL = pm.config.Repositories[0].URL
+		registryURL = pm.getConfig().Repositories[0].URL
 	}
 
-	return pm.uploadPackage(registryURL, archivePath, token)
+	return pm.uploadPackage(ctx, registryURL, archivePath, token)
 }
 
 // Вспомогательные методы
@@ -495,45 +1402,251 @@ func (pm *PackageManager) getInstalledPackage(packageName string) (*PackageInfo,
 	return info, exists
 }
 
-func (pm *PackageManager) findPackage(packageName, version, arch, osName string) (*PackageInfo, string, error) {
-	for _, repo := range pm.config.Repositories {
-		if !repo.Enabled {
-			continue
+// maxRetryAfter наибольшая пауза по Retry-After, которую клиент готов выждать
+const maxRetryAfter = time.Minute
+
+// network возвращает текущие HTTP-клиент и rate limiter
+func (pm *PackageManager) network() (*http.Client, *RateLimiter) {
+	pm.networkMutex.RLock()
+	defer pm.networkMutex.RUnlock()
+	return pm.httpClient, pm.rateLimiter
+}
+
+// doRequest применяет rate limiting и выполняет HTTP запрос к репозиторию.
+// Ответ 429 с заголовком Retry-After повторяется после указанной паузы, не более max_retries раз.
+func (pm *PackageManager) doRequest(req *http.Request) (*http.Response, error) {
+	resp, err := pm.sendRequest(req)
+
+	for attempt := 0; attempt < pm.getConfig().MaxRetries; attempt++ {
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+
+		delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok || delay > maxRetryAfter {
+			return resp, nil
+		}
+
+		// Тело запроса уже прочитано: повтор возможен, только если его можно получить заново
+		retry := req.Clone(req.Context())
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, nil
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			retry.Body = body
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		logger.Infof("Репозиторий %s ограничил частоту запросов, повтор через %s", req.URL.Host, delay)
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		resp, err = pm.sendRequest(retry)
+	}
+
+	return resp, err
+}
+
+// sendRequest выполняет одну попытку запроса и учитывает ее в состоянии доступности хоста:
+// сбоем считаются ошибка соединения и ответ 5xx
+func (pm *PackageManager) sendRequest(req *http.Request) (*http.Response, error) {
+	if pm.offline(req.Context()) {
+		return nil, fmt.Errorf("запрос к %s %w", req.URL.Host, errNotAvailableOffline)
+	}
+
+	client, _ := pm.network()
+	if err := pm.rateLimiterFor(req.URL.String()).WaitCtx(req.Context()); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(req.Context(), pm.requestTimeout(req.URL.String()))
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+	} else {
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	}
+	// Текст ошибки содержит URL запроса, в котором могут быть токены
+	err = redactError(err)
+
+	host := strings.ToLower(req.URL.Host)
+	switch {
+	case err != nil:
+		// Отмена вызова не говорит о доступности репозитория
+		if req.Context().Err() == nil {
+			pm.health.recordFailure(host, err.Error())
+		}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		pm.health.recordFailure(host, resp.Status)
+	default:
+		pm.health.recordSuccess(host)
+	}
+
+	return resp, err
+}
+
+// sleepContext ждет указанное время или отмены контекста
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter разбирает заголовок Retry-After: число секунд или HTTP-дату
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
 		}
+		return time.Duration(seconds) * time.Second, true
+	}
 
-		info, url, err := pm.findInRepository(repo, packageName, version, arch, osName)
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	// Дата в прошлом означает, что повторять можно сразу
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// resolvedPackage результат поиска пакета в репозиториях
+type resolvedPackage struct {
+	Info        *PackageInfo
+	Repository  Repository
+	Version     *RepositoryVersion
+	File        *RepositoryFile
+	DownloadURL string
+	Automatic   bool // устанавливается как зависимость другого пакета
+}
+
+// findPackage ищет пакет для платформы; пустые arch и osName заменяются целевой платформой
+func (pm *PackageManager) findPackage(ctx context.Context, packageName, version, arch, osName string) (*resolvedPackage, error) {
+	arch, osName = pm.targetPlatform(arch, osName)
+	resolved, _, err := pm.selectSource(ctx, packageName, version, arch, osName)
+	return resolved, err
+}
+
+// selectSource перебирает включенные репозитории по убыванию приоритета и возвращает
+// первый подходящий источник вместе со списком отклоненных репозиториев
+func (pm *PackageManager) selectSource(ctx context.Context, packageName, version, arch, osName string) (*resolvedPackage, []SourceAttempt, error) {
+	var skipped []SourceAttempt
+	var failures []error
+	var offlineErr error
+	for _, repo := range pm.repositoriesByPriority() {
+		resolved, err := pm.findInRepository(ctx, repo, packageName, version, arch, osName)
 		if err == nil {
-			return info, url, nil
+			return resolved, skipped, nil
+		}
+
+		// Отмена прерывает перебор репозиториев
+		if ctx.Err() != nil {
+			return nil, skipped, ctx.Err()
+		}
+		if offlineErr == nil && errors.Is(err, errNotAvailableOffline) {
+			offlineErr = err
 		}
+		skipped = append(skipped, SourceAttempt{Repository: repo.Name, URL: repo.URL, Error: err.Error()})
+		failures = append(failures, err)
 	}
 
-	return nil, "", fmt.Errorf("пакет %s не найден", packageName)
+	// В автономном режиме причина важнее общего «не найден»: пакет может быть в сети
+	if offlineErr != nil {
+		return nil, skipped, offlineErr
+	}
+	// Ошибки репозиториев сохраняются как причины: по ним видно, был ли пакет без
+	// нужной версии, недоступен репозиторий или отклонен токен
+	return nil, skipped, errorWithCause(errors.Join(append([]error{ErrPackageNotFound}, failures...)...), "пакет %s не найден", packageName)
 }
 
-func (pm *PackageManager) findInRepository(repo Repository, packageName, version, arch, osName string) (*PackageInfo, string, error) {
-	// Получаем информацию о пакете из репозитория
+// ResolveSource определяет, из какого репозитория и какого файла был бы установлен пакет,
+// не скачивая архив
+func (pm *PackageManager) ResolveSource(ctx context.Context, packageName, version, arch, osName string) (*SourceResolution, error) {
+	arch, osName = pm.targetPlatform(arch, osName)
+
+	resolved, skipped, err := pm.selectSource(ctx, packageName, version, arch, osName)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		reasons := make([]string, 0, len(skipped))
+		for _, attempt := range skipped {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", attempt.Repository, attempt.Error))
+		}
+		if len(reasons) > 0 {
+			return nil, fmt.Errorf("%w (%s)", err, strings.Join(reasons, "; "))
+		}
+		return nil, err
+	}
+
+	format := resolved.File.Format
+	if format == "" {
+		format, _ = detectArchiveFormat(resolved.File.Filename)
+	}
+
+	return &SourceResolution{
+		Name:          resolved.Info.Name,
+		Version:       resolved.Version.Version,
+		OS:            osName,
+		Arch:          arch,
+		Repository:    resolved.Repository.Name,
+		RepositoryURL: resolved.Repository.URL,
+		Priority:      resolved.Repository.Priority,
+		Filename:      resolved.File.Filename,
+		Format:        format,
+		Size:          resolved.File.Size,
+		Checksum:      resolved.File.Checksum,
+		DownloadURL:   resolved.DownloadURL,
+		Skipped:       skipped,
+	}, nil
+}
+
+// fetchRepositoryPackage получает описание пакета со всеми версиями из репозитория
+func (pm *PackageManager) fetchRepositoryPackage(ctx context.Context, repo Repository, packageName string) (*RepositoryPackage, error) {
+	if pm.offline(ctx) {
+		return pm.indexedPackage(repo, packageName)
+	}
+
 	url := fmt.Sprintf("%s/api/v1/packages/%s", repo.URL, packageName)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	if repo.AuthToken != "" {
 		req.Header.Set("Authorization", "Bearer "+repo.AuthToken)
 	}
 
-	// Применяем rate limiting
-	pm.rateLimiter.Wait()
-
-	resp, err := pm.httpClient.Do(req)
+	resp, err := pm.doRequest(req)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не найден в репозитории %s", packageName, repo.Name)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("ошибка получения информации о пакете: %d", resp.StatusCode)
+		return nil, statusError(resp.StatusCode, "ошибка получения информации о пакете: %d", resp.StatusCode)
 	}
 
 	var apiResp struct {
@@ -542,47 +1655,69 @@ func (pm *PackageManager) findInRepository(repo Repository, packageName, version
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	if !apiResp.Success || apiResp.Data == nil {
-		return nil, "", fmt.Errorf("пакет не найден в репозитории")
+		return nil, errorWithCause(ErrPackageNotFound, "пакет не найден в репозитории")
+	}
+
+	return apiResp.Data, nil
+}
+
+// findRepositoryPackage ищет описание пакета в самом приоритетном репозитории, где он есть
+func (pm *PackageManager) findRepositoryPackage(ctx context.Context, packageName string) (*RepositoryPackage, Repository, error) {
+	for _, repo := range pm.repositoriesByPriority() {
+		pkg, err := pm.fetchRepositoryPackage(ctx, repo, packageName)
+		if err == nil {
+			return pkg, repo, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, Repository{}, ctx.Err()
+		}
 	}
 
-	pkg := apiResp.Data
+	return nil, Repository{}, errorWithCause(ErrPackageNotFound, "пакет %s не найден", packageName)
+}
+
+func (pm *PackageManager) findInRepository(ctx context.Context, repo Repository, packageName, version, arch, osName string) (*resolvedPackage, error) {
+	// Получаем информацию о пакете из репозитория
+	pkg, err := pm.fetchRepositoryPackage(ctx, repo, packageName)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePackageName(pkg.Name); err != nil {
+		return nil, fmt.Errorf("репозиторий %s вернул %w", repo.Name, err)
+	}
 
 	// Выбираем версию
 	var selectedVersion *RepositoryVersion
 	if version == "" {
-		// Берем последнюю версию
-		if len(pkg.Versions) > 0 {
-			selectedVersion = &pkg.Versions[len(pkg.Versions)-1]
-		}
+		// Берем наибольшую версию: репозиторий не обязан перечислять их по порядку
+		selectedVersion = newestVersion(pkg.Versions)
 	} else {
-		// Ищем указанную версию
-		for _, v := range pkg.Versions {
-			if v.Version == version {
-				selectedVersion = &v
-				break
-			}
+		// Ищем указанную версию (с учетом метаданных сборки)
+		selectedVersion, err = matchVersion(version, pkg.Versions)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	if selectedVersion == nil {
-		return nil, "", fmt.Errorf("версия %s не найдена", version)
-	}
-
-	// Ищем подходящий файл
-	var selectedFile *RepositoryFile
-	for _, file := range selectedVersion.Files {
-		if file.OS == osName && file.Arch == arch {
-			selectedFile = &file
-			break
-		}
+		return nil, errorWithCause(ErrVersionNotFound, "версия %s не найдена", version)
 	}
 
+	// Ищем подходящий файл: для самой платформы, совместимой архитектуры или любой платформы
+	selectedFile := selectPlatformFile(selectedVersion.Files, osName, arch, pm.getConfig().ArchCompatibility)
 	if selectedFile == nil {
-		return nil, "", fmt.Errorf("файл для %s/%s не найден", osName, arch)
+		return nil, errorWithCause(ErrFileUnavailable, "файл для %s/%s не найден", osName, arch)
+	}
+	if selectedFile.OS != osName || selectedFile.Arch != arch {
+		logger.Infof("Для %s/%s пакета %s %s выбран файл %s/%s", osName, arch, pkg.Name, selectedVersion.Version, selectedFile.OS, selectedFile.Arch)
+	}
+	if pm.offline(ctx) && !pm.inCache(selectedFile.Checksum) {
+		return nil, fmt.Errorf("пакет %s %s %w: архива нет в кеше", pkg.Name, selectedVersion.Version, errNotAvailableOffline)
 	}
 
 	info := &PackageInfo{
@@ -592,28 +1727,61 @@ func (pm *PackageManager) findInRepository(repo Repository, packageName, version
 		Author:      pkg.Author,
 		License:     pkg.License,
 		Size:        selectedFile.Size,
+		Keywords:    pkg.Keywords,
 	}
 
 	// Строим URL для скачивания на основе информации о файле
 	downloadURL := fmt.Sprintf("%s/api/v1/download/%s/%s/%s",
 		repo.URL, pkg.Name, selectedVersion.Version, selectedFile.Filename)
 
-	return info, downloadURL, nil
+	return &resolvedPackage{
+		Info:        info,
+		Repository:  repo,
+		Version:     selectedVersion,
+		File:        selectedFile,
+		DownloadURL: downloadURL,
+	}, nil
 }
 
-func (pm *PackageManager) downloadPackage(url, packageName, version string) (string, error) {
-	resp, err := pm.httpClient.Get(url)
+// downloadPackage скачивает архив во временный файл. size — ожидаемый размер, известный
+// заранее (-1, если неизвестен); он используется для прогресса, если сервер не сообщил Content-Length.
+func (pm *PackageManager) downloadPackage(ctx context.Context, url, packageName, version string, size int64) (string, error) {
+	if pm.offline(ctx) {
+		return "", fmt.Errorf("архив %s %w", url, errNotAvailableOffline)
+	}
+
+	// Скачивание архива ограничивается отдельным таймаутом
+	ctx, cancel := withTimeout(ctx, pm.downloadTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	client, _ := pm.network()
+	if checkRedirects, _ := ctx.Value(redirectPolicyKey{}).(bool); checkRedirects {
+		client = pm.redirectCheckingClient(client)
+	}
+	if err := pm.rateLimiterFor(url).WaitCtx(ctx); err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ошибка скачивания: %d", resp.StatusCode)
+		return "", statusError(resp.StatusCode, "ошибка скачивания: %d", resp.StatusCode)
 	}
 
-	// Создаем временный файл
-	tempFile := filepath.Join(pm.config.TempPath, fmt.Sprintf("%s-%s.tmp", packageName, version))
+	// Создаем временный файл, сохраняя расширение архива для определения формата
+	ext := ".tmp"
+	if format, err := detectArchiveFormat(resp.Request.URL.Path); err == nil {
+		ext = archiveExtension(format)
+	}
+	tempFile := filepath.Join(pm.getConfig().TempPath, fmt.Sprintf("%s-%s%s", packageName, version, ext))
 
 	file, err := os.Create(tempFile)
 	if err != nil {
@@ -621,8 +1789,15 @@ func (pm *PackageManager) downloadPackage(url, packageName, version string) (str
 	}
 	defer file.Close()
 
-	// Копируем данные
-	if _, err := io.Copy(file, resp.Body); err != nil {
+	// Копируем данные блоками, сообщая о скорости и оставшемся времени
+	total := resp.ContentLength
+	if total < 0 {
+		total = size
+	}
+	written, err := copyDownload(ctx, file, resp.Body, total, pm.getConfig().MaxDownloadSize, progressFromContext(ctx))
+	pm.metrics.recordDownload(written)
+	if err != nil {
+		file.Close()
 		os.Remove(tempFile)
 		return "", err
 	}
@@ -630,19 +1805,98 @@ func (pm *PackageManager) downloadPackage(url, packageName, version string) (str
 	return tempFile, nil
 }
 
-func (pm *PackageManager) loadInstalledPackages() error {
-	// Загружаем глобальные пакеты
-	globalInfoPath := filepath.Join(pm.config.GlobalPath, "packages.json")
-	if err := pm.loadPackagesFromFile(globalInfoPath); err != nil && !os.IsNotExist(err) {
-		return err
+// maxRedirects наибольшее число перенаправлений при скачивании, как у http.Client по умолчанию
+const maxRedirects = 10
+
+type redirectPolicyKey struct{}
+
+// withRedirectPolicy возвращает контекст, в котором скачивание проверяет адрес каждого
+// перенаправления политикой validateDownloadURL (force_https и allowed_hosts)
+func withRedirectPolicy(ctx context.Context) context.Context {
+	return context.WithValue(ctx, redirectPolicyKey{}, true)
+}
+
+// redirectCheckingClient возвращает копию client, которая отклоняет перенаправления
+// на адреса, запрещенные политикой конфигурации
+func (pm *PackageManager) redirectCheckingClient(client *http.Client) *http.Client {
+	checked := *client
+	checked.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := pm.validateDownloadURL(req.URL.String()); err != nil {
+			return fmt.Errorf("перенаправление на %s отклонено: %w", req.URL.Redacted(), err)
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("превышено число перенаправлений (%d)", maxRedirects)
+		}
+		return nil
+	}
+	return &checked
+}
+
+// validateDownloadURL проверяет, что URL разрешен политикой конфигурации
+func (pm *PackageManager) validateDownloadURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("некорректный URL: %w", err)
 	}
 
-	// Загружаем локальные пакеты
-	localInfoPath := filepath.Join(pm.config.LocalPath, "packages.json")
-	if err := pm.loadPackagesFromFile(localInfoPath); err != nil && !os.IsNotExist(err) {
-		return err
+	switch parsed.Scheme {
+	case "https":
+	case "http":
+		if pm.getConfig().ForceHTTPS {
+			return fmt.Errorf("разрешены только HTTPS ссылки (force_https)")
+		}
+	default:
+		return fmt.Errorf("неподдерживаемая схема URL: %s", parsed.Scheme)
+	}
+
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("URL не содержит хост")
+	}
+
+	if len(pm.getConfig().AllowedHosts) == 0 {
+		return nil
+	}
+
+	for _, allowed := range pm.getConfig().AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(strings.ToLower(host), allowed[1:]) {
+				return nil
+			}
+			continue
+		}
+		if strings.EqualFold(host, allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("хост %s не входит в список разрешенных (allowed_hosts)", host)
+}
+
+func (pm *PackageManager) loadInstalledPackages() error {
+	// Глобальные пакеты загружаются первыми, локальные перекрывают их
+	corrupt := false
+	for _, root := range []string{pm.getConfig().GlobalPath, pm.getConfig().LocalPath} {
+		path := filepath.Join(root, packagesFileName)
+		err := pm.loadPackagesFromFile(path)
+		switch {
+		case isCorruptJSON(err):
+			logger.Warnf("Файл %s поврежден (%v); список пакетов будет восстановлен по каталогам установки", path, err)
+			corrupt = true
+		case err != nil && !os.IsNotExist(err):
+			return err
+		}
 	}
 
+	if corrupt {
+		if _, err := pm.RepairState(false); err != nil {
+			return fmt.Errorf("ошибка восстановления packages.json: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -668,78 +1922,89 @@ func (pm *PackageManager) loadPackagesFromFile(path string) error {
 }
 
 func (pm *PackageManager) savePackageInfo(info *PackageInfo) error {
-	var packagesPath string
-	if info.Global {
-		packagesPath = filepath.Join(pm.config.GlobalPath, "packages.json")
-	} else {
-		packagesPath = filepath.Join(pm.config.LocalPath, "packages.json")
-	}
+	return pm.updatePackagesFile(info.Global, func(packages map[string]*PackageInfo) bool {
+		packages[info.Name] = info
+		return true
+	})
+}
 
-	// Загружаем существующие пакеты
-	var packages map[string]*PackageInfo
-	if data, err := os.ReadFile(packagesPath); err == nil {
-		if err := json.Unmarshal(data, &packages); err != nil {
-			log.Printf("Error unmarshaling packages: %v", err)
+func (pm *PackageManager) removePackageInfo(packageName string, global bool) error {
+	return pm.updatePackagesFile(global, func(packages map[string]*PackageInfo) bool {
+		if _, ok := packages[packageName]; !ok {
+			return false
 		}
-	}
-	if packages == nil {
-		packages = make(map[string]*PackageInfo)
-	}
-
-	// Добавляем новый пакет
-	packages[info.Name] = info
-
-	// Сохраняем
-	data, err := json.MarshalIndent(packages, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(packagesPath, data, 0644)
+		delete(packages, packageName)
+		return true
+	})
 }
 
-func (pm *PackageManager) removePackageInfo(packageName string, global bool) error {
+// updatePackagesFile читает packages.json области, применяет update и записывает
+// результат, если update сообщил об изменении. Вся последовательность выполняется
+// под packagesFileMutex и файловой блокировкой, поэтому параллельные вызовы не теряют записи.
+func (pm *PackageManager) updatePackagesFile(global bool, update func(packages map[string]*PackageInfo) bool) error {
 	var packagesPath string
 	if global {
-		packagesPath = filepath.Join(pm.config.GlobalPath, "packages.json")
+		packagesPath = filepath.Join(pm.getConfig().GlobalPath, packagesFileName)
 	} else {
-		packagesPath = filepath.Join(pm.config.LocalPath, "packages.json")
+		packagesPath = filepath.Join(pm.getConfig().LocalPath, packagesFileName)
+	}
+
+	pm.packagesFileMutex.Lock()
+	defer pm.packagesFileMutex.Unlock()
+
+	// Чтение, изменение и запись packages.json не должны чередоваться с другими процессами
+	lock, err := lockPackagesFile(packagesPath)
+	if err != nil {
+		return err
 	}
+	defer lock.Unlock()
 
-	// Загружаем существующие пакеты
+	// Загружаем существующие пакеты. Файл, который не удается прочитать или разобрать,
+	// не перезаписывается: иначе пропали бы записи всех установленных пакетов
 	var packages map[string]*PackageInfo
-	if data, err := os.ReadFile(packagesPath); err == nil {
+	data, err := os.ReadFile(packagesPath)
+	switch {
+	case err == nil:
 		if err := json.Unmarshal(data, &packages); err != nil {
-			log.Printf("Error unmarshaling packages: %v", err)
+			return fmt.Errorf("файл %s поврежден, восстановите его инструментом repair_state: %w", packagesPath, err)
 		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("ошибка чтения %s: %w", packagesPath, err)
 	}
 	if packages == nil {
-		return nil
+		packages = make(map[string]*PackageInfo)
 	}
 
-	// Удаляем пакет
-	delete(packages, packageName)
+	if !update(packages) {
+		return nil
+	}
 
 	// Сохраняем
-	data, err := json.MarshalIndent(packages, "", "  ")
+	data, err = json.MarshalIndent(packages, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(packagesPath, data, 0644)
+	return writeFileAtomic(packagesPath, data, 0644)
 }
 
-func (pm *PackageManager) getInstallPath(packageName string, global bool) string {
-	if global {
-		return filepath.Join(pm.config.GlobalPath, packageName)
+// getInstallPath возвращает каталог установки пакета в области global/local. Имя пакета
+// приходит из манифеста архива или ответа репозитория, поэтому проверяется, что каталог
+// лежит непосредственно в корне области и не совпадает с ним.
+func (pm *PackageManager) getInstallPath(packageName string, global bool) (string, error) {
+	if err := validatePackageName(packageName); err != nil {
+		return "", err
 	}
-	return filepath.Join(pm.config.LocalPath, packageName)
-}
 
-func (pm *PackageManager) extractArchive(archivePath, destPath string) error {
-	// Простая заглушка для извлечения архивов
-	// В реальной реализации здесь должна быть логика для разных форматов
-	return fmt.Errorf("извлечение архивов пока не реализовано")
+	root := pm.getConfig().LocalPath
+	if global {
+		root = pm.getConfig().GlobalPath
+	}
+	installPath := filepath.Join(root, packageName)
+	if rel, err := filepath.Rel(root, installPath); err != nil || rel != packageName {
+		return "", fmt.Errorf("каталог пакета %q выходит за пределы %s", packageName, root)
+	}
+	return installPath, nil
 }
 
 func (pm *PackageManager) copyFiles(srcDir, destDir string) error {
@@ -759,6 +2024,10 @@ func (pm *PackageManager) copyFiles(srcDir, destDir string) error {
 			return os.MkdirAll(destPath, info.Mode())
 		}
 
+		if info.Mode()&os.ModeSymlink != 0 {
+			return pm.copySymlink(path, destPath)
+		}
+
 		srcFile, err := os.Open(path)
 		if err != nil {
 			return err
@@ -771,6 +2040,11 @@ func (pm *PackageManager) copyFiles(srcDir, destDir string) error {
 		}
 		defer destFile.Close()
 
+		// Сохраняем права файла, в том числе бит исполнения
+		if err := destFile.Chmod(info.Mode().Perm()); err != nil {
+			return err
+		}
+
 		_, err = io.Copy(destFile, srcFile)
 		return err
 	})
@@ -804,35 +2078,60 @@ func (pm *PackageManager) calculateDirSize(dir string) int64 {
 		}
 		return nil
 	}); err != nil {
-		log.Printf("Error walking directory %s: %v", dir, err)
+		logger.Warnf("Error walking directory %s: %v", dir, err)
 	}
 
 	return size
 }
 
-func (pm *PackageManager) searchInRepository(repo Repository, query string) ([]SearchResult, error) {
-	url := fmt.Sprintf("%s/api/v1/search?q=%s", repo.URL, query)
+// matches сообщает, удовлетворяет ли результат поиска всем заданным условиям
+func (f SearchFilter) matches(result SearchResult) bool {
+	if f.Author != "" && !strings.Contains(strings.ToLower(result.Author), strings.ToLower(f.Author)) {
+		return false
+	}
+	if f.License != "" && !strings.EqualFold(result.License, f.License) {
+		return false
+	}
+	return result.Downloads >= f.MinDownloads
+}
+
+// query добавляет условия фильтра к параметрам поискового запроса
+func (f SearchFilter) query(values url.Values) {
+	if f.Author != "" {
+		values.Set("author", f.Author)
+	}
+	if f.License != "" {
+		values.Set("license", f.License)
+	}
+	if f.MinDownloads > 0 {
+		values.Set("min_downloads", strconv.FormatInt(f.MinDownloads, 10))
+	}
+}
+
+// searchInRepository запрашивает у репозитория первые limit результатов поиска.
+// Возвращает также общее число найденного, сообщенное репозиторием.
+func (pm *PackageManager) searchInRepository(ctx context.Context, repo Repository, query string, filter SearchFilter, limit int) ([]SearchResult, int, error) {
+	values := url.Values{"q": {query}, "page": {"1"}, "limit": {strconv.Itoa(limit)}}
+	filter.query(values)
+	searchURL := fmt.Sprintf("%s/api/v1/search?%s", repo.URL, values.Encode())
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if repo.AuthToken != "" {
 		req.Header.Set("Authorization", "Bearer "+repo.AuthToken)
 	}
 
-	// Применяем rate limiting
-	pm.rateLimiter.Wait()
-
-	resp, err := pm.httpClient.Do(req)
+	resp, err := pm.doRequest(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ошибка поиска: %d", resp.StatusCode)
+		return nil, 0, statusError(resp.StatusCode, "ошибка поиска: %d", resp.StatusCode)
 	}
 
 	var apiResp struct {
@@ -845,22 +2144,17 @@ func (pm *PackageManager) searchInRepository(repo Repository, query string) ([]S
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if !apiResp.Success {
-		return nil, fmt.Errorf("ошибка поиска в репозитории")
+		return nil, 0, fmt.Errorf("ошибка поиска в репозитории")
 	}
 
-	return apiResp.Data.Results, nil
+	return apiResp.Data.Results, apiResp.Data.Total, nil
 }
 
-func (pm *PackageManager) createArchive(srcDir, outputPath, format string, compressionLevel int) error {
-	// Заглушка для создания архивов
-	return fmt.Errorf("создание архивов пока не реализовано")
-}
-
-func (pm *PackageManager) uploadPackage(registryURL, archivePath, token string) error {
+func (pm *PackageManager) uploadPackage(ctx context.Context, registryURL, archivePath, token string) error {
 	// Открываем файл для загрузки
 	file, err := os.Open(archivePath)
 	if err != nil {
@@ -886,7 +2180,7 @@ func (pm *PackageManager) uploadPackage(registryURL, archivePath, token string)
 
 	// Создаем POST запрос
 	uploadURL := fmt.Sprintf("%s/api/v1/upload", registryURL)
-	req, err := http.NewRequest("POST", uploadURL, &body)
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, &body)
 	if err != nil {
 		return fmt.Errorf("ошибка создания запроса: %w", err)
 	}
@@ -897,11 +2191,8 @@ func (pm *PackageManager) uploadPackage(registryURL, archivePath, token string)
 		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
-	// Применяем rate limiting
-	pm.rateLimiter.Wait()
-
 	// Выполняем запрос
-	resp, err := pm.httpClient.Do(req)
+	resp, err := pm.doRequest(req)
 	if err != nil {
 		return fmt.Errorf("ошибка выполнения запроса: %w", err)
 	}
@@ -909,11 +2200,11 @@ func (pm *PackageManager) uploadPackage(registryURL, archivePath, token string)
 
 	// Проверяем статус ответа
 	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("неверный токен авторизации")
+		return errorWithCause(ErrUnauthorized, "неверный токен авторизации")
 	}
 
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("ошибка сервера: %d", resp.StatusCode)
+		return statusError(resp.StatusCode, "ошибка сервера: %d", resp.StatusCode)
 	}
 
 	// Читаем ответ
@@ -936,12 +2227,12 @@ func (pm *PackageManager) uploadPackage(registryURL, archivePath, token string)
 }
 
 // RefreshRepositoryIndex принудительно обновляет индекс пакетов в репозитории
-func (pm *PackageManager) RefreshRepositoryIndex(repositoryURL, authToken string) error {
+func (pm *PackageManager) RefreshRepositoryIndex(ctx context.Context, repositoryURL, authToken string) error {
 	// Создаем URL для эндпоинта обновления индекса
 	refreshURL := fmt.Sprintf("%s/api/v1/refresh", repositoryURL)
 
 	// Создаем POST запрос
-	req, err := http.NewRequest("POST", refreshURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", refreshURL, nil)
 	if err != nil {
 		return fmt.Errorf("ошибка создания запроса: %w", err)
 	}
@@ -950,11 +2241,8 @@ func (pm *PackageManager) RefreshRepositoryIndex(repositoryURL, authToken string
 	req.Header.Set("Authorization", "Bearer "+authToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	// Применяем rate limiting
-	pm.rateLimiter.Wait()
-
 	// Выполняем запрос
-	resp, err := pm.httpClient.Do(req)
+	resp, err := pm.doRequest(req)
 	if err != nil {
 		return fmt.Errorf("ошибка выполнения запроса: %w", err)
 	}
@@ -962,11 +2250,11 @@ func (pm *PackageManager) RefreshRepositoryIndex(repositoryURL, authToken string
 
 	// Проверяем статус ответа
 	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("неверный токен авторизации")
+		return errorWithCause(ErrUnauthorized, "неверный токен авторизации")
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ошибка сервера: %d", resp.StatusCode)
+		return statusError(resp.StatusCode, "ошибка сервера: %d", resp.StatusCode)
 	}
 
 	// Читаем ответ
@@ -988,22 +2276,19 @@ func (pm *PackageManager) RefreshRepositoryIndex(repositoryURL, authToken string
 	return nil
 }
 
-// GetRepositoryStats получает детальную статистику репозитория
-func (pm *PackageManager) GetRepositoryStats(repositoryURL string) (*Statistics, error) {
+// fetchRepositoryStats запрашивает детальную статистику репозитория
+func (pm *PackageManager) fetchRepositoryStats(ctx context.Context, repositoryURL string) (*Statistics, error) {
 	// Создаем URL для эндпоинта статистики
 	statsURL := fmt.Sprintf("%s/api/v1/stats", repositoryURL)
 
 	// Создаем GET запрос
-	req, err := http.NewRequest("GET", statsURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", statsURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
 	}
 
-	// Применяем rate limiting
-	pm.rateLimiter.Wait()
-
 	// Выполняем запрос
-	resp, err := pm.httpClient.Do(req)
+	resp, err := pm.doRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
 	}
@@ -1011,7 +2296,7 @@ func (pm *PackageManager) GetRepositoryStats(repositoryURL string) (*Statistics,
 
 	// Проверяем статус ответа
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ошибка сервера: %d", resp.StatusCode)
+		return nil, statusError(resp.StatusCode, "ошибка сервера: %d", resp.StatusCode)
 	}
 
 	// Читаем ответ
@@ -1037,21 +2322,18 @@ func (pm *PackageManager) GetRepositoryStats(repositoryURL string) (*Statistics,
 }
 
 // GetRepositoryInfo получает информацию о репозитории
-func (pm *PackageManager) GetRepositoryInfo(repositoryURL string) (map[string]interface{}, error) {
+func (pm *PackageManager) GetRepositoryInfo(ctx context.Context, repositoryURL string) (map[string]interface{}, error) {
 	// Создаем URL для эндпоинта информации о репозитории
 	infoURL := fmt.Sprintf("%s/api/v1/", repositoryURL)
 
 	// Создаем GET запрос
-	req, err := http.NewRequest("GET", infoURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", infoURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
 	}
 
-	// Применяем rate limiting
-	pm.rateLimiter.Wait()
-
 	// Выполняем запрос
-	resp, err := pm.httpClient.Do(req)
+	resp, err := pm.doRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
 	}
@@ -1059,7 +2341,7 @@ func (pm *PackageManager) GetRepositoryInfo(repositoryURL string) (map[string]in
 
 	// Проверяем статус ответа
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ошибка сервера: %d", resp.StatusCode)
+		return nil, statusError(resp.StatusCode, "ошибка сервера: %d", resp.StatusCode)
 	}
 
 	// Читаем ответ
@@ -1084,6 +2366,45 @@ func (pm *PackageManager) GetRepositoryInfo(repositoryURL string) (map[string]in
 	return apiResp.Data, nil
 }
 
+// CheckTimeSync сравнивает локальные часы с заголовком Date ответа репозитория.
+// Положительное расхождение означает, что часы репозитория спешат относительно локальных.
+func (pm *PackageManager) CheckTimeSync(ctx context.Context, repositoryURL string) (*TimeSyncResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/", repositoryURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := pm.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	resp.Body.Close()
+	end := time.Now()
+
+	dateHeader := resp.Header.Get("Date")
+	if dateHeader == "" {
+		return nil, fmt.Errorf("репозиторий не вернул заголовок Date")
+	}
+
+	serverTime, err := http.ParseTime(dateHeader)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный заголовок Date: %w", err)
+	}
+
+	// Сравниваем с серединой интервала запроса, чтобы компенсировать задержку сети
+	roundTrip := end.Sub(start)
+	localTime := start.Add(roundTrip / 2)
+
+	return &TimeSyncResult{
+		RepositoryURL: repositoryURL,
+		LocalTime:     localTime,
+		ServerTime:    serverTime,
+		Skew:          serverTime.Sub(localTime),
+		RoundTrip:     roundTrip,
+	}, nil
+}
+
 // PackageListResponse структура ответа для списка пакетов с пагинацией
 type PackageListResponse struct {
 	Packages   []*RepositoryPackage `json:"packages"`
@@ -1094,7 +2415,7 @@ type PackageListResponse struct {
 }
 
 // ListRepositoryPackages получает список всех пакетов из репозитория с пагинацией
-func (pm *PackageManager) ListRepositoryPackages(repositoryURL string, page, limit int) (*PackageListResponse, error) {
+func (pm *PackageManager) ListRepositoryPackages(ctx context.Context, repositoryURL string, page, limit int) (*PackageListResponse, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -1106,16 +2427,13 @@ func (pm *PackageManager) ListRepositoryPackages(repositoryURL string, page, lim
 	listURL := fmt.Sprintf("%s/api/v1/packages?page=%d&limit=%d", repositoryURL, page, limit)
 
 	// Создаем GET запрос
-	req, err := http.NewRequest("GET", listURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
 	}
 
-	// Применяем rate limiting
-	pm.rateLimiter.Wait()
-
 	// Выполняем запрос
-	resp, err := pm.httpClient.Do(req)
+	resp, err := pm.doRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
 	}
@@ -1123,7 +2441,7 @@ func (pm *PackageManager) ListRepositoryPackages(repositoryURL string, page, lim
 
 	// Проверяем статус ответа
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ошибка сервера: %d", resp.StatusCode)
+		return nil, statusError(resp.StatusCode, "ошибка сервера: %d", resp.StatusCode)
 	}
 
 	// Читаем ответ
@@ -1153,21 +2471,18 @@ func (pm *PackageManager) ListRepositoryPackages(repositoryURL string, page, lim
 }
 
 // GetPackageVersionInfo получает информацию о конкретной версии пакета
-func (pm *PackageManager) GetPackageVersionInfo(repositoryURL, packageName, version string) (*RepositoryVersion, error) {
+func (pm *PackageManager) GetPackageVersionInfo(ctx context.Context, repositoryURL, packageName, version string) (*RepositoryVersion, error) {
 	// Создаем URL для эндпоинта конкретной версии пакета
 	versionURL := fmt.Sprintf("%s/api/v1/packages/%s/%s", repositoryURL, packageName, version)
 
 	// Создаем GET запрос
-	req, err := http.NewRequest("GET", versionURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", versionURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
 	}
 
-	// Применяем rate limiting
-	pm.rateLimiter.Wait()
-
 	// Выполняем запрос
-	resp, err := pm.httpClient.Do(req)
+	resp, err := pm.doRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
 	}
@@ -1175,11 +2490,11 @@ func (pm *PackageManager) GetPackageVersionInfo(repositoryURL, packageName, vers
 
 	// Проверяем статус ответа
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("версия пакета не найдена: %s@%s", packageName, version)
+		return nil, fmt.Errorf("%w: %s@%s", ErrVersionNotFound, packageName, version)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ошибка сервера: %d", resp.StatusCode)
+		return nil, statusError(resp.StatusCode, "ошибка сервера: %d", resp.StatusCode)
 	}
 
 	// Читаем ответ
@@ -1207,3 +2522,43 @@ func (pm *PackageManager) GetPackageVersionInfo(repositoryURL, packageName, vers
 
 	return apiResp.Data, nil
 }
+
+// maxRawJSONSize максимальный объем сырого ответа, возвращаемого для отладки
+const maxRawJSONSize = 256 * 1024
+
+// RawPackageJSON возвращает неразобранный ответ /api/v1/packages/{name}.
+// Ответ обрезается до maxRawJSONSize; truncated сообщает об обрезке.
+func (pm *PackageManager) RawPackageJSON(ctx context.Context, repositoryURL, packageName string) (body []byte, truncated bool, err error) {
+	url := fmt.Sprintf("%s/api/v1/packages/%s", strings.TrimRight(repositoryURL, "/"), packageName)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	// Используем токен, если репозиторий есть в конфигурации
+	for _, repo := range pm.getConfig().Repositories {
+		if strings.TrimRight(repo.URL, "/") == strings.TrimRight(repositoryURL, "/") && repo.AuthToken != "" {
+			req.Header.Set("Authorization", "Bearer "+repo.AuthToken)
+			break
+		}
+	}
+
+	resp, err := pm.doRequest(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(io.LimitReader(resp.Body, maxRawJSONSize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка чтения ответа: %w", err)
+	}
+
+	if len(body) > maxRawJSONSize {
+		body = body[:maxRawJSONSize]
+		truncated = true
+	}
+
+	return body, truncated, nil
+}
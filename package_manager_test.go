@@ -0,0 +1,1947 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestPackageManager создает пакетный менеджер, работающий целиком во временной директории
+func newTestPackageManager(t *testing.T) *PackageManager {
+	t.Helper()
+
+	dir := t.TempDir()
+	pm, err := NewPackageManagerWithConfig(&Config{
+		GlobalPath:        filepath.Join(dir, "global"),
+		LocalPath:         filepath.Join(dir, "local"),
+		CachePath:         filepath.Join(dir, "cache"),
+		TempPath:          filepath.Join(dir, "temp"),
+		Timeout:           5,
+		MaxConcurrency:    2,
+		CompressionLevel:  3,
+		RequestsPerSecond: 1000,
+		MaxRetries:        1,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewPackageManagerWithConfig: %v", err)
+	}
+	// Изменения конфигурации в тестах сохраняются во временную директорию
+	pm.configPath = filepath.Join(dir, "config.json")
+	t.Cleanup(pm.Close)
+
+	return pm
+}
+
+// buildTestArchive собирает архив пакета с указанным манифестом и файлами
+func buildTestArchive(t *testing.T, pm *PackageManager, manifest PackageManifest, files map[string]string, format string) string {
+	t.Helper()
+
+	srcDir := t.TempDir()
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("Failed to marshal manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, manifestFileName), data, 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(srcDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	// Недопустимое имя из манифеста не должно влиять на путь самого архива
+	baseName := manifest.Name
+	if validatePackageName(baseName) != nil {
+		baseName = "package"
+	}
+	archivePath := filepath.Join(t.TempDir(), baseName+"-"+manifest.Version+archiveExtension(format))
+	if err := pm.createArchive(srcDir, archivePath, format, 3); err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+
+	return archivePath
+}
+
+// serveFile поднимает тестовый сервер, отдающий файл по пути /files/<имя файла>
+func serveFile(t *testing.T, path string) (*httptest.Server, string) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/"+filepath.Base(path) {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, path)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, server.URL + "/files/" + filepath.Base(path)
+}
+
+// mockRepository тестовый репозиторий пакетов с API criage-server
+type mockRepository struct {
+	*httptest.Server
+	mu        sync.Mutex
+	packages  map[string]*RepositoryPackage
+	files     map[string]string // имя файла -> путь к архиву на диске
+	downloads int
+	probes    int // запросы HEAD к файлам
+}
+
+// newMockRepository поднимает тестовый репозиторий с указанными пакетами
+func newMockRepository(t *testing.T, packages ...*RepositoryPackage) *mockRepository {
+	t.Helper()
+
+	repo := &mockRepository{
+		packages: make(map[string]*RepositoryPackage),
+		files:    make(map[string]string),
+	}
+	for _, pkg := range packages {
+		repo.packages[pkg.Name] = pkg
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/packages", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		repo.mu.Lock()
+		var all []*RepositoryPackage
+		for _, pkg := range repo.packages {
+			all = append(all, pkg)
+		}
+		repo.mu.Unlock()
+		sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+
+		start := (page - 1) * limit
+		if start > len(all) {
+			start = len(all)
+		}
+		end := start + limit
+		if end > len(all) {
+			end = len(all)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": PackageListResponse{
+			Packages:   all[start:end],
+			Total:      len(all),
+			Page:       page,
+			Limit:      limit,
+			TotalPages: (len(all) + limit - 1) / limit,
+		}})
+	})
+	mux.HandleFunc("/api/v1/packages/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/v1/packages/")
+		repo.mu.Lock()
+		pkg, ok := repo.packages[name]
+		repo.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": pkg})
+	})
+	mux.HandleFunc("/api/v1/search", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		results := []SearchResult{}
+
+		repo.mu.Lock()
+		for _, pkg := range repo.packages {
+			if strings.Contains(pkg.Name, query) {
+				results = append(results, SearchResult{Name: pkg.Name, Version: latestVersion(pkg), Description: pkg.Description, Score: 1})
+			}
+		}
+		repo.mu.Unlock()
+		sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": map[string]interface{}{
+			"query": query, "results": results, "total": len(results),
+		}})
+	})
+	mux.HandleFunc("/api/v1/download/", func(w http.ResponseWriter, r *http.Request) {
+		repo.mu.Lock()
+		path, ok := repo.files[filepath.Base(r.URL.Path)]
+		// Проверка наличия файла запросом HEAD скачиванием не считается
+		if r.Method == http.MethodHead {
+			repo.probes++
+		} else {
+			repo.downloads++
+		}
+		repo.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, path)
+	})
+
+	repo.Server = httptest.NewServer(mux)
+	t.Cleanup(repo.Close)
+
+	return repo
+}
+
+// publish добавляет в репозиторий версию пакета с архивом для текущей платформы
+func (m *mockRepository) publish(t *testing.T, name, version, archivePath string) {
+	t.Helper()
+
+	checksum, err := calculateChecksum(archivePath, defaultChecksumAlgorithm)
+	if err != nil {
+		t.Fatalf("checksum: %v", err)
+	}
+	format, err := detectArchiveFormat(archivePath)
+	if err != nil {
+		t.Fatalf("detect format: %v", err)
+	}
+	filename := fmt.Sprintf("%s-%s%s", name, version, archiveExtension(format))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pkg, ok := m.packages[name]
+	if !ok {
+		pkg = &RepositoryPackage{Name: name}
+		m.packages[name] = pkg
+	}
+	pkg.Versions = append(pkg.Versions, RepositoryVersion{
+		Version: version,
+		Files: []RepositoryFile{{
+			OS:       runtime.GOOS,
+			Arch:     runtime.GOARCH,
+			Filename: filename,
+			Checksum: checksum,
+		}},
+	})
+	pkg.LatestVersion = version
+	m.files[filename] = archivePath
+}
+
+// setDependencies задает зависимости опубликованной версии пакета
+func (m *mockRepository) setDependencies(name, version string, deps map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.packages[name].Versions {
+		if m.packages[name].Versions[i].Version == version {
+			m.packages[name].Versions[i].Dependencies = deps
+		}
+	}
+}
+
+// downloadCount возвращает число обращений к скачиванию файлов
+func (m *mockRepository) downloadCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.downloads
+}
+
+// repository возвращает описание репозитория для конфигурации
+func (m *mockRepository) repository(name string, priority int) Repository {
+	return Repository{Name: name, URL: m.URL, Priority: priority, Enabled: true}
+}
+
+// TestInstallFromURL проверяет установку пакета из архива по прямой ссылке
+func TestInstallFromURL(t *testing.T) {
+	for _, format := range []string{FormatTarGz, FormatTarZst, FormatTarXz, FormatTarBz2, FormatZip, FormatCriage} {
+		t.Run(format, func(t *testing.T) {
+			pm := newTestPackageManager(t)
+			manifest := PackageManifest{
+				Name:    "demo",
+				Version: "1.0.0",
+				Author:  "Test Author",
+				Files:   []string{"src/"},
+			}
+			archivePath := buildTestArchive(t, pm, manifest, map[string]string{"src/main.txt": "hello"}, format)
+			_, archiveURL := serveFile(t, archivePath)
+
+			checksum, err := calculateChecksum(archivePath, defaultChecksumAlgorithm)
+			if err != nil {
+				t.Fatalf("Failed to calculate checksum: %v", err)
+			}
+
+			info, err := pm.InstallFromURL(context.Background(), archiveURL, checksum, false, false)
+			if err != nil {
+				t.Fatalf("InstallFromURL failed: %v", err)
+			}
+
+			if info.Name != "demo" || info.Version != "1.0.0" {
+				t.Errorf("Unexpected package info: %s@%s", info.Name, info.Version)
+			}
+
+			content, err := os.ReadFile(filepath.Join(info.InstallPath, "src", "main.txt"))
+			if err != nil || string(content) != "hello" {
+				t.Errorf("Installed file mismatch: %q, %v", content, err)
+			}
+
+			if _, err := os.Stat(filepath.Join(info.InstallPath, archiveMetadataName)); !os.IsNotExist(err) {
+				t.Error("Archive metadata entry should not be installed")
+			}
+
+			data, err := os.ReadFile(filepath.Join(pm.getConfig().LocalPath, "packages.json"))
+			if err != nil {
+				t.Fatalf("Failed to read packages.json: %v", err)
+			}
+			if !strings.Contains(string(data), `"demo"`) {
+				t.Error("packages.json should contain installed package")
+			}
+
+			if _, err := pm.InstallFromURL(context.Background(), archiveURL, "", false, false); err == nil {
+				t.Error("Reinstall without force should fail")
+			}
+		})
+	}
+}
+
+// TestInstallFromURLRejections проверяет отказ при неверной контрольной сумме и политике URL
+func TestInstallFromURLRejections(t *testing.T) {
+	pm := newTestPackageManager(t)
+	manifest := PackageManifest{Name: "demo", Version: "1.0.0"}
+	archivePath := buildTestArchive(t, pm, manifest, nil, FormatTarGz)
+	_, archiveURL := serveFile(t, archivePath)
+
+	if _, err := pm.InstallFromURL(context.Background(), archiveURL, "sha256:deadbeef", false, false); err == nil || !strings.Contains(err.Error(), "контрольная сумма") {
+		t.Errorf("Expected checksum mismatch error, got %v", err)
+	}
+
+	pm.getConfig().ForceHTTPS = true
+	if _, err := pm.InstallFromURL(context.Background(), archiveURL, "", false, false); err == nil {
+		t.Error("Expected HTTP URL to be rejected with force_https")
+	}
+
+	pm.getConfig().ForceHTTPS = false
+	pm.getConfig().AllowedHosts = []string{"packages.criage.ru"}
+	if _, err := pm.InstallFromURL(context.Background(), archiveURL, "", false, false); err == nil {
+		t.Error("Expected host outside allow-list to be rejected")
+	}
+
+	if _, exists := pm.getInstalledPackage("demo"); exists {
+		t.Error("Package should not be installed after rejections")
+	}
+}
+
+// TestInstallRejectsPathTraversalNames проверяет, что имя пакета из манифеста архива
+// не позволяет заменить каталог за пределами корня установки или сам корень
+func TestInstallRejectsPathTraversalNames(t *testing.T) {
+	pm := newTestPackageManager(t)
+	victim := filepath.Join(filepath.Dir(pm.getConfig().LocalPath), "victim")
+	if err := os.MkdirAll(victim, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(victim, "data.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(pm.getConfig().LocalPath, "marker.txt")
+	if err := os.WriteFile(marker, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"../victim", "..", ".", " ", "a/b", `..\victim`, "/tmp/victim", "C:victim"} {
+		archivePath := buildTestArchive(t, pm, PackageManifest{Name: name, Version: "1.0.0"}, map[string]string{"data.txt": "evil"}, FormatTarGz)
+		if _, err := pm.installFromArchive(context.Background(), archivePath, false, true, nil); err == nil {
+			t.Errorf("expected install of %q to be rejected", name)
+		}
+
+		_, archiveURL := serveFile(t, archivePath)
+		if _, err := pm.InstallFromURL(context.Background(), archiveURL, "", false, true); err == nil {
+			t.Errorf("expected install_from_url of %q to be rejected", name)
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(victim, "data.txt")); err != nil || string(data) != "keep" {
+		t.Errorf("sibling directory was modified: %q, %v", data, err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("install root was replaced: %v", err)
+	}
+	if len(pm.installedPackages) != 0 {
+		t.Errorf("expected no installed packages, got %d", len(pm.installedPackages))
+	}
+}
+
+// TestInstallFromURLRedirectPolicy проверяет, что политика allowed_hosts применяется
+// к каждому перенаправлению, а не только к исходной ссылке
+func TestInstallFromURLRedirectPolicy(t *testing.T) {
+	pm := newTestPackageManager(t)
+	archivePath := buildTestArchive(t, pm, PackageManifest{Name: "demo", Version: "1.0.0"}, nil, FormatTarGz)
+	_, archiveURL := serveFile(t, archivePath)
+
+	parsed, err := url.Parse(archiveURL)
+	if err != nil {
+		t.Fatalf("parse URL: %v", err)
+	}
+	// Тот же сервер под другим именем хоста, не входящим в allowed_hosts
+	disallowed := *parsed
+	disallowed.Host = "localhost:" + parsed.Port()
+
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/allowed.tar.gz":
+			http.Redirect(w, r, archiveURL, http.StatusFound)
+		default:
+			http.Redirect(w, r, disallowed.String(), http.StatusFound)
+		}
+	}))
+	defer redirector.Close()
+	pm.getConfig().AllowedHosts = []string{"127.0.0.1"}
+
+	if _, err := pm.InstallFromURL(context.Background(), redirector.URL+"/escape.tar.gz", "", false, false); err == nil || !strings.Contains(err.Error(), "allowed_hosts") {
+		t.Errorf("expected redirect to a disallowed host to be rejected, got %v", err)
+	}
+	if _, exists := pm.getInstalledPackage("demo"); exists {
+		t.Fatal("package should not be installed after rejected redirect")
+	}
+
+	if _, err := pm.InstallFromURL(context.Background(), redirector.URL+"/allowed.tar.gz", "", false, false); err != nil {
+		t.Errorf("expected redirect within allowed hosts to succeed: %v", err)
+	}
+}
+
+// TestInstallFromURLTool проверяет инструмент install_from_url с аргументом expected_checksum
+func TestInstallFromURLTool(t *testing.T) {
+	pm := newTestPackageManager(t)
+	s := newTestServer(t, pm)
+	archivePath := buildTestArchive(t, pm, PackageManifest{Name: "prerelease", Version: "2.0.0-rc.1"},
+		map[string]string{"main.txt": "rc"}, FormatTarZst)
+	_, archiveURL := serveFile(t, archivePath)
+	checksum, err := calculateChecksum(archivePath, defaultChecksumAlgorithm)
+	if err != nil {
+		t.Fatalf("checksum: %v", err)
+	}
+
+	if _, err := callToolText(t, s, "install_from_url", map[string]interface{}{
+		"url": archiveURL, "expected_checksum": "sha256:" + strings.Repeat("0", 64),
+	}); err == nil || !strings.Contains(err.Error(), "контрольная сумма") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if _, exists := pm.getInstalledPackage("prerelease"); exists {
+		t.Fatal("package must not be installed after checksum mismatch")
+	}
+
+	if _, err := callToolText(t, s, "install_from_url", map[string]interface{}{
+		"url": archiveURL, "expected_checksum": checksum, "checksum": "sha256:" + strings.Repeat("1", 64),
+	}); err == nil {
+		t.Error("expected error for conflicting checksum arguments")
+	}
+
+	text, err := callToolText(t, s, "install_from_url", map[string]interface{}{
+		"url": archiveURL, "expected_checksum": strings.TrimPrefix(checksum, "sha256:"),
+	})
+	if err != nil {
+		t.Fatalf("install_from_url: %v", err)
+	}
+	if !strings.Contains(text, "prerelease (2.0.0-rc.1)") || !strings.Contains(text, "Контрольная сумма совпадает") {
+		t.Errorf("unexpected output: %q", text)
+	}
+}
+
+// TestInstallFromFile проверяет установку из локального архива со сверкой контрольных сумм содержимого
+func TestInstallFromFile(t *testing.T) {
+	pm := newTestPackageManager(t)
+	s := newTestServer(t, pm)
+	archivePath := buildTestArchive(t, pm, PackageManifest{Name: "local-tool", Version: "1.4.0"},
+		map[string]string{"bin/run.sh": "echo run"}, FormatCriage)
+
+	text, err := callToolText(t, s, "install_from_file", map[string]interface{}{"path": archivePath})
+	if err != nil {
+		t.Fatalf("install_from_file: %v", err)
+	}
+	if !strings.Contains(text, "local-tool (1.4.0)") || !strings.Contains(text, "совпадает с контрольными суммами") {
+		t.Errorf("unexpected install output: %q", text)
+	}
+
+	list, err := callToolText(t, s, "list_packages", nil)
+	if err != nil {
+		t.Fatalf("list_packages: %v", err)
+	}
+	if !strings.Contains(list, "local-tool") {
+		t.Errorf("expected installed package in list, got %q", list)
+	}
+	if data, err := os.ReadFile(filepath.Join(pm.getConfig().LocalPath, "local-tool", "bin", "run.sh")); err != nil || string(data) != "echo run" {
+		t.Errorf("unexpected installed file: %q (err %v)", data, err)
+	}
+
+	// Архив без метаданных устанавливается без проверки
+	plain := buildTestArchive(t, pm, PackageManifest{Name: "plain", Version: "1.0.0"}, nil, FormatTarGz)
+	if _, verified, err := pm.InstallFromFile(context.Background(), plain, false, false); err != nil || verified {
+		t.Errorf("expected unverified install of tar.gz, got verified=%v, err %v", verified, err)
+	}
+
+	if _, _, err := pm.InstallFromFile(context.Background(), filepath.Join(t.TempDir(), "missing.criage"), false, false); err == nil {
+		t.Error("expected error for missing archive")
+	}
+}
+
+// TestInstallFromFileChecksumMismatch проверяет отказ от установки архива с измененным содержимым
+func TestInstallFromFileChecksumMismatch(t *testing.T) {
+	pm := newTestPackageManager(t)
+	archivePath := filepath.Join(t.TempDir(), "tampered-1.0.0.tar.gz")
+
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	manifest, _ := json.Marshal(PackageManifest{Name: "tampered", Version: "1.0.0"})
+	manifestSum := sha256.Sum256(manifest)
+	originalSum := sha256.Sum256([]byte("original"))
+	metadata, _ := json.Marshal(ArchiveMetadata{Checksums: map[string]string{
+		manifestFileName: hex.EncodeToString(manifestSum[:]),
+		"data.txt":       hex.EncodeToString(originalSum[:]),
+	}})
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{{archiveMetadataName, metadata}, {manifestFileName, manifest}, {"data.txt", []byte("modified")}} {
+		tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.data)), Typeflag: tar.TypeReg})
+		tw.Write(entry.data)
+	}
+	tw.Close()
+	gz.Close()
+	file.Close()
+
+	_, _, err = pm.InstallFromFile(context.Background(), archivePath, false, false)
+	if err == nil || !strings.Contains(err.Error(), "data.txt") {
+		t.Fatalf("expected checksum mismatch for data.txt, got %v", err)
+	}
+	if _, exists := pm.getInstalledPackage("tampered"); exists {
+		t.Error("tampered archive must not be installed")
+	}
+}
+
+// installTestArchive собирает архив и устанавливает его через installFromArchive
+func installTestArchive(t *testing.T, pm *PackageManager, manifest PackageManifest, global bool) *PackageInfo {
+	t.Helper()
+
+	archivePath := buildTestArchive(t, pm, manifest, map[string]string{"src/main.txt": manifest.Name}, FormatTarGz)
+	info, err := pm.installFromArchive(context.Background(), archivePath, global, true, nil)
+	if err != nil {
+		t.Fatalf("Failed to install %s: %v", manifest.Name, err)
+	}
+
+	return info
+}
+
+// TestPackageKeywordsPersistAndGroup проверяет сохранение ключевых слов и группировку по категориям
+func TestPackageKeywordsPersistAndGroup(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "web-kit", Version: "1.0.0", Keywords: []string{"web", "HTTP"}}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "router", Version: "2.0.0", Keywords: []string{"web"}}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "plain", Version: "0.1.0"}, false)
+
+	// Перечитываем индекс с диска, чтобы убедиться в сохранении ключевых слов
+	pm.installedPackages = make(map[string]*PackageInfo)
+	if err := pm.loadInstalledPackages(); err != nil {
+		t.Fatalf("Failed to reload packages: %v", err)
+	}
+
+	info, err := pm.GetPackageInfo("web-kit")
+	if err != nil {
+		t.Fatalf("GetPackageInfo failed: %v", err)
+	}
+	if len(info.Keywords) != 2 || info.Keywords[0] != "web" || info.Keywords[1] != "HTTP" {
+		t.Errorf("Keywords not persisted: %v", info.Keywords)
+	}
+
+	groups := pm.PackagesByCategory("")
+	if len(groups["web"]) != 2 || groups["web"][0].Name != "router" || groups["web"][1].Name != "web-kit" {
+		t.Errorf("Unexpected web group: %v", groups["web"])
+	}
+	if len(groups["http"]) != 1 {
+		t.Errorf("Keywords should be grouped case-insensitively, got %v", groups["http"])
+	}
+	if len(groups[uncategorizedKey]) != 1 || groups[uncategorizedKey][0].Name != "plain" {
+		t.Errorf("Package without keywords should be uncategorized: %v", groups[uncategorizedKey])
+	}
+
+	filtered := pm.PackagesByCategory("Web")
+	if len(filtered) != 1 || len(filtered["web"]) != 2 {
+		t.Errorf("Filter should return only the web group, got %v", filtered)
+	}
+}
+
+// TestVerifyPackage проверяет обнаружение удаленных файлов, изменения размера и пропажи манифеста
+func TestVerifyPackage(t *testing.T) {
+	pm := newTestPackageManager(t)
+	info := installTestArchive(t, pm, PackageManifest{Name: "checked", Version: "1.0.0", Files: []string{"src/", "src/main.txt"}}, false)
+
+	result, err := pm.VerifyPackage("checked")
+	if err != nil {
+		t.Fatalf("VerifyPackage failed: %v", err)
+	}
+	if !result.OK || result.CheckedFiles != 2 || !result.ManifestPresent {
+		t.Errorf("Freshly installed package should verify: %+v", result)
+	}
+
+	if err := os.Remove(filepath.Join(info.InstallPath, "src", "main.txt")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	result, err = pm.VerifyPackage("checked")
+	if err != nil {
+		t.Fatalf("VerifyPackage failed: %v", err)
+	}
+	if result.OK {
+		t.Error("Verification should fail after file removal")
+	}
+	if len(result.MissingFiles) != 1 || result.MissingFiles[0] != "src/main.txt" {
+		t.Errorf("Unexpected missing files: %v", result.MissingFiles)
+	}
+	if result.ActualSize >= result.ExpectedSize {
+		t.Errorf("Size drift not detected: actual %d, expected %d", result.ActualSize, result.ExpectedSize)
+	}
+
+	if err := os.Remove(filepath.Join(info.InstallPath, manifestFileName)); err != nil {
+		t.Fatalf("Failed to remove manifest: %v", err)
+	}
+	result, _ = pm.VerifyPackage("checked")
+	if result.ManifestPresent {
+		t.Error("Missing manifest not detected")
+	}
+
+	if _, err := pm.VerifyPackage("absent"); err == nil {
+		t.Error("Expected error for package that is not installed")
+	}
+}
+
+// TestPackageFiles проверяет список файлов пакета с размерами и наличием на диске
+func TestPackageFiles(t *testing.T) {
+	pm := newTestPackageManager(t)
+	manifest := PackageManifest{Name: "files", Version: "1.0.0", Files: []string{"bin/", "bin/tool", "README.md"}}
+	archivePath := buildTestArchive(t, pm, manifest, map[string]string{"bin/tool": "12345", "README.md": "hi"}, FormatTarGz)
+	info, err := pm.installFromArchive(context.Background(), archivePath, false, true, nil)
+	if err != nil {
+		t.Fatalf("Failed to install: %v", err)
+	}
+	if err := os.Remove(filepath.Join(info.InstallPath, "README.md")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	result, err := pm.PackageFiles("files")
+	if err != nil {
+		t.Fatalf("PackageFiles failed: %v", err)
+	}
+	want := []PackageFile{
+		{Path: "bin/", Size: 5, Dir: true, Exists: true},
+		{Path: "bin/tool", Size: 5, Exists: true},
+		{Path: "README.md"},
+	}
+	if !reflect.DeepEqual(result.Files, want) {
+		t.Errorf("Files = %+v, want %+v", result.Files, want)
+	}
+	if result.TotalSize != 5 || result.Missing != 1 {
+		t.Errorf("Unexpected totals: size %d, missing %d", result.TotalSize, result.Missing)
+	}
+
+	text, err := callToolText(t, newTestServer(t, pm), "package_files", map[string]interface{}{"name": "files"})
+	if err != nil {
+		t.Fatalf("package_files failed: %v", err)
+	}
+	if !strings.Contains(text, "README.md — отсутствует") || !strings.Contains(text, "bin/tool (5 B)") {
+		t.Errorf("Unexpected tool output:\n%s", text)
+	}
+
+	if _, err := pm.PackageFiles("absent"); !errors.Is(err, ErrPackageNotFound) {
+		t.Errorf("Expected ErrPackageNotFound, got %v", err)
+	}
+}
+
+// TestDownloadCacheHit проверяет, что повторная установка той же версии берется из кеша
+func TestDownloadCacheHit(t *testing.T) {
+	pm := newTestPackageManager(t)
+	archivePath := buildTestArchive(t, pm, PackageManifest{Name: "cached", Version: "1.0.0"},
+		map[string]string{"src/main.txt": "cached"}, FormatTarGz)
+
+	repo := newMockRepository(t)
+	repo.publish(t, "cached", "1.0.0", archivePath)
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	ctx := context.Background()
+	if _, err := pm.InstallPackage(ctx, "cached", "1.0.0", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("first install: %v", err)
+	}
+	if got := repo.downloadCount(); got != 1 {
+		t.Fatalf("expected 1 download, got %d", got)
+	}
+
+	entries, err := os.ReadDir(pm.cacheDir())
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one cached archive, got %v (err %v)", entries, err)
+	}
+
+	if _, err := pm.InstallPackage(ctx, "cached", "1.0.0", false, true, false, false, "", ""); err != nil {
+		t.Fatalf("second install: %v", err)
+	}
+	if got := repo.downloadCount(); got != 1 {
+		t.Errorf("expected cache hit without download, got %d downloads", got)
+	}
+	if _, err := os.Stat(filepath.Join(pm.getConfig().LocalPath, "cached", "src", "main.txt")); err != nil {
+		t.Errorf("package not installed from cache: %v", err)
+	}
+}
+
+// roundTripFunc позволяет подставить функцию в качестве транспорта HTTP-клиента
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// TestNewPackageManagerWithConfig проверяет менеджер, созданный целиком внутри временного
+// каталога: установка идет через переданный HTTP-клиент, а файл конфигурации не пишется
+func TestNewPackageManagerWithConfig(t *testing.T) {
+	dir := t.TempDir()
+	config := defaultConfig(dir)
+	config.LocalPath = filepath.Join(dir, "local")
+
+	var mu sync.Mutex
+	requests := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+
+	pm, err := NewPackageManagerWithConfig(config, client)
+	if err != nil {
+		t.Fatalf("NewPackageManagerWithConfig: %v", err)
+	}
+	t.Cleanup(pm.Close)
+
+	repo := newMockRepository(t)
+	repo.publish(t, "hello", "1.0.0", buildTestArchive(t, pm, PackageManifest{Name: "hello", Version: "1.0.0"},
+		map[string]string{"src/main.txt": "hello"}, FormatTarGz))
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	if _, err := pm.InstallPackage(context.Background(), "hello", "", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("InstallPackage: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "local", "hello", "src", "main.txt")); err != nil {
+		t.Errorf("package not installed into temp dir: %v", err)
+	}
+	mu.Lock()
+	if requests == 0 {
+		t.Error("expected requests through the injected client")
+	}
+	mu.Unlock()
+
+	for _, path := range []string{config.GlobalPath, config.CachePath, config.TempPath} {
+		if !strings.HasPrefix(path, dir) {
+			t.Errorf("path %s is outside the temp dir", path)
+		}
+	}
+	if _, err := pm.SetConfigValue("timeout", "10"); err == nil {
+		t.Error("expected error saving config without a config file")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.json")); !os.IsNotExist(err) {
+		t.Errorf("config file should not be written, stat err: %v", err)
+	}
+}
+
+// TestDownloadCacheEviction проверяет вытеснение давно не использовавшихся архивов
+func TestDownloadCacheEviction(t *testing.T) {
+	pm := newTestPackageManager(t)
+	if err := os.MkdirAll(pm.cacheDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	names := []string{"oldest.tar.gz", "middle.tar.gz", "newest.tar.gz"}
+	for i, name := range names {
+		path := filepath.Join(pm.cacheDir(), name)
+		if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(time.Duration(i-len(names)) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pm.getConfig().MaxCacheSize = 250
+	if err := pm.evictCache(""); err != nil {
+		t.Fatalf("evictCache: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(pm.cacheDir(), "oldest.tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("expected oldest entry to be evicted, stat err: %v", err)
+	}
+	for _, name := range names[1:] {
+		if _, err := os.Stat(filepath.Join(pm.cacheDir(), name)); err != nil {
+			t.Errorf("expected %s to stay in cache: %v", name, err)
+		}
+	}
+}
+
+// TestCleanCacheDryRun проверяет, что dry_run только сообщает об удалении, а очистка не трогает другие каталоги
+func TestCleanCacheDryRun(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "kept", Version: "1.0.0"}, false)
+
+	for _, dir := range []string{pm.cacheDir(), pm.getConfig().TempPath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := filepath.Join(pm.cacheDir(), "old.tar.gz")
+	fresh := filepath.Join(pm.cacheDir(), "fresh.tar.gz")
+	tempFile := filepath.Join(pm.getConfig().TempPath, "partial.tmp")
+	for _, path := range []string{old, fresh, tempFile} {
+		if err := os.WriteFile(path, make([]byte, 10), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+	if err := os.Chtimes(old, lastWeek, lastWeek); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := pm.CleanCache(0, true)
+	if err != nil {
+		t.Fatalf("CleanCache dry run: %v", err)
+	}
+	if result.Files != 2 || result.Bytes != 20 {
+		t.Errorf("expected 2 files / 20 bytes, got %d / %d", result.Files, result.Bytes)
+	}
+	for _, path := range []string{old, fresh} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("dry run removed %s", path)
+		}
+	}
+
+	result, err = pm.CleanCache(24*time.Hour, false)
+	if err != nil {
+		t.Fatalf("CleanCache: %v", err)
+	}
+	if result.Files != 1 {
+		t.Errorf("expected only the stale archive to be removed, got %d", result.Files)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("stale archive was not removed")
+	}
+	for _, path := range []string{fresh, tempFile, filepath.Join(pm.getConfig().LocalPath, "kept", "src", "main.txt")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be kept: %v", path, err)
+		}
+	}
+}
+
+// buildSymlinkArchive собирает архив с файлом bin/tool и ссылкой bin/alias -> tool
+func buildSymlinkArchive(t *testing.T, pm *PackageManager) string {
+	t.Helper()
+
+	srcDir := t.TempDir()
+	data, err := json.Marshal(PackageManifest{Name: "linked", Version: "1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, manifestFileName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(srcDir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "bin", "tool"), []byte("tool"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("tool", filepath.Join(srcDir, "bin", "alias")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "linked-1.0.0"+archiveExtension(FormatTarGz))
+	if err := pm.createArchive(srcDir, archivePath, FormatTarGz, 3); err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	return archivePath
+}
+
+// TestSymlinkPolicy проверяет установку пакета со ссылками при каждой политике
+func TestSymlinkPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		check   func(t *testing.T, alias string)
+		skipped int
+	}{
+		{
+			policy: SymlinkPreserve,
+			check: func(t *testing.T, alias string) {
+				info, err := os.Lstat(alias)
+				if err != nil || info.Mode()&os.ModeSymlink == 0 {
+					t.Errorf("expected alias to stay a symlink (err %v)", err)
+				}
+			},
+		},
+		{
+			policy: SymlinkDereference,
+			check: func(t *testing.T, alias string) {
+				info, err := os.Lstat(alias)
+				if err != nil || !info.Mode().IsRegular() {
+					t.Fatalf("expected alias to be a regular file (err %v)", err)
+				}
+				if data, _ := os.ReadFile(alias); string(data) != "tool" {
+					t.Errorf("expected dereferenced content, got %q", data)
+				}
+			},
+		},
+		{
+			policy: SymlinkSkip,
+			check: func(t *testing.T, alias string) {
+				if _, err := os.Lstat(alias); !os.IsNotExist(err) {
+					t.Errorf("expected alias to be skipped, stat err: %v", err)
+				}
+			},
+			skipped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			pm := newTestPackageManager(t)
+			pm.getConfig().SymlinkPolicy = tt.policy
+			archivePath := buildSymlinkArchive(t, pm)
+
+			info, err := pm.installFromArchive(context.Background(), archivePath, false, false, nil)
+			if err != nil {
+				t.Fatalf("install failed: %v", err)
+			}
+
+			tt.check(t, filepath.Join(info.InstallPath, "bin", "alias"))
+			if len(info.SkippedSymlinks) != tt.skipped {
+				t.Errorf("expected %d skipped symlinks, got %v", tt.skipped, info.SkippedSymlinks)
+			}
+			if _, err := os.Stat(filepath.Join(info.InstallPath, "bin", "tool")); err != nil {
+				t.Errorf("regular file missing: %v", err)
+			}
+		})
+	}
+}
+
+// TestSymlinkPolicyUnknown проверяет отказ при неизвестной политике
+func TestSymlinkPolicyUnknown(t *testing.T) {
+	pm := newTestPackageManager(t)
+	archivePath := buildSymlinkArchive(t, pm)
+	pm.getConfig().SymlinkPolicy = "follow"
+
+	if _, err := pm.installFromArchive(context.Background(), archivePath, false, false, nil); err == nil {
+		t.Fatal("expected error for unknown symlink policy")
+	}
+}
+
+// TestUnsupportedArchiveFormat проверяет, что ошибка о неподдерживаемом формате перечисляет доступные
+func TestUnsupportedArchiveFormat(t *testing.T) {
+	pm := newTestPackageManager(t)
+	srcDir := t.TempDir()
+
+	createErr := pm.createArchive(srcDir, filepath.Join(t.TempDir(), "pkg.rar"), "rar", 3)
+	_, extractErr := pm.extractArchive(context.Background(), filepath.Join(srcDir, "pkg-1.0.0.rar"), t.TempDir())
+
+	for name, err := range map[string]error{"createArchive": createErr, "extractArchive": extractErr} {
+		if err == nil {
+			t.Errorf("%s: expected error for rar", name)
+			continue
+		}
+		for _, format := range supportedFormats {
+			if !strings.Contains(err.Error(), format) {
+				t.Errorf("%s: expected error to list %s, got %v", name, format, err)
+			}
+		}
+	}
+	if createErr != nil && !strings.Contains(createErr.Error(), "'rar'") {
+		t.Errorf("expected error to name the requested format, got %v", createErr)
+	}
+}
+
+// TestXzAndBzip2RoundTrip проверяет упаковку и извлечение tar.xz и tar.bz2, в том числе
+// по сигнатуре, когда расширение файла не указывает формат
+func TestXzAndBzip2RoundTrip(t *testing.T) {
+	pm := newTestPackageManager(t)
+	files := map[string]string{
+		"README.md":      strings.Repeat("criage package manager\n", 500),
+		"bin/zeros.bin":  strings.Repeat("\x00", 70000),
+		"data/mixed.txt": strings.Repeat("aaaab", 1000) + "tail",
+		"empty.txt":      "",
+	}
+
+	for _, format := range []string{FormatTarXz, FormatTarBz2} {
+		codec, _ := compressionCodecFor(format)
+		for _, level := range []int{codec.MinLevel, codec.DefaultLevel} {
+			srcDir := t.TempDir()
+			for name, content := range files {
+				path := filepath.Join(srcDir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("MkdirAll: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			}
+
+			archivePath := filepath.Join(t.TempDir(), "pkg"+archiveExtension(format))
+			if err := pm.createArchive(srcDir, archivePath, format, level); err != nil {
+				t.Fatalf("createArchive(%s, %d): %v", format, level, err)
+			}
+			// Расширение без формата: извлечение определяет формат по сигнатуре
+			renamed := filepath.Join(filepath.Dir(archivePath), "pkg.download")
+			if err := os.Rename(archivePath, renamed); err != nil {
+				t.Fatalf("Rename: %v", err)
+			}
+			if sniffed, err := sniffArchiveFormat(renamed); err != nil || sniffed != format {
+				t.Errorf("sniffArchiveFormat: got %q, %v; want %s", sniffed, err, format)
+			}
+
+			destDir := t.TempDir()
+			if _, err := pm.extractArchive(context.Background(), renamed, destDir); err != nil {
+				t.Fatalf("extractArchive(%s, %d): %v", format, level, err)
+			}
+			for name, want := range files {
+				got, err := os.ReadFile(filepath.Join(destDir, filepath.FromSlash(name)))
+				if err != nil || string(got) != want {
+					t.Errorf("%s level %d: %s mismatch (%d bytes, err %v)", format, level, name, len(got), err)
+				}
+			}
+		}
+	}
+}
+
+// TestArchiveFormatSniffing проверяет определение формата по сигнатуре для архивов
+// с неверным расширением или без него и откат к расширению при неизвестной сигнатуре
+func TestArchiveFormatSniffing(t *testing.T) {
+	pm := newTestPackageManager(t)
+	manifest := PackageManifest{Name: "sniffed", Version: "1.0.0"}
+
+	for _, format := range supportedFormats {
+		archivePath := buildTestArchive(t, pm, manifest, map[string]string{"data.txt": format}, format)
+		content, err := os.ReadFile(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Формат с другим алгоритмом сжатия для заведомо неверного расширения
+		wrong := FormatTarGz
+		if format == FormatTarGz {
+			wrong = FormatTarZst
+		}
+		want := format
+		if format == FormatCriage {
+			want = FormatTarZst // без расширения criage не отличить от tar.zst
+		}
+
+		dir := t.TempDir()
+		for _, name := range []string{"package" + archiveExtension(wrong), "package", "package.tmp"} {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, err := archiveFormat(path); err != nil || got != want {
+				t.Errorf("%s as %s: got %q, %v; want %s", format, name, got, err, want)
+			}
+			destDir := t.TempDir()
+			if _, err := pm.extractArchive(context.Background(), path, destDir); err != nil {
+				t.Errorf("%s as %s: extractArchive: %v", format, name, err)
+				continue
+			}
+			if data, err := os.ReadFile(filepath.Join(destDir, "data.txt")); err != nil || string(data) != format {
+				t.Errorf("%s as %s: unexpected content %q, %v", format, name, data, err)
+			}
+		}
+	}
+
+	// Расширение, согласованное с содержимым, сохраняется
+	criagePath := buildTestArchive(t, pm, manifest, nil, FormatCriage)
+	if got, err := archiveFormat(criagePath); err != nil || got != FormatCriage {
+		t.Errorf("expected criage extension to be kept, got %q, %v", got, err)
+	}
+
+	// Сигнатура не распознана: формат берется из расширения
+	unknown := filepath.Join(t.TempDir(), "broken.tar.gz")
+	if err := os.WriteFile(unknown, []byte("not an archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := archiveFormat(unknown); err != nil || got != FormatTarGz {
+		t.Errorf("expected fallback to extension, got %q, %v", got, err)
+	}
+	if _, err := archiveFormat(filepath.Join(t.TempDir(), "garbage.bin")); err == nil {
+		t.Error("expected error for a missing file without a known extension")
+	}
+}
+
+// TestCompressionLevelValidation проверяет границы уровня сжатия для каждого формата
+func TestCompressionLevelValidation(t *testing.T) {
+	pm := newTestPackageManager(t)
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	tests := []struct {
+		format string
+		level  int
+		valid  bool
+		bounds string
+	}{
+		{FormatCriage, 1, true, ""},
+		{FormatCriage, 22, true, ""},
+		{FormatCriage, 0, false, "от 1 до 22"},
+		{FormatCriage, 23, false, "от 1 до 22"},
+		{FormatTarZst, 19, true, ""},
+		{FormatTarZst, -1, false, "от 1 до 22"},
+		{FormatTarXz, 0, true, ""},
+		{FormatTarXz, 10, false, "от 0 до 9"},
+		{FormatTarBz2, 9, true, ""},
+		{FormatTarBz2, 0, false, "от 1 до 9"},
+		{FormatTarGz, 1, true, ""},
+		{FormatTarGz, 9, true, ""},
+		{FormatTarGz, 0, false, "от 1 до 9"},
+		{FormatTarGz, 10, false, "от 1 до 9"},
+		{FormatTarGz, 22, false, "от 1 до 9"},
+	}
+
+	for _, tt := range tests {
+		archivePath := filepath.Join(t.TempDir(), "pkg"+archiveExtension(tt.format))
+		err := pm.createArchive(srcDir, archivePath, tt.format, tt.level)
+		if tt.valid {
+			if err != nil {
+				t.Errorf("%s level %d: unexpected error: %v", tt.format, tt.level, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), tt.bounds) || !strings.Contains(err.Error(), tt.format) {
+			t.Errorf("%s level %d: expected error naming range %q, got %v", tt.format, tt.level, tt.bounds, err)
+		}
+		if _, statErr := os.Stat(archivePath); !os.IsNotExist(statErr) {
+			t.Errorf("%s level %d: archive must not be created", tt.format, tt.level)
+		}
+		// Сборка отклоняет уровень до чтения манифеста
+		if err := pm.BuildPackage(archivePath, tt.format, tt.level); err == nil || !strings.Contains(err.Error(), tt.bounds) {
+			t.Errorf("BuildPackage %s level %d: expected range error, got %v", tt.format, tt.level, err)
+		}
+	}
+
+	// Рекомендуемый уровень по умолчанию допустим для каждого формата
+	for _, format := range supportedFormats {
+		if err := validateCompressionLevel(format, recommendedCompressionLevel(format)); err != nil {
+			t.Errorf("recommended level for %s is invalid: %v", format, err)
+		}
+	}
+}
+
+// TestListOutdatedPackages проверяет, что outdated возвращает только отстающие пакеты
+func TestListOutdatedPackages(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "stale", Version: "1.2.0"}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "fresh", Version: "2.0.0"}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "local-only", Version: "0.1.0"}, false)
+
+	repo := newMockRepository(t,
+		&RepositoryPackage{Name: "stale", Versions: []RepositoryVersion{{Version: "1.2.0"}, {Version: "1.10.0"}}},
+		&RepositoryPackage{Name: "fresh", Versions: []RepositoryVersion{{Version: "1.9.0"}, {Version: "2.0.0"}}},
+	)
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	all, err := pm.ListPackages(context.Background(), false, false)
+	if err != nil {
+		t.Fatalf("ListPackages: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 installed packages, got %d", len(all))
+	}
+
+	outdated, err := pm.ListPackages(context.Background(), false, true)
+	if err != nil {
+		t.Fatalf("ListPackages outdated: %v", err)
+	}
+	if len(outdated) != 1 || outdated[0].Name != "stale" {
+		t.Fatalf("expected only stale package, got %+v", outdated)
+	}
+	if outdated[0].AvailableVersion != "1.10.0" {
+		t.Errorf("expected available version 1.10.0, got %q", outdated[0].AvailableVersion)
+	}
+
+	if info, _ := pm.getInstalledPackage("stale"); info.AvailableVersion != "" {
+		t.Errorf("outdated check must not modify stored package info")
+	}
+}
+
+// TestUpdateAll проверяет обновление смеси актуальных и устаревших пакетов
+func TestUpdateAll(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "stale", Version: "1.0.0"}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "current", Version: "2.0.0"}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "broken", Version: "1.0.0"}, false)
+
+	repo := newMockRepository(t,
+		&RepositoryPackage{Name: "current", Versions: []RepositoryVersion{{Version: "2.0.0"}}},
+		// Новая версия объявлена, но файлов для платформы нет
+		&RepositoryPackage{Name: "broken", Versions: []RepositoryVersion{{Version: "1.5.0"}}},
+	)
+	repo.publish(t, "stale", "1.1.0", buildTestArchive(t, pm, PackageManifest{Name: "stale", Version: "1.1.0"},
+		map[string]string{"src/main.txt": "new"}, FormatTarGz))
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	result, err := pm.UpdateAll(context.Background(), false)
+	if err != nil {
+		t.Fatalf("UpdateAll: %v", err)
+	}
+
+	if result.Updated != 1 || result.Failed != 1 || len(result.Packages) != 2 {
+		t.Fatalf("unexpected summary: %+v", result)
+	}
+	for _, pkg := range result.Packages {
+		switch pkg.Name {
+		case "stale":
+			if !pkg.Success || pkg.ToVersion != "1.1.0" {
+				t.Errorf("expected stale to be updated, got %+v", pkg)
+			}
+		case "broken":
+			if pkg.Success || pkg.Error == "" {
+				t.Errorf("expected broken update to fail with error, got %+v", pkg)
+			}
+		default:
+			t.Errorf("unexpected package in result: %s", pkg.Name)
+		}
+	}
+
+	if info, _ := pm.getInstalledPackage("stale"); info.Version != "1.1.0" {
+		t.Errorf("expected stale to be 1.1.0 after update, got %s", info.Version)
+	}
+	if info, _ := pm.getInstalledPackage("broken"); info.Version != "1.0.0" {
+		t.Errorf("failed update must keep installed version, got %s", info.Version)
+	}
+}
+
+// TestPinPackage проверяет, что закрепленные пакеты пропускаются при обновлении
+func TestPinPackage(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	for _, name := range []string{"held", "free"} {
+		installTestArchive(t, pm, PackageManifest{Name: name, Version: "1.0.0"}, false)
+		repo.publish(t, name, "1.1.0", buildTestArchive(t, pm, PackageManifest{Name: name, Version: "1.1.0"},
+			map[string]string{"version.txt": "1.1.0"}, FormatTarGz))
+	}
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	if _, err := pm.PinPackage("held", "2.0.0"); err == nil {
+		t.Error("expected error when pinning a version that is not installed")
+	}
+	info, err := pm.PinPackage("held", "")
+	if err != nil {
+		t.Fatalf("PinPackage: %v", err)
+	}
+	if !info.Pinned || info.PinnedVersion != "1.0.0" {
+		t.Errorf("expected pin at 1.0.0, got %+v", info)
+	}
+
+	// Закрепление сохраняется в packages.json
+	reloaded := newTestPackageManager(t)
+	if err := reloaded.loadPackagesFromFile(filepath.Join(pm.getConfig().LocalPath, packagesFileName)); err != nil {
+		t.Fatalf("reload packages: %v", err)
+	}
+	if info, _ := reloaded.getInstalledPackage("held"); info == nil || !info.Pinned {
+		t.Errorf("expected pin to persist, got %+v", info)
+	}
+
+	result, err := pm.UpdateAll(ctx, false)
+	if err != nil {
+		t.Fatalf("UpdateAll: %v", err)
+	}
+	if result.Updated != 1 || result.Skipped != 1 || result.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", result)
+	}
+	for _, pkg := range result.Packages {
+		if pkg.Name == "held" && (!pkg.Skipped || pkg.Success) {
+			t.Errorf("expected held to be skipped, got %+v", pkg)
+		}
+	}
+
+	update, err := pm.UpdatePackage(ctx, "held")
+	if err != nil {
+		t.Fatalf("UpdatePackage on pinned package must not fail: %v", err)
+	}
+	if !update.Skipped {
+		t.Errorf("expected pinned package to be skipped, got %+v", update)
+	}
+	if info, _ := pm.getInstalledPackage("held"); info.Version != "1.0.0" {
+		t.Errorf("pinned package must stay at 1.0.0, got %s", info.Version)
+	}
+
+	if _, err := pm.UnpinPackage("held"); err != nil {
+		t.Fatalf("UnpinPackage: %v", err)
+	}
+	if update, err = pm.UpdatePackage(ctx, "held"); err != nil || !update.Success {
+		t.Fatalf("expected update after unpin, got %+v, %v", update, err)
+	}
+	if info, _ := pm.getInstalledPackage("held"); info.Version != "1.1.0" || info.Pinned {
+		t.Errorf("expected unpinned held 1.1.0, got %+v", info)
+	}
+}
+
+// TestInstallBuildMetadata проверяет установку сборок, отличающихся только метаданными
+func TestInstallBuildMetadata(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	for _, build := range []string{"build.5", "build.6"} {
+		archivePath := buildTestArchive(t, pm, PackageManifest{Name: "tool", Version: "1.0.0"},
+			map[string]string{"build.txt": build}, FormatTarGz)
+		repo.publish(t, "tool", "1.0.0+"+build, archivePath)
+	}
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	if _, err := pm.InstallPackage(ctx, "tool", "1.0.0", false, false, false, false, "", ""); err == nil {
+		t.Fatal("expected ambiguous version error without build metadata")
+	}
+
+	if _, err := pm.InstallPackage(ctx, "tool", "1.0.0+build.5", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("install build.5: %v", err)
+	}
+	info, _ := pm.getInstalledPackage("tool")
+	if info.ResolvedVersion != "1.0.0+build.5" || info.Checksum == "" {
+		t.Errorf("expected resolved build.5 with checksum, got %q / %q", info.ResolvedVersion, info.Checksum)
+	}
+
+	if _, err := pm.InstallPackage(ctx, "tool", "1.0.0+build.5", false, false, false, false, "", ""); err == nil {
+		t.Error("expected already installed error for the same build")
+	}
+
+	if _, err := pm.InstallPackage(ctx, "tool", "1.0.0+build.6", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("install build.6: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(pm.getConfig().LocalPath, "tool", "build.txt"))
+	if err != nil || string(data) != "build.6" {
+		t.Errorf("expected build.6 contents, got %q (err %v)", data, err)
+	}
+	if info, _ := pm.getInstalledPackage("tool"); info.ResolvedVersion != "1.0.0+build.6" {
+		t.Errorf("expected resolved build.6, got %q", info.ResolvedVersion)
+	}
+}
+
+// TestResolveSource проверяет выбор репозитория и файла без скачивания архива
+func TestResolveSource(t *testing.T) {
+	file := func(osName, arch string) RepositoryFile {
+		return RepositoryFile{
+			OS:       osName,
+			Arch:     arch,
+			Filename: fmt.Sprintf("tool-1.0.0-%s-%s.tar.zst", osName, arch),
+			Size:     1024,
+			Checksum: "abc123",
+		}
+	}
+
+	disabled := newMockRepository(t, &RepositoryPackage{Name: "tool", Versions: []RepositoryVersion{{Version: "1.0.0", Files: []RepositoryFile{file("linux", "amd64")}}}})
+	empty := newMockRepository(t)
+	otherPlatform := newMockRepository(t, &RepositoryPackage{Name: "tool", Versions: []RepositoryVersion{{Version: "1.0.0", Files: []RepositoryFile{file("plan9", "386")}}}})
+	mirror := newMockRepository(t, &RepositoryPackage{Name: "tool", Versions: []RepositoryVersion{{Version: "1.0.0", Files: []RepositoryFile{file("linux", "amd64"), file("darwin", "arm64")}}}})
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{
+		{Name: "disabled", URL: disabled.URL, Priority: 4},
+		empty.repository("empty", 3),
+		otherPlatform.repository("other", 2),
+		mirror.repository("mirror", 1),
+	}
+
+	source, err := pm.ResolveSource(context.Background(), "tool", "", "arm64", "darwin")
+	if err != nil {
+		t.Fatalf("ResolveSource: %v", err)
+	}
+
+	if source.Repository != "mirror" || source.RepositoryURL != mirror.URL || source.Priority != 1 {
+		t.Errorf("expected mirror repository, got %s (%s, priority %d)", source.Repository, source.RepositoryURL, source.Priority)
+	}
+	if source.Filename != "tool-1.0.0-darwin-arm64.tar.zst" || source.Format != FormatTarZst || source.Size != 1024 || source.Checksum != "abc123" {
+		t.Errorf("unexpected file: %+v", source)
+	}
+	if len(source.Skipped) != 2 || source.Skipped[0].Repository != "empty" || source.Skipped[1].Repository != "other" {
+		t.Errorf("expected empty and other to be skipped, got %+v", source.Skipped)
+	}
+	if mirror.downloadCount() != 0 {
+		t.Errorf("resolve_source must not download archives, got %d downloads", mirror.downloadCount())
+	}
+
+	if _, err := pm.ResolveSource(context.Background(), "tool", "", "riscv64", "linux"); err == nil || !strings.Contains(err.Error(), "mirror") {
+		t.Errorf("expected error listing rejected repositories, got %v", err)
+	}
+}
+
+// TestRepositoryPriority проверяет, что пакет, доступный в нескольких репозиториях,
+// берется из более приоритетного, а при равной релевантности он же идет выше в поиске
+func TestRepositoryPriority(t *testing.T) {
+	pm := newTestPackageManager(t)
+	primary := newMockRepository(t)
+	mirror := newMockRepository(t)
+	for _, repo := range []*mockRepository{primary, mirror} {
+		repo.publish(t, "tool", "1.0.0", buildTestArchive(t, pm, PackageManifest{Name: "tool", Version: "1.0.0"},
+			map[string]string{"src/main.txt": "tool"}, FormatTarGz))
+	}
+
+	// Порядок в конфигурации противоположен приоритету
+	pm.getConfig().Repositories = []Repository{primary.repository("primary", 1), mirror.repository("mirror", 10)}
+	ctx := context.Background()
+
+	resolved, err := pm.findPackage(ctx, "tool", "1.0.0", "", "")
+	if err != nil {
+		t.Fatalf("findPackage: %v", err)
+	}
+	if resolved.Repository.Name != "mirror" {
+		t.Errorf("expected higher-priority mirror to win, got %s", resolved.Repository.Name)
+	}
+
+	page, err := pm.SearchPackages(ctx, "tool", SearchFilter{}, 1, 20)
+	if err != nil {
+		t.Fatalf("SearchPackages: %v", err)
+	}
+	results := page.Results
+	if len(results) != 2 || results[0].Repository != "mirror" || results[1].Repository != "primary" {
+		t.Errorf("expected mirror result first on equal score, got %+v", results)
+	}
+
+	pm.getConfig().Repositories[0].Priority = 20
+	if resolved, err = pm.findPackage(ctx, "tool", "1.0.0", "", ""); err != nil || resolved.Repository.Name != "primary" {
+		t.Errorf("expected primary after raising its priority, got %+v, %v", resolved, err)
+	}
+}
+
+// TestPlatformOverridePrecedence проверяет приоритет платформы: аргумент, затем конфигурация, затем runtime
+func TestPlatformOverridePrecedence(t *testing.T) {
+	pm := newTestPackageManager(t)
+
+	info := pm.Platform("", "")
+	if info.OS != runtime.GOOS || info.Arch != runtime.GOARCH || info.OSSource != PlatformFromRuntime || info.ArchSource != PlatformFromRuntime {
+		t.Errorf("expected runtime platform without overrides, got %+v", info)
+	}
+
+	pm.getConfig().DefaultOS = "plan9"
+	pm.getConfig().DefaultArch = "386"
+	info = pm.Platform("arm64", "")
+	if info.OS != "plan9" || info.OSSource != PlatformFromConfig {
+		t.Errorf("expected configured OS, got %+v", info)
+	}
+	if info.Arch != "arm64" || info.ArchSource != PlatformFromArgument {
+		t.Errorf("expected argument arch, got %+v", info)
+	}
+	if info.DetectedOS != runtime.GOOS || info.DetectedArch != runtime.GOARCH {
+		t.Errorf("detected platform must not change, got %+v", info)
+	}
+
+	// Все пути поиска пакета используют одну цепочку приоритетов
+	file := func(osName, arch string) RepositoryFile {
+		return RepositoryFile{OS: osName, Arch: arch, Filename: fmt.Sprintf("tool-1.0.0-%s-%s.tar.gz", osName, arch)}
+	}
+	repo := newMockRepository(t, &RepositoryPackage{Name: "tool", Versions: []RepositoryVersion{{
+		Version: "1.0.0",
+		Files:   []RepositoryFile{file(runtime.GOOS, runtime.GOARCH), file("plan9", "386"), file("plan9", "arm64")},
+	}}})
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	source, err := pm.ResolveSource(ctx, "tool", "", "", "")
+	if err != nil || source.Filename != "tool-1.0.0-plan9-386.tar.gz" {
+		t.Errorf("expected configured platform file, got %+v, %v", source, err)
+	}
+	source, err = pm.ResolveSource(ctx, "tool", "", "arm64", "")
+	if err != nil || source.Filename != "tool-1.0.0-plan9-arm64.tar.gz" {
+		t.Errorf("expected argument to override configured arch, got %+v, %v", source, err)
+	}
+	resolved, err := pm.findPackage(ctx, "tool", "", "", "")
+	if err != nil || resolved.File.OS != "plan9" || resolved.File.Arch != "386" {
+		t.Errorf("expected install lookup to use configured platform, got %+v, %v", resolved, err)
+	}
+}
+
+// TestDowngradePackage проверяет откат на старую версию и отказ при более новой
+func TestDowngradePackage(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	for _, version := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		repo.publish(t, "tool", version, buildTestArchive(t, pm, PackageManifest{Name: "tool", Version: version},
+			map[string]string{"version.txt": version}, FormatTarGz))
+	}
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	if _, err := pm.InstallPackage(ctx, "tool", "2.0.0", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	if _, err := pm.DowngradePackage(ctx, "tool", "3.0.0", false); err == nil {
+		t.Error("expected error when downgrading to a newer version")
+	}
+	if _, err := pm.DowngradePackage(ctx, "tool", "2.0.0", false); err == nil {
+		t.Error("expected error when downgrading to the same version without allow_same")
+	}
+	if _, err := pm.DowngradePackage(ctx, "tool", "0.9.0", false); err == nil {
+		t.Error("expected error for unavailable version")
+	}
+	if info, _ := pm.getInstalledPackage("tool"); info.Version != "2.0.0" {
+		t.Fatalf("failed downgrades must keep 2.0.0, got %s", info.Version)
+	}
+
+	result, err := pm.DowngradePackage(ctx, "tool", "1.0.0", false)
+	if err != nil {
+		t.Fatalf("DowngradePackage: %v", err)
+	}
+	if result.FromVersion != "2.0.0" || result.ToVersion != "1.0.0" || !result.Success {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	data, err := os.ReadFile(filepath.Join(pm.getConfig().LocalPath, "tool", "version.txt"))
+	if err != nil || string(data) != "1.0.0" {
+		t.Errorf("expected 1.0.0 contents after rollback, got %q (err %v)", data, err)
+	}
+
+	if _, err := pm.DowngradePackage(ctx, "tool", "1.0.0", true); err != nil {
+		t.Errorf("expected reinstall of the same version with allow_same: %v", err)
+	}
+}
+
+// TestUpdatePackageComparesSemver проверяет, что обновление сравнивает версии по semver:
+// более новая установленная версия не откатывается, а "1.0" считается равной "1.0.0"
+func TestUpdatePackageComparesSemver(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	for _, version := range []string{"1.0.0", "1.5.0"} {
+		repo.publish(t, "newer", version, buildTestArchive(t, pm, PackageManifest{Name: "newer", Version: version}, nil, FormatTarGz))
+	}
+	repo.publish(t, "short", "1.0.0", buildTestArchive(t, pm, PackageManifest{Name: "short", Version: "1.0.0"}, nil, FormatTarGz))
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	installTestArchive(t, pm, PackageManifest{Name: "newer", Version: "2.0.0"}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "short", Version: "1.0"}, false)
+
+	if _, err := pm.UpdatePackage(ctx, "newer"); err == nil || !strings.Contains(err.Error(), "новее") {
+		t.Errorf("expected refusal to downgrade 2.0.0 to 1.5.0, got %v", err)
+	}
+	if _, err := pm.UpdatePackage(ctx, "short"); err == nil || !strings.Contains(err.Error(), "последнюю версию") {
+		t.Errorf("expected 1.0 to be up to date with 1.0.0, got %v", err)
+	}
+	for name, version := range map[string]string{"newer": "2.0.0", "short": "1.0"} {
+		if info, _ := pm.getInstalledPackage(name); info == nil || info.Version != version {
+			t.Errorf("%s should stay at %s, got %+v", name, version, info)
+		}
+	}
+	if got := repo.downloadCount(); got != 0 {
+		t.Errorf("expected no downloads, got %d", got)
+	}
+}
+
+// TestRetryAfter проверяет повтор запроса после ответа 429 с Retry-After в секундах и в виде даты
+func TestRetryAfter(t *testing.T) {
+	testCases := []struct {
+		name       string
+		retryAfter func() string
+		minDelay   time.Duration
+	}{
+		{"seconds", func() string { return "1" }, time.Second},
+		{"http date", func() string { return time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat) }, 500 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests++
+				first := requests == 1
+				mu.Unlock()
+
+				if first {
+					w.Header().Set("Retry-After", tc.retryAfter())
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"data":    &RepositoryPackage{Name: "tool", LatestVersion: "1.0.0"},
+				})
+			}))
+			defer server.Close()
+
+			pm := newTestPackageManager(t)
+			repo := Repository{Name: "throttled", URL: server.URL, Enabled: true}
+
+			start := time.Now()
+			pkg, err := pm.fetchRepositoryPackage(context.Background(), repo, "tool")
+			if err != nil {
+				t.Fatalf("fetchRepositoryPackage: %v", err)
+			}
+			if pkg.Name != "tool" {
+				t.Errorf("unexpected package %+v", pkg)
+			}
+			if elapsed := time.Since(start); elapsed < tc.minDelay {
+				t.Errorf("expected to wait at least %s, waited %s", tc.minDelay, elapsed)
+			}
+			if requests != 2 {
+				t.Errorf("expected exactly one retry, got %d requests", requests)
+			}
+		})
+	}
+}
+
+// TestParseRetryAfter проверяет разбор значений заголовка Retry-After
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"0", 0, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"", 0, false},
+		{"-5", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, tc := range testCases {
+		delay, ok := parseRetryAfter(tc.value, now)
+		if delay != tc.expected || ok != tc.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %v; expected %s, %v", tc.value, delay, ok, tc.expected, tc.ok)
+		}
+	}
+}
+
+// TestInstallDryRun проверяет, что dry run сообщает план установки, ничего не скачивая и не записывая
+func TestInstallDryRun(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t, &RepositoryPackage{Name: "util", Versions: []RepositoryVersion{{Version: "2.0.0"}}})
+	for _, version := range []string{"1.0.0", "1.2.0"} {
+		repo.publish(t, "lib", version, buildTestArchive(t, pm, PackageManifest{Name: "lib", Version: version}, nil, FormatTarGz))
+	}
+	repo.publish(t, "app", "1.0.0", buildTestArchive(t, pm, PackageManifest{Name: "app", Version: "1.0.0"},
+		map[string]string{"main.txt": "app"}, FormatTarGz))
+	repo.setDependencies("app", "1.0.0", map[string]string{"lib": "^1.0.0", "util": "*"})
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	pm.installedPackages["util"] = &PackageInfo{Name: "util", Version: "2.0.0", InstallPath: "/opt/util"}
+
+	snapshot := func() []string {
+		var files []string
+		for _, dir := range []string{pm.getConfig().LocalPath, pm.getConfig().GlobalPath, pm.getConfig().CachePath, pm.getConfig().TempPath} {
+			filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err == nil {
+					files = append(files, path)
+				}
+				return nil
+			})
+		}
+		return files
+	}
+	before := snapshot()
+
+	plan, err := pm.InstallPackage(context.Background(), "app", "", false, false, false, true, "", "")
+	if err != nil {
+		t.Fatalf("InstallPackage dry run: %v", err)
+	}
+	if !plan.DryRun || len(plan.Actions) != 3 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	expected := []InstallAction{
+		{Name: "app", Version: "1.0.0", Action: InstallActionInstall, Path: filepath.Join(pm.getConfig().LocalPath, "app")},
+		{Name: "lib", Version: "1.2.0", Action: InstallActionInstall, Path: filepath.Join(pm.getConfig().LocalPath, "lib"), Dependency: true, Constraint: "^1.0.0"},
+		{Name: "util", Version: "2.0.0", Action: InstallActionSatisfied, Path: "/opt/util", Dependency: true, Constraint: "*", InstalledVersion: "2.0.0"},
+	}
+	for i, want := range expected {
+		got := plan.Actions[i]
+		got.DownloadURL = ""
+		if got != want {
+			t.Errorf("action %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	if after := snapshot(); !reflect.DeepEqual(before, after) {
+		t.Errorf("dry run changed files: before %v, after %v", before, after)
+	}
+	if _, err := os.Stat(filepath.Join(pm.getConfig().LocalPath, "packages.json")); !os.IsNotExist(err) {
+		t.Errorf("dry run must not write packages.json, stat error: %v", err)
+	}
+	if _, exists := pm.getInstalledPackage("app"); exists {
+		t.Error("dry run must not register the package")
+	}
+	if repo.downloadCount() != 0 {
+		t.Errorf("expected no downloads, got %d", repo.downloadCount())
+	}
+
+	server := newTestServer(t, pm)
+	text, err := callToolText(t, server, "install_package", map[string]interface{}{"name": "app", "dry_run": true})
+	if err != nil {
+		t.Fatalf("install_package dry run: %v", err)
+	}
+	if !strings.Contains(text, "would install app@1.0.0 to "+filepath.Join(pm.getConfig().LocalPath, "app")) {
+		t.Errorf("expected planned install in output, got %q", text)
+	}
+}
+
+// TestSearchFilters проверяет передачу фильтров репозиторию и их повторное применение
+// к результатам репозитория, который фильтры игнорирует
+func TestSearchFilters(t *testing.T) {
+	var received url.Values
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = r.URL.Query()
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": map[string]interface{}{
+			"results": []SearchResult{
+				{Name: "alpha", Author: "Jane Doe", License: "MIT", Downloads: 500, Score: 0.9},
+				{Name: "beta", Author: "jane doe", License: "mit", Downloads: 50, Score: 0.8},
+				{Name: "gamma", Author: "John Roe", License: "MIT", Downloads: 900, Score: 0.7},
+				{Name: "delta", Author: "Jane Doe", License: "Apache-2.0", Downloads: 1000, Score: 0.6},
+				{Name: "epsilon", Author: "Jane Doe <jane@example.com>", License: "MIT", Downloads: 100, Score: 0.95},
+			},
+		}})
+	}))
+	defer server.Close()
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{{Name: "plain", URL: server.URL, Enabled: true}}
+	ctx := context.Background()
+
+	testCases := []struct {
+		name     string
+		filter   SearchFilter
+		expected []string
+	}{
+		{"no filter", SearchFilter{}, []string{"epsilon", "alpha", "beta", "gamma", "delta"}},
+		{"author", SearchFilter{Author: "JANE"}, []string{"epsilon", "alpha", "beta", "delta"}},
+		{"license", SearchFilter{License: "mit"}, []string{"epsilon", "alpha", "beta", "gamma"}},
+		{"min downloads", SearchFilter{MinDownloads: 500}, []string{"alpha", "gamma", "delta"}},
+		{"combined", SearchFilter{Author: "jane", License: "MIT", MinDownloads: 100}, []string{"epsilon", "alpha"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := pm.SearchPackages(ctx, "a b", tc.filter, 1, 20)
+			if err != nil {
+				t.Fatalf("SearchPackages: %v", err)
+			}
+			if page.Total != len(tc.expected) {
+				t.Errorf("expected total %d, got %d", len(tc.expected), page.Total)
+			}
+			var names []string
+			for _, result := range page.Results {
+				names = append(names, result.Name)
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, names)
+			}
+		})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if received.Get("q") != "a b" || received.Get("author") != "jane" || received.Get("license") != "MIT" || received.Get("min_downloads") != "100" {
+		t.Errorf("filters not forwarded to repository: %v", received)
+	}
+}
+
+// TestSearchPagination проверяет страницы объединенных результатов репозитория, который
+// сам ограничивает выдачу, и репозитория, возвращающего все результаты сразу
+func TestSearchPagination(t *testing.T) {
+	newSearchServer := func(prefix string, count int, offset float64, paginated bool, limits *[]string) *httptest.Server {
+		var mu sync.Mutex
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			results := make([]SearchResult, count)
+			for i := range results {
+				results[i] = SearchResult{Name: fmt.Sprintf("%s%02d", prefix, i), Score: 1 - offset - float64(i)*0.01}
+			}
+			if paginated {
+				mu.Lock()
+				*limits = append(*limits, r.URL.Query().Get("limit"))
+				mu.Unlock()
+				if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit < len(results) {
+					results = results[:limit]
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": map[string]interface{}{
+				"results": results, "total": count,
+			}})
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	var limits []string
+	full := newSearchServer("a", 25, 0, false, nil)
+	paged := newSearchServer("b", 20, 0.005, true, &limits)
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{
+		{Name: "full", URL: full.URL, Enabled: true},
+		{Name: "paged", URL: paged.URL, Enabled: true},
+	}
+	ctx := context.Background()
+
+	testCases := []struct {
+		name     string
+		page     int
+		expected []string
+	}{
+		{"first page", 1, []string{"a00", "b00", "a01", "b01", "a02", "b02", "a03", "b03", "a04", "b04"}},
+		{"middle page", 3, []string{"a10", "b10", "a11", "b11", "a12", "b12", "a13", "b13", "a14", "b14"}},
+		{"last partial page", 5, []string{"a20", "a21", "a22", "a23", "a24"}},
+		{"out of range", 6, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := pm.SearchPackages(ctx, "x", SearchFilter{}, tc.page, 10)
+			if err != nil {
+				t.Fatalf("SearchPackages: %v", err)
+			}
+			if result.Total != 45 || result.TotalPages != 5 || result.Page != tc.page || result.Limit != 10 {
+				t.Errorf("unexpected page header: %+v", result)
+			}
+
+			var names []string
+			for _, r := range result.Results {
+				names = append(names, r.Name)
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, names)
+			}
+		})
+	}
+
+	// Репозиторию передается размер выдачи до конца запрошенной страницы
+	if want := []string{"10", "30", "50", "60"}; !reflect.DeepEqual(limits, want) {
+		t.Errorf("expected forwarded limits %v, got %v", want, limits)
+	}
+
+	s := newTestServer(t, pm)
+	text, err := callToolText(t, s, "search_packages", map[string]interface{}{"query": "x", "page": float64(9), "limit": float64(10)})
+	if err != nil {
+		t.Fatalf("search_packages: %v", err)
+	}
+	if !strings.Contains(text, "Найдено пакетов: 45 (страница 9 из 5") || !strings.Contains(text, "результатов нет") {
+		t.Errorf("unexpected out-of-range output: %q", text)
+	}
+}
+
+// TestParallelInstallsPersisted проверяет, что параллельные установки и удаления в одном
+// менеджере сохраняются в packages.json без потерь и без временных файлов
+func TestParallelInstallsPersisted(t *testing.T) {
+	pm := newTestPackageManager(t)
+
+	const count = 20
+	archives := make([]string, count)
+	for i := range archives {
+		name := fmt.Sprintf("pkg%02d", i)
+		archives[i] = buildTestArchive(t, pm, PackageManifest{Name: name, Version: "1.0.0"},
+			map[string]string{"data.txt": name}, FormatTarGz)
+	}
+	installTestArchive(t, pm, PackageManifest{Name: "obsolete", Version: "1.0.0"}, false)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, count+1)
+	for _, archive := range archives {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pm.installFromArchive(context.Background(), archive, false, false, nil)
+			errs <- err
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs <- pm.UninstallPackage("obsolete", false, false)
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("operation failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(pm.getConfig().LocalPath, packagesFileName))
+	if err != nil {
+		t.Fatalf("Failed to read packages.json: %v", err)
+	}
+	var packages map[string]*PackageInfo
+	if err := json.Unmarshal(data, &packages); err != nil {
+		t.Fatalf("packages.json is not valid JSON: %v", err)
+	}
+	for i := 0; i < count; i++ {
+		if _, ok := packages[fmt.Sprintf("pkg%02d", i)]; !ok {
+			t.Errorf("pkg%02d was not persisted", i)
+		}
+	}
+	if _, ok := packages["obsolete"]; ok || len(packages) != count {
+		t.Errorf("expected exactly %d packages without obsolete, got %d", count, len(packages))
+	}
+
+	entries, err := os.ReadDir(pm.getConfig().LocalPath)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("temporary file left behind: %s", entry.Name())
+		}
+	}
+}
+
+// TestInstallMissingFileFailsEarly проверяет, что отсутствие архива в репозитории
+// обнаруживается запросом HEAD до скачивания
+func TestInstallMissingFileFailsEarly(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	repo.publish(t, "tool", "1.0.0", buildTestArchive(t, pm, PackageManifest{Name: "tool", Version: "1.0.0"},
+		map[string]string{"bin/tool": "tool"}, FormatTarGz))
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	// Описание версии есть, а файла на сервере нет
+	repo.mu.Lock()
+	repo.files = map[string]string{}
+	repo.mu.Unlock()
+
+	_, err := pm.InstallPackage(context.Background(), "tool", "", false, false, false, false, "", "")
+	if !errors.Is(err, ErrFileUnavailable) {
+		t.Fatalf("expected ErrFileUnavailable, got %v", err)
+	}
+	want := fmt.Sprintf("tool 1.0.0 для %s/%s отсутствует в репозитории mock", runtime.GOOS, runtime.GOARCH)
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %q in error, got %v", want, err)
+	}
+
+	repo.mu.Lock()
+	probes, downloads := repo.probes, repo.downloads
+	repo.mu.Unlock()
+	if probes != 1 || downloads != 0 {
+		t.Errorf("expected one HEAD and no GET, got %d HEAD and %d GET", probes, downloads)
+	}
+	if _, exists := pm.getInstalledPackage("tool"); exists {
+		t.Error("package must not be installed")
+	}
+}
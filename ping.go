@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+)
+
+// processStarted время запуска процесса сервера для расчета времени работы
+var processStarted = time.Now()
+
+// Ping проверяет связь со всеми включенными репозиториями (GET /api/v1/) и доступность
+// каталогов конфигурации для записи. Недоступный репозиторий не считается ошибкой вызова:
+// он отражается в отчете.
+func (pm *PackageManager) Ping(ctx context.Context) *PingReport {
+	var repositories []Repository
+	for _, repo := range pm.getConfig().Repositories {
+		if repo.Enabled {
+			repositories = append(repositories, repo)
+		}
+	}
+
+	report := &PingReport{Repositories: make([]RepositoryPing, len(repositories))}
+	pm.runConcurrently(len(repositories), func(i int) {
+		repo := repositories[i]
+		result := RepositoryPing{Name: repo.Name, URL: repo.URL}
+
+		status, latency, err := pm.pingRepository(ctx, repo)
+		result.StatusCode = status
+		result.LatencyMs = float64(latency.Microseconds()) / 1000
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Reachable = true
+		}
+		report.Repositories[i] = result
+	})
+
+	for _, p := range pm.configuredPaths() {
+		status := PathStatus{Name: p.name, Path: p.path, Writable: true}
+		if abs, err := filepath.Abs(p.path); err == nil {
+			status.Path = abs
+		}
+		if err := checkWritableDir(p.path); err != nil {
+			status.Writable = false
+			status.Error = err.Error()
+		}
+		report.Paths = append(report.Paths, status)
+	}
+
+	return report
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPing проверяет отчет о доступности репозиториев и каталогов
+func TestPing(t *testing.T) {
+	reachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"success": true}`))
+	}))
+	defer reachable.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{
+		{Name: "main", URL: reachable.URL, Enabled: true},
+		{Name: "broken", URL: failing.URL, Enabled: true},
+		{Name: "offline", URL: unreachable.URL, Enabled: true},
+		{Name: "disabled", URL: unreachable.URL, Enabled: false},
+	}
+
+	// Вместо каталога временных файлов — обычный файл
+	os.RemoveAll(pm.getConfig().TempPath)
+	if err := os.WriteFile(pm.getConfig().TempPath, []byte("not a directory"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	report := pm.Ping(context.Background())
+	if len(report.Repositories) != 3 {
+		t.Fatalf("expected 3 enabled repositories, got %+v", report.Repositories)
+	}
+	main, broken, offline := report.Repositories[0], report.Repositories[1], report.Repositories[2]
+	if !main.Reachable || main.StatusCode != http.StatusOK || main.LatencyMs <= 0 {
+		t.Errorf("expected main to be reachable with latency, got %+v", main)
+	}
+	if broken.Reachable || broken.StatusCode != http.StatusBadGateway || broken.Error == "" {
+		t.Errorf("expected broken to report server error, got %+v", broken)
+	}
+	if offline.Reachable || offline.StatusCode != 0 || offline.Error == "" {
+		t.Errorf("expected offline to be unreachable, got %+v", offline)
+	}
+
+	writable := map[string]bool{}
+	for _, path := range report.Paths {
+		writable[path.Name] = path.Writable
+		if !filepath.IsAbs(path.Path) {
+			t.Errorf("expected absolute path for %s, got %s", path.Name, path.Path)
+		}
+	}
+	if !writable["global_path"] || !writable["local_path"] || !writable["cache_path"] || writable["temp_path"] {
+		t.Errorf("unexpected writability: %v", writable)
+	}
+
+	s := newTestServer(t, pm)
+	text, err := callToolText(t, s, "ping", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	for _, want := range []string{ServerVersion, "✅ main", "❌ broken", "❌ offline", "❌ temp_path"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in output:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "disabled") {
+		t.Errorf("disabled repository must not be pinged:\n%s", text)
+	}
+}
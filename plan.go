@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplayPlan устанавливает пакеты плана строго в указанных версиях, без разрешения
+// зависимостей и выбора последних версий. Сначала проверяется доступность всех
+// версий: если хотя бы одной нет, ничего не устанавливается.
+func (pm *PackageManager) ReplayPlan(ctx context.Context, plan []PlanEntry) (*ReplayResult, error) {
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("план пуст")
+	}
+
+	resolved := make([]*resolvedPackage, len(plan))
+	for i, entry := range plan {
+		if entry.Name == "" || entry.Version == "" {
+			return nil, fmt.Errorf("запись плана %d: имя и версия обязательны", i+1)
+		}
+		switch entry.Scope {
+		case "", scopeLocal, scopeGlobal:
+		default:
+			return nil, fmt.Errorf("запись плана %d: неизвестная область %q (допустимо: local, global)", i+1, entry.Scope)
+		}
+
+		if info, exists := pm.getInstalledPackage(entry.Name); exists && installedExactly(info, entry) {
+			continue
+		}
+
+		source, err := pm.findPackage(ctx, entry.Name, entry.Version, "", "")
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("версия %s пакета %s недоступна: %w", entry.Version, entry.Name, err)
+		}
+		// Версия из плана должна совпадать точно, включая метаданные сборки
+		if source.Version.Version != entry.Version {
+			return nil, errorWithCause(ErrVersionNotFound, "версия %s пакета %s недоступна: репозиторий предлагает %s", entry.Version, entry.Name, source.Version.Version)
+		}
+		resolved[i] = source
+	}
+
+	result := &ReplayResult{}
+	for i, entry := range plan {
+		step := ReplayStep{PlanEntry: entry}
+		if resolved[i] == nil {
+			step.Status = ReplaySkipped
+			result.Steps = append(result.Steps, step)
+			continue
+		}
+
+		if _, _, err := pm.installResolved(ctx, resolved[i], entry.Scope == scopeGlobal, true); err != nil {
+			step.Status = ReplayFailed
+			step.Error = err.Error()
+			result.Steps = append(result.Steps, step)
+			result.Failed++
+			// План выполняется строго по порядку: после сбоя оставшиеся шаги не выполняются
+			return result, fmt.Errorf("ошибка установки %s %s: %w", entry.Name, entry.Version, err)
+		}
+
+		step.Status = ReplayInstalled
+		result.Steps = append(result.Steps, step)
+		result.Installed++
+	}
+
+	return result, nil
+}
+
+// installedExactly сообщает, установлен ли пакет в точности как в записи плана
+func installedExactly(info *PackageInfo, entry PlanEntry) bool {
+	version := info.ResolvedVersion
+	if version == "" {
+		version = info.Version
+	}
+	return version == entry.Version && info.Global == (entry.Scope == scopeGlobal)
+}
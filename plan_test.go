@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReplayPlan проверяет установку пакетов плана в точно указанных версиях
+func TestReplayPlan(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	for _, version := range []string{"1.0.0", "1.1.0", "2.0.0"} {
+		repo.publish(t, "lib", version, buildTestArchive(t, pm, PackageManifest{Name: "lib", Version: version},
+			map[string]string{"version.txt": version}, FormatTarGz))
+	}
+	repo.publish(t, "tool", "0.5.0", buildTestArchive(t, pm, PackageManifest{Name: "tool", Version: "0.5.0"},
+		map[string]string{"version.txt": "0.5.0"}, FormatTarGz))
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+	ctx := context.Background()
+
+	plan := []PlanEntry{
+		{Name: "lib", Version: "1.1.0"},
+		{Name: "tool", Version: "0.5.0", Scope: scopeGlobal},
+	}
+	result, err := pm.ReplayPlan(ctx, plan)
+	if err != nil {
+		t.Fatalf("ReplayPlan: %v", err)
+	}
+	if result.Installed != 2 || result.Failed != 0 {
+		t.Errorf("unexpected summary: %+v", result)
+	}
+
+	// Установлена версия из плана, а не последняя
+	data, err := os.ReadFile(filepath.Join(pm.getConfig().LocalPath, "lib", "version.txt"))
+	if err != nil || string(data) != "1.1.0" {
+		t.Errorf("expected lib 1.1.0, got %q (err %v)", data, err)
+	}
+	if info, exists := pm.getInstalledPackage("tool"); !exists || !info.Global || info.Version != "0.5.0" {
+		t.Errorf("expected global tool 0.5.0, got %+v", info)
+	}
+
+	// Повторное выполнение ничего не переустанавливает
+	downloads := repo.downloadCount()
+	again, err := pm.ReplayPlan(ctx, plan)
+	if err != nil {
+		t.Fatalf("second ReplayPlan: %v", err)
+	}
+	if again.Installed != 0 || repo.downloadCount() != downloads {
+		t.Errorf("expected replay to be idempotent, got %+v", again)
+	}
+	for _, step := range again.Steps {
+		if step.Status != ReplaySkipped {
+			t.Errorf("expected %s to be skipped, got %s", step.Name, step.Status)
+		}
+	}
+}
+
+// TestReplayPlanUnavailableVersion проверяет, что план с недоступной версией не выполняется вовсе
+func TestReplayPlanUnavailableVersion(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t)
+	repo.publish(t, "lib", "1.0.0", buildTestArchive(t, pm, PackageManifest{Name: "lib", Version: "1.0.0"},
+		map[string]string{"version.txt": "1.0.0"}, FormatTarGz))
+	repo.publish(t, "tool", "0.5.0+build.1", buildTestArchive(t, pm, PackageManifest{Name: "tool", Version: "0.5.0"},
+		map[string]string{"version.txt": "0.5.0"}, FormatTarGz))
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	for _, plan := range [][]PlanEntry{
+		{{Name: "lib", Version: "1.0.0"}, {Name: "tool", Version: "9.9.9"}},
+		// Неточная версия не подменяется найденной сборкой
+		{{Name: "lib", Version: "1.0.0"}, {Name: "tool", Version: "0.5.0"}},
+		{{Name: "lib", Version: "1.0.0", Scope: "system"}},
+	} {
+		if _, err := pm.ReplayPlan(context.Background(), plan); err == nil {
+			t.Errorf("expected error for plan %+v", plan)
+		}
+	}
+
+	if _, exists := pm.getInstalledPackage("lib"); exists {
+		t.Error("no package must be installed when the plan cannot be satisfied")
+	}
+	if repo.downloadCount() != 0 {
+		t.Errorf("expected no downloads, got %d", repo.downloadCount())
+	}
+}
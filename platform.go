@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// platformWildcards значения os/arch файла репозитория, подходящие к любой платформе
+var platformWildcards = []string{"any", "noarch"}
+
+// isPlatformWildcard сообщает, что значение os или arch файла подходит к любой платформе
+func isPlatformWildcard(value string) bool {
+	return containsString(platformWildcards, strings.ToLower(value))
+}
+
+// selectPlatformFile выбирает файл версии для платформы osName/arch. Порядок предпочтения:
+// точное совпадение ОС и архитектуры, затем совместимые архитектуры из compatibility
+// (в порядке перечисления), затем файл с любой архитектурой; все это сначала для той же ОС,
+// затем для файлов с любой ОС. Возвращает nil, если подходящего файла нет.
+func selectPlatformFile(files []RepositoryFile, osName, arch string, compatibility map[string][]string) *RepositoryFile {
+	archRanks := map[string]int{arch: 0}
+	for i, compatible := range compatibility[arch] {
+		if _, exists := archRanks[compatible]; !exists {
+			archRanks[compatible] = i + 1
+		}
+	}
+	wildcardRank := len(compatibility[arch]) + 1
+
+	rank := func(file RepositoryFile) (int, bool) {
+		archRank, ok := archRanks[file.Arch]
+		if !ok {
+			if !isPlatformWildcard(file.Arch) {
+				return 0, false
+			}
+			archRank = wildcardRank
+		}
+		switch {
+		case file.OS == osName:
+			return archRank, true
+		case isPlatformWildcard(file.OS):
+			return wildcardRank + 1 + archRank, true
+		default:
+			return 0, false
+		}
+	}
+
+	var selected *RepositoryFile
+	best := 0
+	for i := range files {
+		if r, ok := rank(files[i]); ok && (selected == nil || r < best) {
+			selected, best = &files[i], r
+		}
+	}
+	return selected
+}
+
+// archCompatibilitySetting таблица совместимых архитектур: объект «архитектура → список
+// архитектур» (список — массивом или строкой через запятую) или тот же объект в виде JSON-строки.
+// Пустой объект или строка очищают таблицу.
+func archCompatibilitySetting() configSetting {
+	return configSetting{apply: func(c *Config, value interface{}) error {
+		if s, ok := value.(string); ok {
+			if strings.TrimSpace(s) == "" {
+				c.ArchCompatibility = nil
+				return nil
+			}
+			var parsed map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &parsed); err != nil {
+				return fmt.Errorf("ожидается JSON-объект: %v", err)
+			}
+			value = parsed
+		}
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("ожидается объект «архитектура → совместимые архитектуры»")
+		}
+
+		table := make(map[string][]string, len(entries))
+		for arch, raw := range entries {
+			arch = strings.TrimSpace(arch)
+			if arch == "" || isPlatformWildcard(arch) {
+				return fmt.Errorf("недопустимая архитектура %q", arch)
+			}
+
+			var compatible []string
+			switch v := raw.(type) {
+			case string:
+				compatible = strings.Split(v, ",")
+			case []interface{}:
+				for _, item := range v {
+					s, ok := item.(string)
+					if !ok {
+						return fmt.Errorf("%s: ожидается список строк", arch)
+					}
+					compatible = append(compatible, s)
+				}
+			default:
+				return fmt.Errorf("%s: ожидается список архитектур", arch)
+			}
+
+			for _, item := range compatible {
+				item = strings.TrimSpace(item)
+				if item == "" || item == arch || isPlatformWildcard(item) {
+					return fmt.Errorf("%s: недопустимая совместимая архитектура %q", arch, item)
+				}
+				table[arch] = append(table[arch], item)
+			}
+		}
+		if len(table) == 0 {
+			table = nil
+		}
+		c.ArchCompatibility = table
+		return nil
+	}}
+}
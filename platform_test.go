@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestSelectPlatformFile проверяет порядок выбора файла: точная платформа, совместимые
+// архитектуры, файлы с любой архитектурой, затем файлы с любой ОС
+func TestSelectPlatformFile(t *testing.T) {
+	compatibility := map[string][]string{"arm64": {"amd64", "386"}}
+	file := func(osName, arch string) RepositoryFile {
+		return RepositoryFile{OS: osName, Arch: arch, Filename: osName + "-" + arch}
+	}
+
+	tests := []struct {
+		name  string
+		files []RepositoryFile
+		want  string
+	}{
+		{"exact", []RepositoryFile{file("any", "noarch"), file("linux", "amd64"), file("linux", "arm64")}, "linux-arm64"},
+		{"compatible arch", []RepositoryFile{file("linux", "386"), file("linux", "amd64"), file("darwin", "arm64")}, "linux-amd64"},
+		{"second compatible arch", []RepositoryFile{file("linux", "386"), file("windows", "amd64")}, "linux-386"},
+		{"noarch before any os", []RepositoryFile{file("any", "arm64"), file("linux", "noarch")}, "linux-noarch"},
+		{"any os", []RepositoryFile{file("darwin", "arm64"), file("ANY", "amd64")}, "ANY-amd64"},
+		{"universal", []RepositoryFile{file("windows", "arm64"), file("any", "any")}, "any-any"},
+		{"none", []RepositoryFile{file("windows", "arm64"), file("linux", "riscv64")}, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if selected := selectPlatformFile(tt.files, "linux", "arm64", compatibility); selected != nil {
+			got = selected.Filename
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	if selected := selectPlatformFile([]RepositoryFile{file("linux", "amd64")}, "linux", "arm64", nil); selected != nil {
+		t.Errorf("expected no fallback without compatibility table, got %s", selected.Filename)
+	}
+}
+
+// TestFindInRepositoryPlatformFallback проверяет установку пакетов, опубликованных без
+// привязки к платформе, и выбор файла совместимой архитектуры из конфигурации
+func TestFindInRepositoryPlatformFallback(t *testing.T) {
+	pm, transport := newFixtureManager(t)
+	transport.handleJSON(t, "/api/v1/packages/scripts", &RepositoryPackage{
+		Name:     "scripts",
+		Versions: []RepositoryVersion{{Version: "1.0.0", Files: []RepositoryFile{{OS: "any", Arch: "noarch", Filename: "scripts-1.0.0-any-noarch.tar.gz"}}}},
+	})
+	transport.handleJSON(t, "/api/v1/packages/tool", &RepositoryPackage{
+		Name: "tool",
+		Versions: []RepositoryVersion{{Version: "1.0.0", Files: []RepositoryFile{
+			{OS: "darwin", Arch: "amd64", Filename: "tool-1.0.0-darwin-amd64.tar.gz"},
+			{OS: "linux", Arch: "amd64", Filename: "tool-1.0.0-linux-amd64.tar.gz"},
+		}}},
+	})
+	repo := pm.getConfig().Repositories[0]
+	ctx := context.Background()
+
+	resolved, err := pm.findInRepository(ctx, repo, "scripts", "", "arm64", "darwin")
+	if err != nil {
+		t.Fatalf("noarch package: %v", err)
+	}
+	if resolved.File.Filename != "scripts-1.0.0-any-noarch.tar.gz" {
+		t.Errorf("unexpected file for noarch package: %s", resolved.File.Filename)
+	}
+
+	if _, err := pm.findInRepository(ctx, repo, "tool", "", "arm64", "darwin"); !errors.Is(err, ErrFileUnavailable) {
+		t.Fatalf("expected ErrFileUnavailable without compatibility table, got %v", err)
+	}
+
+	if err := configSettings["arch_compatibility"].apply(pm.getConfig(), map[string]interface{}{"arm64": []interface{}{"amd64"}}); err != nil {
+		t.Fatalf("apply arch_compatibility: %v", err)
+	}
+	resolved, err = pm.findInRepository(ctx, repo, "tool", "", "arm64", "darwin")
+	if err != nil {
+		t.Fatalf("compatible arch: %v", err)
+	}
+	if resolved.File.Filename != "tool-1.0.0-darwin-amd64.tar.gz" {
+		t.Errorf("expected darwin/amd64 file, got %s", resolved.File.Filename)
+	}
+}
+
+// TestArchCompatibilitySetting проверяет разбор таблицы совместимости в config_set
+func TestArchCompatibilitySetting(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	pm := newTestPackageManager(t)
+
+	if _, err := pm.SetConfigValue("arch_compatibility", `{"arm64": "amd64, 386", "amd64": ["386"]}`); err != nil {
+		t.Fatalf("SetConfigValue: %v", err)
+	}
+	want := map[string][]string{"arm64": {"amd64", "386"}, "amd64": {"386"}}
+	if !reflect.DeepEqual(pm.getConfig().ArchCompatibility, want) {
+		t.Errorf("expected %v, got %v", want, pm.getConfig().ArchCompatibility)
+	}
+	reloaded, err := loadConfigFile(pm.configPath)
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if !reflect.DeepEqual(reloaded.ArchCompatibility, want) {
+		t.Errorf("table not persisted: %v", reloaded.ArchCompatibility)
+	}
+
+	for _, value := range []interface{}{
+		map[string]interface{}{"arm64": []interface{}{"noarch"}},
+		map[string]interface{}{"arm64": []interface{}{"arm64"}},
+		map[string]interface{}{"any": "amd64"},
+		map[string]interface{}{"arm64": 1.0},
+		`not json`,
+		42.0,
+	} {
+		if _, err := pm.SetConfigValue("arch_compatibility", value); err == nil {
+			t.Errorf("expected error for %v", value)
+		}
+	}
+
+	if _, err := pm.SetConfigValue("arch_compatibility", ""); err != nil || pm.getConfig().ArchCompatibility != nil {
+		t.Errorf("expected empty value to clear the table, got %v (err %v)", pm.getConfig().ArchCompatibility, err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// progressStep минимальный объем данных между двумя уведомлениями о прогрессе
+const progressStep = 64 * 1024
+
+// ProgressFunc получает сведения о ходе длительной операции.
+// total равен 0, если общий объем неизвестен. message начинается с названия этапа;
+// подробности (скорость, оставшееся время) отделяются от него двоеточием.
+type ProgressFunc func(progress, total int64, message string)
+
+// progressPhase возвращает название этапа из сообщения о прогрессе
+func progressPhase(message string) string {
+	if i := strings.Index(message, ": "); i >= 0 {
+		return message[:i]
+	}
+	return message
+}
+
+type progressKey struct{}
+
+// withProgress возвращает контекст, через который операции сообщают о прогрессе
+func withProgress(ctx context.Context, report ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// progressFromContext извлекает получателя прогресса из контекста (nil, если не задан)
+func progressFromContext(ctx context.Context) ProgressFunc {
+	report, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return report
+}
+
+// progressReader подсчитывает прочитанные байты и периодически сообщает о прогрессе
+type progressReader struct {
+	reader   io.Reader
+	total    int64
+	read     int64
+	reported int64
+	message  string
+	report   ProgressFunc
+}
+
+// newProgressReader оборачивает reader подсчетом прогресса; без получателя возвращает reader как есть
+func newProgressReader(reader io.Reader, total int64, message string, report ProgressFunc) io.Reader {
+	if report == nil {
+		return reader
+	}
+	if total < 0 {
+		total = 0
+	}
+
+	return &progressReader{
+		reader:  reader,
+		total:   total,
+		message: message,
+		report:  report,
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+
+	if r.read-r.reported >= progressStep || (err == io.EOF && r.read != r.reported) {
+		r.reported = r.read
+		r.report(r.read, r.total, r.message)
+	}
+
+	return n, err
+}
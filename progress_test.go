@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestProgressReaderReportsTotal проверяет, что итоговое уведомление содержит весь объем данных
+func TestProgressReaderReportsTotal(t *testing.T) {
+	data := make([]byte, progressStep+10)
+	var reports [][2]int64
+	reader := newProgressReader(bytes.NewReader(data), int64(len(data)), "test", func(progress, total int64, message string) {
+		reports = append(reports, [2]int64{progress, total})
+	})
+
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	if len(reports) == 0 {
+		t.Fatal("Expected progress reports")
+	}
+	final := reports[len(reports)-1]
+	if final[0] != int64(len(data)) || final[1] != int64(len(data)) {
+		t.Errorf("Final report should cover all data: %v", final)
+	}
+}
+
+// TestDownloadETA проверяет расчет скорости и оставшегося времени скачивания
+func TestDownloadETA(t *testing.T) {
+	testCases := []struct {
+		done, total int64
+		elapsed     time.Duration
+		rate        float64
+		remaining   time.Duration
+	}{
+		{1000, 4000, time.Second, 1000, 3 * time.Second},
+		{2048, 2048, 2 * time.Second, 1024, 0},
+		{500, 0, time.Second, 500, -1},
+		{0, 4000, time.Second, 0, -1},
+		{1000, 4000, 0, 0, -1},
+	}
+
+	for _, tc := range testCases {
+		rate, remaining := downloadETA(tc.done, tc.total, tc.elapsed)
+		if rate != tc.rate || remaining != tc.remaining {
+			t.Errorf("downloadETA(%d, %d, %s) = %.0f, %s; expected %.0f, %s", tc.done, tc.total, tc.elapsed, rate, remaining, tc.rate, tc.remaining)
+		}
+	}
+
+	if message := formatDownloadProgress(1536, 90*time.Second); message != "Скачивание: 1.5 KB/с, осталось 1m30s" {
+		t.Errorf("unexpected progress message %q", message)
+	}
+}
+
+// TestDownloadSizeGuard проверяет прерывание скачивания, превышающего max_download_size
+func TestDownloadSizeGuard(t *testing.T) {
+	data := make([]byte, 3*downloadChunkSize)
+	ctx := context.Background()
+
+	// Объявленный размер проверяется до чтения
+	var dst bytes.Buffer
+	if _, err := copyDownload(ctx, &dst, bytes.NewReader(data), int64(len(data)), 1024, nil); err == nil || dst.Len() != 0 {
+		t.Errorf("expected rejection by Content-Length, got %v after %d bytes", err, dst.Len())
+	}
+
+	// Без Content-Length скачивание прерывается по мере чтения
+	dst.Reset()
+	written, err := copyDownload(ctx, &dst, bytes.NewReader(data), -1, downloadChunkSize+1, nil)
+	if err == nil || !strings.Contains(err.Error(), "max_download_size") {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+	if written > downloadChunkSize+1 {
+		t.Errorf("wrote %d bytes past the limit", written)
+	}
+
+	dst.Reset()
+	if written, err := copyDownload(ctx, &dst, bytes.NewReader(data), int64(len(data)), int64(len(data)), nil); err != nil || written != int64(len(data)) {
+		t.Errorf("expected download within the limit to succeed, got %d, %v", written, err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// proxySettings выбор прокси для запросов: параметры конфигурации, а для незаданных —
+// переменные окружения HTTP_PROXY, HTTPS_PROXY и NO_PROXY
+type proxySettings struct {
+	httpProxy  *url.URL
+	httpsProxy *url.URL
+	noProxy    []string
+}
+
+// newProxySettings разбирает настройки прокси из конфигурации и окружения
+func newProxySettings(config *Config) (*proxySettings, error) {
+	settings := &proxySettings{}
+
+	var err error
+	if settings.httpProxy, err = parseProxyURL(firstNonEmpty(config.HTTPProxy, getenvAny("HTTP_PROXY", "http_proxy"))); err != nil {
+		return nil, fmt.Errorf("http_proxy: %w", err)
+	}
+	if settings.httpsProxy, err = parseProxyURL(firstNonEmpty(config.HTTPSProxy, getenvAny("HTTPS_PROXY", "https_proxy"))); err != nil {
+		return nil, fmt.Errorf("https_proxy: %w", err)
+	}
+
+	for _, entry := range strings.Split(firstNonEmpty(config.NoProxy, getenvAny("NO_PROXY", "no_proxy")), ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			settings.noProxy = append(settings.noProxy, entry)
+		}
+	}
+
+	return settings, nil
+}
+
+// proxy возвращает прокси для запроса или nil для прямого соединения; подходит для http.Transport.Proxy
+func (p *proxySettings) proxy(req *http.Request) (*url.URL, error) {
+	proxyURL := p.httpProxy
+	if req.URL.Scheme == "https" {
+		proxyURL = p.httpsProxy
+	}
+	if proxyURL == nil || p.bypass(req.URL) {
+		return nil, nil
+	}
+	return proxyURL, nil
+}
+
+// bypass сообщает, что запрос к адресу выполняется без прокси. Как и в стандартной
+// библиотеке, запросы к localhost и loopback-адресам через прокси не направляются.
+func (p *proxySettings) bypass(target *url.URL) bool {
+	host := strings.ToLower(target.Hostname())
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	for _, entry := range p.noProxy {
+		if entry == "*" {
+			return true
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		// Запись с портом действует только на этот порт
+		if entryHost, entryPort, err := net.SplitHostPort(entry); err == nil {
+			if entryPort != target.Port() {
+				continue
+			}
+			entry = entryHost
+		}
+
+		// ".example.com" и "example.com" относятся также ко всем поддоменам
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProxyURL разбирает адрес прокси; адрес без схемы считается http
+func parseProxyURL(value string) (*url.URL, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("некорректный адрес прокси: %s", redactSecrets(value))
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("неподдерживаемая схема прокси: %s", parsed.Scheme)
+	}
+	return parsed, nil
+}
+
+// getenvAny возвращает первое непустое значение из переменных окружения
+func getenvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// firstNonEmpty возвращает первое непустое значение
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
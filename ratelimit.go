@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter ограничивает частоту HTTP запросов по принципу token bucket: разрешения
+// поступают с заданной частотой и в простое накапливаются до burst, так что короткая
+// серия запросов проходит без ожидания, а дальше соблюдается заданная частота.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // время поступления одного разрешения
+	burst    float64
+	tokens   float64 // отрицательное значение — разрешения, уже выданные ожидающим
+	updated  time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRateLimiter создает новый rate limiter с заданной частотой запросов в секунду
+// без накопления разрешений
+func NewRateLimiter(requestsPerSecond int) *RateLimiter {
+	return NewBurstRateLimiter(requestsPerSecond, 1)
+}
+
+// NewBurstRateLimiter создает rate limiter с заданной частотой запросов в секунду,
+// накапливающий в простое до burst разрешений; вначале запас полон
+func NewBurstRateLimiter(requestsPerSecond, burst int) *RateLimiter {
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = 10 // по умолчанию 10 запросов в секунду
+	}
+	burst = max(burst, 1)
+
+	return &RateLimiter{
+		interval: time.Second / time.Duration(requestsPerSecond),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		updated:  time.Now(),
+		done:     make(chan struct{}),
+	}
+}
+
+// reserve забирает разрешение и возвращает, сколько нужно подождать до его поступления.
+// Разрешение резервируется сразу, поэтому ожидающие обслуживаются по очереди и
+// частота не превышается при любой нагрузке.
+func (rl *RateLimiter) reserve() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	rl.tokens = min(rl.burst, rl.tokens+float64(now.Sub(rl.updated))/float64(rl.interval))
+	rl.updated = now
+
+	rl.tokens--
+	if rl.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-rl.tokens * float64(rl.interval))
+}
+
+// Wait ждет разрешения на выполнение запроса; nil — частота не ограничена.
+// После Close ожидающие и новые вызовы возвращаются сразу.
+func (rl *RateLimiter) Wait() {
+	rl.WaitCtx(context.Background())
+}
+
+// WaitCtx ждет разрешения на выполнение запроса или отмены ctx. При отмене возвращается
+// ошибка контекста, а зарезервированное разрешение возвращается в запас.
+func (rl *RateLimiter) WaitCtx(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if rl == nil {
+		return nil
+	}
+	select {
+	case <-rl.done:
+		return nil
+	default:
+	}
+
+	delay := rl.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-rl.done:
+		return nil
+	case <-ctx.Done():
+		rl.mu.Lock()
+		rl.tokens++
+		rl.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Close останавливает rate limiter; повторные и одновременные вызовы безопасны
+func (rl *RateLimiter) Close() {
+	if rl == nil {
+		return
+	}
+	rl.closeOnce.Do(func() {
+		close(rl.done)
+	})
+}
+
+// Частота запросов ограничивается отдельно для каждого репозитория: медленный
+// внешний репозиторий не тормозит запросы к локальному зеркалу. Запросы к адресам
+// вне настроенных репозиториев делят общий ограничитель.
+
+// repositoryLimiter ограничитель частоты запросов к репозиторию и параметры, с которыми
+// он создан; nil означает отсутствие ограничения
+type repositoryLimiter struct {
+	rate    int
+	burst   int
+	limiter *RateLimiter
+}
+
+// optionalRateLimiter создает ограничитель частоты; при rate <= 0 частота не ограничивается
+func optionalRateLimiter(rate, burst int) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return NewBurstRateLimiter(rate, burst)
+}
+
+// repositoryRateLimit возвращает частоту запросов к репозиторию: собственную rate_limit
+// или общую requests_per_second; 0 — без ограничения
+func (pm *PackageManager) repositoryRateLimit(repo Repository) int {
+	switch {
+	case repo.RateLimit > 0:
+		return repo.RateLimit
+	case repo.RateLimit < 0:
+		return 0
+	default:
+		return max(pm.getConfig().RequestsPerSecond, 0)
+	}
+}
+
+// repositoryForURL возвращает репозиторий, которому принадлежит rawURL; при вложенных
+// URL выбирается самый длинный совпадающий префикс
+func (pm *PackageManager) repositoryForURL(rawURL string) (Repository, bool) {
+	var found Repository
+	best := -1
+	for _, repo := range pm.getConfig().Repositories {
+		base := strings.TrimRight(repo.URL, "/")
+		if base != "" && len(base) > best && urlWithin(rawURL, base) {
+			best = len(base)
+			found = repo
+		}
+	}
+	return found, best >= 0
+}
+
+// rateLimiterFor возвращает ограничитель частоты для запроса к rawURL, создавая
+// ограничитель репозитория при первом обращении или смене его частоты и запаса
+func (pm *PackageManager) rateLimiterFor(rawURL string) *RateLimiter {
+	repo, ok := pm.repositoryForURL(rawURL)
+	if !ok {
+		_, limiter := pm.network()
+		return limiter
+	}
+	rate, burst := pm.repositoryRateLimit(repo), pm.getConfig().RateBurst
+
+	pm.networkMutex.RLock()
+	entry, exists := pm.rateLimiters[repo.Name]
+	pm.networkMutex.RUnlock()
+	if exists && entry.rate == rate && entry.burst == burst {
+		return entry.limiter
+	}
+
+	pm.networkMutex.Lock()
+	defer pm.networkMutex.Unlock()
+
+	if entry, exists := pm.rateLimiters[repo.Name]; exists {
+		if entry.rate == rate && entry.burst == burst {
+			return entry.limiter
+		}
+		entry.limiter.Close()
+	}
+	if pm.rateLimiters == nil {
+		pm.rateLimiters = make(map[string]repositoryLimiter)
+	}
+	limiter := optionalRateLimiter(rate, burst)
+	pm.rateLimiters[repo.Name] = repositoryLimiter{rate: rate, burst: burst, limiter: limiter}
+	return limiter
+}
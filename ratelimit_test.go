@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRepositoryRateLimits проверяет, что частота запросов к репозиториям ограничивается
+// независимо, а 0 и -1 отключают ограничение
+func TestRepositoryRateLimits(t *testing.T) {
+	pm := newTestPackageManager(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	slow := httptest.NewServer(handler)
+	defer slow.Close()
+	fast := httptest.NewServer(handler)
+	defer fast.Close()
+
+	pm.getConfig().RequestsPerSecond = 4
+	pm.getConfig().Repositories = []Repository{
+		{Name: "slow", URL: slow.URL, Enabled: true},
+		{Name: "fast", URL: fast.URL, Enabled: true, RateLimit: 1000},
+	}
+	t.Cleanup(func() {
+		for _, entry := range pm.rateLimiters {
+			entry.limiter.Close()
+		}
+	})
+
+	get := func(url string) time.Duration {
+		t.Helper()
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		resp, err := pm.doRequest(req)
+		if err != nil {
+			t.Fatalf("doRequest: %v", err)
+		}
+		resp.Body.Close()
+		return time.Since(start)
+	}
+
+	// Три запроса при 4 в секунду занимают не меньше двух интервалов по 250 мс
+	var elapsed time.Duration
+	for i := 0; i < 3; i++ {
+		elapsed += get(slow.URL + "/api/v1/packages")
+	}
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("expected slow repository to be throttled, took %v", elapsed)
+	}
+	// Исчерпанный лимит первого репозитория не задерживает второй
+	if elapsed := get(fast.URL + "/api/v1/packages"); elapsed > 200*time.Millisecond {
+		t.Errorf("expected fast repository not to wait for slow one, took %v", elapsed)
+	}
+
+	slowLimiter := pm.rateLimiterFor(slow.URL + "/api/v1/search")
+	if slowLimiter == nil || slowLimiter == pm.rateLimiterFor(fast.URL) || slowLimiter == pm.rateLimiter {
+		t.Error("expected a separate limiter per repository")
+	}
+	if limiter := pm.rateLimiterFor("https://elsewhere.example.com/archive.tar.gz"); limiter != pm.rateLimiter {
+		t.Error("expected URLs outside repositories to use the shared limiter")
+	}
+
+	// Смена частоты заменяет ограничитель; -1 и общий 0 отключают ограничение
+	pm.getConfig().Repositories[1].RateLimit = -1
+	if limiter := pm.rateLimiterFor(fast.URL); limiter != nil {
+		t.Error("expected rate_limit -1 to disable limiting")
+	}
+	pm.getConfig().RequestsPerSecond = 0
+	if limiter := pm.rateLimiterFor(slow.URL); limiter != nil {
+		t.Error("expected requests_per_second 0 to disable limiting")
+	}
+	if elapsed := get(slow.URL) + get(slow.URL) + get(slow.URL); elapsed > 200*time.Millisecond {
+		t.Errorf("expected unlimited requests to run without delay, took %v", elapsed)
+	}
+}
+
+// TestRateLimiterCloseDuringWait проверяет, что Close, в том числе повторный и
+// одновременный, не вызывает панику и освобождает ожидающие Wait
+func TestRateLimiterCloseDuringWait(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		rl := NewRateLimiter(1)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 5; j++ {
+					rl.Wait()
+				}
+			}()
+		}
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rl.Close()
+			}()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("round %d: Wait calls did not return after Close", round)
+		}
+
+		// После остановки Wait не блокируется
+		rl.Wait()
+	}
+}
+
+// TestRateLimiterBurst проверяет, что запас разрешений расходуется без ожидания,
+// после чего соблюдается заданная частота, а в простое запас восполняется не больше burst
+func TestRateLimiterBurst(t *testing.T) {
+	rl := NewBurstRateLimiter(20, 5) // разрешение каждые 50 мс, запас 5
+	defer rl.Close()
+
+	timed := func(n int) time.Duration {
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			rl.Wait()
+		}
+		return time.Since(start)
+	}
+
+	if elapsed := timed(5); elapsed > 30*time.Millisecond {
+		t.Errorf("expected full burst to pass without waiting, took %v", elapsed)
+	}
+	if elapsed := timed(4); elapsed < 180*time.Millisecond {
+		t.Errorf("expected steady rate after the burst, 4 requests took only %v", elapsed)
+	}
+
+	// Простой дольше запаса восполняет не больше burst разрешений
+	time.Sleep(400 * time.Millisecond)
+	if elapsed := timed(5); elapsed > 30*time.Millisecond {
+		t.Errorf("expected refilled burst to pass without waiting, took %v", elapsed)
+	}
+	if elapsed := timed(1); elapsed < 40*time.Millisecond {
+		t.Errorf("expected burst to be capped at 5, next request took only %v", elapsed)
+	}
+}
+
+// TestRateLimiterWaitCtx проверяет, что отмена контекста прерывает ожидание разрешения
+// без задержки и возвращает зарезервированное разрешение
+func TestRateLimiterWaitCtx(t *testing.T) {
+	rl := NewRateLimiter(1) // следующее разрешение через секунду
+	defer rl.Close()
+	if err := rl.WaitCtx(context.Background()); err != nil {
+		t.Fatalf("WaitCtx: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	if err := rl.WaitCtx(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected prompt return after cancellation, took %v", elapsed)
+	}
+	if err := rl.WaitCtx(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancelled context to fail immediately, got %v", err)
+	}
+
+	// Отмененное ожидание не сдвигает очередь: следующее разрешение — через секунду от первого
+	start = time.Now()
+	rl.Wait()
+	if elapsed := time.Since(start); elapsed > 1100*time.Millisecond {
+		t.Errorf("expected cancelled reservation to be released, waited %v", elapsed)
+	}
+
+	var unlimited *RateLimiter
+	if err := unlimited.WaitCtx(context.Background()); err != nil {
+		t.Errorf("expected nil limiter not to wait, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Доли локальной оценки релевантности. Вместе дают 1: точное совпадение имени
+// с многократным упоминанием в начале описания.
+const (
+	relevanceNameMatch     = 0.3  // запрос входит в имя
+	relevanceNamePosition  = 0.2  // чем ближе к началу имени, тем больше
+	relevanceExactName     = 0.2  // имя совпадает с запросом
+	relevanceDescPosition  = 0.15 // чем ближе к началу описания, тем больше
+	relevanceDescFrequency = 0.05 // за каждое вхождение в описание, не более трех
+)
+
+// localRelevance оценивает релевантность результата от 0 до 1 по позиции и частоте
+// вхождений запроса в имя и описание. Используется для репозиториев, не возвращающих Score.
+func localRelevance(query string, result SearchResult) float64 {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return 0
+	}
+
+	var score float64
+	name := strings.ToLower(result.Name)
+	if pos := strings.Index(name, query); pos >= 0 {
+		score += relevanceNameMatch + relevanceNamePosition*(1-float64(pos)/float64(len(name)))
+		if name == query {
+			score += relevanceExactName
+		}
+	}
+
+	description := strings.ToLower(result.Description)
+	if pos := strings.Index(description, query); pos >= 0 {
+		count := min(strings.Count(description, query), 3)
+		score += relevanceDescPosition*(1-float64(pos)/float64(len(description))) + relevanceDescFrequency*float64(count)
+	}
+
+	return min(score, 1)
+}
+
+// highlightMatches выделяет вхождения запроса в тексте маркерами **...** без учета
+// регистра, сохраняя написание исходного текста
+func highlightMatches(text, query string) string {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return text
+	}
+
+	var output strings.Builder
+	last := 0
+	for i := 0; i+len(query) <= len(text); {
+		if strings.EqualFold(text[i:i+len(query)], query) {
+			output.WriteString(text[last:i])
+			output.WriteString("**" + text[i:i+len(query)] + "**")
+			i += len(query)
+			last = i
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	if last == 0 {
+		return text
+	}
+	output.WriteString(text[last:])
+	return output.String()
+}
+
+// formatScore выводит оценку релевантности в процентах; оценки выше 1 считаются полными
+func formatScore(score float64) string {
+	return fmt.Sprintf("%.0f%%", min(max(score, 0), 1)*100)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestHighlightMatches проверяет выделение вхождений запроса без учета регистра
+func TestHighlightMatches(t *testing.T) {
+	tests := []struct {
+		text, query, want string
+	}{
+		{"json-tool", "json", "**json**-tool"},
+		{"JSON formatter for json", "json", "**JSON** formatter for **json**"},
+		{"aaaa", "aa", "**aa****aa**"},
+		{"Парсер YAML", "парсер", "**Парсер** YAML"},
+		{"yaml-lint", "json", "yaml-lint"},
+		{"json", "  ", "json"},
+		{"", "json", ""},
+	}
+	for _, tt := range tests {
+		if got := highlightMatches(tt.text, tt.query); got != tt.want {
+			t.Errorf("highlightMatches(%q, %q) = %q, want %q", tt.text, tt.query, got, tt.want)
+		}
+	}
+
+	if got := formatScore(0.874); got != "87%" {
+		t.Errorf("expected 87%%, got %s", got)
+	}
+	if got := formatScore(9.5); got != "100%" {
+		t.Errorf("expected scores above 1 to be shown as 100%%, got %s", got)
+	}
+}
+
+// TestLocalRelevance проверяет порядок локальной оценки и ее применение к результатам
+// репозитория без Score, не затрагивая оценки, вернувшиеся из репозитория
+func TestLocalRelevance(t *testing.T) {
+	results := []SearchResult{
+		{Name: "yaml-lint", Description: "Linter, reads json and json5"},
+		{Name: "fast-json", Description: "JSON parser"},
+		{Name: "json", Description: "JSON toolkit: json, json5"},
+		{Name: "xml", Description: "Converter"},
+		{Name: "json-tool", Description: "Formatter"},
+		{Name: "remote", Description: "Scored by repository", Score: 0.5},
+	}
+
+	scores := map[string]float64{}
+	for _, result := range results[:5] {
+		score := localRelevance("JSON", result)
+		if score < 0 || score > 1 {
+			t.Errorf("%s: score %v is out of range", result.Name, score)
+		}
+		scores[result.Name] = score
+	}
+	if scores["json"] != 1 {
+		t.Errorf("exact name with repeated description matches must score 1, got %v", scores["json"])
+	}
+	if scores["xml"] != 0 {
+		t.Errorf("unrelated package must score 0, got %v", scores["xml"])
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": map[string]interface{}{"results": results}})
+	}))
+	defer server.Close()
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{{Name: "plain", URL: server.URL, Enabled: true}}
+	page, err := pm.SearchPackages(context.Background(), "json", SearchFilter{}, 1, 20)
+	if err != nil {
+		t.Fatalf("SearchPackages: %v", err)
+	}
+
+	var names []string
+	for _, result := range page.Results {
+		names = append(names, result.Name)
+		if result.Name == "remote" && result.Score != 0.5 {
+			t.Errorf("repository score must be kept, got %v", result.Score)
+		}
+	}
+	// Упоминание в описании поднимает fast-json выше json-tool, несмотря на позицию в имени
+	want := []string{"json", "fast-json", "json-tool", "remote", "yaml-lint", "xml"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("expected results ordered by local relevance %v, got %v", want, names)
+	}
+}
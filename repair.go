@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isCorruptJSON сообщает, что ошибка вызвана содержимым JSON, а не его чтением
+func isCorruptJSON(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// RepairState восстанавливает packages.json глобальной и локальной областей по каталогам
+// установки: записи строятся заново из манифестов найденных пакетов. Без force
+// восстанавливаются только файлы, которые не удается разобрать.
+func (pm *PackageManager) RepairState(force bool) (*RepairStateResult, error) {
+	pm.packagesMutex.Lock()
+	defer pm.packagesMutex.Unlock()
+	pm.packagesFileMutex.Lock()
+	defer pm.packagesFileMutex.Unlock()
+
+	roots := map[string]string{
+		scopeGlobal: pm.getConfig().GlobalPath,
+		scopeLocal:  pm.getConfig().LocalPath,
+	}
+	scopes := []string{scopeGlobal, scopeLocal}
+
+	result := &RepairStateResult{Repaired: []string{}}
+	loaded := make(map[string]map[string]*PackageInfo, len(scopes))
+	for _, scope := range scopes {
+		path := filepath.Join(roots[scope], packagesFileName)
+		lock, err := lockPackagesFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		packages, err := pm.repairPackagesFile(scope, roots[scope], force, result)
+		lock.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		loaded[scope] = packages
+	}
+
+	// Память приводится в соответствие с файлами: локальные записи перекрывают глобальные, как при загрузке
+	pm.installedPackages = make(map[string]*PackageInfo)
+	for _, scope := range scopes {
+		for name, info := range loaded[scope] {
+			pm.installedPackages[name] = info
+		}
+	}
+
+	return result, nil
+}
+
+// repairPackagesFile восстанавливает packages.json одной области, если он поврежден
+// (или всегда при force), и возвращает записи области
+func (pm *PackageManager) repairPackagesFile(scope, root string, force bool, result *RepairStateResult) (map[string]*PackageInfo, error) {
+	path := filepath.Join(root, packagesFileName)
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("ошибка чтения %s: %w", path, err)
+	}
+	if !force {
+		if err != nil {
+			return nil, nil
+		}
+		var packages map[string]*PackageInfo
+		if err := json.Unmarshal(data, &packages); err == nil {
+			return packages, nil
+		}
+	}
+
+	packages := make(map[string]*PackageInfo)
+	var recovered []RecoveredPackage
+	entries, err := os.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("ошибка чтения %s: %w", root, err)
+	}
+	for _, entry := range entries {
+		// Служебные каталоги (.locks, .config-backup, резервные копии установки) пропускаются
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		installPath := filepath.Join(root, entry.Name())
+		manifest, err := readManifestFile(installPath)
+		if err != nil {
+			reason := fmt.Sprintf("не удалось прочитать %s: %v", manifestFileName, err)
+			if os.IsNotExist(err) {
+				reason = fmt.Sprintf("нет %s", manifestFileName)
+			}
+			result.Skipped = append(result.Skipped, IndexChange{Scope: scope, Name: entry.Name(), Reason: reason})
+			continue
+		}
+		if manifest.Name == "" || manifest.Version == "" {
+			result.Skipped = append(result.Skipped, IndexChange{Scope: scope, Name: entry.Name(), Reason: "в манифесте не указаны имя или версия"})
+			continue
+		}
+		if _, exists := packages[manifest.Name]; exists {
+			result.Skipped = append(result.Skipped, IndexChange{Scope: scope, Name: entry.Name(), Reason: fmt.Sprintf("пакет %s уже найден в другом каталоге", manifest.Name)})
+			continue
+		}
+
+		info := &PackageInfo{
+			Name:            manifest.Name,
+			Version:         manifest.Version,
+			Description:     manifest.Description,
+			Author:          manifest.Author,
+			License:         manifest.License,
+			InstallPath:     installPath,
+			Global:          scope == scopeGlobal,
+			Dependencies:    manifest.Dependencies,
+			Size:            pm.calculateDirSize(installPath),
+			Files:           manifest.Files,
+			Scripts:         manifest.Scripts,
+			Keywords:        manifest.Keywords,
+			Config:          manifest.Config,
+			PreservedConfig: pm.PreservedConfig(manifest.Name, scope == scopeGlobal),
+		}
+		// Дата установки не сохранилась; ближайшая оценка — время изменения каталога
+		if stat, err := entry.Info(); err == nil {
+			info.InstallDate = stat.ModTime()
+		}
+		packages[info.Name] = info
+		recovered = append(recovered, RecoveredPackage{Scope: scope, Name: info.Name, Version: info.Version, InstallPath: installPath})
+	}
+
+	data, err = json.MarshalIndent(packages, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return nil, fmt.Errorf("ошибка записи %s: %w", path, err)
+	}
+	sort.Slice(recovered, func(i, j int) bool { return recovered[i].Name < recovered[j].Name })
+	result.Recovered = append(result.Recovered, recovered...)
+	result.Repaired = append(result.Repaired, path)
+
+	logger.Infof("Файл %s восстановлен по каталогам установки: пакетов %d", path, len(packages))
+	return packages, nil
+}
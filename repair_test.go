@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRepairCorruptPackagesFile проверяет восстановление packages.json по манифестам
+// в каталогах установки при загрузке и инструментом repair_state
+func TestRepairCorruptPackagesFile(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "alpha", Version: "1.0.0", License: "MIT"}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "beta", Version: "2.1.0", Files: []string{"src/main.txt"}}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "gamma", Version: "0.3.0"}, true)
+	// Каталог без манифеста не становится записью
+	if err := os.MkdirAll(filepath.Join(pm.getConfig().LocalPath, "stray"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	localFile := filepath.Join(pm.getConfig().LocalPath, packagesFileName)
+	if err := os.WriteFile(localFile, []byte("{\"alpha\": garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pm.installedPackages = make(map[string]*PackageInfo)
+	if err := pm.loadInstalledPackages(); err != nil {
+		t.Fatalf("loadInstalledPackages should recover from corrupt JSON: %v", err)
+	}
+
+	for name, version := range map[string]string{"alpha": "1.0.0", "beta": "2.1.0", "gamma": "0.3.0"} {
+		info, ok := pm.getInstalledPackage(name)
+		if !ok || info.Version != version {
+			t.Errorf("package %s not recovered: %+v", name, info)
+		}
+	}
+	if info, _ := pm.getInstalledPackage("beta"); info != nil && (info.Global || info.Size == 0 || len(info.Files) != 1 || info.InstallDate.IsZero()) {
+		t.Errorf("recovered entry is incomplete: %+v", info)
+	}
+	if info, _ := pm.getInstalledPackage("gamma"); info != nil && !info.Global {
+		t.Error("global package should stay global")
+	}
+	if _, ok := pm.getInstalledPackage("stray"); ok {
+		t.Error("directory without manifest should not be recovered")
+	}
+
+	packages, err := pm.readPackagesFile(false)
+	if err != nil {
+		t.Fatalf("packages.json should be rewritten: %v", err)
+	}
+	if len(packages) != 2 || packages["alpha"] == nil || packages["alpha"].License != "MIT" {
+		t.Errorf("unexpected rewritten packages.json: %+v", packages)
+	}
+
+	// Неповрежденные файлы без force не трогаются
+	result, err := pm.RepairState(false)
+	if err != nil {
+		t.Fatalf("RepairState failed: %v", err)
+	}
+	if len(result.Repaired) != 0 {
+		t.Errorf("expected no repairs for valid files, got %+v", result)
+	}
+
+	if err := os.WriteFile(localFile, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	text, err := callToolText(t, newTestServer(t, pm), "repair_state", nil)
+	if err != nil {
+		t.Fatalf("repair_state failed: %v", err)
+	}
+	if !strings.Contains(text, "[local] beta (2.1.0)") || !strings.Contains(text, "stray: нет criage.yaml") || strings.Contains(text, "gamma") {
+		t.Errorf("unexpected tool output:\n%s", text)
+	}
+}
+
+// TestUpdateCorruptPackagesFile проверяет, что запись пакета не затирает поврежденный
+// packages.json, а сообщает об ошибке с указанием на repair_state
+func TestUpdateCorruptPackagesFile(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "alpha", Version: "1.0.0"}, false)
+
+	localFile := filepath.Join(pm.getConfig().LocalPath, packagesFileName)
+	corrupt := []byte("{\"alpha\": garbage")
+	if err := os.WriteFile(localFile, corrupt, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := buildTestArchive(t, pm, PackageManifest{Name: "beta", Version: "1.0.0"}, nil, FormatTarGz)
+	_, err := pm.installFromArchive(context.Background(), archivePath, false, false, nil)
+	if err == nil || !strings.Contains(err.Error(), "repair_state") {
+		t.Fatalf("expected error pointing to repair_state, got %v", err)
+	}
+	if data, _ := os.ReadFile(localFile); string(data) != string(corrupt) {
+		t.Errorf("corrupt packages.json was overwritten: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(pm.getConfig().LocalPath, "beta")); !os.IsNotExist(err) {
+		t.Errorf("failed install should be rolled back, stat err: %v", err)
+	}
+
+	if _, err := pm.RepairState(false); err != nil {
+		t.Fatalf("RepairState: %v", err)
+	}
+	if _, ok := pm.getInstalledPackage("alpha"); !ok {
+		t.Error("alpha should be recovered after repair_state")
+	}
+}
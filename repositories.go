@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// repositoriesByPriority возвращает включенные репозитории по убыванию приоритета;
+// при равном приоритете сохраняется порядок конфигурации. Репозитории, временно
+// пониженные из-за сбоев, идут после остальных.
+func (pm *PackageManager) repositoriesByPriority() []Repository {
+	var repositories []Repository
+	demoted := make(map[string]bool)
+	for _, repo := range pm.getConfig().Repositories {
+		if repo.Enabled {
+			repositories = append(repositories, repo)
+			demoted[repo.Name] = pm.health.demoted(repositoryHost(repo.URL))
+		}
+	}
+
+	sort.SliceStable(repositories, func(i, j int) bool {
+		if demoted[repositories[i].Name] != demoted[repositories[j].Name] {
+			return !demoted[repositories[i].Name]
+		}
+		return repositories[i].Priority > repositories[j].Priority
+	})
+	return repositories
+}
+
+// ListRepositories возвращает копию списка настроенных репозиториев
+func (pm *PackageManager) ListRepositories() []Repository {
+	return append([]Repository(nil), pm.getConfig().Repositories...)
+}
+
+// AddRepository добавляет репозиторий и сохраняет конфигурацию.
+// Имя должно быть уникальным, URL — корректным адресом http(s).
+// Репозитории с большим приоритетом проверяются раньше.
+func (pm *PackageManager) AddRepository(repo Repository) (*Repository, error) {
+	repo.Name = strings.TrimSpace(repo.Name)
+	if repo.Name == "" {
+		return nil, fmt.Errorf("имя репозитория обязательно")
+	}
+
+	normalized, err := pm.validateRepositoryURL(repo.URL)
+	if err != nil {
+		return nil, err
+	}
+	repo.URL = normalized
+
+	if repo.Timeout < 0 {
+		return nil, fmt.Errorf("таймаут репозитория не может быть отрицательным")
+	}
+	if repo.RateLimit < -1 {
+		return nil, fmt.Errorf("частота запросов к репозиторию должна быть не меньше -1 (-1 — без ограничения)")
+	}
+	if repo.PublicKey != "" {
+		if _, err := parseMinisignPublicKey(repo.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	pm.configMutex.Lock()
+	defer pm.configMutex.Unlock()
+
+	_, err = pm.updateConfig(func(config *Config) error {
+		for _, existing := range config.Repositories {
+			if strings.EqualFold(existing.Name, repo.Name) {
+				return fmt.Errorf("репозиторий %s уже существует", existing.Name)
+			}
+		}
+		config.Repositories = append(config.Repositories, repo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Добавлен репозиторий %s (%s)", repo.Name, repo.URL)
+	return &repo, nil
+}
+
+// RemoveRepository удаляет репозиторий по имени и сохраняет конфигурацию.
+// Последний репозиторий удалить нельзя.
+func (pm *PackageManager) RemoveRepository(name string) (*Repository, error) {
+	pm.configMutex.Lock()
+	defer pm.configMutex.Unlock()
+
+	var removed Repository
+	_, err := pm.updateConfig(func(config *Config) error {
+		index := -1
+		for i, repo := range config.Repositories {
+			if strings.EqualFold(repo.Name, name) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("репозиторий %s не найден", name)
+		}
+		if len(config.Repositories) == 1 {
+			return fmt.Errorf("нельзя удалить последний репозиторий %s", config.Repositories[index].Name)
+		}
+
+		removed = config.Repositories[index]
+		config.Repositories = append(config.Repositories[:index], config.Repositories[index+1:]...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Закрепленный сертификат удаленного репозитория больше не должен действовать
+	if removed.CertFingerprint != "" {
+		if err := pm.reconfigureNetwork(); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Infof("Удален репозиторий %s", removed.Name)
+	return &removed, nil
+}
+
+// validateRepositoryURL проверяет адрес репозитория и возвращает его без завершающей косой черты
+func (pm *PackageManager) validateRepositoryURL(rawURL string) (string, error) {
+	rawURL = strings.TrimRight(strings.TrimSpace(rawURL), "/")
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("некорректный URL репозитория: %s", rawURL)
+	}
+
+	switch parsed.Scheme {
+	case "https":
+	case "http":
+		if pm.getConfig().ForceHTTPS {
+			return "", fmt.Errorf("разрешены только HTTPS репозитории (force_https)")
+		}
+	default:
+		return "", fmt.Errorf("неподдерживаемая схема URL репозитория: %s", parsed.Scheme)
+	}
+
+	return rawURL, nil
+}
+
+// saveConfig сохраняет конфигурацию в файл; вызывается под configMutex
+func (pm *PackageManager) saveConfig(config *Config) error {
+	if pm.configPath == "" {
+		return fmt.Errorf("путь к файлу конфигурации не задан")
+	}
+	if err := writeConfig(pm.configPath, config); err != nil {
+		return fmt.Errorf("ошибка сохранения конфигурации: %w", err)
+	}
+	return nil
+}
+
+// redactedRepository возвращает копию репозитория со скрытым токеном для вывода
+func redactedRepository(repo Repository) Repository {
+	if repo.AuthToken != "" {
+		repo.AuthToken = "***"
+	}
+	return repo
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestManageRepositories проверяет добавление и удаление репозиториев с сохранением конфигурации
+func TestManageRepositories(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{{Name: "main", URL: "https://packages.example.com", Priority: 1, Enabled: true}}
+
+	added, err := pm.AddRepository(Repository{Name: "mirror", URL: "https://mirror.example.com/", Priority: 2, Enabled: true, AuthToken: "secret"})
+	if err != nil {
+		t.Fatalf("AddRepository: %v", err)
+	}
+	if added.URL != "https://mirror.example.com" || added.Priority != 2 {
+		t.Errorf("expected normalized URL and requested priority, got %+v", added)
+	}
+
+	for _, invalid := range []Repository{
+		{Name: "MAIN", URL: "https://other.example.com"},
+		{Name: "", URL: "https://other.example.com"},
+		{Name: "bad", URL: "not a url"},
+		{Name: "ftp", URL: "ftp://files.example.com"},
+	} {
+		if _, err := pm.AddRepository(invalid); err == nil {
+			t.Errorf("expected error for %+v", invalid)
+		}
+	}
+
+	// Изменения переживают перезагрузку конфигурации
+	reloaded, err := loadConfigFile(pm.configPath)
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if len(reloaded.Repositories) != 2 || reloaded.Repositories[1].Name != "mirror" || reloaded.Repositories[1].AuthToken != "secret" {
+		t.Fatalf("expected mirror to be persisted, got %+v", reloaded.Repositories)
+	}
+
+	if _, err := pm.RemoveRepository("missing"); err == nil {
+		t.Error("expected error for unknown repository")
+	}
+	if _, err := pm.RemoveRepository("main"); err != nil {
+		t.Fatalf("RemoveRepository: %v", err)
+	}
+	if _, err := pm.RemoveRepository("mirror"); err == nil || !strings.Contains(err.Error(), "последний") {
+		t.Errorf("expected refusal to remove the last repository, got %v", err)
+	}
+
+	reloaded, err = loadConfigFile(pm.configPath)
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if len(reloaded.Repositories) != 1 || reloaded.Repositories[0].Name != "mirror" {
+		t.Errorf("expected only mirror after removal, got %+v", reloaded.Repositories)
+	}
+	if repos := pm.ListRepositories(); len(repos) != 1 || repos[0].Name != "mirror" {
+		t.Errorf("unexpected repositories %+v", repos)
+	}
+}
+
+// TestConfigConcurrentAccess смешивает изменения конфигурации с поиском репозиториев и
+// путей установки; гонки данных между ними обнаруживает go test -race
+func TestConfigConcurrentAccess(t *testing.T) {
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{{Name: "main", URL: "https://packages.example.com", Priority: 1, Enabled: true}}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			name := fmt.Sprintf("mirror-%d", i)
+			if _, err := pm.AddRepository(Repository{Name: name, URL: "https://" + name + ".example.com", Priority: i, Enabled: true}); err != nil {
+				t.Errorf("AddRepository: %v", err)
+				return
+			}
+			if _, err := pm.RemoveRepository(name); err != nil {
+				t.Errorf("RemoveRepository: %v", err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := pm.SetConfigValue("force_https", i%2 == 0); err != nil {
+				t.Errorf("SetConfigValue: %v", err)
+				return
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			if repositories := pm.ListRepositories(); len(repositories) != 1 || repositories[0].Name != "main" {
+				t.Errorf("expected only main repository after mutations, got %+v", repositories)
+			}
+			return
+		default:
+		}
+		if repositories := pm.repositoriesByPriority(); len(repositories) == 0 {
+			t.Fatal("main repository missing during mutations")
+		}
+		if _, ok := pm.repositoryForURL("https://packages.example.com/api/v1/packages/demo"); !ok {
+			t.Fatal("repositoryForURL lost main repository during mutations")
+		}
+		if _, err := pm.getInstallPath("demo", false); err != nil {
+			t.Fatalf("getInstallPath: %v", err)
+		}
+		pm.validateDownloadURL("https://packages.example.com/files/demo.tar.gz")
+		pm.ConfigSnapshot()
+	}
+}
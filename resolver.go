@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxResolveRounds ограничивает число пересчетов при согласовании версий
+const maxResolveRounds = 100
+
+// dependencyResolver подбирает версии транзитивных зависимостей, отслеживая,
+// какие ограничения наложил на каждую зависимость каждый из требующих ее пакетов
+type dependencyResolver struct {
+	pm       *PackageManager
+	packages map[string]*RepositoryPackage // кеш описаний пакетов из репозиториев
+}
+
+func newDependencyResolver(pm *PackageManager) *dependencyResolver {
+	return &dependencyResolver{pm: pm, packages: make(map[string]*RepositoryPackage)}
+}
+
+// repositoryPackage возвращает описание пакета, запрашивая репозитории один раз
+func (r *dependencyResolver) repositoryPackage(ctx context.Context, name string) (*RepositoryPackage, error) {
+	if pkg, ok := r.packages[name]; ok {
+		return pkg, nil
+	}
+
+	pkg, _, err := r.pm.findRepositoryPackage(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r.packages[name] = pkg
+	return pkg, nil
+}
+
+// versionDependencies возвращает зависимости выбранной версии пакета
+func (r *dependencyResolver) versionDependencies(name, version string) map[string]string {
+	pkg := r.packages[name]
+	if pkg == nil {
+		return nil
+	}
+	for _, v := range pkg.Versions {
+		if v.Version == version {
+			return v.Dependencies
+		}
+	}
+	return nil
+}
+
+// resolve согласовывает версии всех зависимостей root. Ограничения собираются заново
+// на каждом круге из корня и выбранных версий, пока выбор не перестанет меняться.
+func (r *dependencyResolver) resolve(ctx context.Context, root string, rootDeps map[string]string) ([]ResolvedConstraint, error) {
+	selected := make(map[string]string)
+
+	for round := 0; round < maxResolveRounds; round++ {
+		constraints := make(map[string][]RequirerConstraint)
+		addDeps := func(requirer string, deps map[string]string) {
+			for name, constraint := range deps {
+				constraints[name] = append(constraints[name], RequirerConstraint{Requirer: requirer, Constraint: constraint})
+			}
+		}
+
+		// Обход от корня: учитываются только достижимые выбранные версии
+		addDeps(root, rootDeps)
+		visited := map[string]bool{}
+		queue := sortedKeys(rootDeps)
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			if visited[name] {
+				continue
+			}
+			visited[name] = true
+
+			version, ok := selected[name]
+			if !ok {
+				continue
+			}
+			deps := r.versionDependencies(name, version)
+			addDeps(name+"@"+version, deps)
+			queue = append(queue, sortedKeys(deps)...)
+		}
+
+		changed := false
+		for _, name := range sortedKeys(constraints) {
+			version, err := r.choose(ctx, name, constraints[name])
+			if err != nil {
+				return nil, err
+			}
+			if selected[name] != version {
+				selected[name] = version
+				changed = true
+			}
+		}
+		for name := range selected {
+			if _, ok := constraints[name]; !ok {
+				delete(selected, name)
+				changed = true
+			}
+		}
+
+		if !changed {
+			result := make([]ResolvedConstraint, 0, len(constraints))
+			for _, name := range sortedKeys(constraints) {
+				reqs := constraints[name]
+				sort.Slice(reqs, func(i, j int) bool { return reqs[i].Requirer < reqs[j].Requirer })
+
+				parts := make([]string, len(reqs))
+				for i, req := range reqs {
+					parts[i] = normalizedConstraint(req.Constraint)
+				}
+				result = append(result, ResolvedConstraint{
+					Name:        name,
+					Constraints: reqs,
+					Combined:    strings.Join(parts, ", "),
+					Version:     selected[name],
+				})
+			}
+			return result, nil
+		}
+	}
+
+	return nil, fmt.Errorf("не удалось согласовать версии зависимостей за %d итераций", maxResolveRounds)
+}
+
+// choose выбирает старшую версию пакета, удовлетворяющую всем ограничениям
+func (r *dependencyResolver) choose(ctx context.Context, name string, reqs []RequirerConstraint) (string, error) {
+	parsed := make([]versionConstraint, len(reqs))
+	for i, req := range reqs {
+		c, err := parseConstraint(req.Constraint)
+		if err != nil {
+			return "", fmt.Errorf("%s требует %s: %w", req.Requirer, name, err)
+		}
+		parsed[i] = c
+	}
+
+	pkg, err := r.repositoryPackage(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("зависимость %s не найдена: %w", name, err)
+	}
+
+	best := ""
+	for _, v := range pkg.Versions {
+		ok := true
+		for _, c := range parsed {
+			if !c.satisfies(v.Version) {
+				ok = false
+				break
+			}
+		}
+		if ok && (best == "" || compareVersions(v.Version, best) > 0) {
+			best = v.Version
+		}
+	}
+
+	if best == "" {
+		parts := make([]string, len(reqs))
+		for i, req := range reqs {
+			parts[i] = fmt.Sprintf("%s требует %s", req.Requirer, normalizedConstraint(req.Constraint))
+		}
+		return "", fmt.Errorf("нет версии %s, удовлетворяющей всем ограничениям: %s", name, strings.Join(parts, "; "))
+	}
+
+	return best, nil
+}
+
+// normalizedConstraint заменяет пустое ограничение на "*" для вывода
+func normalizedConstraint(constraint string) string {
+	if strings.TrimSpace(constraint) == "" {
+		return "*"
+	}
+	return constraint
+}
+
+// sortedKeys возвращает ключи карты в алфавитном порядке
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ResolvedConstraints разрешает зависимости корневого пакета и для каждой транзитивной
+// зависимости сообщает ограничения всех требующих ее пакетов и выбранную версию.
+// Корень задается либо манифестом (manifestPath), либо пакетом репозитория (name, version).
+func (pm *PackageManager) ResolvedConstraints(ctx context.Context, name, version, manifestPath string) (*ConstraintReport, error) {
+	resolver := newDependencyResolver(pm)
+	report := &ConstraintReport{}
+	var rootDeps map[string]string
+
+	switch {
+	case manifestPath != "":
+		manifest, err := readManifestFile(manifestPath)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения манифеста: %w", err)
+		}
+		report.Root = manifest.Name + "@" + manifest.Version
+		rootDeps = manifest.Dependencies
+	case name != "":
+		pkg, err := resolver.repositoryPackage(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("пакет %s не найден: %w", name, err)
+		}
+		if version == "" {
+			version = latestVersion(pkg)
+		}
+		selected, err := matchVersion(version, pkg.Versions)
+		if err != nil {
+			return nil, err
+		}
+		report.Root = name + "@" + selected.Version
+		rootDeps = selected.Dependencies
+	default:
+		return nil, fmt.Errorf("укажите имя пакета или путь к манифесту")
+	}
+
+	dependencies, err := resolver.resolve(ctx, report.Root, rootDeps)
+	if err != nil {
+		return nil, err
+	}
+	report.Dependencies = dependencies
+
+	return report, nil
+}
+
+// readManifestFile читает манифест из файла или из criage.yaml в указанном каталоге
+func readManifestFile(path string) (*PackageManifest, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, manifestFileName)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest PackageManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
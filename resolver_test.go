@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestResolvedConstraints проверяет объединение пересекающихся ограничений и
+// пересчет выбора, когда более поздний требующий пакет сужает диапазон
+func TestResolvedConstraints(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t,
+		&RepositoryPackage{Name: "app", Versions: []RepositoryVersion{
+			{Version: "1.0.0", Dependencies: map[string]string{"a": "^1.0.0", "b": "*", "c": "1.x"}},
+		}},
+		&RepositoryPackage{Name: "a", Versions: []RepositoryVersion{
+			{Version: "1.0.0"},
+			{Version: "1.2.0"},
+			{Version: "1.3.5", Dependencies: map[string]string{"c": "~1.1"}},
+			{Version: "1.4.0", Dependencies: map[string]string{"c": "^1.2.0"}},
+			{Version: "2.0.0"},
+		}},
+		&RepositoryPackage{Name: "b", Versions: []RepositoryVersion{
+			{Version: "1.0.0", Dependencies: map[string]string{"a": ">=1.2.0 <1.4.0"}},
+			{Version: "2.0.0", Dependencies: map[string]string{"a": ">=1.3.0, <1.4.0 || >=2.0.0"}},
+		}},
+		&RepositoryPackage{Name: "c", Versions: []RepositoryVersion{
+			{Version: "1.1.0"},
+			{Version: "1.1.4"},
+			{Version: "1.2.0"},
+		}},
+	)
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	report, err := pm.ResolvedConstraints(context.Background(), "app", "", "")
+	if err != nil {
+		t.Fatalf("ResolvedConstraints: %v", err)
+	}
+	if report.Root != "app@1.0.0" {
+		t.Errorf("unexpected root %q", report.Root)
+	}
+
+	expected := map[string]struct {
+		version   string
+		requirers []string
+	}{
+		"a": {"1.3.5", []string{"app@1.0.0", "b@2.0.0"}},
+		"b": {"2.0.0", []string{"app@1.0.0"}},
+		// a@1.4.0 был выбран на первом круге, но его ограничение не должно остаться в отчете
+		"c": {"1.1.4", []string{"a@1.3.5", "app@1.0.0"}},
+	}
+	if len(report.Dependencies) != len(expected) {
+		t.Fatalf("expected %d dependencies, got %+v", len(expected), report.Dependencies)
+	}
+	for _, dep := range report.Dependencies {
+		want, ok := expected[dep.Name]
+		if !ok {
+			t.Errorf("unexpected dependency %s", dep.Name)
+			continue
+		}
+		if dep.Version != want.version {
+			t.Errorf("%s: expected version %s, got %s", dep.Name, want.version, dep.Version)
+		}
+		var requirers []string
+		for _, req := range dep.Constraints {
+			requirers = append(requirers, req.Requirer)
+		}
+		if strings.Join(requirers, ",") != strings.Join(want.requirers, ",") {
+			t.Errorf("%s: expected requirers %v, got %v", dep.Name, want.requirers, requirers)
+		}
+	}
+
+	// Корень из манифеста разрешается так же, как пакет репозитория
+	dir := t.TempDir()
+	manifest := `{"name": "local", "version": "0.1.0", "dependencies": {"a": "~1.2"}}`
+	if err := os.WriteFile(filepath.Join(dir, manifestFileName), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report, err = pm.ResolvedConstraints(context.Background(), "", "", dir)
+	if err != nil {
+		t.Fatalf("ResolvedConstraints from manifest: %v", err)
+	}
+	if len(report.Dependencies) != 1 || report.Dependencies[0].Version != "1.2.0" {
+		t.Errorf("expected a 1.2.0 from manifest, got %+v", report.Dependencies)
+	}
+}
+
+// TestResolvedConstraintsConflict проверяет сообщение о несовместимых ограничениях
+func TestResolvedConstraintsConflict(t *testing.T) {
+	pm := newTestPackageManager(t)
+	repo := newMockRepository(t,
+		&RepositoryPackage{Name: "app", Versions: []RepositoryVersion{
+			{Version: "1.0.0", Dependencies: map[string]string{"a": "^2.0.0", "b": "1.0.0"}},
+		}},
+		&RepositoryPackage{Name: "a", Versions: []RepositoryVersion{{Version: "1.3.0"}, {Version: "2.1.0"}}},
+		&RepositoryPackage{Name: "b", Versions: []RepositoryVersion{
+			{Version: "1.0.0", Dependencies: map[string]string{"a": "<1.4.0"}},
+		}},
+	)
+	pm.getConfig().Repositories = []Repository{repo.repository("mock", 1)}
+
+	_, err := pm.ResolvedConstraints(context.Background(), "app", "1.0.0", "")
+	if err == nil {
+		t.Fatal("expected conflict error")
+	}
+	for _, part := range []string{"app@1.0.0 требует ^2.0.0", "b@1.0.0 требует <1.4.0"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("expected error to mention %q, got %v", part, err)
+		}
+	}
+}
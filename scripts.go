@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultScriptTimeout время выполнения скрипта пакета по умолчанию
+	defaultScriptTimeout = 5 * time.Minute
+	// maxScriptTimeout наибольшее допустимое время выполнения скрипта
+	maxScriptTimeout = time.Hour
+	// maxScriptOutput объем сохраняемого вывода скрипта; остальное отбрасывается
+	maxScriptOutput = 1 << 20
+)
+
+// scriptOutput собирает общий вывод stdout и stderr скрипта и передает каждую
+// завершенную строку получателю прогресса
+type scriptOutput struct {
+	buf       bytes.Buffer
+	line      []byte
+	lines     int64
+	truncated bool
+	report    ProgressFunc
+}
+
+func (o *scriptOutput) Write(p []byte) (int, error) {
+	if room := maxScriptOutput - o.buf.Len(); room < len(p) {
+		o.buf.Write(p[:max(room, 0)])
+		o.truncated = true
+	} else {
+		o.buf.Write(p)
+	}
+
+	if o.report != nil {
+		for _, b := range p {
+			if b != '\n' {
+				o.line = append(o.line, b)
+				continue
+			}
+			o.flush()
+		}
+	}
+	return len(p), nil
+}
+
+// flush сообщает о накопленной строке вывода
+func (o *scriptOutput) flush() {
+	if o.report == nil || len(o.line) == 0 {
+		return
+	}
+	o.lines++
+	o.report(o.lines, 0, "Вывод: "+strings.TrimRight(string(o.line), "\r"))
+	o.line = o.line[:0]
+}
+
+// packageVariables переменные с контекстом пакета, доступные скриптам
+func packageVariables(info *PackageInfo) map[string]string {
+	return map[string]string{
+		"CRIAGE_PKG_NAME":     info.Name,
+		"CRIAGE_PKG_VERSION":  info.Version,
+		"CRIAGE_INSTALL_PATH": info.InstallPath,
+		"CRIAGE_GLOBAL":       strconv.FormatBool(info.Global),
+	}
+}
+
+// packageEnvironment окружение скрипта: окружение сервера и переменные пакета
+func packageEnvironment(info *PackageInfo) []string {
+	vars := packageVariables(info)
+	env := os.Environ()
+	for _, name := range sortedKeys(vars) {
+		env = append(env, name+"="+vars[name])
+	}
+	return env
+}
+
+// variablePattern ссылка на переменную в форме $NAME или ${NAME}
+var variablePattern = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// expandPackageVariables подставляет в команду переменные пакета ($NAME или ${NAME}),
+// заключая значения в кавычки оболочки: имя и версия пакета берутся из манифеста, и
+// метасимволы в них не должны становиться частью команды. Остальной текст, включая
+// неизвестные переменные и формы вроде ${NAME:-default}, остается без изменений.
+func expandPackageVariables(command string, info *PackageInfo) string {
+	vars := packageVariables(info)
+	return variablePattern.ReplaceAllStringFunc(command, func(match string) string {
+		name := strings.Trim(match, "${}")
+		if value, ok := vars[name]; ok {
+			return shellQuote(value)
+		}
+		return match
+	})
+}
+
+// shellQuote заключает значение в кавычки оболочки, запускающей скрипты (см. shellCommand)
+func shellQuote(value string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// shellCommand создает команду запуска строки скрипта через оболочку системы
+func shellCommand(ctx context.Context, script string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", script)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", script)
+}
+
+// RunScript выполняет именованный скрипт из манифеста установленного пакета в каталоге
+// установки. Строки вывода по мере появления передаются получателю прогресса из ctx.
+// Ненулевой код выхода и превышение timeout не считаются ошибкой вызова: они отражаются в результате.
+func (pm *PackageManager) RunScript(ctx context.Context, packageName, scriptName string, timeout time.Duration) (*ScriptResult, error) {
+	info, exists := pm.getInstalledPackage(packageName)
+	if !exists {
+		return nil, errorWithCause(ErrPackageNotFound, "пакет %s не установлен", packageName)
+	}
+
+	script, ok := info.Scripts[scriptName]
+	if !ok {
+		if len(info.Scripts) == 0 {
+			return nil, fmt.Errorf("пакет %s не объявляет скриптов", packageName)
+		}
+		return nil, fmt.Errorf("скрипт %q не найден в пакете %s; доступны: %s", scriptName, packageName, strings.Join(sortedKeys(info.Scripts), ", "))
+	}
+
+	if timeout <= 0 {
+		timeout = defaultScriptTimeout
+	}
+	if timeout > maxScriptTimeout {
+		return nil, fmt.Errorf("время выполнения скрипта не может превышать %s", maxScriptTimeout)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	command := expandPackageVariables(script, info)
+	output := &scriptOutput{report: progressFromContext(ctx)}
+	cmd := shellCommand(runCtx, command)
+	cmd.Dir = info.InstallPath
+	cmd.Env = packageEnvironment(info)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Дочерние процессы оболочки могут держать вывод открытым после ее завершения
+	cmd.WaitDelay = time.Second
+
+	started := time.Now()
+	err := cmd.Run()
+	output.flush()
+
+	result := &ScriptResult{
+		Package:    packageName,
+		Script:     scriptName,
+		Command:    command,
+		Output:     output.buf.String(),
+		Truncated:  output.truncated,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil:
+		result.TimedOut = true
+		result.ExitCode = -1
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("ошибка запуска скрипта %s: %w", scriptName, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRunScript проверяет выполнение скриптов манифеста в каталоге установки,
+// передачу вывода в прогресс, коды выхода, ограничение времени и неизвестные скрипты
+func TestRunScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("скрипты теста написаны для sh")
+	}
+
+	pm := newTestPackageManager(t)
+	archivePath := buildTestArchive(t, pm, PackageManifest{
+		Name:    "tool",
+		Version: "1.0.0",
+		Scripts: map[string]string{
+			"hello": "echo hello; echo oops >&2",
+			"pwd":   "pwd",
+			"fail":  "echo failing; exit 3",
+			"hang":  "sleep 10",
+			"vars":  "echo $CRIAGE_PKG_NAME $CRIAGE_PKG_VERSION $CRIAGE_GLOBAL ${CRIAGE_UNSET:-kept} ${CRIAGE_PKG_NAME:-unset}",
+		},
+	}, map[string]string{"bin/tool": "binary"}, FormatTarGz)
+	info, err := pm.installFromArchive(context.Background(), archivePath, false, false, nil)
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	var mu sync.Mutex
+	var progress []string
+	ctx := withProgress(context.Background(), func(_, _ int64, message string) {
+		mu.Lock()
+		progress = append(progress, message)
+		mu.Unlock()
+	})
+
+	result, err := pm.RunScript(ctx, "tool", "hello", 0)
+	if err != nil {
+		t.Fatalf("RunScript: %v", err)
+	}
+	if result.ExitCode != 0 || result.TimedOut || result.Output != "hello\noops\n" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(progress) != 2 || !strings.HasSuffix(progress[0], "hello") || !strings.HasSuffix(progress[1], "oops") {
+		t.Errorf("expected output lines to be streamed as progress, got %q", progress)
+	}
+
+	result, err = pm.RunScript(context.Background(), "tool", "pwd", 0)
+	if err != nil {
+		t.Fatalf("RunScript: %v", err)
+	}
+	want, _ := filepath.EvalSymlinks(info.InstallPath)
+	if got, _ := filepath.EvalSymlinks(strings.TrimSpace(result.Output)); got != want {
+		t.Errorf("expected script to run in %s, got %s", want, got)
+	}
+
+	result, err = pm.RunScript(context.Background(), "tool", "fail", 0)
+	if err != nil || result.ExitCode != 3 || result.Output != "failing\n" {
+		t.Errorf("expected exit code 3, got %+v, %v", result, err)
+	}
+
+	result, err = pm.RunScript(context.Background(), "tool", "vars", 0)
+	if err != nil || result.Output != "tool 1.0.0 false kept tool\n" {
+		t.Errorf("expected package variables to be expanded, got %+v, %v", result, err)
+	}
+
+	started := time.Now()
+	result, err = pm.RunScript(context.Background(), "tool", "hang", 200*time.Millisecond)
+	if err != nil || !result.TimedOut || result.ExitCode != -1 {
+		t.Errorf("expected timeout, got %+v, %v", result, err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("timed out script took %v to stop", elapsed)
+	}
+
+	_, err = pm.RunScript(context.Background(), "tool", "build", 0)
+	if err == nil || !strings.Contains(err.Error(), "fail, hang, hello, pwd, vars") {
+		t.Errorf("expected unknown script error listing available scripts, got %v", err)
+	}
+	if _, err := pm.RunScript(context.Background(), "missing", "hello", 0); err == nil {
+		t.Error("expected error for package that is not installed")
+	}
+}
+
+// TestExpandPackageVariables проверяет подстановку документированных переменных пакета
+// в кавычках и сохранение остального текста команды для оболочки
+func TestExpandPackageVariables(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ожидаемые команды записаны для sh")
+	}
+	info := &PackageInfo{Name: "tool", Version: "1.2.0", InstallPath: "/opt/criage/tool", Global: true}
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"echo $CRIAGE_PKG_NAME@$CRIAGE_PKG_VERSION", "echo 'tool'@'1.2.0'"},
+		{"cd ${CRIAGE_INSTALL_PATH}/bin", "cd '/opt/criage/tool'/bin"},
+		{"test $CRIAGE_GLOBAL = true", "test 'true' = true"},
+		{"echo $HOME ${PATH}", "echo $HOME ${PATH}"},
+		{"echo $CRIAGE_UNKNOWN ${CRIAGE_OTHER}", "echo $CRIAGE_UNKNOWN ${CRIAGE_OTHER}"},
+		{"echo ${CRIAGE_PKG_NAME:-none}", "echo ${CRIAGE_PKG_NAME:-none}"},
+		{"exit $?", "exit $?"},
+		{"echo $(date) costs $", "echo $(date) costs $"},
+		{"no variables", "no variables"},
+	}
+	for _, tt := range tests {
+		if got := expandPackageVariables(tt.command, info); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.command, tt.want, got)
+		}
+	}
+
+	// Кавычки и метасимволы в значениях не становятся частью команды
+	hostile := &PackageInfo{Name: "tool; echo injected", Version: "1.0'$(echo x)", InstallPath: "/opt/my dir/it's"}
+	command := expandPackageVariables(`printf '%s|' $CRIAGE_PKG_NAME $CRIAGE_PKG_VERSION $CRIAGE_INSTALL_PATH`, hostile)
+	output, err := exec.Command("sh", "-c", command).Output()
+	if err != nil {
+		t.Fatalf("sh -c %q: %v", command, err)
+	}
+	if want := "tool; echo injected|1.0'$(echo x)|/opt/my dir/it's|"; string(output) != want {
+		t.Errorf("expected %q, got %q", want, output)
+	}
+}
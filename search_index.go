@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// searchIndexDir подкаталог CachePath для локальных поисковых индексов
+const searchIndexDir = "index"
+
+// searchIndexPageSize размер страницы при выгрузке списка пакетов репозитория
+const searchIndexPageSize = 100
+
+// Веса совпадений при поиске по индексу
+const (
+	scoreExactName   = 10
+	scoreNameTerm    = 5
+	scoreKeywordTerm = 3
+	scoreDescription = 1
+)
+
+// indexDir возвращает каталог поисковых индексов
+func (pm *PackageManager) indexDir() string {
+	return filepath.Join(pm.getConfig().CachePath, searchIndexDir)
+}
+
+// indexPath возвращает путь к файлу индекса репозитория
+func (pm *PackageManager) indexPath(repositoryURL string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.TrimRight(strings.TrimPrefix(strings.TrimPrefix(repositoryURL, "https://"), "http://"), "/"))
+
+	return filepath.Join(pm.indexDir(), name+".json")
+}
+
+// tokenize разбивает текст на термы в нижнем регистре
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// BuildSearchIndex выгружает все пакеты репозитория постранично и сохраняет
+// инвертированный индекс по имени, описанию и ключевым словам в CachePath
+func (pm *PackageManager) BuildSearchIndex(ctx context.Context, repositoryURL string) (*SearchIndex, error) {
+	index := &SearchIndex{
+		RepositoryURL: repositoryURL,
+		BuiltAt:       time.Now(),
+		Packages:      make(map[string]*RepositoryPackage),
+		Terms:         make(map[string][]string),
+	}
+
+	for page := 1; ; page++ {
+		list, err := pm.ListRepositoryPackages(ctx, repositoryURL, page, searchIndexPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения страницы %d: %w", page, err)
+		}
+
+		for _, pkg := range list.Packages {
+			index.Packages[pkg.Name] = pkg
+		}
+
+		if len(list.Packages) == 0 || page >= list.TotalPages {
+			break
+		}
+	}
+
+	for name, pkg := range index.Packages {
+		terms := make(map[string]bool)
+		for _, term := range tokenize(pkg.Name) {
+			terms[term] = true
+		}
+		for _, term := range tokenize(pkg.Description) {
+			terms[term] = true
+		}
+		for _, keyword := range pkg.Keywords {
+			for _, term := range tokenize(keyword) {
+				terms[term] = true
+			}
+		}
+		for term := range terms {
+			index.Terms[term] = append(index.Terms[term], name)
+		}
+	}
+	for term := range index.Terms {
+		sort.Strings(index.Terms[term])
+	}
+
+	if err := os.MkdirAll(pm.indexDir(), 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога индекса: %w", err)
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(pm.indexPath(repositoryURL), data, 0644); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения индекса: %w", err)
+	}
+
+	return index, nil
+}
+
+// loadSearchIndexes загружает сохраненные индексы: указанного репозитория или все
+func (pm *PackageManager) loadSearchIndexes(repositoryURL string) ([]*SearchIndex, error) {
+	var paths []string
+	if repositoryURL != "" {
+		paths = []string{pm.indexPath(repositoryURL)}
+	} else {
+		matches, err := filepath.Glob(filepath.Join(pm.indexDir(), "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		paths = matches
+	}
+
+	var indexes []*SearchIndex
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("ошибка чтения индекса: %w", err)
+		}
+
+		var index SearchIndex
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("поврежденный индекс %s: %w", path, err)
+		}
+		indexes = append(indexes, &index)
+	}
+
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("локальный индекс не найден, выполните build_search_index")
+	}
+
+	return indexes, nil
+}
+
+// SearchOffline ищет пакеты по локальным индексам без обращения к сети.
+// Пакет должен содержать все термы запроса (как префиксы слов).
+func (pm *PackageManager) SearchOffline(query, repositoryURL string) ([]SearchResult, error) {
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return nil, fmt.Errorf("пустой поисковый запрос")
+	}
+
+	indexes, err := pm.loadSearchIndexes(repositoryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var results []SearchResult
+	for _, index := range indexes {
+		for name, score := range index.match(queryTerms, strings.ToLower(strings.TrimSpace(query))) {
+			// Пакет из нескольких репозиториев показываем один раз
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			pkg := index.Packages[name]
+			results = append(results, SearchResult{
+				Name:        pkg.Name,
+				Version:     pkg.LatestVersion,
+				Description: pkg.Description,
+				Author:      pkg.Author,
+				Downloads:   pkg.Downloads,
+				Updated:     pkg.Updated,
+				Score:       score,
+			})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Name < results[j].Name
+	})
+
+	return results, nil
+}
+
+// match возвращает пакеты, содержащие все термы запроса, с оценкой релевантности
+func (index *SearchIndex) match(queryTerms []string, query string) map[string]float64 {
+	var candidates map[string]bool
+	for _, queryTerm := range queryTerms {
+		found := make(map[string]bool)
+		for term, names := range index.Terms {
+			if !strings.HasPrefix(term, queryTerm) {
+				continue
+			}
+			for _, name := range names {
+				if candidates == nil || candidates[name] {
+					found[name] = true
+				}
+			}
+		}
+		candidates = found
+		if len(candidates) == 0 {
+			return nil
+		}
+	}
+
+	scores := make(map[string]float64, len(candidates))
+	for name := range candidates {
+		pkg := index.Packages[name]
+		var score float64
+		if strings.ToLower(pkg.Name) == query {
+			score += scoreExactName
+		}
+		for _, queryTerm := range queryTerms {
+			if hasTermWithPrefix(tokenize(pkg.Name), queryTerm) {
+				score += scoreNameTerm
+			}
+			for _, keyword := range pkg.Keywords {
+				if hasTermWithPrefix(tokenize(keyword), queryTerm) {
+					score += scoreKeywordTerm
+					break
+				}
+			}
+			if hasTermWithPrefix(tokenize(pkg.Description), queryTerm) {
+				score += scoreDescription
+			}
+		}
+		scores[name] = score
+	}
+
+	return scores
+}
+
+// hasTermWithPrefix сообщает, начинается ли какой-либо из термов с prefix
+func hasTermWithPrefix(terms []string, prefix string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(term, prefix) {
+			return true
+		}
+	}
+	return false
+}
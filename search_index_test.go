@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestSearchIndex проверяет построение индекса через пагинацию и поиск по нему без сети
+func TestSearchIndex(t *testing.T) {
+	pm := newTestPackageManager(t)
+
+	packages := []*RepositoryPackage{
+		{Name: "json-tool", Description: "Formatter for JSON documents", Keywords: []string{"cli"}, LatestVersion: "1.0.0"},
+		{Name: "yaml-lint", Description: "Linter that also converts JSON", Keywords: []string{"lint", "cli"}, LatestVersion: "2.1.0"},
+		{Name: "http-server", Description: "Static file server", Keywords: []string{"web"}, LatestVersion: "0.3.0"},
+	}
+	// Больше одной страницы, чтобы проверить пагинацию
+	for i := 0; i < searchIndexPageSize; i++ {
+		packages = append(packages, &RepositoryPackage{Name: fmt.Sprintf("filler-%03d", i), Description: "Filler package"})
+	}
+	repo := newMockRepository(t, packages...)
+	pm.getConfig().Repositories = []Repository{repo.repository("test", 1)}
+
+	if _, err := pm.SearchOffline("json", ""); err == nil {
+		t.Fatal("expected error before index is built")
+	}
+
+	index, err := pm.BuildSearchIndex(context.Background(), repo.URL)
+	if err != nil {
+		t.Fatalf("BuildSearchIndex: %v", err)
+	}
+	if len(index.Packages) != len(packages) {
+		t.Fatalf("indexed %d packages, want %d", len(index.Packages), len(packages))
+	}
+
+	// Индекс читается с диска, репозиторий больше не нужен
+	repo.Close()
+
+	testCases := []struct {
+		query string
+		want  []string
+	}{
+		{"json", []string{"json-tool", "yaml-lint"}},
+		{"JSON cli", []string{"json-tool", "yaml-lint"}},
+		{"lint", []string{"yaml-lint"}},
+		{"serv", []string{"http-server"}},
+		{"json-tool", []string{"json-tool"}},
+		{"missing", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			results, err := pm.SearchOffline(tc.query, "")
+			if err != nil {
+				t.Fatalf("SearchOffline: %v", err)
+			}
+
+			var names []string
+			for _, result := range results {
+				names = append(names, result.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("results = %v, want %v", names, tc.want)
+			}
+		})
+	}
+
+	results, err := pm.SearchOffline("json", repo.URL)
+	if err != nil {
+		t.Fatalf("SearchOffline with repository: %v", err)
+	}
+	if len(results) == 0 || results[0].Version != "1.0.0" {
+		t.Errorf("unexpected results for repository index: %+v", results)
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semVersion разобранная семантическая версия
+type semVersion struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+// parseVersion разбирает версию вида [v]MAJOR[.MINOR[.PATCH]][-PRERELEASE][+BUILD]
+func parseVersion(version string) (semVersion, error) {
+	var v semVersion
+
+	s := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 || parts[0] == "" {
+		return v, fmt.Errorf("некорректная версия: %s", version)
+	}
+
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("некорректная версия: %s", version)
+		}
+		*numbers[i] = n
+	}
+
+	return v, nil
+}
+
+// compareVersions сравнивает две версии по правилам semver (метаданные сборки не учитываются).
+// Некорректные версии сравниваются как строки.
+func compareVersions(a, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+
+	for _, pair := range [][2]int{{va.Major, vb.Major}, {va.Minor, vb.Minor}, {va.Patch, vb.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return comparePrerelease(va.Prerelease, vb.Prerelease)
+}
+
+// comparePrerelease сравнивает метки предварительных версий; версия без метки старше любой предварительной
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		na, errA := strconv.Atoi(partsA[i])
+		nb, errB := strconv.Atoi(partsB[i])
+
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(partsA[i], partsB[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case len(partsA) < len(partsB):
+		return -1
+	case len(partsA) > len(partsB):
+		return 1
+	}
+	return 0
+}
+
+// newestVersion возвращает наибольшую по semver версию из списка; порядок, в котором
+// репозиторий перечислил версии, не важен. Из равных версий (отличающихся только
+// метаданными сборки) берется перечисленная последней. Для пустого списка — nil.
+func newestVersion(versions []RepositoryVersion) *RepositoryVersion {
+	var newest *RepositoryVersion
+	for i := range versions {
+		if newest == nil || compareVersions(versions[i].Version, newest.Version) >= 0 {
+			newest = &versions[i]
+		}
+	}
+	return newest
+}
+
+// matchVersion выбирает версию пакета по запрошенной строке. Точное совпадение имеет приоритет;
+// запрос без метаданных сборки подходит к единственной сборке той же версии, а при нескольких
+// сборках требуется указать нужную явно.
+func matchVersion(requested string, versions []RepositoryVersion) (*RepositoryVersion, error) {
+	for i := range versions {
+		if versions[i].Version == requested {
+			return &versions[i], nil
+		}
+	}
+
+	want, err := parseVersion(requested)
+	if err != nil {
+		return nil, errorWithCause(ErrVersionNotFound, "версия %s не найдена", requested)
+	}
+
+	var candidates []*RepositoryVersion
+	for i := range versions {
+		have, err := parseVersion(versions[i].Version)
+		if err != nil || compareVersions(versions[i].Version, requested) != 0 {
+			continue
+		}
+		if want.Build != "" && have.Build != want.Build {
+			continue
+		}
+		candidates = append(candidates, &versions[i])
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, errorWithCause(ErrVersionNotFound, "версия %s не найдена", requested)
+	case 1:
+		return candidates[0], nil
+	}
+
+	builds := make([]string, len(candidates))
+	for i, c := range candidates {
+		builds[i] = c.Version
+	}
+	return nil, fmt.Errorf("версия %s неоднозначна, укажите сборку: %s", requested, strings.Join(builds, ", "))
+}
+
+// comparator одно сравнение в ограничении версии, например >=1.2.0
+type comparator struct {
+	op      string
+	version string
+}
+
+// versionConstraint ограничение версии: альтернативы через "||", внутри альтернативы
+// все сравнения (через пробел или запятую) должны выполняться
+type versionConstraint struct {
+	alternatives [][]comparator
+}
+
+// parseConstraint разбирает ограничение версии зависимости. Поддерживаются "*" и пустая
+// строка (любая версия), точная версия, сравнения =, >, >=, <, <=, диапазоны ^ и ~,
+// а также неполные версии (1.2, 1.x).
+func parseConstraint(constraint string) (versionConstraint, error) {
+	var result versionConstraint
+
+	for _, alternative := range strings.Split(constraint, "||") {
+		tokens := strings.FieldsFunc(alternative, func(r rune) bool { return r == ' ' || r == ',' })
+
+		var comparators []comparator
+		for i := 0; i < len(tokens); i++ {
+			token := tokens[i]
+			// Оператор, отделенный от версии пробелом: ">= 1.2.0"
+			if strings.Trim(token, "<>=^~") == "" && i+1 < len(tokens) {
+				i++
+				token += tokens[i]
+			}
+
+			expanded, err := expandComparator(token)
+			if err != nil {
+				return result, fmt.Errorf("некорректное ограничение версии %q: %w", constraint, err)
+			}
+			comparators = append(comparators, expanded...)
+		}
+		result.alternatives = append(result.alternatives, comparators)
+	}
+
+	return result, nil
+}
+
+// expandComparator переводит одно условие в набор простых сравнений
+func expandComparator(token string) ([]comparator, error) {
+	switch token {
+	case "*", "x", "X", "latest":
+		return nil, nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(token, op) {
+			v := strings.TrimPrefix(token, op)
+			if _, err := parseVersion(v); err != nil {
+				return nil, err
+			}
+			return []comparator{{op, v}}, nil
+		}
+	}
+
+	prefix := ""
+	if strings.HasPrefix(token, "^") || strings.HasPrefix(token, "~") {
+		prefix, token = token[:1], token[1:]
+	}
+
+	// Неполная версия: 1, 1.2, 1.x, 1.2.*
+	core, suffix := token, ""
+	if i := strings.IndexAny(token, "-+"); i >= 0 {
+		core, suffix = token[:i], token[i:]
+	}
+	parts := strings.Split(core, ".")
+	for len(parts) > 1 && (parts[len(parts)-1] == "x" || parts[len(parts)-1] == "X" || parts[len(parts)-1] == "*") {
+		parts = parts[:len(parts)-1]
+	}
+	v, err := parseVersion(strings.Join(parts, ".") + suffix)
+	if err != nil {
+		return nil, err
+	}
+	given := len(parts)
+
+	lower := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		lower += "-" + v.Prerelease
+	}
+
+	var upper string
+	switch {
+	case prefix == "^":
+		switch {
+		case v.Major > 0 || given == 1:
+			upper = fmt.Sprintf("%d.0.0", v.Major+1)
+		case v.Minor > 0 || given == 2:
+			upper = fmt.Sprintf("0.%d.0", v.Minor+1)
+		default:
+			upper = fmt.Sprintf("0.0.%d", v.Patch+1)
+		}
+	case prefix == "~" || given < 3:
+		if given == 1 {
+			upper = fmt.Sprintf("%d.0.0", v.Major+1)
+		} else {
+			upper = fmt.Sprintf("%d.%d.0", v.Major, v.Minor+1)
+		}
+	default:
+		return []comparator{{"=", lower}}, nil
+	}
+
+	// Нижняя граница без метки: 2.0.0-0 меньше любой предварительной версии 2.0.0
+	return []comparator{{">=", lower}, {"<", upper + "-0"}}, nil
+}
+
+// satisfies сообщает, удовлетворяет ли версия ограничению. Предварительные версии
+// подходят, только если альтернатива явно упоминает предварительную версию того же ядра.
+func (c versionConstraint) satisfies(version string) bool {
+	candidate, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+
+	for _, alternative := range c.alternatives {
+		if comparatorsHold(alternative, version, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func comparatorsHold(comparators []comparator, version string, candidate semVersion) bool {
+	prereleaseAllowed := candidate.Prerelease == ""
+	for _, cmp := range comparators {
+		result := compareVersions(version, cmp.version)
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = result == 0
+		case ">":
+			ok = result > 0
+		case ">=":
+			ok = result >= 0
+		case "<":
+			ok = result < 0
+		case "<=":
+			ok = result <= 0
+		}
+		if !ok {
+			return false
+		}
+
+		if bound, err := parseVersion(cmp.version); err == nil && bound.Prerelease != "" && bound.Prerelease != "0" &&
+			bound.Major == candidate.Major && bound.Minor == candidate.Minor && bound.Patch == candidate.Patch {
+			prereleaseAllowed = true
+		}
+	}
+	return prereleaseAllowed
+}
@@ -0,0 +1,116 @@
+package main
+
+import "testing"
+
+// TestCompareVersions проверяет сравнение семантических версий
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.2.0", "1.10.0", -1},
+		{"v2.0.0", "1.9.9", 1},
+		{"1.0", "1.0.0", 0},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha.2", "1.0.0-alpha.10", -1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+	}
+
+	for _, tc := range testCases {
+		if got := compareVersions(tc.a, tc.b); got != tc.expected {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d", tc.a, tc.b, got, tc.expected)
+		}
+	}
+}
+
+// TestMatchVersion проверяет выбор версии с учетом метаданных сборки
+func TestMatchVersion(t *testing.T) {
+	versions := []RepositoryVersion{
+		{Version: "1.0.0+build.5"},
+		{Version: "1.0.0+build.6"},
+		{Version: "1.1.0+build.1"},
+		{Version: "2.0.0"},
+	}
+
+	testCases := []struct {
+		requested string
+		expected  string
+		wantErr   bool
+	}{
+		{"1.0.0+build.5", "1.0.0+build.5", false},
+		{"1.0.0+build.6", "1.0.0+build.6", false},
+		{"v1.0.0+build.6", "1.0.0+build.6", false},
+		{"1.1.0", "1.1.0+build.1", false},
+		{"2.0.0", "2.0.0", false},
+		{"1.0.0", "", true},
+		{"1.0.0+build.7", "", true},
+		{"3.0.0", "", true},
+	}
+
+	for _, tc := range testCases {
+		got, err := matchVersion(tc.requested, versions)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("matchVersion(%q) = %s, expected error", tc.requested, got.Version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("matchVersion(%q) failed: %v", tc.requested, err)
+			continue
+		}
+		if got.Version != tc.expected {
+			t.Errorf("matchVersion(%q) = %s, expected %s", tc.requested, got.Version, tc.expected)
+		}
+	}
+}
+
+// TestVersionConstraint проверяет разбор и проверку ограничений версий зависимостей
+func TestVersionConstraint(t *testing.T) {
+	testCases := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"", "3.0.0", true},
+		{"*", "0.0.1", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "1.2.4", false},
+		{">=1.2.0 <1.4.0", "1.3.9", true},
+		{">=1.2.0, <1.4.0", "1.4.0", false},
+		{">= 1.2.0", "1.2.0", true},
+		{"^1.2.0", "1.9.0", true},
+		{"^1.2.0", "2.0.0", false},
+		{"^0.2.3", "0.3.0", false},
+		{"~1.2.0", "1.2.9", true},
+		{"~1.2.0", "1.3.0", false},
+		{"1.x", "1.5.0", true},
+		{"1.2", "1.3.0", false},
+		{"<1.0.0 || >=2.0.0", "2.1.0", true},
+		{"<1.0.0 || >=2.0.0", "1.5.0", false},
+		// Предварительные версии подходят только при явном упоминании
+		{"^1.0.0", "1.1.0-beta", false},
+		{"<2.0.0", "2.0.0-rc.1", false},
+		{">=1.1.0-alpha", "1.1.0-beta", true},
+		{">=1.1.0-alpha", "1.2.0-beta", false},
+	}
+
+	for _, tc := range testCases {
+		c, err := parseConstraint(tc.constraint)
+		if err != nil {
+			t.Errorf("parseConstraint(%q): %v", tc.constraint, err)
+			continue
+		}
+		if got := c.satisfies(tc.version); got != tc.expected {
+			t.Errorf("%q satisfies %q = %v, expected %v", tc.constraint, tc.version, got, tc.expected)
+		}
+	}
+
+	for _, invalid := range []string{">=", "^abc", "1.2.3.4.5"} {
+		if _, err := parseConstraint(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
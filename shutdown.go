@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultShutdownTimeout сколько выполняющиеся вызовы могут завершаться после сигнала,
+// прежде чем их контексты будут отменены
+const defaultShutdownTimeout = 30 * time.Second
+
+// errShuttingDown вызов отклонен, потому что сервер завершает работу
+var errShuttingDown = errors.New("сервер завершает работу")
+
+// callsContext возвращает родительский контекст вызовов инструментов; вызывается под callsMutex
+func (s *MCPServer) callsContext() context.Context {
+	if s.callsCtx == nil {
+		s.callsCtx, s.cancelCalls = context.WithCancel(context.Background())
+	}
+	return s.callsCtx
+}
+
+// shuttingDownError ответ на вызов, полученный после начала завершения работы
+func shuttingDownError(id interface{}, err error) *MCPMessage {
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &MCPError{
+			Code:    -32000,
+			Message: err.Error(),
+		},
+	}
+}
+
+// shutdown завершает работу сервера: новые вызовы больше не принимаются, выполняющиеся
+// получают shutdownTimeout на завершение, после чего их контексты отменяются, чтобы
+// установки откатились по журналу, а не оборвались на середине записи. Затем
+// освобождаются сетевые ресурсы менеджера пакетов.
+func (s *MCPServer) shutdown() {
+	timeout := s.shutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	s.callsMutex.Lock()
+	s.closing = true
+	active := len(s.activeCalls)
+	s.callsContext()
+	s.callsMutex.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		s.callsWG.Wait()
+		close(finished)
+	}()
+
+	if active > 0 {
+		logger.Infof("Завершение работы: ожидание выполняющихся вызовов (%d, не дольше %s)", active, timeout)
+	}
+	select {
+	case <-finished:
+	case <-time.After(timeout):
+		logger.Warnf("Вызовы не завершились за %s и отменяются", timeout)
+		s.callsMutex.Lock()
+		s.cancelCalls()
+		s.callsMutex.Unlock()
+
+		// Отмененным вызовам дается столько же времени на откат
+		select {
+		case <-finished:
+		case <-time.After(timeout):
+			logger.Errorf("Вызовы не завершились после отмены, сервер завершает работу без них")
+		}
+	}
+
+	s.packageManager.Close()
+	logger.Infof("Сервер завершил работу")
+}
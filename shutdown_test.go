@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// slowRepository поднимает репозиторий, который сообщает о начале запроса в started и
+// отвечает только после закрытия release или отмены запроса клиентом
+func slowRepository(t *testing.T, release chan struct{}) (*httptest.Server, chan struct{}) {
+	t.Helper()
+
+	started := make(chan struct{}, 1)
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			http.NotFound(w, r)
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(repo.Close)
+	return repo, started
+}
+
+// startSlowInstall запускает установку через медленный репозиторий и ждет начала запроса
+func startSlowInstall(t *testing.T, session *testSession, started chan struct{}) {
+	t.Helper()
+
+	session.send(MCPMessage{
+		JSONRPC: "2.0",
+		ID:      7,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "install_package",
+			"arguments": map[string]interface{}{"name": "slow-package"},
+		},
+	})
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Tool call did not reach the repository")
+	}
+}
+
+// waitServeDone ждет завершения ServeContext и возвращает результат обратно в канал для очистки сеанса
+func waitServeDone(t *testing.T, session *testSession, timeout time.Duration) bool {
+	t.Helper()
+
+	select {
+	case err := <-session.done:
+		session.done <- err
+		if err != nil {
+			t.Errorf("ServeContext returned error: %v", err)
+		}
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// TestGracefulShutdownWaitsForCalls проверяет, что после сигнала сервер не принимает новые
+// вызовы, но дожидается выполняющегося и отправляет его ответ
+func TestGracefulShutdownWaitsForCalls(t *testing.T) {
+	release := make(chan struct{})
+	repo, started := slowRepository(t, release)
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{{Name: "slow", URL: repo.URL, Enabled: true}}
+	pm.getConfig().Timeout = 60
+	s := newTestServer(t, pm)
+
+	ctx, signal := context.WithCancel(context.Background())
+	defer signal()
+	session := startTestSessionContext(t, ctx, s)
+	startSlowInstall(t, session, started)
+
+	signal()
+	if waitServeDone(t, session, 200*time.Millisecond) {
+		t.Fatal("Server exited before the in-flight call finished")
+	}
+	if _, _, err := s.trackCall(8); !errors.Is(err, errShuttingDown) {
+		t.Errorf("Expected new calls to be rejected during shutdown, got %v", err)
+	}
+
+	close(release)
+	response := session.receive(5 * time.Second)
+	if requestKey(response.ID) != "7" || response.Error != nil {
+		t.Errorf("Expected completed response to the in-flight call, got %+v", response)
+	}
+	if !waitServeDone(t, session, 5*time.Second) {
+		t.Fatal("Server did not exit after the in-flight call finished")
+	}
+}
+
+// TestGracefulShutdownCancelsSlowCalls проверяет отмену вызовов, не завершившихся за отведенное время
+func TestGracefulShutdownCancelsSlowCalls(t *testing.T) {
+	repo, started := slowRepository(t, make(chan struct{}))
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{{Name: "slow", URL: repo.URL, Enabled: true}}
+	pm.getConfig().Timeout = 60
+	s := newTestServer(t, pm)
+	s.shutdownTimeout = 100 * time.Millisecond
+
+	ctx, signal := context.WithCancel(context.Background())
+	defer signal()
+	session := startTestSessionContext(t, ctx, s)
+	startSlowInstall(t, session, started)
+
+	signal()
+	response := session.receive(5 * time.Second)
+	if requestKey(response.ID) != "7" {
+		t.Fatalf("Expected response to the cancelled call, got %+v", response)
+	}
+	if response.Error == nil || response.Error.Code != -32800 {
+		t.Errorf("Expected cancellation error, got %+v", response)
+	}
+	if !waitServeDone(t, session, 5*time.Second) {
+		t.Fatal("Server did not exit after cancelling calls")
+	}
+}
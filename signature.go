@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Подписи пакетов в формате minisign: открытый ключ Ed25519 репозитория и подпись
+// архива в RepositoryFile.Signature. Поддерживаются подписи всего файла ("Ed") и
+// подписи хеша BLAKE2b-512 ("ED"), которые minisign создает по умолчанию.
+
+const (
+	minisignAlgorithm       = "Ed" // алгоритм ключа и подписи всего файла
+	minisignHashedAlgorithm = "ED" // подпись хеша BLAKE2b-512 файла
+
+	minisignKeyIDSize         = 8
+	minisignTrustedPrefix     = "trusted comment: "
+	minisignUntrustedPrefix   = "untrusted comment: "
+	minisignPublicKeySize     = 2 + minisignKeyIDSize + ed25519.PublicKeySize
+	minisignSignatureLineSize = 2 + minisignKeyIDSize + ed25519.SignatureSize
+)
+
+// minisignPublicKey открытый ключ minisign
+type minisignPublicKey struct {
+	keyID [minisignKeyIDSize]byte
+	key   ed25519.PublicKey
+}
+
+// minisignSignature разобранная подпись minisign
+type minisignSignature struct {
+	algorithm       string
+	keyID           [minisignKeyIDSize]byte
+	signature       []byte
+	trustedComment  string
+	globalSignature []byte
+}
+
+// minisignLines возвращает непустые строки текста ключа или подписи
+func minisignLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseMinisignPublicKey разбирает открытый ключ: содержимое файла .pub minisign
+// или только строку base64 из него
+func parseMinisignPublicKey(text string) (*minisignPublicKey, error) {
+	lines := minisignLines(text)
+	if len(lines) > 0 && strings.HasPrefix(lines[0], minisignUntrustedPrefix) {
+		lines = lines[1:]
+	}
+	if len(lines) != 1 {
+		return nil, fmt.Errorf("некорректный открытый ключ minisign")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(raw) != minisignPublicKeySize {
+		return nil, fmt.Errorf("некорректный открытый ключ minisign")
+	}
+	if string(raw[:2]) != minisignAlgorithm {
+		return nil, fmt.Errorf("неподдерживаемый алгоритм ключа minisign %q", raw[:2])
+	}
+
+	key := &minisignPublicKey{key: ed25519.PublicKey(raw[2+minisignKeyIDSize:])}
+	copy(key.keyID[:], raw[2:])
+	return key, nil
+}
+
+// parseMinisignSignature разбирает подпись minisign: комментарий, подпись,
+// доверенный комментарий и подпись доверенного комментария
+func parseMinisignSignature(text string) (*minisignSignature, error) {
+	lines := minisignLines(text)
+	if len(lines) > 0 && strings.HasPrefix(lines[0], minisignUntrustedPrefix) {
+		lines = lines[1:]
+	}
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], minisignTrustedPrefix) {
+		return nil, fmt.Errorf("некорректная подпись minisign")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(raw) != minisignSignatureLineSize {
+		return nil, fmt.Errorf("некорректная подпись minisign")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("некорректная подпись доверенного комментария minisign")
+	}
+
+	signature := &minisignSignature{
+		algorithm:       string(raw[:2]),
+		signature:       raw[2+minisignKeyIDSize:],
+		trustedComment:  strings.TrimPrefix(lines[1], minisignTrustedPrefix),
+		globalSignature: global,
+	}
+	copy(signature.keyID[:], raw[2:])
+	return signature, nil
+}
+
+// verifyMinisign проверяет подпись minisign файла открытым ключом
+func verifyMinisign(path, publicKey, signatureText string) error {
+	key, err := parseMinisignPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	signature, err := parseMinisignSignature(signatureText)
+	if err != nil {
+		return err
+	}
+	if signature.keyID != key.keyID {
+		return fmt.Errorf("подпись создана ключом %s, ожидался ключ %s", minisignKeyID(signature.keyID), minisignKeyID(key.keyID))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var message []byte
+	switch signature.algorithm {
+	case minisignHashedAlgorithm:
+		hash, _ := blake2b.New512(nil)
+		if _, err := io.Copy(hash, file); err != nil {
+			return err
+		}
+		message = hash.Sum(nil)
+	case minisignAlgorithm:
+		if message, err = io.ReadAll(file); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("неподдерживаемый алгоритм подписи minisign %q", signature.algorithm)
+	}
+
+	if !ed25519.Verify(key.key, message, signature.signature) {
+		return fmt.Errorf("подпись не соответствует содержимому архива")
+	}
+	global := bytes.Join([][]byte{signature.signature, []byte(signature.trustedComment)}, nil)
+	if !ed25519.Verify(key.key, global, signature.globalSignature) {
+		return fmt.Errorf("подпись доверенного комментария не прошла проверку")
+	}
+	return nil
+}
+
+// verifyResolvedSignature проверяет подпись скачанного архива, если у репозитория
+// задан открытый ключ. Для такого репозитория неподписанный архив не устанавливается.
+func verifyResolvedSignature(archivePath string, resolved *resolvedPackage) error {
+	if resolved.Repository.PublicKey == "" {
+		return nil
+	}
+	if resolved.File.Signature == "" {
+		return fmt.Errorf("архив пакета %s (%s) не подписан, а репозиторий %s требует подпись", resolved.Info.Name, resolved.Info.Version, resolved.Repository.Name)
+	}
+	if err := verifyMinisign(archivePath, resolved.Repository.PublicKey, resolved.File.Signature); err != nil {
+		return fmt.Errorf("подпись пакета %s (%s) не прошла проверку: %w", resolved.Info.Name, resolved.Info.Version, err)
+	}
+
+	logger.Debugf("Подпись пакета %s (%s) проверена ключом репозитория %s", resolved.Info.Name, resolved.Info.Version, resolved.Repository.Name)
+	return nil
+}
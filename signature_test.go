@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// testMinisignKey ключ для подписи тестовых архивов в формате minisign
+type testMinisignKey struct {
+	keyID   []byte
+	private ed25519.PrivateKey
+	public  string
+}
+
+// newTestMinisignKey создает ключ minisign и возвращает его вместе с текстом открытого ключа
+func newTestMinisignKey(t *testing.T, keyID string) *testMinisignKey {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := bytes.Join([][]byte{[]byte(minisignAlgorithm), []byte(keyID), public}, nil)
+	return &testMinisignKey{
+		keyID:   []byte(keyID),
+		private: private,
+		public:  "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(raw) + "\n",
+	}
+}
+
+// sign подписывает данные как minisign: хеш BLAKE2b-512 или, если hashed=false, сами данные
+func (k *testMinisignKey) sign(data []byte, hashed bool) string {
+	algorithm, message := minisignAlgorithm, data
+	if hashed {
+		sum := blake2b.Sum512(data)
+		algorithm, message = minisignHashedAlgorithm, sum[:]
+	}
+	signature := ed25519.Sign(k.private, message)
+	trusted := "timestamp:1700000000\tfile:archive"
+	global := ed25519.Sign(k.private, append(append([]byte{}, signature...), trusted...))
+
+	raw := bytes.Join([][]byte{[]byte(algorithm), k.keyID, signature}, nil)
+	return "untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(raw) + "\n" +
+		minisignTrustedPrefix + trusted + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n"
+}
+
+// TestVerifyMinisign проверяет подписи обоих режимов minisign и отказ для подмененных
+// данных, чужого ключа и измененного доверенного комментария
+func TestVerifyMinisign(t *testing.T) {
+	key := newTestMinisignKey(t, "KEYID001")
+	data := []byte("package archive contents")
+	path := filepath.Join(t.TempDir(), "archive.tar.gz")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hashed := range []bool{true, false} {
+		if err := verifyMinisign(path, key.public, key.sign(data, hashed)); err != nil {
+			t.Errorf("hashed=%v: expected valid signature, got %v", hashed, err)
+		}
+		if err := verifyMinisign(path, key.public, key.sign([]byte("tampered"), hashed)); err == nil {
+			t.Errorf("hashed=%v: expected signature of other data to be rejected", hashed)
+		}
+	}
+
+	other := newTestMinisignKey(t, "KEYID002")
+	if err := verifyMinisign(path, key.public, other.sign(data, true)); err == nil || !strings.Contains(err.Error(), "ожидался ключ") {
+		t.Errorf("expected key ID mismatch, got %v", err)
+	}
+
+	forged := strings.Replace(key.sign(data, true), "file:archive", "file:other", 1)
+	if err := verifyMinisign(path, key.public, forged); err == nil || !strings.Contains(err.Error(), "доверенного комментария") {
+		t.Errorf("expected forged trusted comment to be rejected, got %v", err)
+	}
+
+	if _, err := parseMinisignPublicKey("not a key"); err == nil {
+		t.Error("expected malformed public key to be rejected")
+	}
+}
+
+// TestSignedRepositoryInstall проверяет, что для репозитория с ключом установка требует
+// подпись архива и прерывается до извлечения при неверной подписи
+func TestSignedRepositoryInstall(t *testing.T) {
+	pm := newTestPackageManager(t)
+	key := newTestMinisignKey(t, "REPOKEY1")
+	archivePath := buildTestArchive(t, pm, PackageManifest{Name: "signed", Version: "1.0.0"}, map[string]string{"bin/tool": "tool"}, FormatTarGz)
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newMockRepository(t)
+	defer repo.Close()
+	repo.publish(t, "signed", "1.0.0", archivePath)
+	repository := repo.repository("signed-repo", 1)
+	repository.PublicKey = key.public
+	pm.getConfig().Repositories = []Repository{repository}
+
+	setSignature := func(signature string) {
+		repo.mu.Lock()
+		repo.packages["signed"].Versions[0].Files[0].Signature = signature
+		repo.mu.Unlock()
+	}
+	ctx := context.Background()
+
+	setSignature(key.sign(append(archive, '!'), true))
+	if _, err := pm.InstallPackage(ctx, "signed", "", false, false, false, false, "", ""); err == nil || !strings.Contains(err.Error(), "подпись пакета signed") {
+		t.Fatalf("expected tampered signature to fail the install, got %v", err)
+	}
+	if _, exists := pm.getInstalledPackage("signed"); exists {
+		t.Fatal("package must not be installed with a bad signature")
+	}
+
+	setSignature("")
+	if _, err := pm.InstallPackage(ctx, "signed", "", false, false, false, false, "", ""); err == nil || !strings.Contains(err.Error(), "не подписан") {
+		t.Fatalf("expected unsigned archive to be rejected, got %v", err)
+	}
+
+	setSignature(key.sign(archive, true))
+	if _, err := pm.InstallPackage(ctx, "signed", "", false, false, false, false, "", ""); err != nil {
+		t.Fatalf("expected signed archive to install, got %v", err)
+	}
+	if _, exists := pm.getInstalledPackage("signed"); !exists {
+		t.Error("signed package was not installed")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// minisignKeyID возвращает идентификатор ключа так, как его выводит minisign:
+// шестнадцатеричное число, записанное в ключе в порядке little-endian
+func minisignKeyID(id [minisignKeyIDSize]byte) string {
+	var reversed [minisignKeyIDSize]byte
+	for i := range id {
+		reversed[i] = id[minisignKeyIDSize-1-i]
+	}
+	return fmt.Sprintf("%X", reversed)
+}
+
+// ListSigningKeys возвращает ключи подписи репозиториев, для которых они заданы
+func (pm *PackageManager) ListSigningKeys() []SigningKey {
+	var keys []SigningKey
+	for _, repo := range pm.ListRepositories() {
+		if repo.PublicKey == "" {
+			continue
+		}
+		key := SigningKey{Repository: repo.Name, PublicKey: repo.PublicKey}
+		if parsed, err := parseMinisignPublicKey(repo.PublicKey); err == nil {
+			key.KeyID = minisignKeyID(parsed.keyID)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// AddSigningKey закрепляет за репозиторием открытый ключ minisign и сохраняет
+// конфигурацию. После этого устанавливаются только подписанные этим ключом архивы.
+// Заменить ключ можно только после его удаления.
+func (pm *PackageManager) AddSigningKey(repository, publicKey string) (*SigningKey, error) {
+	parsed, err := parseMinisignPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKey = strings.TrimSpace(publicKey)
+
+	var key *SigningKey
+	err = pm.updateRepository(repository, func(repo *Repository) error {
+		if repo.PublicKey != "" {
+			if existing, err := parseMinisignPublicKey(repo.PublicKey); err == nil && existing.keyID == parsed.keyID {
+				return fmt.Errorf("ключ %s уже добавлен для репозитория %s", minisignKeyID(parsed.keyID), repo.Name)
+			}
+			return fmt.Errorf("для репозитория %s уже задан ключ подписи; удалите его, чтобы добавить другой", repo.Name)
+		}
+		repo.PublicKey = publicKey
+		key = &SigningKey{Repository: repo.Name, KeyID: minisignKeyID(parsed.keyID), PublicKey: publicKey}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Для репозитория %s добавлен ключ подписи %s", key.Repository, key.KeyID)
+	return key, nil
+}
+
+// RemoveSigningKey удаляет ключ подписи репозитория и сохраняет конфигурацию;
+// архивы репозитория после этого устанавливаются без проверки подписи
+func (pm *PackageManager) RemoveSigningKey(repository string) (*SigningKey, error) {
+	var key *SigningKey
+	err := pm.updateRepository(repository, func(repo *Repository) error {
+		if repo.PublicKey == "" {
+			return fmt.Errorf("для репозитория %s не задан ключ подписи", repo.Name)
+		}
+		key = &SigningKey{Repository: repo.Name, PublicKey: repo.PublicKey}
+		if parsed, err := parseMinisignPublicKey(repo.PublicKey); err == nil {
+			key.KeyID = minisignKeyID(parsed.keyID)
+		}
+		repo.PublicKey = ""
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Удален ключ подписи репозитория %s", key.Repository)
+	return key, nil
+}
+
+// updateRepository изменяет репозиторий по имени и сохраняет конфигурацию;
+// при ошибке изменения или сохранения конфигурация остается прежней
+func (pm *PackageManager) updateRepository(name string, update func(repo *Repository) error) error {
+	pm.configMutex.Lock()
+	defer pm.configMutex.Unlock()
+
+	_, err := pm.updateConfig(func(config *Config) error {
+		for i := range config.Repositories {
+			if strings.EqualFold(config.Repositories[i].Name, name) {
+				return update(&config.Repositories[i])
+			}
+		}
+		return fmt.Errorf("репозиторий %s не найден", name)
+	})
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestManageSigningKeys проверяет добавление, вывод и удаление ключей подписи
+// с сохранением конфигурации и отказ для некорректных и повторных ключей
+func TestManageSigningKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	pm := newTestPackageManager(t)
+	pm.getConfig().Repositories = []Repository{
+		{Name: "main", URL: "https://packages.example.com", Priority: 1, Enabled: true},
+		{Name: "mirror", URL: "https://mirror.example.com", Priority: 2, Enabled: true},
+	}
+	key := newTestMinisignKey(t, "KEYID001")
+
+	if keys := pm.ListSigningKeys(); len(keys) != 0 {
+		t.Fatalf("expected no signing keys, got %+v", keys)
+	}
+	for _, malformed := range []string{"", "not a key", "untrusted comment: key\nUkVBRE1F"} {
+		if _, err := pm.AddSigningKey("main", malformed); err == nil {
+			t.Errorf("expected malformed key %q to be rejected", malformed)
+		}
+	}
+	if _, err := pm.AddSigningKey("missing", key.public); err == nil || !strings.Contains(err.Error(), "не найден") {
+		t.Errorf("expected unknown repository error, got %v", err)
+	}
+
+	added, err := pm.AddSigningKey("MAIN", key.public)
+	if err != nil {
+		t.Fatalf("AddSigningKey: %v", err)
+	}
+	// minisign выводит идентификатор как число little-endian
+	if added.Repository != "main" || added.KeyID != "313030444959454B" {
+		t.Errorf("unexpected signing key %+v", added)
+	}
+	if _, err := pm.AddSigningKey("main", key.public); err == nil || !strings.Contains(err.Error(), "уже добавлен") {
+		t.Errorf("expected duplicate key to be rejected, got %v", err)
+	}
+	if _, err := pm.AddSigningKey("main", newTestMinisignKey(t, "KEYID002").public); err == nil || !strings.Contains(err.Error(), "уже задан") {
+		t.Errorf("expected second key for the repository to be rejected, got %v", err)
+	}
+
+	reloaded, err := loadConfigFile(pm.configPath)
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if reloaded.Repositories[0].PublicKey == "" || reloaded.Repositories[1].PublicKey != "" {
+		t.Fatalf("expected only main to have a persisted key, got %+v", reloaded.Repositories)
+	}
+	if keys := pm.ListSigningKeys(); len(keys) != 1 || keys[0].Repository != "main" || keys[0].KeyID != added.KeyID {
+		t.Errorf("unexpected signing keys %+v", keys)
+	}
+
+	removed, err := pm.RemoveSigningKey("main")
+	if err != nil {
+		t.Fatalf("RemoveSigningKey: %v", err)
+	}
+	if removed.KeyID != added.KeyID {
+		t.Errorf("expected removed key %s, got %+v", added.KeyID, removed)
+	}
+	if _, err := pm.RemoveSigningKey("main"); err == nil {
+		t.Error("expected error when removing a missing key")
+	}
+	if reloaded, err = loadConfigFile(pm.configPath); err != nil || reloaded.Repositories[0].PublicKey != "" {
+		t.Errorf("expected key removal to be persisted, got %+v, %v", reloaded, err)
+	}
+}
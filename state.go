@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stateSnapshotVersion версия формата снимка состояния установленных пакетов
+const stateSnapshotVersion = 1
+
+// readPackagesFile читает packages.json области; отсутствующий файл означает пустую область
+func (pm *PackageManager) readPackagesFile(global bool) (map[string]*PackageInfo, error) {
+	root := pm.getConfig().LocalPath
+	if global {
+		root = pm.getConfig().GlobalPath
+	}
+	path := filepath.Join(root, packagesFileName)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения %s: %w", path, err)
+	}
+
+	var packages map[string]*PackageInfo
+	if err := json.Unmarshal(data, &packages); err != nil {
+		return nil, fmt.Errorf("поврежденный JSON в %s: %w", path, err)
+	}
+	return packages, nil
+}
+
+// ExportState возвращает снимок всех установленных пакетов обеих областей. Записи читаются
+// из packages.json, поэтому одноименные глобальный и локальный пакеты попадают в снимок оба.
+func (pm *PackageManager) ExportState() (*StateSnapshot, error) {
+	snapshot := &StateSnapshot{
+		Version:    stateSnapshotVersion,
+		ExportedAt: time.Now().UTC(),
+		Packages:   []StatePackage{},
+	}
+
+	for _, scope := range []string{scopeGlobal, scopeLocal} {
+		packages, err := pm.readPackagesFile(scope == scopeGlobal)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range sortedKeys(packages) {
+			info := packages[name]
+			if info == nil {
+				continue
+			}
+			entry := StatePackage{Scope: scope, PackageInfo: *info}
+			if entry.Name == "" {
+				entry.Name = name
+			}
+			snapshot.Packages = append(snapshot.Packages, entry)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// ImportState заменяет packages.json обеих областей содержимым снимка, не устанавливая
+// и не удаляя файлы пакетов. Пакеты, каталог установки которых не найден, перечисляются в результате.
+func (pm *PackageManager) ImportState(snapshot *StateSnapshot) (*ImportStateResult, error) {
+	if snapshot.Version != stateSnapshotVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия снимка состояния: %d (ожидается %d)", snapshot.Version, stateSnapshotVersion)
+	}
+
+	scoped := map[string]map[string]*PackageInfo{
+		scopeGlobal: make(map[string]*PackageInfo),
+		scopeLocal:  make(map[string]*PackageInfo),
+	}
+	result := &ImportStateResult{}
+	for i, entry := range snapshot.Packages {
+		name := strings.TrimSpace(entry.Name)
+		if name == "" {
+			return nil, fmt.Errorf("запись %d: не указано имя пакета", i)
+		}
+		packages, ok := scoped[entry.Scope]
+		if !ok {
+			return nil, fmt.Errorf("пакет %s: неизвестная область %q (ожидается %s или %s)", name, entry.Scope, scopeGlobal, scopeLocal)
+		}
+		if _, exists := packages[name]; exists {
+			return nil, fmt.Errorf("пакет %s указан в области %s несколько раз", name, entry.Scope)
+		}
+
+		info := entry.PackageInfo
+		info.Name = name
+		info.Global = entry.Scope == scopeGlobal
+		packages[name] = &info
+
+		if info.InstallPath == "" {
+			result.Missing = append(result.Missing, name)
+		} else if _, err := os.Stat(info.InstallPath); err != nil {
+			result.Missing = append(result.Missing, name)
+		}
+	}
+
+	pm.packagesMutex.Lock()
+	defer pm.packagesMutex.Unlock()
+
+	for _, scope := range []string{scopeGlobal, scopeLocal} {
+		err := pm.updatePackagesFile(scope == scopeGlobal, func(packages map[string]*PackageInfo) bool {
+			clear(packages)
+			maps.Copy(packages, scoped[scope])
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка записи индекса области %s: %w", scope, err)
+		}
+	}
+
+	// Локальные записи перекрывают глобальные, как при загрузке
+	pm.installedPackages = make(map[string]*PackageInfo)
+	maps.Copy(pm.installedPackages, scoped[scopeGlobal])
+	maps.Copy(pm.installedPackages, scoped[scopeLocal])
+
+	result.Global = len(scoped[scopeGlobal])
+	result.Local = len(scoped[scopeLocal])
+	sort.Strings(result.Missing)
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExportImportState проверяет, что снимок состояния содержит пакеты обеих областей,
+// а импорт восстанавливает packages.json и список пакетов без переустановки файлов
+func TestExportImportState(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "app", Version: "1.0.0", Dependencies: map[string]string{"lib": "^2.0.0"}}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "lib", Version: "2.1.0"}, false)
+	installTestArchive(t, pm, PackageManifest{Name: "lib", Version: "2.0.0"}, true)
+
+	snapshot, err := pm.ExportState()
+	if err != nil {
+		t.Fatalf("ExportState: %v", err)
+	}
+
+	var entries []string
+	for _, entry := range snapshot.Packages {
+		entries = append(entries, entry.Scope+":"+entry.Name+"@"+entry.Version)
+	}
+	if want := []string{"global:lib@2.0.0", "local:app@1.0.0", "local:lib@2.1.0"}; !reflect.DeepEqual(entries, want) {
+		t.Fatalf("expected snapshot entries %v, got %v", want, entries)
+	}
+	app := snapshot.Packages[1]
+	if app.InstallDate.IsZero() || app.Size == 0 || app.Dependencies["lib"] != "^2.0.0" {
+		t.Errorf("snapshot entry lacks install details: %+v", app)
+	}
+
+	// Снимок проходит через JSON, как в инструментах export_state и import_state
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded StateSnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	localIndex := filepath.Join(pm.getConfig().LocalPath, packagesFileName)
+	globalIndex := filepath.Join(pm.getConfig().GlobalPath, packagesFileName)
+	wantLocal, _ := os.ReadFile(localIndex)
+	wantGlobal, _ := os.ReadFile(globalIndex)
+	os.Remove(localIndex)
+	os.Remove(globalIndex)
+
+	// Каталог установки одного из пакетов удален: импорт сохраняет запись, но сообщает о нем
+	if err := os.RemoveAll(app.InstallPath); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := pm.ImportState(&decoded)
+	if err != nil {
+		t.Fatalf("ImportState: %v", err)
+	}
+	if result.Global != 1 || result.Local != 2 || !reflect.DeepEqual(result.Missing, []string{"app"}) {
+		t.Errorf("unexpected import result: %+v", result)
+	}
+
+	for path, want := range map[string][]byte{localIndex: wantLocal, globalIndex: wantGlobal} {
+		var wantPackages, gotPackages map[string]*PackageInfo
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("index %s was not restored: %v", path, err)
+		}
+		json.Unmarshal(want, &wantPackages)
+		json.Unmarshal(got, &gotPackages)
+		if !reflect.DeepEqual(wantPackages, gotPackages) {
+			t.Errorf("%s differs after round-trip:\nwant %s\ngot  %s", path, want, got)
+		}
+	}
+
+	// Локальная запись перекрывает глобальную, как при загрузке
+	if info, ok := pm.getInstalledPackage("lib"); !ok || info.Version != "2.1.0" || info.Global {
+		t.Errorf("expected local lib to be visible after import, got %+v", info)
+	}
+
+	again, err := pm.ExportState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Packages, decoded.Packages) {
+		t.Errorf("re-exported state differs from imported snapshot")
+	}
+}
+
+// TestImportStateValidation проверяет отказ от некорректных снимков без изменения индекса
+func TestImportStateValidation(t *testing.T) {
+	pm := newTestPackageManager(t)
+	installTestArchive(t, pm, PackageManifest{Name: "app", Version: "1.0.0"}, false)
+
+	entry := func(scope, name string) StatePackage {
+		return StatePackage{Scope: scope, PackageInfo: PackageInfo{Name: name, Version: "1.0.0"}}
+	}
+	tests := []struct {
+		name     string
+		snapshot StateSnapshot
+		want     string
+	}{
+		{"version", StateSnapshot{Version: 99}, "версия"},
+		{"scope", StateSnapshot{Version: stateSnapshotVersion, Packages: []StatePackage{entry("system", "a")}}, "область"},
+		{"name", StateSnapshot{Version: stateSnapshotVersion, Packages: []StatePackage{entry(scopeLocal, " ")}}, "имя"},
+		{"duplicate", StateSnapshot{Version: stateSnapshotVersion, Packages: []StatePackage{entry(scopeLocal, "a"), entry(scopeLocal, "a")}}, "несколько раз"},
+	}
+	for _, tt := range tests {
+		if _, err := pm.ImportState(&tt.snapshot); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	if _, ok := pm.getInstalledPackage("app"); !ok {
+		t.Error("rejected snapshot must not change installed packages")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultStatsCacheTTL время жизни статистики репозитория в кеше по умолчанию
+const defaultStatsCacheTTL = 60 * time.Second
+
+// statsCacheEntry статистика репозитория и время ее получения
+type statsCacheEntry struct {
+	stats   *Statistics
+	fetched time.Time
+}
+
+// statsCache хранит статистику репозиториев, чтобы частые опросы get_repository_stats
+// не обращались к репозиторию каждый раз
+type statsCache struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]statsCacheEntry // URL репозитория -> статистика
+}
+
+func newStatsCache() *statsCache {
+	return &statsCache{
+		now:     time.Now,
+		entries: make(map[string]statsCacheEntry),
+	}
+}
+
+// get возвращает статистику, полученную не раньше ttl назад
+func (c *statsCache) get(key string, ttl time.Duration) (*Statistics, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || c.now().Sub(entry.fetched) >= ttl {
+		return nil, false
+	}
+	return entry.stats, true
+}
+
+// put запоминает свежую статистику
+func (c *statsCache) put(key string, stats *Statistics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = statsCacheEntry{stats: stats, fetched: c.now()}
+}
+
+// statsCacheTTL возвращает время жизни кеша статистики: stats_cache_ttl в секундах,
+// 0 — значение по умолчанию, отрицательное значение отключает кеш
+func (pm *PackageManager) statsCacheTTL() time.Duration {
+	switch ttl := pm.getConfig().StatsCacheTTL; {
+	case ttl == 0:
+		return defaultStatsCacheTTL
+	case ttl < 0:
+		return 0
+	default:
+		return time.Duration(ttl) * time.Second
+	}
+}
+
+// GetRepositoryStats возвращает статистику репозитория, повторно используя полученную
+// в пределах stats_cache_ttl. forceRefresh запрашивает репозиторий в обход кеша;
+// cached сообщает, что статистика взята из кеша.
+func (pm *PackageManager) GetRepositoryStats(ctx context.Context, repositoryURL string, forceRefresh bool) (stats *Statistics, cached bool, err error) {
+	key := strings.TrimRight(repositoryURL, "/")
+	ttl := pm.statsCacheTTL()
+
+	if !forceRefresh && ttl > 0 {
+		if stats, ok := pm.stats.get(key, ttl); ok {
+			return stats, true, nil
+		}
+	}
+
+	stats, err = pm.fetchRepositoryStats(ctx, repositoryURL)
+	if err != nil {
+		return nil, false, err
+	}
+	pm.stats.put(key, stats)
+	return stats, false, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRepositoryStatsCache проверяет повторное использование статистики в пределах TTL,
+// обновление после истечения TTL и принудительное обновление
+func TestRepositoryStatsCache(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    Statistics{TotalPackages: int(n)},
+		})
+	}))
+	defer server.Close()
+
+	pm := newTestPackageManager(t)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	pm.stats.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	fetch := func(force bool) (*Statistics, bool) {
+		t.Helper()
+		stats, cached, err := pm.GetRepositoryStats(ctx, server.URL, force)
+		if err != nil {
+			t.Fatalf("GetRepositoryStats: %v", err)
+		}
+		return stats, cached
+	}
+
+	if stats, cached := fetch(false); cached || stats.TotalPackages != 1 {
+		t.Fatalf("expected fresh stats on first call, got %+v (cached %v)", stats, cached)
+	}
+
+	// В пределах TTL (по умолчанию 60 с) репозиторий не запрашивается, в том числе по URL с "/"
+	now = now.Add(59 * time.Second)
+	if stats, cached := fetch(false); !cached || stats.TotalPackages != 1 {
+		t.Errorf("expected cache hit within TTL, got %+v (cached %v)", stats, cached)
+	}
+	if _, cached, _ := pm.GetRepositoryStats(ctx, server.URL+"/", false); !cached {
+		t.Error("expected cache hit for URL with trailing slash")
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request within TTL, got %d", requests.Load())
+	}
+
+	// После истечения TTL статистика запрашивается заново
+	now = now.Add(time.Second)
+	if stats, cached := fetch(false); cached || stats.TotalPackages != 2 {
+		t.Errorf("expected refresh after TTL, got %+v (cached %v)", stats, cached)
+	}
+
+	// force_refresh обходит кеш и обновляет его
+	if stats, cached := fetch(true); cached || stats.TotalPackages != 3 {
+		t.Errorf("expected forced refresh, got %+v (cached %v)", stats, cached)
+	}
+	if stats, cached := fetch(false); !cached || stats.TotalPackages != 3 {
+		t.Errorf("expected forced result to be cached, got %+v (cached %v)", stats, cached)
+	}
+
+	// Настраиваемый TTL и отключение кеша
+	pm.getConfig().StatsCacheTTL = 5
+	now = now.Add(5 * time.Second)
+	if _, cached := fetch(false); cached {
+		t.Error("expected refresh after custom TTL")
+	}
+	pm.getConfig().StatsCacheTTL = -1
+	if _, cached := fetch(false); cached {
+		t.Error("expected no caching with negative TTL")
+	}
+	if requests.Load() != 5 {
+		t.Errorf("expected 5 requests in total, got %d", requests.Load())
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Политики обработки символических ссылок при установке
+const (
+	SymlinkPreserve    = "preserve"
+	SymlinkDereference = "dereference"
+	SymlinkSkip        = "skip"
+)
+
+// maxSymlinkHops ограничивает длину цепочки ссылок при разыменовании
+const maxSymlinkHops = 40
+
+// symlinkPolicy возвращает политику из конфигурации (по умолчанию preserve)
+func (pm *PackageManager) symlinkPolicy() (string, error) {
+	switch pm.getConfig().SymlinkPolicy {
+	case "", SymlinkPreserve:
+		return SymlinkPreserve, nil
+	case SymlinkDereference, SymlinkSkip:
+		return pm.getConfig().SymlinkPolicy, nil
+	default:
+		return "", fmt.Errorf("неизвестная политика символических ссылок: %s (допустимо: preserve, dereference, skip)", pm.getConfig().SymlinkPolicy)
+	}
+}
+
+// pendingSymlink символическая ссылка из архива, обрабатываемая после извлечения файлов
+type pendingSymlink struct {
+	name     string // имя записи в архиве
+	target   string // путь ссылки в каталоге назначения
+	linkname string // относительный путь, на который указывает ссылка
+}
+
+// applySymlinks создает отложенные ссылки согласно политике и возвращает имена пропущенных
+func applySymlinks(destPath string, links []pendingSymlink, policy string) ([]string, error) {
+	var skipped []string
+
+	// Индекс ссылок по пути назначения для разыменования цепочек
+	byTarget := make(map[string]pendingSymlink, len(links))
+	for _, link := range links {
+		byTarget[link.target] = link
+	}
+
+	for _, link := range links {
+		switch policy {
+		case SymlinkSkip:
+			skipped = append(skipped, link.name)
+		case SymlinkDereference:
+			resolved, err := resolvePendingSymlink(destPath, link, byTarget)
+			if err != nil {
+				return skipped, err
+			}
+			info, err := os.Stat(resolved)
+			if err != nil {
+				// Ссылка указывает на отсутствующий файл: копировать нечего
+				skipped = append(skipped, link.name)
+				continue
+			}
+			if info.IsDir() {
+				err = copyTree(resolved, link.target)
+			} else {
+				err = copyFile(resolved, link.target, info.Mode().Perm())
+			}
+			if err != nil {
+				return skipped, fmt.Errorf("ошибка разыменования %s: %w", link.name, err)
+			}
+		default:
+			if err := os.Symlink(link.linkname, link.target); err != nil {
+				return skipped, err
+			}
+		}
+	}
+
+	return skipped, nil
+}
+
+// resolvePendingSymlink проходит цепочку отложенных ссылок до конечного пути внутри destPath
+func resolvePendingSymlink(destPath string, link pendingSymlink, byTarget map[string]pendingSymlink) (string, error) {
+	current := link
+	for hop := 0; hop < maxSymlinkHops; hop++ {
+		rel, err := filepath.Rel(destPath, filepath.Join(filepath.Dir(current.target), current.linkname))
+		if err != nil {
+			return "", err
+		}
+		resolved, err := safeJoin(destPath, rel)
+		if err != nil {
+			return "", err
+		}
+
+		next, ok := byTarget[resolved]
+		if !ok {
+			return resolved, nil
+		}
+		current = next
+	}
+
+	return "", fmt.Errorf("слишком длинная цепочка символических ссылок: %s", link.name)
+}
+
+// copySymlink переносит символическую ссылку при копировании файлов согласно политике
+func (pm *PackageManager) copySymlink(path, destPath string) error {
+	policy, err := pm.symlinkPolicy()
+	if err != nil {
+		return err
+	}
+
+	switch policy {
+	case SymlinkSkip:
+		return nil
+	case SymlinkDereference:
+		info, err := os.Stat(path)
+		if err != nil {
+			// Висячая ссылка: копировать нечего
+			return nil
+		}
+		if info.IsDir() {
+			return copyTree(path, destPath)
+		}
+		return copyFile(path, destPath, info.Mode().Perm())
+	default:
+		linkname, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(linkname, destPath)
+	}
+}
+
+// copyFile копирует содержимое файла с указанными правами
+func copyFile(src, dest string, mode os.FileMode) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	return extractFile(srcFile, dest, mode)
+}
+
+// copyTree копирует каталог, разыменовывая вложенные ссылки
+func copyTree(srcDir, destDir string) error {
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destDir, relPath)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(path); err != nil || info.IsDir() {
+				// Висячие ссылки и ссылки на каталоги внутри копируемого дерева пропускаются
+				return nil
+			}
+		}
+
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// Таймауты применяются к каждому запросу через его контекст, а не через http.Client:
+// так у репозиториев могут быть собственные ограничения, а у скачивания архивов — свое.
+
+// cancelOnClose тело ответа, освобождающее контекст запроса при закрытии
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// seconds переводит значение конфигурации в секундах в длительность; 0 — без ограничения
+func seconds(value int) time.Duration {
+	return time.Duration(max(value, 0)) * time.Second
+}
+
+// requestTimeout возвращает таймаут запроса к rawURL: собственный таймаут репозитория,
+// которому принадлежит URL, или общий timeout конфигурации
+func (pm *PackageManager) requestTimeout(rawURL string) time.Duration {
+	best := -1
+	timeout := seconds(pm.getConfig().Timeout)
+	for _, repo := range pm.getConfig().Repositories {
+		base := strings.TrimRight(repo.URL, "/")
+		if repo.Timeout <= 0 || base == "" || len(base) <= best {
+			continue
+		}
+		// При вложенных URL выбирается самый длинный совпадающий префикс
+		if urlWithin(rawURL, base) {
+			best = len(base)
+			timeout = seconds(repo.Timeout)
+		}
+	}
+	return timeout
+}
+
+// urlWithin сообщает, относится ли rawURL к адресу base (без завершающей косой черты)
+func urlWithin(rawURL, base string) bool {
+	return rawURL == base || strings.HasPrefix(rawURL, base+"/") || strings.HasPrefix(rawURL, base+"?")
+}
+
+// downloadTimeout возвращает таймаут скачивания архива: download_timeout или общий timeout
+func (pm *PackageManager) downloadTimeout() time.Duration {
+	if pm.getConfig().DownloadTimeout > 0 {
+		return seconds(pm.getConfig().DownloadTimeout)
+	}
+	return seconds(pm.getConfig().Timeout)
+}
+
+// withTimeout ограничивает контекст временем timeout; 0 — без ограничения
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
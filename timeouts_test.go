@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRequestTimeoutSelection проверяет выбор таймаута по репозиторию, которому принадлежит URL
+func TestRequestTimeoutSelection(t *testing.T) {
+	pm := newTestPackageManager(t)
+	pm.getConfig().Timeout = 30
+	pm.getConfig().Repositories = []Repository{
+		{Name: "mirror", URL: "https://mirror.example.com/", Timeout: 120},
+		{Name: "team", URL: "https://mirror.example.com/team", Timeout: 10},
+		{Name: "plain", URL: "https://plain.example.com"},
+	}
+
+	tests := []struct {
+		url  string
+		want time.Duration
+	}{
+		{"https://mirror.example.com/api/v1/search?q=x", 120 * time.Second},
+		{"https://mirror.example.com/team/api/v1/packages", 10 * time.Second},
+		{"https://mirror.example.com/teams/api/v1/packages", 120 * time.Second},
+		{"https://plain.example.com/api/v1/search", 30 * time.Second},
+		{"https://mirror.example.com.evil.test/api", 30 * time.Second},
+		{"https://other.example.com/file.tar.gz", 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := pm.requestTimeout(tt.url); got != tt.want {
+			t.Errorf("requestTimeout(%s) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+
+	if got := pm.downloadTimeout(); got != 30*time.Second {
+		t.Errorf("download timeout must fall back to timeout, got %s", got)
+	}
+	pm.getConfig().DownloadTimeout = 600
+	if got := pm.downloadTimeout(); got != 600*time.Second {
+		t.Errorf("expected download timeout 600s, got %s", got)
+	}
+}
+
+// TestRepositoryTimeoutOverridesGlobal проверяет, что медленный репозиторий со своим
+// таймаутом отвечает там, где общий таймаут прерывает запрос, а скачивание
+// ограничивается download_timeout
+func TestRepositoryTimeoutOverridesGlobal(t *testing.T) {
+	const delay = 1200 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		if r.URL.Path == "/files/archive.bin" {
+			w.Write([]byte("archive"))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": PackageListResponse{Packages: []*RepositoryPackage{}}})
+	}))
+	defer server.Close()
+
+	pm := newTestPackageManager(t)
+	pm.getConfig().Timeout = 1
+	pm.getConfig().Repositories = []Repository{{Name: "slow", URL: server.URL, Enabled: true}}
+	ctx := context.Background()
+
+	if _, err := pm.ListRepositoryPackages(ctx, server.URL, 1, 10); err == nil {
+		t.Fatal("expected global timeout to interrupt the slow repository")
+	}
+
+	pm.getConfig().Repositories[0].Timeout = 5
+	if _, err := pm.ListRepositoryPackages(ctx, server.URL, 1, 10); err != nil {
+		t.Fatalf("expected repository timeout to override global: %v", err)
+	}
+
+	pm.getConfig().DownloadTimeout = 5
+	path, err := pm.downloadPackage(ctx, server.URL+"/files/archive.bin", "slow", "1.0.0", -1)
+	if err != nil {
+		t.Fatalf("expected download timeout to override global: %v", err)
+	}
+	os.Remove(path)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// spkiPinPrefix префикс закрепленного открытого ключа (SHA-256 от SubjectPublicKeyInfo в base64)
+const spkiPinPrefix = "sha256/"
+
+// certPin закрепленный отпечаток сертификата или открытого ключа
+type certPin struct {
+	spki   bool
+	digest [sha256.Size]byte
+}
+
+// parseCertPin разбирает cert_fingerprint: либо SHA-256 сертификата в hex
+// (допускаются двоеточия), либо "sha256/<base64>" для открытого ключа
+func parseCertPin(value string) (certPin, error) {
+	var pin certPin
+	var raw []byte
+	var err error
+
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, spkiPinPrefix) {
+		pin.spki = true
+		raw, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(value, spkiPinPrefix))
+	} else {
+		raw, err = hex.DecodeString(strings.ReplaceAll(value, ":", ""))
+	}
+	if err != nil || len(raw) != sha256.Size {
+		return pin, fmt.Errorf("некорректный отпечаток сертификата: %s", value)
+	}
+
+	copy(pin.digest[:], raw)
+	return pin, nil
+}
+
+// matches сообщает, соответствует ли сертификат закрепленному отпечатку
+func (p certPin) matches(cert *x509.Certificate) bool {
+	if p.spki {
+		return sha256.Sum256(cert.RawSubjectPublicKeyInfo) == p.digest
+	}
+	return sha256.Sum256(cert.Raw) == p.digest
+}
+
+// pinnedTransport направляет запросы к хостам с закрепленными сертификатами
+// в отдельные транспорты, проверяющие сертификат сервера при установке соединения
+type pinnedTransport struct {
+	base   http.RoundTripper
+	pinned map[string]http.RoundTripper // хост -> транспорт с проверкой отпечатка
+}
+
+func (t *pinnedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if transport, ok := t.pinned[strings.ToLower(req.URL.Hostname())]; ok {
+		// Без TLS проверять нечего: закрепление не должно обходиться переходом на http
+		if req.URL.Scheme != "https" {
+			return nil, fmt.Errorf("для %s закреплен сертификат, требуется HTTPS", req.URL.Hostname())
+		}
+		return transport.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// newHTTPTransport создает транспорт HTTP-клиента с учетом настроек репозиториев.
+// base служит основой для всех соединений (nil — транспорт по умолчанию).
+func newHTTPTransport(config *Config, base *http.Transport) (http.RoundTripper, error) {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	// Параметры пула соединений из конфигурации (0 — значение транспорта)
+	if config.MaxIdleConns > 0 {
+		base.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		base.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.IdleConnTimeout > 0 {
+		base.IdleConnTimeout = time.Duration(config.IdleConnTimeout) * time.Second
+	}
+
+	// Прокси из конфигурации; без нее действует прокси из окружения, как у транспорта по умолчанию
+	if config.HTTPProxy != "" || config.HTTPSProxy != "" || config.NoProxy != "" {
+		proxy, err := newProxySettings(config)
+		if err != nil {
+			return nil, err
+		}
+		base.Proxy = proxy.proxy
+	}
+
+	if config.CACertPath != "" || config.InsecureSkipVerify || config.ClientCertPath != "" || config.ClientKeyPath != "" {
+		if base.TLSClientConfig == nil {
+			base.TLSClientConfig = &tls.Config{}
+		}
+	}
+	if config.CACertPath != "" {
+		roots, err := loadCACertificates(config.CACertPath)
+		if err != nil {
+			return nil, err
+		}
+		base.TLSClientConfig.RootCAs = roots
+	}
+	if config.ClientCertPath != "" || config.ClientKeyPath != "" {
+		certificate, err := loadClientCertificate(config.ClientCertPath, config.ClientKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		base.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+	}
+	if config.InsecureSkipVerify {
+		// Закрепленные сертификаты проверяются и в этом режиме: VerifyConnection вызывается всегда
+		logger.Warnf("⚠️ ВНИМАНИЕ: проверка TLS-сертификатов репозиториев отключена (insecure_skip_verify). Соединения уязвимы для перехвата; для частного CA используйте ca_cert_path")
+		base.TLSClientConfig.InsecureSkipVerify = true
+	}
+
+	pins := make(map[string][]certPin)
+	for _, repo := range config.Repositories {
+		if repo.CertFingerprint == "" {
+			continue
+		}
+
+		parsed, err := url.Parse(repo.URL)
+		if err != nil || parsed.Hostname() == "" {
+			return nil, fmt.Errorf("некорректный URL репозитория %s: %s", repo.Name, repo.URL)
+		}
+		pin, err := parseCertPin(repo.CertFingerprint)
+		if err != nil {
+			return nil, fmt.Errorf("репозиторий %s: %w", repo.Name, err)
+		}
+
+		host := strings.ToLower(parsed.Hostname())
+		pins[host] = append(pins[host], pin)
+	}
+
+	if len(pins) == 0 {
+		return base, nil
+	}
+
+	transport := &pinnedTransport{base: base, pinned: make(map[string]http.RoundTripper, len(pins))}
+	for host, hostPins := range pins {
+		pinned := base.Clone()
+		if pinned.TLSClientConfig == nil {
+			pinned.TLSClientConfig = &tls.Config{}
+		}
+
+		host, hostPins := host, hostPins
+		// Проверка выполняется после стандартной проверки цепочки, до отправки запроса
+		pinned.TLSClientConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("сервер %s не предоставил сертификат", host)
+			}
+			for _, pin := range hostPins {
+				if pin.matches(state.PeerCertificates[0]) {
+					return nil
+				}
+			}
+			return fmt.Errorf("сертификат сервера %s не совпадает с закрепленным отпечатком", host)
+		}
+		transport.pinned[host] = pinned
+	}
+
+	return transport, nil
+}
+
+// loadCACertificates возвращает системные корневые сертификаты, дополненные сертификатами из PEM-файла
+func loadCACertificates(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ca_cert_path: %w", err)
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("ca_cert_path %s не содержит сертификатов в формате PEM", path)
+	}
+	return roots, nil
+}
+
+// loadClientCertificate загружает клиентский сертификат и проверяет, что ключ ему соответствует
+func loadClientCertificate(certPath, keyPath string) (tls.Certificate, error) {
+	if certPath == "" || keyPath == "" {
+		return tls.Certificate{}, fmt.Errorf("для клиентского сертификата нужны оба параметра: client_cert_path и client_key_path")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("ошибка загрузки клиентского сертификата %s и ключа %s: %w", certPath, keyPath, err)
+	}
+	return certificate, nil
+}
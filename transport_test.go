@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCertificatePinning проверяет, что соединение с репозиторием принимается только
+// при совпадении сертификата сервера с закрепленным отпечатком
+func TestCertificatePinning(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    &RepositoryPackage{Name: "tool", LatestVersion: "1.0.0"},
+		})
+	}))
+	defer server.Close()
+
+	certDigest := sha256.Sum256(server.Certificate().Raw)
+	keyDigest := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	wrongDigest := sha256.Sum256([]byte("other certificate"))
+
+	testCases := []struct {
+		name        string
+		fingerprint string
+		wantErr     bool
+	}{
+		{"matching certificate", hex.EncodeToString(certDigest[:]), false},
+		{"matching certificate with colons", strings.ToUpper(colonHex(certDigest[:])), false},
+		{"matching public key", spkiPinPrefix + base64.StdEncoding.EncodeToString(keyDigest[:]), false},
+		{"mismatched certificate", hex.EncodeToString(wrongDigest[:]), true},
+		{"no pin", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pm := newTestPackageManager(t)
+			repo := Repository{Name: "pinned", URL: server.URL, Enabled: true, CertFingerprint: tc.fingerprint}
+			pm.getConfig().Repositories = []Repository{repo}
+
+			// Базовый транспорт доверяет тестовому сертификату, как доверял бы системным CA
+			base := server.Client().Transport.(*http.Transport).Clone()
+			transport, err := newHTTPTransport(pm.getConfig(), base)
+			if err != nil {
+				t.Fatalf("newHTTPTransport: %v", err)
+			}
+			pm.httpClient = &http.Client{Timeout: 5 * time.Second, Transport: transport}
+
+			_, err = pm.fetchRepositoryPackage(context.Background(), repo, "tool")
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "закрепленным отпечатком") {
+					t.Fatalf("expected pin mismatch error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchRepositoryPackage: %v", err)
+			}
+		})
+	}
+}
+
+// TestCertificatePinningRequiresHTTPS проверяет отказ от нешифрованных запросов к хосту с закрепленным сертификатом
+func TestCertificatePinningRequiresHTTPS(t *testing.T) {
+	digest := sha256.Sum256([]byte("certificate"))
+	config := &Config{Repositories: []Repository{{Name: "pinned", URL: "https://repo.example.com", CertFingerprint: hex.EncodeToString(digest[:])}}}
+
+	transport, err := newHTTPTransport(config, nil)
+	if err != nil {
+		t.Fatalf("newHTTPTransport: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://repo.example.com/api/v1/", nil)
+	if _, err := transport.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "HTTPS") {
+		t.Errorf("expected HTTPS requirement error, got %v", err)
+	}
+
+	config.Repositories[0].CertFingerprint = "not-a-fingerprint"
+	if _, err := newHTTPTransport(config, nil); err == nil {
+		t.Error("expected error for malformed fingerprint")
+	}
+}
+
+// colonHex форматирует байты как AA:BB:CC, как выводят отпечатки openssl и браузеры
+func colonHex(data []byte) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = hex.EncodeToString([]byte{b})
+	}
+	return strings.Join(parts, ":")
+}
+
+// TestProxyConfiguration проверяет, что запросы к репозиторию идут через прокси из конфигурации
+func TestProxyConfiguration(t *testing.T) {
+	var proxied []string
+	var mu sync.Mutex
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Через прокси запрос приходит с абсолютным URL исходного адреса
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    &RepositoryPackage{Name: "tool", LatestVersion: "1.0.0"},
+		})
+	}))
+	defer proxy.Close()
+
+	pm := newTestPackageManager(t)
+	repo := Repository{Name: "corp", URL: "http://repo.corp.example", Enabled: true}
+	pm.getConfig().Repositories = []Repository{repo}
+	pm.getConfig().HTTPProxy = proxy.URL
+
+	transport, err := newHTTPTransport(pm.getConfig(), nil)
+	if err != nil {
+		t.Fatalf("newHTTPTransport: %v", err)
+	}
+	pm.httpClient = &http.Client{Timeout: 5 * time.Second, Transport: transport}
+
+	pkg, err := pm.fetchRepositoryPackage(context.Background(), repo, "tool")
+	if err != nil {
+		t.Fatalf("fetchRepositoryPackage: %v", err)
+	}
+	if pkg.Name != "tool" {
+		t.Errorf("unexpected package %+v", pkg)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(proxied) != 1 || !strings.HasPrefix(proxied[0], "http://repo.corp.example/api/v1/packages/tool") {
+		t.Errorf("expected request through proxy, got %v", proxied)
+	}
+}
+
+// TestProxyBypass проверяет выбор прокси с учетом no_proxy
+func TestProxyBypass(t *testing.T) {
+	settings, err := newProxySettings(&Config{
+		HTTPProxy:  "proxy.corp:3128",
+		HTTPSProxy: "http://secure-proxy.corp:3128",
+		NoProxy:    ".internal, mirror.example.com, 10.0.0.0/8, repo.example.org:8443",
+	})
+	if err != nil {
+		t.Fatalf("newProxySettings: %v", err)
+	}
+
+	testCases := []struct {
+		url   string
+		proxy string
+	}{
+		{"http://packages.criage.ru/api", "http://proxy.corp:3128"},
+		{"https://packages.criage.ru/api", "http://secure-proxy.corp:3128"},
+		{"https://repo.internal/api", ""},
+		{"https://a.b.internal/api", ""},
+		{"https://mirror.example.com/api", ""},
+		{"https://cdn.mirror.example.com/api", ""},
+		{"https://notmirror.example.com/api", "http://secure-proxy.corp:3128"},
+		{"http://10.1.2.3/api", ""},
+		{"https://repo.example.org:8443/api", ""},
+		{"https://repo.example.org/api", "http://secure-proxy.corp:3128"},
+		{"http://localhost:8080/api", ""},
+		{"http://127.0.0.1:8080/api", ""},
+	}
+	for _, tc := range testCases {
+		req, _ := http.NewRequest("GET", tc.url, nil)
+		proxyURL, err := settings.proxy(req)
+		if err != nil {
+			t.Fatalf("proxy(%s): %v", tc.url, err)
+		}
+		got := ""
+		if proxyURL != nil {
+			got = proxyURL.String()
+		}
+		if got != tc.proxy {
+			t.Errorf("proxy(%s) = %q, want %q", tc.url, got, tc.proxy)
+		}
+	}
+
+	if _, err := newProxySettings(&Config{HTTPProxy: "ftp://proxy.corp"}); err == nil {
+		t.Error("expected error for unsupported proxy scheme")
+	}
+}
+
+// TestCACertPath проверяет, что сервер с сертификатом частного CA принимается только
+// с ca_cert_path, а insecure_skip_verify отключает проверку с предупреждением в журнале
+func TestCACertPath(t *testing.T) {
+	ca := newTestCA(t)
+	_, _, serverCert := ca.issue(t, x509.ExtKeyUsageServerAuth)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	server.StartTLS()
+	defer server.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bundle, ca.pem, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var logs bytes.Buffer
+	previous := logger
+	logger = NewLogger(&logs, LevelInfo)
+	t.Cleanup(func() { logger = previous })
+
+	for _, tc := range []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"without bundle", Config{}, true},
+		{"with bundle", Config{CACertPath: bundle}, false},
+		{"insecure", Config{InsecureSkipVerify: true}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transport, err := newHTTPTransport(&tc.config, nil)
+			if err != nil {
+				t.Fatalf("newHTTPTransport: %v", err)
+			}
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			resp, err := transport.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("unexpected result: %v", err)
+			}
+		})
+	}
+
+	if !strings.Contains(logs.String(), "insecure_skip_verify") {
+		t.Errorf("expected a warning about insecure_skip_verify, got:\n%s", logs.String())
+	}
+	if _, err := newHTTPTransport(&Config{CACertPath: filepath.Join(t.TempDir(), "missing.pem")}, nil); err == nil {
+		t.Error("expected error for missing ca_cert_path")
+	}
+	if err := os.WriteFile(bundle, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := newHTTPTransport(&Config{CACertPath: bundle}, nil); err == nil {
+		t.Error("expected error for ca_cert_path without certificates")
+	}
+}
+
+// testCA частный удостоверяющий центр для тестов TLS
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA создает корневой сертификат частного CA
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Private CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate (CA): %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue выпускает сертификат с указанным назначением: для сервера на 127.0.0.1 или для клиента.
+// Возвращает сертификат и ключ в PEM и готовую пару для tls.Config.
+func (ca *testCA) issue(t *testing.T, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte, pair tls.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// TestClientCertificate проверяет взаимную аутентификацию TLS с репозиторием,
+// требующим клиентский сертификат, выпущенный частным CA
+func TestClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	_, _, serverCert := ca.issue(t, x509.ExtKeyUsageServerAuth)
+	clientCertPEM, clientKeyPEM, _ := ca.issue(t, x509.ExtKeyUsageClientAuth)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.pem")
+	certPath := filepath.Join(dir, "client.pem")
+	keyPath := filepath.Join(dir, "client.key")
+	for path, data := range map[string][]byte{caPath: ca.pem, certPath: clientCertPEM, keyPath: clientKeyPEM} {
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"without client certificate", Config{CACertPath: caPath}, true},
+		{"with client certificate", Config{CACertPath: caPath, ClientCertPath: certPath, ClientKeyPath: keyPath}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			transport, err := newHTTPTransport(&tc.config, nil)
+			if err != nil {
+				t.Fatalf("newHTTPTransport: %v", err)
+			}
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			resp, err := transport.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("unexpected result: %v", err)
+			}
+		})
+	}
+
+	// Ключ от другого сертификата и неполная пара отклоняются при настройке
+	_, otherKeyPEM, _ := ca.issue(t, x509.ExtKeyUsageClientAuth)
+	otherKeyPath := filepath.Join(dir, "other.key")
+	if err := os.WriteFile(otherKeyPath, otherKeyPEM, 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	for _, config := range []Config{
+		{ClientCertPath: certPath, ClientKeyPath: otherKeyPath},
+		{ClientCertPath: certPath},
+		{ClientCertPath: certPath, ClientKeyPath: filepath.Join(dir, "missing.key")},
+	} {
+		if _, err := newHTTPTransport(&config, nil); err == nil || !strings.Contains(err.Error(), "клиентск") {
+			t.Errorf("expected client certificate error for %+v, got %v", config, err)
+		}
+	}
+}
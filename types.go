@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -18,6 +19,31 @@ type PackageInfo struct {
 	Size         int64             `json:"size"`
 	Files        []string          `json:"files"`
 	Scripts      map[string]string `json:"scripts"`
+	Keywords     []string          `json:"keywords,omitempty"`
+
+	// Config файлы конфигурации из манифеста относительно каталога пакета
+	Config []string `json:"config,omitempty"`
+	// PreservedConfig файлы конфигурации, сохраненные при прошлом удалении и ожидающие восстановления
+	PreservedConfig []string `json:"preserved_config,omitempty"`
+
+	// SkippedSymlinks символические ссылки, не созданные из-за symlink_policy
+	SkippedSymlinks []string `json:"skipped_symlinks,omitempty"`
+
+	// ResolvedVersion точная версия из репозитория, включая метаданные сборки
+	ResolvedVersion string `json:"resolved_version,omitempty"`
+	// Checksum контрольная сумма установленного архива
+	Checksum string `json:"checksum,omitempty"`
+
+	// Pinned пакет закреплен и пропускается при обновлении
+	Pinned bool `json:"pinned,omitempty"`
+	// PinnedVersion версия, на которой закреплен пакет
+	PinnedVersion string `json:"pinned_version,omitempty"`
+
+	// Automatic пакет установлен как зависимость, а не по запросу пользователя
+	Automatic bool `json:"automatic,omitempty"`
+
+	// AvailableVersion более новая версия в репозитории (заполняется при поиске устаревших пакетов)
+	AvailableVersion string `json:"available_version,omitempty"`
 }
 
 // SearchResult результат поиска пакетов
@@ -26,9 +52,47 @@ type SearchResult struct {
 	Version     string    `json:"version"`
 	Description string    `json:"description"`
 	Author      string    `json:"author"`
+	License     string    `json:"license,omitempty"`
 	Downloads   int64     `json:"downloads"`
 	Updated     time.Time `json:"updated"`
 	Score       float64   `json:"score"`
+	Repository  string    `json:"repository,omitempty"` // имя репозитория, вернувшего результат
+}
+
+// SearchPage страница результатов поиска по всем репозиториям
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	Total      int            `json:"total"`
+	Page       int            `json:"page"`
+	Limit      int            `json:"limit"`
+	TotalPages int            `json:"total_pages"`
+
+	// Suggestions близкие по написанию пакеты, если поиск ничего не нашел
+	Suggestions []SearchSuggestion `json:"suggestions,omitempty"`
+	// Warnings отсутствующие или устаревшие локальные индексы при поиске по ним
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// IndexSyncResult результат синхронизации локальной копии индекса репозитория
+type IndexSyncResult struct {
+	Repository string    `json:"repository"`
+	URL        string    `json:"url"`
+	Packages   int       `json:"packages"`
+	SyncedAt   time.Time `json:"synced_at"`
+}
+
+// SearchSuggestion пакет с именем, похожим на поисковый запрос
+type SearchSuggestion struct {
+	Name       string `json:"name"`
+	Distance   int    `json:"distance"` // расстояние редактирования до запроса
+	Repository string `json:"repository,omitempty"`
+}
+
+// SearchFilter необязательные условия отбора результатов поиска
+type SearchFilter struct {
+	Author       string `json:"author,omitempty"`  // подстрока автора без учета регистра
+	License      string `json:"license,omitempty"` // лицензия без учета регистра
+	MinDownloads int64  `json:"min_downloads,omitempty"`
 }
 
 // PackageManifest манифест пакета
@@ -44,6 +108,8 @@ type PackageManifest struct {
 	Dependencies map[string]string      `json:"dependencies"`
 	DevDeps      map[string]string      `json:"dev_dependencies"`
 	Files        []string               `json:"files"`
+	Executables  []string               `json:"executables,omitempty"` // исполняемые файлы относительно корня пакета
+	Config       []string               `json:"config,omitempty"`      // файлы конфигурации, сохраняемые при удалении без purge
 	Scripts      map[string]string      `json:"scripts"`
 	Hooks        *PackageHooks          `json:"hooks"`
 	Metadata     map[string]interface{} `json:"metadata"`
@@ -65,9 +131,47 @@ type Config struct {
 	CachePath        string       `json:"cache_path"`
 	TempPath         string       `json:"temp_path"`
 	Timeout          int          `json:"timeout"`
+	DownloadTimeout  int          `json:"download_timeout,omitempty"` // в секундах, 0 — общий timeout
 	MaxConcurrency   int          `json:"max_concurrency"`
 	CompressionLevel int          `json:"compression_level"`
 	ForceHTTPS       bool         `json:"force_https"`
+	Offline          bool         `json:"offline,omitempty"` // пакеты берутся только из локального индекса и кеша
+	AllowedHosts     []string     `json:"allowed_hosts,omitempty"`
+	MaxCacheSize     int64        `json:"max_cache_size"`            // в байтах, 0 — без ограничения
+	MaxDownloadSize  int64        `json:"max_download_size"`         // в байтах, 0 — без ограничения
+	StatsCacheTTL    int          `json:"stats_cache_ttl,omitempty"` // в секундах, 0 — 60 с, отрицательное — без кеша
+	IndexMaxAge      int          `json:"index_max_age,omitempty"`   // в секундах, 0 — сутки, отрицательное — без проверки
+	SymlinkPolicy    string       `json:"symlink_policy,omitempty"`  // preserve, dereference или skip
+	LogLevel         string       `json:"log_level,omitempty"`       // debug, info, warn или error
+	LogFile          string       `json:"log_file,omitempty"`        // по умолчанию stderr
+	DefaultOS        string       `json:"default_os,omitempty"`      // целевая ОС вместо определенной при запуске
+	DefaultArch      string       `json:"default_arch,omitempty"`    // целевая архитектура вместо определенной при запуске
+	Language         string       `json:"language,omitempty"`        // язык сообщений инструментов: ru или en
+
+	// ArchCompatibility архитектуры, файлы которых подходят для данной, в порядке предпочтения
+	// (например, "arm64": ["amd64"] при эмуляции); используются, если файла для самой архитектуры нет
+	ArchCompatibility map[string][]string `json:"arch_compatibility,omitempty"`
+
+	// Сетевые настройки; 0 в параметрах пула соединений — значение транспорта по умолчанию
+	RequestsPerSecond   int                       `json:"requests_per_second"`
+	RateBurst           int                       `json:"rate_burst,omitempty"` // запросов без ожидания после простоя; 0 — 1
+	MaxRetries          int                       `json:"max_retries"`          // повторы после 429 с Retry-After
+	MaxIdleConns        int                       `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int                       `json:"max_idle_conns_per_host,omitempty"`
+	IdleConnTimeout     int                       `json:"idle_conn_timeout,omitempty"` // в секундах
+	NetworkProfiles     map[string]NetworkProfile `json:"network_profiles,omitempty"`
+	NetworkProfile      string                    `json:"network_profile,omitempty"` // активный профиль
+
+	// Прокси и доверенные сертификаты; незаданные параметры прокси берутся из окружения
+	HTTPProxy  string `json:"http_proxy,omitempty"`   // по умолчанию HTTP_PROXY
+	HTTPSProxy string `json:"https_proxy,omitempty"`  // по умолчанию HTTPS_PROXY
+	NoProxy    string `json:"no_proxy,omitempty"`     // по умолчанию NO_PROXY
+	CACertPath string `json:"ca_cert_path,omitempty"` // PEM-файл с дополнительными корневыми сертификатами
+	// Клиентский сертификат и ключ в PEM для репозиториев с взаимной аутентификацией TLS
+	ClientCertPath string `json:"client_cert_path,omitempty"`
+	ClientKeyPath  string `json:"client_key_path,omitempty"`
+	// InsecureSkipVerify отключает проверку сертификатов серверов; только для отладки
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
 }
 
 // Repository репозиторий пакетов
@@ -77,6 +181,37 @@ type Repository struct {
 	Priority  int    `json:"priority"`
 	Enabled   bool   `json:"enabled"`
 	AuthToken string `json:"auth_token,omitempty"`
+	// CertFingerprint закрепленный SHA-256 сертификата сервера (hex) или открытого ключа ("sha256/<base64>")
+	CertFingerprint string `json:"cert_fingerprint,omitempty"`
+	// Timeout таймаут запросов к репозиторию в секундах; 0 — общий timeout
+	Timeout int `json:"timeout,omitempty"`
+	// RateLimit частота запросов к репозиторию в секунду; 0 — общий requests_per_second, -1 — без ограничения
+	RateLimit int `json:"rate_limit,omitempty"`
+	// PublicKey открытый ключ minisign; если задан, архивы репозитория должны быть подписаны им
+	PublicKey string `json:"public_key,omitempty"`
+
+	// legacyToken отмечает, что токен прочитан из устаревшего ключа "token"
+	legacyToken bool
+}
+
+// UnmarshalJSON читает токен также из устаревшего ключа "token" старых конфигураций
+func (r *Repository) UnmarshalJSON(data []byte) error {
+	type repositoryFields Repository
+	aux := struct {
+		*repositoryFields
+		Token string `json:"token"`
+	}{repositoryFields: (*repositoryFields)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aux.Token != "" && r.AuthToken == "" {
+		r.AuthToken = aux.Token
+		r.legacyToken = true
+	}
+
+	return nil
 }
 
 // RepositoryPackage информация о пакете в репозитории (соответствует PackageEntry в criage-server)
@@ -98,6 +233,7 @@ type RepositoryPackage struct {
 type RepositoryVersion struct {
 	Version      string            `json:"version"`
 	Description  string            `json:"description"`
+	Changelog    string            `json:"changelog,omitempty"`
 	Dependencies map[string]string `json:"dependencies"`
 	DevDeps      map[string]string `json:"devDependencies"`
 	Files        []RepositoryFile  `json:"files"`
@@ -115,6 +251,8 @@ type RepositoryFile struct {
 	Filename string `json:"filename"`
 	Size     int64  `json:"size"`
 	Checksum string `json:"checksum"`
+	// Signature подпись архива в формате minisign (содержимое файла .minisig)
+	Signature string `json:"signature,omitempty"`
 	// URL убран, так как FileEntry в criage-server не содержит URL
 }
 
@@ -140,11 +278,12 @@ type CompressionSettings struct {
 
 // ArchiveMetadata метаданные архива
 type ArchiveMetadata struct {
-	CompressionType string           `json:"compression_type"`
-	CreatedAt       string           `json:"created_at"`
-	CreatedBy       string           `json:"created_by"`
-	PackageManifest *PackageManifest `json:"package_manifest,omitempty"`
-	BuildManifest   *BuildManifest   `json:"build_manifest,omitempty"`
+	CompressionType string            `json:"compression_type"`
+	CreatedAt       string            `json:"created_at"`
+	CreatedBy       string            `json:"created_by"`
+	PackageManifest *PackageManifest  `json:"package_manifest,omitempty"`
+	BuildManifest   *BuildManifest    `json:"build_manifest,omitempty"`
+	Checksums       map[string]string `json:"checksums,omitempty"` // путь в архиве -> контрольная сумма содержимого
 }
 
 // Statistics статистика репозитория
@@ -156,3 +295,546 @@ type Statistics struct {
 	LastUpdated       time.Time      `json:"lastUpdated"`
 	TotalPackages     int            `json:"totalPackages"`
 }
+
+// VerifyResult результат проверки целостности установленного пакета
+type VerifyResult struct {
+	Name            string   `json:"name"`
+	Version         string   `json:"version"`
+	InstallPath     string   `json:"install_path"`
+	CheckedFiles    int      `json:"checked_files"`
+	MissingFiles    []string `json:"missing_files,omitempty"`
+	ExpectedSize    int64    `json:"expected_size"`
+	ActualSize      int64    `json:"actual_size"`
+	ManifestPresent bool     `json:"manifest_present"`
+	OK              bool     `json:"ok"`
+}
+
+// PackageFile файл, записанный за установленным пакетом
+type PackageFile struct {
+	Path   string `json:"path"` // относительно каталога установки
+	Size   int64  `json:"size"` // для каталога — суммарный размер содержимого
+	Dir    bool   `json:"dir,omitempty"`
+	Exists bool   `json:"exists"`
+}
+
+// PackageFilesResult файлы установленного пакета и их состояние на диске
+type PackageFilesResult struct {
+	Name        string        `json:"name"`
+	Version     string        `json:"version"`
+	InstallPath string        `json:"install_path"`
+	Files       []PackageFile `json:"files"`
+	TotalSize   int64         `json:"total_size"`
+	Missing     int           `json:"missing"`
+}
+
+// TimeSyncResult результат сравнения локальных часов с часами репозитория
+type TimeSyncResult struct {
+	RepositoryURL string        `json:"repository_url"`
+	LocalTime     time.Time     `json:"local_time"`
+	ServerTime    time.Time     `json:"server_time"`
+	Skew          time.Duration `json:"skew"`
+	RoundTrip     time.Duration `json:"round_trip"`
+}
+
+// CacheCleanResult результат очистки кеша архивов
+type CacheCleanResult struct {
+	Files  int      `json:"files"`
+	Bytes  int64    `json:"bytes"`
+	Paths  []string `json:"paths,omitempty"`
+	DryRun bool     `json:"dry_run"`
+}
+
+// PackageUsage место, занимаемое установленным пакетом
+type PackageUsage struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Global       bool   `json:"global"`
+	Size         int64  `json:"size"`          // по каталогу установки
+	RecordedSize int64  `json:"recorded_size"` // записанный при установке
+}
+
+// ScopeUsage место, занимаемое пакетами одной области установки
+type ScopeUsage struct {
+	Path     string `json:"path"`
+	Packages int    `json:"packages"`
+	Size     int64  `json:"size"`
+}
+
+// DirectoryUsage размер служебного каталога
+type DirectoryUsage struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// DiskUsageResult место на диске, занимаемое criage
+type DiskUsageResult struct {
+	Total   int64          `json:"total"`
+	Global  ScopeUsage     `json:"global"`
+	Local   ScopeUsage     `json:"local"`
+	Cache   DirectoryUsage `json:"cache"`
+	Temp    DirectoryUsage `json:"temp"`
+	Largest []PackageUsage `json:"largest"`
+}
+
+// PackageUpdateResult результат обновления одного пакета
+type PackageUpdateResult struct {
+	Name        string `json:"name"`
+	FromVersion string `json:"from_version"`
+	ToVersion   string `json:"to_version"`
+	Success     bool   `json:"success"`
+	Skipped     bool   `json:"skipped,omitempty"` // пакет закреплен и не обновлялся
+	Error       string `json:"error,omitempty"`
+}
+
+// UpdateAllResult итог обновления всех устаревших пакетов
+type UpdateAllResult struct {
+	Packages []PackageUpdateResult `json:"packages"`
+	Updated  int                   `json:"updated"`
+	Skipped  int                   `json:"skipped"`
+	Failed   int                   `json:"failed"`
+}
+
+// DiagnosticStatus итог отдельной проверки doctor
+type DiagnosticStatus string
+
+const (
+	DiagnosticPass DiagnosticStatus = "pass"
+	DiagnosticWarn DiagnosticStatus = "warn"
+	DiagnosticFail DiagnosticStatus = "fail"
+)
+
+// Diagnostic результат отдельной проверки настройки окружения
+type Diagnostic struct {
+	Check   string           `json:"check"` // config, path, packages_file, install_path или repository
+	Subject string           `json:"subject,omitempty"`
+	Status  DiagnosticStatus `json:"status"`
+	Message string           `json:"message"`
+	Fix     string           `json:"fix,omitempty"` // что сделать, чтобы устранить проблему
+}
+
+// DoctorReport результаты проверок настройки окружения
+type DoctorReport struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Passed      int          `json:"passed"`
+	Warnings    int          `json:"warnings"`
+	Failures    int          `json:"failures"`
+}
+
+// AuditFinding замечание проверки окружения
+type AuditFinding struct {
+	Check    string `json:"check"`    // doctor, verify_all, outdated_packages, find_orphans или license_report
+	Severity string `json:"severity"` // error, warning или info
+	Subject  string `json:"subject,omitempty"`
+	Message  string `json:"message"`
+}
+
+// RepositoryPing результат проверки доступности репозитория
+type RepositoryPing struct {
+	Name       string  `json:"name"`
+	URL        string  `json:"url"`
+	Reachable  bool    `json:"reachable"`
+	StatusCode int     `json:"status_code,omitempty"`
+	LatencyMs  float64 `json:"latency_ms,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// PathStatus состояние каталога из конфигурации
+type PathStatus struct {
+	Name     string `json:"name"` // global_path, local_path, cache_path или temp_path
+	Path     string `json:"path"`
+	Writable bool   `json:"writable"`
+	Error    string `json:"error,omitempty"`
+}
+
+// PingReport результат диагностики связи: доступность репозиториев и каталогов
+type PingReport struct {
+	Repositories []RepositoryPing `json:"repositories"`
+	Paths        []PathStatus     `json:"paths"`
+}
+
+// CompressionCodec алгоритм сжатия, использующие его форматы и допустимые уровни
+type CompressionCodec struct {
+	Name         string   `json:"name"`
+	Formats      []string `json:"formats"`
+	MinLevel     int      `json:"min_level"`
+	MaxLevel     int      `json:"max_level"`
+	DefaultLevel int      `json:"default_level"`
+}
+
+// ServerRepository включенный репозиторий в описании сервера (без токена)
+type ServerRepository struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Priority int    `json:"priority"`
+}
+
+// ServerDescription возможности сервера, доступные клиенту во время работы
+type ServerDescription struct {
+	Name                      string             `json:"name"`
+	Version                   string             `json:"version"`
+	ProtocolVersion           string             `json:"protocol_version,omitempty"` // пусто до initialize
+	SupportedProtocolVersions []string           `json:"supported_protocol_versions"`
+	ArchiveFormats            []string           `json:"archive_formats"`
+	Compression               []CompressionCodec `json:"compression"`
+	Repositories              []ServerRepository `json:"repositories"`
+}
+
+// AuditReport сводный отчет о состоянии окружения
+type AuditReport struct {
+	Findings []AuditFinding `json:"findings"`
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+	Info     int            `json:"info"`
+}
+
+// PackageResource содержимое ресурса criage://packages/<name>
+type PackageResource struct {
+	Package  *PackageInfo     `json:"package"`
+	Manifest *PackageManifest `json:"manifest,omitempty"`
+	Files    []string         `json:"files"`
+}
+
+// ArchiveNamingResult результат проверки имени файла архива
+type ArchiveNamingResult struct {
+	Filename string   `json:"filename"`
+	Expected string   `json:"expected,omitempty"`
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	OS       string   `json:"os,omitempty"`
+	Arch     string   `json:"arch,omitempty"`
+	Format   string   `json:"format"`
+	Problems []string `json:"problems,omitempty"`
+	OK       bool     `json:"ok"`
+}
+
+// SearchIndex локальный поисковый индекс репозитория
+type SearchIndex struct {
+	RepositoryURL string                        `json:"repository_url"`
+	BuiltAt       time.Time                     `json:"built_at"`
+	Packages      map[string]*RepositoryPackage `json:"packages"`
+	Terms         map[string][]string           `json:"terms"`
+}
+
+// SourceAttempt репозиторий, отклоненный при выборе источника пакета
+type SourceAttempt struct {
+	Repository string `json:"repository"`
+	URL        string `json:"url"`
+	Error      string `json:"error"`
+}
+
+// SourceResolution источник, из которого был бы установлен пакет
+type SourceResolution struct {
+	Name          string          `json:"name"`
+	Version       string          `json:"version"`
+	OS            string          `json:"os"`
+	Arch          string          `json:"arch"`
+	Repository    string          `json:"repository"`
+	RepositoryURL string          `json:"repository_url"`
+	Priority      int             `json:"priority"`
+	Filename      string          `json:"filename"`
+	Format        string          `json:"format,omitempty"`
+	Size          int64           `json:"size"`
+	Checksum      string          `json:"checksum"`
+	DownloadURL   string          `json:"download_url"`
+	Skipped       []SourceAttempt `json:"skipped,omitempty"`
+}
+
+// StatePackage установленный пакет в снимке состояния с указанием области
+type StatePackage struct {
+	Scope string `json:"scope"` // global или local
+	PackageInfo
+}
+
+// StateSnapshot снимок установленных пакетов глобальной и локальной областей
+type StateSnapshot struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Packages   []StatePackage `json:"packages"`
+}
+
+// SigningKey ключ подписи, закрепленный за репозиторием
+type SigningKey struct {
+	Repository string `json:"repository"`
+	KeyID      string `json:"key_id"` // идентификатор ключа в виде, который выводит minisign
+	PublicKey  string `json:"public_key"`
+}
+
+// ImportStateResult результат восстановления packages.json из снимка состояния
+type ImportStateResult struct {
+	Global  int      `json:"global"`
+	Local   int      `json:"local"`
+	Missing []string `json:"missing,omitempty"` // пакеты без каталога установки
+}
+
+// IndexChange запись packages.json, удаленная или исправленная при уплотнении индекса
+type IndexChange struct {
+	Scope  string `json:"scope"` // global или local
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// CompactIndexResult результат уплотнения индекса установленных пакетов
+type CompactIndexResult struct {
+	Removed []IndexChange `json:"removed,omitempty"`
+	Fixed   []IndexChange `json:"fixed,omitempty"`
+	Kept    int           `json:"kept"`
+}
+
+// RecoveredPackage пакет, запись о котором восстановлена по манифесту в каталоге установки
+type RecoveredPackage struct {
+	Scope       string `json:"scope"` // global или local
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	InstallPath string `json:"install_path"`
+}
+
+// RepairStateResult результат восстановления packages.json по каталогам установки
+type RepairStateResult struct {
+	Repaired  []string           `json:"repaired"` // переписанные файлы packages.json
+	Recovered []RecoveredPackage `json:"recovered,omitempty"`
+	Skipped   []IndexChange      `json:"skipped,omitempty"` // каталоги, не ставшие записями
+}
+
+// PlanEntry запись плана установки
+type PlanEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Scope   string `json:"scope,omitempty"` // local (по умолчанию) или global
+}
+
+// Статусы шагов выполнения плана
+const (
+	ReplayInstalled = "installed"
+	ReplaySkipped   = "skipped"
+	ReplayFailed    = "failed"
+)
+
+// ReplayStep результат выполнения одной записи плана
+type ReplayStep struct {
+	PlanEntry
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ReplayResult результат выполнения плана установки
+type ReplayResult struct {
+	Steps     []ReplayStep `json:"steps"`
+	Installed int          `json:"installed"`
+	Failed    int          `json:"failed"`
+}
+
+// Действия плана установки
+const (
+	InstallActionInstall   = "install"   // пакет будет установлен
+	InstallActionReplace   = "replace"   // установленная версия будет заменена
+	InstallActionSatisfied = "satisfied" // зависимость уже установлена в подходящей версии
+	InstallActionConflict  = "conflict"  // установлена версия, не удовлетворяющая ограничениям; не заменяется
+)
+
+// InstallAction одно действие установки пакета или проверки его зависимости
+type InstallAction struct {
+	Name             string `json:"name"`
+	Version          string `json:"version"`
+	Action           string `json:"action"`
+	Path             string `json:"path,omitempty"`
+	Dependency       bool   `json:"dependency,omitempty"` // устанавливается автоматически как зависимость
+	Constraint       string `json:"constraint,omitempty"` // итоговые ограничения версии зависимости
+	InstalledVersion string `json:"installed_version,omitempty"`
+	Cached           bool   `json:"cached,omitempty"` // архив уже есть в кеше и не будет скачиваться
+	DownloadURL      string `json:"download_url,omitempty"`
+
+	// Timing длительность этапов выполненной установки (нет при dry_run)
+	Timing *InstallTiming `json:"timing,omitempty"`
+}
+
+// InstallTiming длительность этапов установки в миллисекундах. Хуки манифеста
+// при установке не выполняются, поэтому отдельного этапа для них нет.
+type InstallTiming struct {
+	DownloadMs float64 `json:"download_ms"` // 0, если архив взят из кеша или с диска
+	ExtractMs  float64 `json:"extract_ms"`
+	CopyMs     float64 `json:"copy_ms"`
+	TotalMs    float64 `json:"total_ms"`
+}
+
+// InstallMetrics счетчики установок с момента запуска сервера
+type InstallMetrics struct {
+	Since            time.Time `json:"since"`
+	Installs         int64     `json:"installs"`
+	Failures         int64     `json:"failures"`
+	BytesDownloaded  int64     `json:"bytes_downloaded"`
+	TotalInstallMs   float64   `json:"total_install_ms"`
+	AverageInstallMs float64   `json:"average_install_ms"`
+}
+
+// InstallPlan действия, которые выполняет (или выполнила бы при dry_run) установка пакета
+type InstallPlan struct {
+	DryRun   bool            `json:"dry_run"`
+	Actions  []InstallAction `json:"actions"`
+	Warnings []string        `json:"warnings,omitempty"`
+
+	// Timing суммарная длительность этапов всех установленных пакетов и общее время
+	Timing *InstallTiming `json:"timing,omitempty"`
+}
+
+// RequirerConstraint ограничение версии, наложенное одним пакетом на зависимость
+type RequirerConstraint struct {
+	Requirer   string `json:"requirer"` // имя@версия требующего пакета или корень
+	Constraint string `json:"constraint"`
+}
+
+// ResolvedConstraint итоговые ограничения зависимости и выбранная версия
+type ResolvedConstraint struct {
+	Name        string               `json:"name"`
+	Constraints []RequirerConstraint `json:"constraints"`
+	Combined    string               `json:"combined"`
+	Version     string               `json:"version"`
+}
+
+// ConstraintReport результат разрешения зависимостей корневого пакета
+type ConstraintReport struct {
+	Root         string               `json:"root"`
+	Dependencies []ResolvedConstraint `json:"dependencies"`
+}
+
+// Источники целевой платформы в порядке приоритета
+const (
+	PlatformFromArgument = "argument"
+	PlatformFromConfig   = "config"
+	PlatformFromRuntime  = "runtime"
+)
+
+// PlatformInfo определенная и действующая целевая платформа
+type PlatformInfo struct {
+	DetectedOS   string `json:"detected_os"`
+	DetectedArch string `json:"detected_arch"`
+	DefaultOS    string `json:"default_os,omitempty"`
+	DefaultArch  string `json:"default_arch,omitempty"`
+	OS           string `json:"os"`
+	Arch         string `json:"arch"`
+	OSSource     string `json:"os_source"` // argument, config или runtime
+	ArchSource   string `json:"arch_source"`
+}
+
+// NetworkProfile именованный набор сетевых настроек: частота запросов, таймауты,
+// число повторов и параметры пула соединений
+type NetworkProfile struct {
+	Name                string `json:"name"`
+	RequestsPerSecond   int    `json:"requests_per_second"`
+	Timeout             int    `json:"timeout"` // в секундах
+	MaxRetries          int    `json:"max_retries"`
+	MaxIdleConns        int    `json:"max_idle_conns"`
+	MaxIdleConnsPerHost int    `json:"max_idle_conns_per_host"`
+	IdleConnTimeout     int    `json:"idle_conn_timeout"` // в секундах
+}
+
+// ConfigChange результат изменения параметра конфигурации
+type ConfigChange struct {
+	Key      string      `json:"key"`
+	Previous interface{} `json:"previous"`
+	Value    interface{} `json:"value"`
+}
+
+// ExecutableCheck результат проверки одного исполняемого файла пакета
+type ExecutableCheck struct {
+	Path       string `json:"path"`
+	Exists     bool   `json:"exists"`
+	Executable bool   `json:"executable"`
+	Ran        bool   `json:"ran,omitempty"`    // запуск с --version завершился успешно
+	Output     string `json:"output,omitempty"` // первая строка вывода --version
+	Error      string `json:"error,omitempty"`
+	OK         bool   `json:"ok"`
+}
+
+// ExecutableCheckResult результат проверки исполняемых файлов пакета
+type ExecutableCheckResult struct {
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Executables []ExecutableCheck `json:"executables"`
+	Failed      int               `json:"failed"`
+}
+
+// ScriptResult результат выполнения скрипта из манифеста пакета
+type ScriptResult struct {
+	Package    string `json:"package"`
+	Script     string `json:"script"`
+	Command    string `json:"command"`   // команда после подстановки переменных пакета
+	ExitCode   int    `json:"exit_code"` // -1, если скрипт прерван по времени
+	Output     string `json:"output"`    // общий вывод stdout и stderr
+	Truncated  bool   `json:"truncated,omitempty"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// RepositoryHealth состояние доступности репозитория
+type RepositoryHealth struct {
+	Name                string     `json:"name"`
+	URL                 string     `json:"url"`
+	Enabled             bool       `json:"enabled"`
+	Healthy             bool       `json:"healthy"`
+	Demoted             bool       `json:"demoted"` // временно проверяется после остальных
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	TotalFailures       int        `json:"total_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	DemotedUntil        *time.Time `json:"demoted_until,omitempty"`
+}
+
+// DependencyChange изменение одной зависимости между версиями пакета
+type DependencyChange struct {
+	Name string `json:"name"`
+	From string `json:"from,omitempty"` // ограничение в исходной версии (пусто — зависимость добавлена)
+	To   string `json:"to,omitempty"`   // ограничение в новой версии (пусто — зависимость удалена)
+}
+
+// PlatformFileChange изменение файла пакета для одной платформы
+type PlatformFileChange struct {
+	Platform string          `json:"platform"` // os/arch
+	From     *RepositoryFile `json:"from,omitempty"`
+	To       *RepositoryFile `json:"to,omitempty"`
+}
+
+// VersionDiff различия двух версий пакета в репозитории
+type VersionDiff struct {
+	Name                string               `json:"name"`
+	FromVersion         string               `json:"from_version"`
+	ToVersion           string               `json:"to_version"`
+	AddedDependencies   []DependencyChange   `json:"added_dependencies"`
+	RemovedDependencies []DependencyChange   `json:"removed_dependencies"`
+	ChangedDependencies []DependencyChange   `json:"changed_dependencies"`
+	FromSize            int64                `json:"from_size"`
+	ToSize              int64                `json:"to_size"`
+	SizeDelta           int64                `json:"size_delta"`
+	AddedFiles          []PlatformFileChange `json:"added_files"`
+	RemovedFiles        []PlatformFileChange `json:"removed_files"`
+	ChangedFiles        []PlatformFileChange `json:"changed_files"`
+}
+
+// DependencyNode узел дерева зависимостей пакета
+type DependencyNode struct {
+	Name         string            `json:"name"`
+	Constraint   string            `json:"constraint,omitempty"` // ограничение, наложенное родителем
+	Version      string            `json:"version,omitempty"`    // установленная или подобранная в репозитории версия
+	Installed    bool              `json:"installed"`
+	Conflict     bool              `json:"conflict,omitempty"` // установленная версия не удовлетворяет ограничению
+	Cycle        bool              `json:"cycle,omitempty"`    // пакет уже встречается выше по ветке
+	Missing      bool              `json:"missing,omitempty"`  // пакет не установлен и не найден в репозиториях
+	Error        string            `json:"error,omitempty"`
+	Dependencies []*DependencyNode `json:"dependencies,omitempty"`
+}
+
+// ReverseDependency установленный пакет, зависящий от другого пакета
+type ReverseDependency struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Constraint string `json:"constraint"`
+	Satisfied  bool   `json:"satisfied"` // установленная версия зависимости удовлетворяет ограничению
+}
+
+// WhyInstalledResult причина установки пакета
+type WhyInstalledResult struct {
+	Name       string              `json:"name"`
+	Version    string              `json:"version"`
+	Automatic  bool                `json:"automatic"` // установлен как зависимость, а не пользователем
+	Dependents []ReverseDependency `json:"dependents"`
+}
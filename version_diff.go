@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// DiffVersions сравнивает две версии пакета в репозитории: зависимости, размер
+// и файлы для платформ
+func (pm *PackageManager) DiffVersions(ctx context.Context, repositoryURL, packageName, fromVersion, toVersion string) (*VersionDiff, error) {
+	from, err := pm.GetPackageVersionInfo(ctx, repositoryURL, packageName, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := pm.GetPackageVersionInfo(ctx, repositoryURL, packageName, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &VersionDiff{
+		Name:                packageName,
+		FromVersion:         from.Version,
+		ToVersion:           to.Version,
+		AddedDependencies:   []DependencyChange{},
+		RemovedDependencies: []DependencyChange{},
+		ChangedDependencies: []DependencyChange{},
+		FromSize:            from.Size,
+		ToSize:              to.Size,
+		SizeDelta:           to.Size - from.Size,
+		AddedFiles:          []PlatformFileChange{},
+		RemovedFiles:        []PlatformFileChange{},
+		ChangedFiles:        []PlatformFileChange{},
+	}
+
+	for _, name := range sortedKeys(from.Dependencies) {
+		constraint, ok := to.Dependencies[name]
+		switch {
+		case !ok:
+			diff.RemovedDependencies = append(diff.RemovedDependencies, DependencyChange{Name: name, From: from.Dependencies[name]})
+		case normalizedConstraint(constraint) != normalizedConstraint(from.Dependencies[name]):
+			diff.ChangedDependencies = append(diff.ChangedDependencies, DependencyChange{Name: name, From: from.Dependencies[name], To: constraint})
+		}
+	}
+	for _, name := range sortedKeys(to.Dependencies) {
+		if _, ok := from.Dependencies[name]; !ok {
+			diff.AddedDependencies = append(diff.AddedDependencies, DependencyChange{Name: name, To: to.Dependencies[name]})
+		}
+	}
+
+	fromFiles := filesByPlatform(from.Files)
+	toFiles := filesByPlatform(to.Files)
+	for _, platform := range sortedKeys(fromFiles) {
+		oldFile := fromFiles[platform]
+		newFile, ok := toFiles[platform]
+		switch {
+		case !ok:
+			diff.RemovedFiles = append(diff.RemovedFiles, PlatformFileChange{Platform: platform, From: oldFile})
+		case oldFile.Checksum != newFile.Checksum || oldFile.Size != newFile.Size || oldFile.Format != newFile.Format:
+			diff.ChangedFiles = append(diff.ChangedFiles, PlatformFileChange{Platform: platform, From: oldFile, To: newFile})
+		}
+	}
+	for _, platform := range sortedKeys(toFiles) {
+		if _, ok := fromFiles[platform]; !ok {
+			diff.AddedFiles = append(diff.AddedFiles, PlatformFileChange{Platform: platform, To: toFiles[platform]})
+		}
+	}
+
+	return diff, nil
+}
+
+// filesByPlatform индексирует файлы версии по платформе os/arch
+func filesByPlatform(files []RepositoryFile) map[string]*RepositoryFile {
+	result := make(map[string]*RepositoryFile, len(files))
+	for i := range files {
+		result[fmt.Sprintf("%s/%s", files[i].OS, files[i].Arch)] = &files[i]
+	}
+	return result
+}
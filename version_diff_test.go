@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCompareVersionsTool проверяет разницу зависимостей, размера и файлов двух версий пакета
+func TestCompareVersionsTool(t *testing.T) {
+	versions := map[string]RepositoryVersion{
+		"1.0.0": {
+			Version:      "1.0.0",
+			Dependencies: map[string]string{"lib": "^1.0.0", "legacy": "~0.3", "json": "*"},
+			Size:         4096,
+			Files: []RepositoryFile{
+				{OS: "linux", Arch: "amd64", Filename: "tool-1.0.0-linux-amd64.tar.gz", Size: 2048, Checksum: "sha256:aa"},
+				{OS: "windows", Arch: "386", Filename: "tool-1.0.0-windows-386.tar.gz", Size: 2048, Checksum: "sha256:bb"},
+			},
+		},
+		"2.0.0": {
+			Version:      "2.0.0",
+			Dependencies: map[string]string{"lib": "^2.0.0", "json": "", "yaml": ">=1.2"},
+			Size:         3072,
+			Files: []RepositoryFile{
+				{OS: "linux", Arch: "amd64", Filename: "tool-2.0.0-linux-amd64.tar.gz", Size: 1536, Checksum: "sha256:cc"},
+				{OS: "linux", Arch: "arm64", Filename: "tool-2.0.0-linux-arm64.tar.gz", Size: 1536, Checksum: "sha256:dd"},
+			},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, ok := versions[strings.TrimPrefix(r.URL.Path, "/api/v1/packages/tool/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": version})
+	}))
+	t.Cleanup(server.Close)
+
+	s := newTestServer(t, newTestPackageManager(t))
+	result, err := s.callTool(context.Background(), "compare_versions", map[string]interface{}{
+		"repository_url": server.URL, "name": "tool", "from_version": "1.0.0", "to_version": "2.0.0",
+	})
+	if err != nil {
+		t.Fatalf("compare_versions failed: %v", err)
+	}
+
+	diff := result.StructuredContent.(*VersionDiff)
+	if len(diff.AddedDependencies) != 1 || len(diff.RemovedDependencies) != 1 || len(diff.ChangedDependencies) != 1 {
+		t.Errorf("unexpected dependency diff: %+v", diff)
+	}
+	if diff.SizeDelta != -1024 {
+		t.Errorf("expected size delta -1024, got %d", diff.SizeDelta)
+	}
+
+	text := result.Content[0].Text
+	for _, want := range []string{
+		"tool: 1.0.0 → 2.0.0",
+		"  + yaml >=1.2\n",
+		"  - legacy ~0.3\n",
+		"  ~ lib ^1.0.0 → ^2.0.0\n",
+		"4.0 KB → 3.0 KB (-1.0 KB)",
+		"  + linux/arm64: tool-2.0.0-linux-arm64.tar.gz\n",
+		"  - windows/386: tool-1.0.0-windows-386.tar.gz\n",
+		"  ~ linux/amd64: tool-1.0.0-linux-amd64.tar.gz (2.0 KB) → tool-2.0.0-linux-amd64.tar.gz (1.5 KB)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in diff:\n%s", want, text)
+		}
+	}
+	// "*" и пустое ограничение равнозначны
+	if strings.Contains(text, "json") {
+		t.Errorf("equivalent constraints must not be reported as changed:\n%s", text)
+	}
+
+	if _, err := s.callTool(context.Background(), "compare_versions", map[string]interface{}{
+		"repository_url": server.URL, "name": "tool", "from_version": "1.0.0", "to_version": "3.0.0",
+	}); err == nil {
+		t.Error("expected error for missing version")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Архивы zip собираются и читаются через поток tar: при упаковке записи tar
+// перекладываются в zip, при чтении записи zip выдаются как поток tar. Благодаря
+// этому извлечение, проверка контрольных сумм и чтение манифеста общие для всех форматов.
+
+// Значения старшего байта CreatorVersion, при которых zip хранит права Unix
+const (
+	zipCreatorUnix  = 3
+	zipCreatorMacOS = 19
+)
+
+// maxZipSymlinkSize ограничивает размер записи zip с целью символической ссылки
+const maxZipSymlinkSize = 4096
+
+// zipWriter принимает поток tar и записывает его содержимое в zip
+type zipWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+// newZipWriter создает zip-архив в w; level 1-9 — уровень сжатия deflate
+func newZipWriter(w io.Writer, level int) *zipWriter {
+	pr, pw := io.Pipe()
+	z := &zipWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := writeZipFromTar(w, pr, level)
+		pr.CloseWithError(err)
+		z.done <- err
+	}()
+	return z
+}
+
+func (z *zipWriter) Write(p []byte) (int, error) {
+	return z.pw.Write(p)
+}
+
+// Close завершает zip-архив; нижележащий writer не закрывается
+func (z *zipWriter) Close() error {
+	z.pw.Close()
+	return <-z.done
+}
+
+// writeZipFromTar перекладывает записи потока tar в zip с сохранением прав и времени изменения
+func writeZipFromTar(w io.Writer, r io.Reader, level int) error {
+	zw := zip.NewWriter(w)
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, level)
+	})
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		fh, err := zip.FileInfoHeader(header.FileInfo())
+		if err != nil {
+			return err
+		}
+		fh.Name = header.Name
+		fh.Method = zip.Deflate
+
+		fw, err := zw.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			_, err = io.Copy(fw, tr)
+		case tar.TypeSymlink:
+			// Как в Info-ZIP: содержимое записи символической ссылки — ее цель
+			_, err = io.WriteString(fw, header.Linkname)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// zipAsTar открывает zip-архив и возвращает его записи потоком tar
+func zipAsTar(r io.ReaderAt, size int64) (io.ReadCloser, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTarFromZip(pw, zr))
+	}()
+	return pr, nil
+}
+
+// writeTarFromZip записывает записи zip в поток tar. Права берутся из архива, если
+// он создан в Unix; иначе файлы получают 0644, а каталоги 0755.
+func writeTarFromZip(w io.Writer, zr *zip.Reader) error {
+	tw := tar.NewWriter(w)
+	for _, f := range zr.File {
+		mode := f.Mode()
+		if creator := f.CreatorVersion >> 8; creator != zipCreatorUnix && creator != zipCreatorMacOS {
+			mode = 0644
+			if f.FileInfo().IsDir() {
+				mode = os.ModeDir | 0755
+			}
+		}
+
+		header := &tar.Header{
+			Name:    f.Name,
+			Mode:    int64(mode.Perm()),
+			ModTime: f.Modified,
+		}
+		switch {
+		case mode.IsDir():
+			header.Typeflag = tar.TypeDir
+			if !strings.HasSuffix(header.Name, "/") {
+				header.Name += "/"
+			}
+		case mode&os.ModeSymlink != 0:
+			link, err := readZipEntry(f, maxZipSymlinkSize)
+			if err != nil {
+				return err
+			}
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = string(link)
+		case mode.IsRegular():
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(f.UncompressedSize64)
+		default:
+			// Устройства, каналы и прочие специальные файлы не извлекаются
+			continue
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg {
+			if err := copyZipEntry(tw, f); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+// copyZipEntry копирует содержимое записи zip; размер и CRC проверяются при чтении
+func copyZipEntry(w io.Writer, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	if _, err := io.Copy(w, rc); err != nil {
+		return fmt.Errorf("ошибка чтения %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// readZipEntry читает небольшую запись zip целиком, не более limit байт
+func readZipEntry(f *zip.File, limit int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("запись %s слишком велика: %d байт", f.Name, f.UncompressedSize64)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения %s: %w", f.Name, err)
+	}
+	return data, nil
+}
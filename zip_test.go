@@ -0,0 +1,189 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// snapshotTree описывает каталог: права и содержимое файлов, цели ссылок и вложенные
+// каталоги по относительным путям. Права каталогов при извлечении не сохраняются.
+func snapshotTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+
+	tree := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+
+		entry := info.Mode().String()
+		switch {
+		case info.IsDir():
+			entry = "dir"
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			entry += " -> " + link
+		case info.Mode().IsRegular():
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			entry += " " + string(data)
+		}
+		tree[filepath.ToSlash(rel)] = entry
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir: %v", err)
+	}
+	return tree
+}
+
+// TestZipRoundTrip проверяет, что пакет zip извлекается в идентичное дерево с правами файлов
+func TestZipRoundTrip(t *testing.T) {
+	pm := newTestPackageManager(t)
+	manifest := PackageManifest{Name: "winpkg", Version: "1.0.0"}
+	archivePath := buildTestArchive(t, pm, manifest, map[string]string{
+		"bin/tool.exe":      "MZ binary",
+		"docs/guide/en.txt": "guide",
+		"empty.txt":         "",
+	}, FormatZip)
+
+	// Исходное дерево пересобирается с особыми правами, пустым каталогом и ссылкой
+	srcDir := t.TempDir()
+	if _, err := pm.extractArchive(context.Background(), archivePath, srcDir); err != nil {
+		t.Fatalf("extractArchive: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(srcDir, "bin", "tool.exe"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(srcDir, "docs", "guide", "en.txt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(srcDir, "cache"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("tool.exe", filepath.Join(srcDir, "bin", "tool")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rebuilt := filepath.Join(t.TempDir(), "winpkg-1.0.0.zip")
+	if err := pm.createArchive(srcDir, rebuilt, FormatZip, 9); err != nil {
+		t.Fatalf("createArchive: %v", err)
+	}
+
+	// Метаданные criage — первая запись zip, а контрольные суммы совпадают с содержимым
+	zr, err := zip.OpenReader(rebuilt)
+	if err != nil {
+		t.Fatalf("OpenReader: %v", err)
+	}
+	first := zr.File[0].Name
+	zr.Close()
+	if first != archiveMetadataName {
+		t.Errorf("expected %s as the first zip entry, got %s", archiveMetadataName, first)
+	}
+	metadata, err := verifyArchiveContents(rebuilt)
+	if err != nil || metadata == nil || metadata.CompressionType != FormatZip || metadata.PackageManifest == nil {
+		t.Fatalf("verifyArchiveContents: %+v, %v", metadata, err)
+	}
+	if metadata.PackageManifest.Name != "winpkg" {
+		t.Errorf("unexpected manifest in metadata: %+v", metadata.PackageManifest)
+	}
+
+	destDir := t.TempDir()
+	if _, err := pm.extractArchive(context.Background(), rebuilt, destDir); err != nil {
+		t.Fatalf("extractArchive: %v", err)
+	}
+	if want, got := snapshotTree(t, srcDir), snapshotTree(t, destDir); !reflect.DeepEqual(want, got) {
+		t.Errorf("extracted tree differs:\nwant %v\ngot  %v", want, got)
+	}
+}
+
+// writeTestZip создает zip с указанными записями; для записей без прав Unix
+// CreatorVersion соответствует FAT, как у архивов из Windows
+func writeTestZip(t *testing.T, entries []*zip.FileHeader, contents []string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "crafted.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	for i, header := range entries {
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(contents[i])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestZipExtractionSafety проверяет защиту от выхода за каталог назначения (zip slip)
+// и права по умолчанию для архивов, не хранящих права Unix
+func TestZipExtractionSafety(t *testing.T) {
+	pm := newTestPackageManager(t)
+
+	for _, name := range []string{"../evil.txt", "docs/../../evil.txt", "docs/../../../evil.txt"} {
+		parent := t.TempDir()
+		destDir := filepath.Join(parent, "dest")
+		archivePath := writeTestZip(t, []*zip.FileHeader{{Name: name}}, []string{"evil"})
+		if _, err := pm.extractArchive(context.Background(), archivePath, destDir); err == nil {
+			t.Errorf("%s: expected path traversal to be rejected", name)
+		}
+		if _, err := os.Stat(filepath.Join(parent, "evil.txt")); !os.IsNotExist(err) {
+			t.Errorf("%s: file was written outside the destination", name)
+		}
+	}
+
+	link := &zip.FileHeader{Name: "escape"}
+	link.SetMode(os.ModeSymlink | 0777)
+	archivePath := writeTestZip(t, []*zip.FileHeader{link}, []string{"../../outside"})
+	if _, err := pm.extractArchive(context.Background(), archivePath, t.TempDir()); err == nil {
+		t.Error("expected symlink escaping the destination to be rejected")
+	}
+
+	// Архив без прав Unix: файлы 0644 независимо от атрибутов FAT
+	archivePath = writeTestZip(t, []*zip.FileHeader{{Name: "readme.txt"}, {Name: "dir/"}}, []string{"hello", ""})
+	destDir := t.TempDir()
+	if _, err := pm.extractArchive(context.Background(), archivePath, destDir); err != nil {
+		t.Fatalf("extractArchive: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(destDir, "readme.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0644 {
+		t.Errorf("expected default mode 0644, got %v", info.Mode().Perm())
+	}
+	if info, err := os.Stat(filepath.Join(destDir, "dir")); err != nil || !info.IsDir() {
+		t.Errorf("expected directory entry to be extracted, got %v", err)
+	}
+}